@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/keep-network/keep-common/pkg/persistence"
+
+	"github.com/keep-network/keep-ecdsa/internal/config"
+	"github.com/keep-network/keep-ecdsa/pkg/custody"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/node"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+
+	"github.com/urfave/cli"
+)
+
+// CustodyCommand contains the definition of the custody command-line
+// subcommand.
+var CustodyCommand cli.Command
+
+const custodyDescription = `Starts the custody daemon holding key shares of the
+	operator in the foreground. The daemon is configured in the CustodyDaemon
+	section of the config file. The client configured with the same custody
+	socket and token file delegates key generation and signing to the daemon
+	and relays protocol messages between the daemon and the network.
+
+	The daemon should run as a separate OS user sharing a group with the user
+	running the client. The socket and the token file created by the daemon
+	are accessible by the group so the client can connect.
+
+	Key shares are persisted in the custody data directory and encrypted with
+	the password provided in the KEEP_CUSTODY_PASSWORD environment variable.
+	The password should not be known to the client.`
+
+func init() {
+	CustodyCommand = cli.Command{
+		Name:        "custody",
+		Usage:       `Starts the custody daemon holding key shares in the foreground`,
+		Description: custodyDescription,
+		Action:      StartCustody,
+	}
+}
+
+// StartCustody starts a custody daemon.
+func StartCustody(c *cli.Context) error {
+	config, err := config.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return fmt.Errorf("failed while reading config file: [%v]", err)
+	}
+
+	daemonConfig := config.CustodyDaemon
+	if daemonConfig.Socket == "" {
+		return fmt.Errorf("custody socket is not configured")
+	}
+	if daemonConfig.TokenFile == "" {
+		return fmt.Errorf("custody token file is not configured")
+	}
+	if daemonConfig.DataDir == "" {
+		return fmt.Errorf("custody data directory is not configured")
+	}
+	if daemonConfig.Password == "" {
+		return fmt.Errorf("custody password is not provided")
+	}
+
+	ctx := context.Background()
+
+	token, err := custody.ReadOrCreateToken(daemonConfig.TokenFile)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(daemonConfig.DataDir, 0700); err != nil {
+		return fmt.Errorf(
+			"failed to create custody data directory [%s]: [%v]",
+			daemonConfig.DataDir,
+			err,
+		)
+	}
+
+	handle, err := persistence.NewDiskHandle(daemonConfig.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed while creating a storage disk handler: [%v]", err)
+	}
+
+	keepsRegistry := registry.NewKeepsRegistry(
+		persistence.NewEncryptedPersistence(
+			handle,
+			daemonConfig.Password,
+		),
+	)
+	keepsRegistry.LoadExistingKeeps()
+
+	logger.Infof(
+		"custody daemon holds key shares of [%d] keeps",
+		len(keepsRegistry.GetKeepsAddresses()),
+	)
+
+	preParamsPool := node.NewPreParamsPool(ctx, &config.TSS)
+
+	server := custody.NewServer(
+		keepsRegistry,
+		preParamsPool,
+		config.Retry.Policy(retry.Network),
+		tss.NewMessageLimiter(&config.TSS.MessageLimits, nil),
+		token,
+	)
+
+	listener, err := custody.Listen(daemonConfig.Socket)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("custody daemon listening on [%s]", daemonConfig.Socket)
+
+	return server.Serve(ctx, listener)
+}
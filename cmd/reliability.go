@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/keep-network/keep-ecdsa/internal/config"
+	"github.com/keep-network/keep-ecdsa/pkg/client"
+
+	"github.com/urfave/cli"
+)
+
+// ReliabilityCommand contains the definition of the reliability command-line
+// subcommand.
+var ReliabilityCommand cli.Command
+
+const reliabilityDescription = `Prints the reliability ledger of co-signer operators
+	recorded by the client in the storage data directory. For each operator
+	the number of key generation and signing attempts executed together with
+	the client is listed along with the number of missed announcements, late
+	readiness signals, protocol timeouts, conflicting public keys and
+	protocol sessions in which message limits were exceeded.
+
+	The least reliable operators are listed first.`
+
+func init() {
+	ReliabilityCommand = cli.Command{
+		Name:        "reliability",
+		Usage:       `Prints the reliability report of co-signer operators`,
+		Description: reliabilityDescription,
+		Action:      PrintReliability,
+	}
+}
+
+// PrintReliability prints the reliability report of co-signer operators.
+func PrintReliability(c *cli.Context) error {
+	config, err := config.ReadConfig(c.GlobalString("config"))
+	if err != nil {
+		return fmt.Errorf("failed while reading config file: [%v]", err)
+	}
+
+	reliabilityLedger, err := client.OpenReliabilityLedger(config)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(
+		writer,
+		"OPERATOR\tSCORE\tATTEMPTS\tMISSED ANNOUNCEMENTS\tLATE READINESS\t"+
+			"TIMEOUTS\tCONFLICTING KEYS\tLIMIT VIOLATIONS\tLAST INCIDENT\tUNRELIABLE",
+	)
+
+	for _, record := range reliabilityLedger.Operators() {
+		lastIncident := "-"
+		if !record.LastIncident.IsZero() {
+			lastIncident = record.LastIncident.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(
+			writer,
+			"%s\t%.2f\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%t\n",
+			record.Operator.Hex(),
+			record.Score(),
+			record.Attempts,
+			record.MissedAnnouncements,
+			record.LateReadiness,
+			record.Timeouts,
+			record.ConflictingKeys,
+			record.MessageLimitViolations,
+			lastIncident,
+			record.IsUnreliable(),
+		)
+	}
+
+	return writer.Flush()
+}
@@ -8,18 +8,41 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/keep-network/keep-common/pkg/chain/ethereum"
 	"github.com/keep-network/keep-core/pkg/net/libp2p"
+	"github.com/keep-network/keep-ecdsa/pkg/confirmation"
+	"github.com/keep-network/keep-ecdsa/pkg/custody"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
+	"github.com/keep-network/keep-ecdsa/pkg/lease"
+	"github.com/keep-network/keep-ecdsa/pkg/node"
+	"github.com/keep-network/keep-ecdsa/pkg/policy"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
-const passwordEnvVariable = "KEEP_ETHEREUM_PASSWORD"
+const (
+	passwordEnvVariable = "KEEP_ETHEREUM_PASSWORD"
+
+	// custodyPasswordEnvVariable is the name of the environment variable
+	// holding the secret with which the custody daemon encrypts key shares.
+	custodyPasswordEnvVariable = "KEEP_CUSTODY_PASSWORD"
+)
 
 // Config is the top level config structure.
 type Config struct {
 	Ethereum               ethereum.Config
+	Operators              []OperatorAccount
 	SanctionedApplications SanctionedApplications
 	Storage                Storage
+	Lease                  lease.Config
+	Custody                custody.Config
+	CustodyDaemon          custody.DaemonConfig
+	Index                  index.Config
 	LibP2P                 libp2p.Config
 	TSS                    tss.Config
+	Scheduler              node.SchedulerConfig
+	Heartbeat              node.HeartbeatConfig
+	Confirmations          confirmation.Config
+	Retry                  retry.Config
+	SigningPolicy          policy.Config
 	Metrics                Metrics
 }
 
@@ -47,6 +70,30 @@ func (sa *SanctionedApplications) Addresses() ([]common.Address, error) {
 	return applicationsAddresses, nil
 }
 
+// OperatorAccount is an operator account run by the client in addition to
+// the account from the Ethereum section. Additional operators share the
+// network host and TSS pre-parameters pool with the main operator but are
+// registered, take part in key generation and store key material separately.
+type OperatorAccount struct {
+	ethereum.Account
+
+	// PasswordEnvVariable is the name of the environment variable holding
+	// the key file password. If not set, the password of the main operator
+	// account is used.
+	PasswordEnvVariable string
+}
+
+// Accounts returns the main operator account followed by additional operator
+// accounts.
+func (c *Config) Accounts() []ethereum.Account {
+	accounts := []ethereum.Account{c.Ethereum.Account}
+	for _, operator := range c.Operators {
+		accounts = append(accounts, operator.Account)
+	}
+
+	return accounts
+}
+
 // Storage stores meta-info about keeping data on disk
 type Storage struct {
 	DataDir string
@@ -54,13 +101,15 @@ type Storage struct {
 
 // Metrics stores meta-info about metrics.
 type Metrics struct {
-	Port                int
-	NetworkMetricsTick  int
-	EthereumMetricsTick int
+	Port                 int
+	NetworkMetricsTick   int
+	EthereumMetricsTick  int
+	SchedulerMetricsTick int
 }
 
 // ReadConfig reads in the configuration file in .toml format. Ethereum key file
-// password is expected to be provided as environment variable.
+// passwords and the custody daemon password are expected to be provided as
+// environment variables.
 func ReadConfig(filePath string) (*Config, error) {
 	config := &Config{}
 	if _, err := toml.DecodeFile(filePath, config); err != nil {
@@ -69,6 +118,17 @@ func ReadConfig(filePath string) (*Config, error) {
 
 	config.Ethereum.Account.KeyFilePassword = os.Getenv(passwordEnvVariable)
 
+	for i, operator := range config.Operators {
+		envVariable := operator.PasswordEnvVariable
+		if envVariable == "" {
+			envVariable = passwordEnvVariable
+		}
+
+		config.Operators[i].KeyFilePassword = os.Getenv(envVariable)
+	}
+
+	config.CustodyDaemon.Password = os.Getenv(custodyPasswordEnvVariable)
+
 	return config, nil
 }
 
@@ -59,6 +59,8 @@ func main() {
 	}
 	app.Commands = []cli.Command{
 		cmd.StartCommand,
+		cmd.CustodyCommand,
+		cmd.ReliabilityCommand,
 		cmd.EthereumCommand,
 	}
 
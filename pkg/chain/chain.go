@@ -20,6 +20,11 @@ type Handle interface {
 	StakeMonitor() (chain.StakeMonitor, error)
 	// BlockCounter returns a block counter.
 	BlockCounter() chain.BlockCounter
+	// BlockHash returns the hash of the block with the given number which is
+	// currently a part of the canonical chain.
+	BlockHash(blockNumber uint64) (common.Hash, error)
+	// BlockTimestamp returns the timestamp of the block with the given number.
+	BlockTimestamp(blockNumber uint64) (time.Time, error)
 
 	BondedECDSAKeepFactory
 	BondedECDSAKeep
@@ -63,6 +68,13 @@ type BondedECDSAKeepFactory interface {
 
 	// GetKeepAtIndex returns the address of the keep at the given index.
 	GetKeepAtIndex(keepIndex *big.Int) (common.Address, error)
+
+	// PastBondedECDSAKeepCreatedEvents returns all keep creation events
+	// emitted by the factory between the given start and end block, inclusive.
+	PastBondedECDSAKeepCreatedEvents(
+		startBlock uint64,
+		endBlock uint64,
+	) ([]*BondedECDSAKeepCreatedEvent, error)
 }
 
 // BondedECDSAKeep is an interface that provides ability to interact with
@@ -143,4 +155,32 @@ type BondedECDSAKeep interface {
 
 	// GetOpenedTimestamp returns timestamp when the keep was created.
 	GetOpenedTimestamp(keepAddress common.Address) (time.Time, error)
+
+	// PastSignatureRequestedEvents returns signature requested events emitted
+	// by the given keep between the given start and end block, inclusive.
+	PastSignatureRequestedEvents(
+		keepAddress common.Address,
+		startBlock uint64,
+		endBlock uint64,
+	) ([]*SignatureRequestedEvent, error)
+
+	// PastKeepClosedEvents returns closed events emitted between the given
+	// start and end block, inclusive. If keep addresses are provided, only
+	// events emitted by those keeps are returned. Otherwise, events emitted
+	// by any keep are returned.
+	PastKeepClosedEvents(
+		startBlock uint64,
+		endBlock uint64,
+		keepAddresses ...common.Address,
+	) ([]*KeepClosedEvent, error)
+
+	// PastKeepTerminatedEvents returns terminated events emitted between
+	// the given start and end block, inclusive. If keep addresses are provided,
+	// only events emitted by those keeps are returned. Otherwise, events
+	// emitted by any keep are returned.
+	PastKeepTerminatedEvents(
+		startBlock uint64,
+		endBlock uint64,
+		keepAddresses ...common.Address,
+	) ([]*KeepTerminatedEvent, error)
 }
@@ -3,9 +3,11 @@ package ethereum
 import (
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 	"time"
 
+	ethereumabi "github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/ethclient"
 
@@ -13,7 +15,9 @@ import (
 	"github.com/keep-network/keep-common/pkg/chain/ethereum/blockcounter"
 	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/gen/abi"
 	"github.com/keep-network/keep-ecdsa/pkg/chain/gen/contract"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
 var (
@@ -37,9 +41,12 @@ type EthereumChain struct {
 	accountKey                     *keystore.Key
 	client                         *ethclient.Client
 	bondedECDSAKeepFactoryContract *contract.BondedECDSAKeepFactory
+	bondedECDSAKeepFactoryFilterer *abi.BondedECDSAKeepFactoryFilterer
+	bondedECDSAKeepABI             *ethereumabi.ABI
 	blockCounter                   *blockcounter.EthereumBlockCounter
 	miningWaiter                   *ethutil.MiningWaiter
 	nonceManager                   *ethutil.NonceManager
+	submissionRetryPolicy          *retry.Policy
 
 	// transactionMutex allows interested parties to forcibly serialize
 	// transaction submission.
@@ -57,8 +64,13 @@ type EthereumChain struct {
 }
 
 // Connect performs initialization for communication with Ethereum blockchain
-// based on provided config.
-func Connect(accountKey *keystore.Key, config *ethereum.Config) (eth.Handle, error) {
+// based on provided config. Failed transaction submissions are retried
+// according to the chain submission policy from the provided retry config.
+func Connect(
+	accountKey *keystore.Key,
+	config *ethereum.Config,
+	retryConfig *retry.Config,
+) (eth.Handle, error) {
 	client, err := ethclient.Dial(config.URL)
 	if err != nil {
 		return nil, err
@@ -100,6 +112,21 @@ func Connect(accountKey *keystore.Key, config *ethereum.Config) (eth.Handle, err
 		return nil, err
 	}
 
+	bondedECDSAKeepFactoryFilterer, err := abi.NewBondedECDSAKeepFactoryFilterer(
+		*bondedECDSAKeepFactoryContractAddress,
+		client,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bondedECDSAKeepABI, err := ethereumabi.JSON(
+		strings.NewReader(abi.BondedECDSAKeepABI),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keep contract abi: [%v]", err)
+	}
+
 	blockCounter, err := blockcounter.CreateBlockCounter(client)
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -113,9 +140,12 @@ func Connect(accountKey *keystore.Key, config *ethereum.Config) (eth.Handle, err
 		accountKey:                     accountKey,
 		client:                         client,
 		bondedECDSAKeepFactoryContract: bondedECDSAKeepFactoryContract,
+		bondedECDSAKeepFactoryFilterer: bondedECDSAKeepFactoryFilterer,
+		bondedECDSAKeepABI:             &bondedECDSAKeepABI,
 		blockCounter:                   blockCounter,
 		nonceManager:                   nonceManager,
 		miningWaiter:                   miningWaiter,
 		transactionMutex:               transactionMutex,
+		submissionRetryPolicy:          retryConfig.Policy(retry.ChainSubmission),
 	}, nil
 }
@@ -2,11 +2,15 @@
 package ethereum
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"time"
 
+	goethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/ipfs/go-log"
 
@@ -16,6 +20,7 @@ import (
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
 	"github.com/keep-network/keep-ecdsa/pkg/chain/gen/contract"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 	"github.com/keep-network/keep-ecdsa/pkg/utils/byteutils"
 )
 
@@ -58,7 +63,9 @@ func (ec *EthereumChain) OnBondedECDSAKeepCreated(
 			handler(&eth.BondedECDSAKeepCreatedEvent{
 				KeepAddress:     KeepAddress,
 				Members:         Members,
+				Application:     Application,
 				HonestThreshold: HonestThreshold.Uint64(),
+				BlockNumber:     blockNumber,
 			})
 		},
 		func(err error) error {
@@ -81,7 +88,10 @@ func (ec *EthereumChain) OnKeepClosed(
 	}
 	return keepContract.WatchKeepClosed(
 		func(blockNumber uint64) {
-			handler(&eth.KeepClosedEvent{BlockNumber: blockNumber})
+			handler(&eth.KeepClosedEvent{
+				KeepAddress: keepAddress,
+				BlockNumber: blockNumber,
+			})
 		},
 		func(err error) error {
 			return fmt.Errorf("keep closed callback failed: [%v]", err)
@@ -101,7 +111,10 @@ func (ec *EthereumChain) OnKeepTerminated(
 	}
 	return keepContract.WatchKeepTerminated(
 		func(blockNumber uint64) {
-			handler(&eth.KeepTerminatedEvent{BlockNumber: blockNumber})
+			handler(&eth.KeepTerminatedEvent{
+				KeepAddress: keepAddress,
+				BlockNumber: blockNumber,
+			})
 		},
 		func(err error) error {
 			return fmt.Errorf("keep terminated callback failed: [%v]", err)
@@ -222,7 +235,7 @@ func (ec *EthereumChain) SubmitKeepPublicKey(
 	// a new cloned contract has not been registered by the ethereum node. Common
 	// case is when Ethereum nodes are behind a load balancer and not fully synced
 	// with each other. To mitigate this issue, a client will retry submitting
-	// a public key up to 4 times with a 250ms interval.
+	// a public key according to the chain submission retry policy.
 	if err := ec.withRetry(submitPubKey); err != nil {
 		return err
 	}
@@ -231,21 +244,17 @@ func (ec *EthereumChain) SubmitKeepPublicKey(
 }
 
 func (ec *EthereumChain) withRetry(fn func() error) error {
-	const numberOfRetries = 10
-	const delay = 12 * time.Second
+	attempt := 0
+	return retry.Do(context.Background(), ec.submissionRetryPolicy, func() error {
+		attempt++
 
-	for i := 1; ; i++ {
 		err := fn()
 		if err != nil {
-			logger.Errorf("Error occurred [%v]; on [%v] retry", err, i)
-			if i == numberOfRetries {
-				return err
-			}
-			time.Sleep(delay)
-		} else {
-			return nil
+			logger.Errorf("Error occurred [%v]; on [%v] attempt", err, attempt)
 		}
-	}
+
+		return err
+	})
 }
 
 func (ec *EthereumChain) getKeepContract(address common.Address) (*contract.BondedECDSAKeep, error) {
@@ -337,6 +346,41 @@ func (ec *EthereumChain) BlockCounter() chain.BlockCounter {
 	return ec.blockCounter
 }
 
+// BlockHash returns the hash of the block with the given number which is
+// currently a part of the canonical chain.
+func (ec *EthereumChain) BlockHash(blockNumber uint64) (common.Hash, error) {
+	header, err := ec.client.HeaderByNumber(
+		context.Background(),
+		new(big.Int).SetUint64(blockNumber),
+	)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf(
+			"failed to get header of block [%v]: [%v]",
+			blockNumber,
+			err,
+		)
+	}
+
+	return header.Hash(), nil
+}
+
+// BlockTimestamp returns the timestamp of the block with the given number.
+func (ec *EthereumChain) BlockTimestamp(blockNumber uint64) (time.Time, error) {
+	header, err := ec.client.HeaderByNumber(
+		context.Background(),
+		new(big.Int).SetUint64(blockNumber),
+	)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"failed to get header of block [%v]: [%v]",
+			blockNumber,
+			err,
+		)
+	}
+
+	return time.Unix(int64(header.Time), 0), nil
+}
+
 // IsRegisteredForApplication checks if the operator is registered
 // as a signer candidate in the factory for the given application.
 func (ec *EthereumChain) IsRegisteredForApplication(application common.Address) (bool, error) {
@@ -487,3 +531,183 @@ func (ec *EthereumChain) GetOpenedTimestamp(keepAddress common.Address) (time.Ti
 
 	return keepOpenTime, nil
 }
+
+// PastBondedECDSAKeepCreatedEvents returns all keep creation events emitted
+// by the factory between the given start and end block, inclusive.
+func (ec *EthereumChain) PastBondedECDSAKeepCreatedEvents(
+	startBlock uint64,
+	endBlock uint64,
+) ([]*eth.BondedECDSAKeepCreatedEvent, error) {
+	iterator, err := ec.bondedECDSAKeepFactoryFilterer.FilterBondedECDSAKeepCreated(
+		&bind.FilterOpts{
+			Start: startBlock,
+			End:   &endBlock,
+		},
+		nil,
+		nil,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to filter keep created events: [%v]",
+			err,
+		)
+	}
+	defer iterator.Close()
+
+	events := make([]*eth.BondedECDSAKeepCreatedEvent, 0)
+	for iterator.Next() {
+		event := iterator.Event
+		events = append(events, &eth.BondedECDSAKeepCreatedEvent{
+			KeepAddress:     event.KeepAddress,
+			Members:         event.Members,
+			Application:     event.Application,
+			HonestThreshold: event.HonestThreshold.Uint64(),
+			BlockNumber:     event.Raw.BlockNumber,
+			BlockHash:       event.Raw.BlockHash,
+		})
+	}
+
+	if err := iterator.Error(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to iterate over keep created events: [%v]",
+			err,
+		)
+	}
+
+	return events, nil
+}
+
+// PastSignatureRequestedEvents returns signature requested events emitted
+// by the given keep between the given start and end block, inclusive.
+func (ec *EthereumChain) PastSignatureRequestedEvents(
+	keepAddress common.Address,
+	startBlock uint64,
+	endBlock uint64,
+) ([]*eth.SignatureRequestedEvent, error) {
+	logs, err := ec.filterKeepLogs(
+		"SignatureRequested",
+		startBlock,
+		endBlock,
+		[]common.Address{keepAddress},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*eth.SignatureRequestedEvent, 0, len(logs))
+	for _, log := range logs {
+		// Digest is the only, indexed parameter of the event so it is
+		// the second topic, right after the event signature.
+		if len(log.Topics) != 2 {
+			return nil, fmt.Errorf(
+				"unexpected number of signature requested event topics: [%v]",
+				len(log.Topics),
+			)
+		}
+
+		events = append(events, &eth.SignatureRequestedEvent{
+			Digest:      log.Topics[1],
+			BlockNumber: log.BlockNumber,
+			BlockHash:   log.BlockHash,
+		})
+	}
+
+	return events, nil
+}
+
+// PastKeepClosedEvents returns closed events emitted between the given start
+// and end block, inclusive. If keep addresses are provided, only events
+// emitted by those keeps are returned.
+func (ec *EthereumChain) PastKeepClosedEvents(
+	startBlock uint64,
+	endBlock uint64,
+	keepAddresses ...common.Address,
+) ([]*eth.KeepClosedEvent, error) {
+	logs, err := ec.filterKeepLogs(
+		"KeepClosed",
+		startBlock,
+		endBlock,
+		keepAddresses,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*eth.KeepClosedEvent, len(logs))
+	for i, log := range logs {
+		events[i] = &eth.KeepClosedEvent{
+			KeepAddress: log.Address,
+			BlockNumber: log.BlockNumber,
+			BlockHash:   log.BlockHash,
+		}
+	}
+
+	return events, nil
+}
+
+// PastKeepTerminatedEvents returns terminated events emitted between the given
+// start and end block, inclusive. If keep addresses are provided, only events
+// emitted by those keeps are returned.
+func (ec *EthereumChain) PastKeepTerminatedEvents(
+	startBlock uint64,
+	endBlock uint64,
+	keepAddresses ...common.Address,
+) ([]*eth.KeepTerminatedEvent, error) {
+	logs, err := ec.filterKeepLogs(
+		"KeepTerminated",
+		startBlock,
+		endBlock,
+		keepAddresses,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*eth.KeepTerminatedEvent, len(logs))
+	for i, log := range logs {
+		events[i] = &eth.KeepTerminatedEvent{
+			KeepAddress: log.Address,
+			BlockNumber: log.BlockNumber,
+			BlockHash:   log.BlockHash,
+		}
+	}
+
+	return events, nil
+}
+
+// filterKeepLogs fetches logs of the given keep contract event emitted between
+// the given start and end block, inclusive. Keep contracts are clones created
+// by the factory so when no addresses are provided, logs emitted by any
+// address are fetched. In such case, it is up to the caller to ignore logs
+// emitted by addresses which are not known keeps.
+func (ec *EthereumChain) filterKeepLogs(
+	eventName string,
+	startBlock uint64,
+	endBlock uint64,
+	addresses []common.Address,
+) ([]types.Log, error) {
+	event, ok := ec.bondedECDSAKeepABI.Events[eventName]
+	if !ok {
+		return nil, fmt.Errorf("unknown keep event [%v]", eventName)
+	}
+
+	logs, err := ec.client.FilterLogs(
+		context.Background(),
+		goethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(startBlock),
+			ToBlock:   new(big.Int).SetUint64(endBlock),
+			Addresses: addresses,
+			Topics:    [][]common.Hash{{event.ID()}},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to filter [%v] events: [%v]",
+			eventName,
+			err,
+		)
+	}
+
+	return logs, nil
+}
@@ -0,0 +1,302 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	ethereumabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/keep-network/keep-common/pkg/chain/ethereum"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/ethereum/ethtest"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/gen/abi"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+var testMaxAttempts = 5
+
+var testRetryConfig = &retry.Config{
+	Default: retry.PolicyConfig{
+		InitialInterval: &retry.Duration{Duration: 100 * time.Millisecond},
+		MaxInterval:     &retry.Duration{Duration: 500 * time.Millisecond},
+		MaxAttempts:     &testMaxAttempts,
+	},
+}
+
+func TestConnectInjectedFailure(t *testing.T) {
+	server, err := ethtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	server.FailRequests("eth_getBlockByNumber", 1)
+
+	if _, err := Connect(newAccountKey(t), testConfig(server), testRetryConfig); err == nil {
+		t.Fatal("expected connection failure")
+	}
+
+	if _, err := Connect(newAccountKey(t), testConfig(server), testRetryConfig); err != nil {
+		t.Fatalf("unexpected error after injected failure: [%v]", err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	server, chain := connect(t)
+	defer server.Close()
+
+	server.Mine()
+
+	if err := waitForBlock(chain, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	firstBlockHash, err := chain.BlockHash(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstBlockTimestamp, err := chain.BlockTimestamp(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Mine()
+
+	if err := waitForBlock(chain, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	secondBlockHash, err := chain.BlockHash(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondBlockTimestamp, err := chain.BlockTimestamp(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if firstBlockHash == secondBlockHash {
+		t.Errorf("blocks have the same hash [%x]", firstBlockHash)
+	}
+	if !secondBlockTimestamp.After(firstBlockTimestamp) {
+		t.Errorf(
+			"block timestamps are not increasing: [%v] and [%v]",
+			firstBlockTimestamp,
+			secondBlockTimestamp,
+		)
+	}
+}
+
+func TestBlockCounterResubscribes(t *testing.T) {
+	server, chain := connect(t)
+	defer server.Close()
+
+	server.DropConnections()
+
+	// The block counter resubscribes to new blocks with a delay, so blocks
+	// are mined until the counter notices one of them.
+	timeout := time.After(15 * time.Second)
+	for {
+		server.Mine()
+
+		if err := waitForBlock(chain, server.BlockNumber()); err == nil {
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("block counter has not resubscribed to new blocks")
+		default:
+		}
+	}
+}
+
+func TestKeepLifecycle(t *testing.T) {
+	skipWithoutContractBindings(t)
+
+	server, chain := connect(t)
+	defer server.Close()
+
+	application := server.Application()
+	server.AuthorizeOperator(chain.Address())
+	server.SetStake(chain.Address(), ethtest.DefaultMinimumStake)
+
+	if err := chain.RegisterAsMemberCandidate(application); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "operator is not registered", func() (bool, error) {
+		return chain.IsRegisteredForApplication(application)
+	})
+
+	keepCreated := make(chan *eth.BondedECDSAKeepCreatedEvent, 1)
+	subscription, err := chain.OnBondedECDSAKeepCreated(
+		func(event *eth.BondedECDSAKeepCreatedEvent) {
+			keepCreated <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	keepAddress, err := server.OpenKeep(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-keepCreated:
+		if event.KeepAddress != keepAddress {
+			t.Errorf("unexpected keep address [%s]", event.KeepAddress.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("keep created event has not been received")
+	}
+
+	signingKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var publicKey [64]byte
+	copy(publicKey[:], crypto.FromECDSAPub(&signingKey.PublicKey)[1:])
+
+	// Failed submissions are retried.
+	server.FailRequests("eth_sendRawTransaction", 2)
+
+	if err := chain.SubmitKeepPublicKey(keepAddress, publicKey); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "public key has not been published", func() (bool, error) {
+		publishedPublicKey, err := chain.GetPublicKey(keepAddress)
+		return len(publishedPublicKey) == len(publicKey), err
+	})
+
+	// The signature request is emitted when subscriptions are dropped and
+	// can be found among past events.
+	digest := [32]byte{0x1}
+	server.DropConnections()
+	if err := server.RequestSignature(keepAddress, digest); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "signature request has not been found", func() (bool, error) {
+		events, err := chain.PastSignatureRequestedEvents(
+			keepAddress,
+			0,
+			server.BlockNumber(),
+		)
+		return len(events) == 1 && events[0].Digest == digest, err
+	})
+
+	// The signature transaction is stuck until it is resubmitted with
+	// a higher gas price.
+	server.SetMinimumGasPrice(
+		new(big.Int).Mul(ethtest.DefaultGasPrice, big.NewInt(2)),
+	)
+
+	signature, err := crypto.Sign(digest[:], signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.SubmitSignature(keepAddress, &ecdsa.Signature{
+		R:          new(big.Int).SetBytes(signature[:32]),
+		S:          new(big.Int).SetBytes(signature[32:64]),
+		RecoveryID: int(signature[64]),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.PendingTransactions()) != 1 {
+		t.Fatal("signature transaction is not pending")
+	}
+	eventually(t, "signature has not been accepted", func() (bool, error) {
+		isAwaitingSignature, err := chain.IsAwaitingSignature(keepAddress, digest)
+		return !isAwaitingSignature, err
+	})
+}
+
+func skipWithoutContractBindings(t *testing.T) {
+	keepABI, err := ethereumabi.JSON(strings.NewReader(abi.BondedECDSAKeepABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keepABI.Methods) == 0 {
+		t.Skip("contract bindings have not been generated")
+	}
+}
+
+func connect(t *testing.T) (*ethtest.Server, eth.Handle) {
+	server, err := ethtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := Connect(newAccountKey(t), testConfig(server), testRetryConfig)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return server, chain
+}
+
+func testConfig(server *ethtest.Server) *ethereum.Config {
+	return &ethereum.Config{
+		URL: server.URL(),
+		ContractAddresses: map[string]string{
+			BondedECDSAKeepFactoryContractName: server.FactoryAddress().Hex(),
+		},
+		MiningCheckInterval: 1,
+	}
+}
+
+func newAccountKey(t *testing.T) *keystore.Key {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &keystore.Key{
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+}
+
+func waitForBlock(chain eth.Handle, blockNumber uint64) error {
+	waiter, err := chain.BlockCounter().BlockHeightWaiter(blockNumber)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-waiter:
+		return nil
+	case <-time.After(time.Second):
+		return fmt.Errorf("block [%v] has not been seen", blockNumber)
+	}
+}
+
+// eventually checks the condition until it is met, failing the test if it is
+// not met within a few seconds. Errors are treated as unmet conditions, as
+// the client may be reconnecting.
+func eventually(t *testing.T, message string, condition func() (bool, error)) {
+	timeout := time.After(10 * time.Second)
+	for {
+		isMet, err := condition()
+		if err == nil && isMet {
+			return
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("%s; last error: [%v]", message, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package ethtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// subscriptionBuffer is the number of notifications buffered for each
+// subscription.
+const subscriptionBuffer = 128
+
+// errorSelector is the selector of Error(string) used to encode revert
+// reasons.
+var errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+type callArgs struct {
+	From     *common.Address `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     hexutil.Bytes   `json:"data"`
+}
+
+type filterArgs struct {
+	BlockHash *common.Hash     `json:"blockHash"`
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+// matches checks if the log matches addresses and topics of the filter.
+// Block range is not taken into account.
+func (fa *filterArgs) matches(log *types.Log) bool {
+	if len(fa.Addresses) > 0 {
+		found := false
+		for _, address := range fa.Addresses {
+			if address == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(fa.Topics) > len(log.Topics) {
+		return false
+	}
+
+	for i, alternatives := range fa.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+
+		found := false
+		for _, topic := range alternatives {
+			if topic == log.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ethAPI serves methods of the eth namespace.
+type ethAPI struct {
+	server *Server
+}
+
+func (api *ethAPI) ChainId() (*hexutil.Big, error) {
+	if err := api.server.injectedFailure("eth_chainId"); err != nil {
+		return nil, err
+	}
+
+	return (*hexutil.Big)(DefaultChainID), nil
+}
+
+func (api *ethAPI) BlockNumber() (hexutil.Uint64, error) {
+	if err := api.server.injectedFailure("eth_blockNumber"); err != nil {
+		return 0, err
+	}
+
+	return hexutil.Uint64(api.server.BlockNumber()), nil
+}
+
+func (api *ethAPI) GetBlockByNumber(
+	number rpc.BlockNumber,
+	fullTransactions bool,
+) (map[string]interface{}, error) {
+	if err := api.server.injectedFailure("eth_getBlockByNumber"); err != nil {
+		return nil, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	block := api.server.blockByNumber(number)
+	if block == nil {
+		return nil, nil
+	}
+
+	return api.server.marshalBlock(block, fullTransactions)
+}
+
+func (api *ethAPI) GetBlockByHash(
+	hash common.Hash,
+	fullTransactions bool,
+) (map[string]interface{}, error) {
+	if err := api.server.injectedFailure("eth_getBlockByHash"); err != nil {
+		return nil, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	for _, block := range api.server.blocks {
+		if block.Hash() == hash {
+			return api.server.marshalBlock(block, fullTransactions)
+		}
+	}
+
+	return nil, nil
+}
+
+func (api *ethAPI) GetTransactionCount(
+	account common.Address,
+	number rpc.BlockNumber,
+) (hexutil.Uint64, error) {
+	if err := api.server.injectedFailure("eth_getTransactionCount"); err != nil {
+		return 0, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	if number == rpc.PendingBlockNumber {
+		return hexutil.Uint64(api.server.pendingNonce(account)), nil
+	}
+
+	return hexutil.Uint64(api.server.nonces[account]), nil
+}
+
+func (api *ethAPI) GetCode(
+	address common.Address,
+	number rpc.BlockNumber,
+) (hexutil.Bytes, error) {
+	if err := api.server.injectedFailure("eth_getCode"); err != nil {
+		return nil, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	if _, ok := api.server.contracts[address]; ok {
+		return contractCode, nil
+	}
+
+	return hexutil.Bytes{}, nil
+}
+
+func (api *ethAPI) GasPrice() (*hexutil.Big, error) {
+	if err := api.server.injectedFailure("eth_gasPrice"); err != nil {
+		return nil, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	return (*hexutil.Big)(api.server.gasPrice), nil
+}
+
+// Call executes the call against the latest state. A reverted call returns
+// the encoded revert reason the same way as Ethereum nodes do.
+func (api *ethAPI) Call(
+	args callArgs,
+	number rpc.BlockNumber,
+) (hexutil.Bytes, error) {
+	if err := api.server.injectedFailure("eth_call"); err != nil {
+		return nil, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	output, err := api.server.call(args)
+	if err != nil {
+		if revert, ok := err.(revertError); ok {
+			return encodeRevertReason(revert.reason)
+		}
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func (api *ethAPI) EstimateGas(args callArgs) (hexutil.Uint64, error) {
+	if err := api.server.injectedFailure("eth_estimateGas"); err != nil {
+		return 0, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	if _, err := api.server.call(args); err != nil {
+		return 0, fmt.Errorf(
+			"gas required exceeds allowance (%d) or always failing transaction",
+			blockGasLimit,
+		)
+	}
+
+	return transactionGasUsed, nil
+}
+
+func (api *ethAPI) SendRawTransaction(encoded hexutil.Bytes) (common.Hash, error) {
+	if err := api.server.injectedFailure("eth_sendRawTransaction"); err != nil {
+		return common.Hash{}, err
+	}
+
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encoded, tx); err != nil {
+		return common.Hash{}, err
+	}
+
+	if err := api.server.submit(tx); err != nil {
+		return common.Hash{}, err
+	}
+
+	return tx.Hash(), nil
+}
+
+func (api *ethAPI) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error) {
+	if err := api.server.injectedFailure("eth_getTransactionReceipt"); err != nil {
+		return nil, err
+	}
+
+	return api.server.Receipt(hash), nil
+}
+
+func (api *ethAPI) GetLogs(args filterArgs) ([]*types.Log, error) {
+	if err := api.server.injectedFailure("eth_getLogs"); err != nil {
+		return nil, err
+	}
+
+	api.server.mutex.Lock()
+	defer api.server.mutex.Unlock()
+
+	fromBlock, toBlock := uint64(0), api.server.latestBlock().NumberU64()
+	if args.FromBlock != nil && *args.FromBlock >= 0 {
+		fromBlock = uint64(*args.FromBlock)
+	}
+	if args.ToBlock != nil && *args.ToBlock >= 0 {
+		toBlock = uint64(*args.ToBlock)
+	}
+
+	logs := make([]*types.Log, 0)
+	for _, log := range api.server.logs {
+		if args.BlockHash != nil {
+			if log.BlockHash != *args.BlockHash {
+				continue
+			}
+		} else if log.BlockNumber < fromBlock || log.BlockNumber > toBlock {
+			continue
+		}
+
+		if args.matches(log) {
+			logs = append(logs, log)
+		}
+	}
+
+	return logs, nil
+}
+
+func (api *ethAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	if err := api.server.injectedFailure("eth_subscribe"); err != nil {
+		return nil, err
+	}
+
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSubscription := notifier.CreateSubscription()
+	headers := make(chan *types.Header, subscriptionBuffer)
+	subscription := api.server.headFeed.Subscribe(headers)
+
+	go func() {
+		defer subscription.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				if err := notifier.Notify(rpcSubscription.ID, header); err != nil {
+					logger.Debugf("could not notify about new head: [%v]", err)
+				}
+			case <-rpcSubscription.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSubscription, nil
+}
+
+func (api *ethAPI) Logs(
+	ctx context.Context,
+	args filterArgs,
+) (*rpc.Subscription, error) {
+	if err := api.server.injectedFailure("eth_subscribe"); err != nil {
+		return nil, err
+	}
+
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSubscription := notifier.CreateSubscription()
+	logs := make(chan []*types.Log, subscriptionBuffer)
+	subscription := api.server.logsFeed.Subscribe(logs)
+
+	go func() {
+		defer subscription.Unsubscribe()
+
+		for {
+			select {
+			case blockLogs := <-logs:
+				for _, log := range blockLogs {
+					if !args.matches(log) {
+						continue
+					}
+
+					if err := notifier.Notify(rpcSubscription.ID, log); err != nil {
+						logger.Debugf("could not notify about log: [%v]", err)
+					}
+				}
+			case <-rpcSubscription.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSubscription, nil
+}
+
+// netAPI serves methods of the net namespace.
+type netAPI struct {
+	server *Server
+}
+
+func (api *netAPI) Version() string {
+	return DefaultChainID.String()
+}
+
+// marshalBlock returns the block in the form returned by Ethereum nodes,
+// with full transactions or with their hashes only.
+func (s *Server) marshalBlock(
+	block *types.Block,
+	fullTransactions bool,
+) (map[string]interface{}, error) {
+	headerJSON, err := json.Marshal(block.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(headerJSON, &fields); err != nil {
+		return nil, err
+	}
+
+	transactions := make([]interface{}, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if !fullTransactions {
+			transactions[i] = tx.Hash()
+			continue
+		}
+
+		transactions[i], err = s.marshalTransaction(block, uint64(i), tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fields["transactions"] = transactions
+	fields["uncles"] = []common.Hash{}
+	fields["size"] = hexutil.Uint64(block.Size())
+	fields["totalDifficulty"] = (*hexutil.Big)(
+		new(big.Int).Add(block.Number(), big.NewInt(1)),
+	)
+
+	return fields, nil
+}
+
+func (s *Server) marshalTransaction(
+	block *types.Block,
+	index uint64,
+	tx *types.Transaction,
+) (map[string]interface{}, error) {
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(txJSON, &fields); err != nil {
+		return nil, err
+	}
+
+	fields["blockHash"] = block.Hash()
+	fields["blockNumber"] = (*hexutil.Big)(block.Number())
+	fields["transactionIndex"] = hexutil.Uint64(index)
+	fields["from"] = s.senders[tx.Hash()]
+
+	return fields, nil
+}
+
+func encodeRevertReason(reason string) ([]byte, error) {
+	stringType, err := abi.NewType("string", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedReason, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, errorSelector...), encodedReason...), nil
+}
@@ -0,0 +1,215 @@
+package ethtest
+
+import (
+	"fmt"
+	"strings"
+
+	ethereumabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// contractCode is the code returned for addresses of emulated contracts.
+// Contract bindings check if there is any code under the contract address
+// before interpreting an empty call result.
+var contractCode = []byte{0x60, 0x80, 0x60, 0x40, 0x52}
+
+// revertError is returned when a call or a transaction of an emulated contract
+// is reverted.
+type revertError struct {
+	reason string
+}
+
+func (re revertError) Error() string {
+	return fmt.Sprintf("execution reverted: %s", re.reason)
+}
+
+// callHandler executes a call of a constant contract method and returns
+// values of the method outputs.
+type callHandler func(from common.Address, args []interface{}) ([]interface{}, error)
+
+// transactionHandler validates a transaction of a contract method and returns
+// a function applying the transaction. Transactions failing the validation are
+// reverted without changing the contract state.
+type transactionHandler func(from common.Address, args []interface{}) (func(), error)
+
+// emulatedContract dispatches ABI-encoded calls and transactions to handlers
+// of contract methods.
+type emulatedContract struct {
+	address      common.Address
+	abi          ethereumabi.ABI
+	calls        map[string]callHandler
+	transactions map[string]transactionHandler
+
+	// logs collects logs emitted by the transaction being applied.
+	logs []*types.Log
+}
+
+func newEmulatedContract(
+	address common.Address,
+	contractABI string,
+) (*emulatedContract, error) {
+	parsedABI, err := ethereumabi.JSON(strings.NewReader(contractABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse contract abi: [%v]", err)
+	}
+
+	return &emulatedContract{
+		address:      address,
+		abi:          parsedABI,
+		calls:        make(map[string]callHandler),
+		transactions: make(map[string]transactionHandler),
+	}, nil
+}
+
+func (ec *emulatedContract) handleCall(method string, handler callHandler) {
+	ec.calls[method] = handler
+}
+
+func (ec *emulatedContract) handleTransaction(
+	method string,
+	handler transactionHandler,
+) {
+	ec.transactions[method] = handler
+}
+
+func (ec *emulatedContract) decode(
+	input []byte,
+) (*ethereumabi.Method, []interface{}, error) {
+	if len(input) < 4 {
+		return nil, nil, revertError{"missing method selector"}
+	}
+
+	method, err := ec.abi.MethodById(input[:4])
+	if err != nil {
+		return nil, nil, revertError{fmt.Sprintf("unknown method [%x]", input[:4])}
+	}
+
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return nil, nil, revertError{
+			fmt.Sprintf("invalid arguments of [%s]: [%v]", method.Name, err),
+		}
+	}
+
+	return method, args, nil
+}
+
+// call executes the call without changing the contract state. Calls of
+// non-constant methods are validated as transactions and return no output,
+// the same way as calls executed for gas estimation.
+func (ec *emulatedContract) call(from common.Address, input []byte) ([]byte, error) {
+	method, args, err := ec.decode(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if handler, ok := ec.calls[method.Name]; ok {
+		outputs, err := handler(from, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return method.Outputs.Pack(outputs...)
+	}
+
+	if handler, ok := ec.transactions[method.Name]; ok {
+		if _, err := handler(from, args); err != nil {
+			return nil, err
+		}
+
+		return []byte{}, nil
+	}
+
+	return nil, revertError{fmt.Sprintf("method [%s] is not emulated", method.Name)}
+}
+
+// transact validates and applies the transaction. Logs emitted by
+// the transaction are returned.
+func (ec *emulatedContract) transact(
+	from common.Address,
+	input []byte,
+) ([]*types.Log, error) {
+	method, args, err := ec.decode(input)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, ok := ec.transactions[method.Name]
+	if !ok {
+		return nil, revertError{
+			fmt.Sprintf("method [%s] is not a transaction", method.Name),
+		}
+	}
+
+	apply, err := handler(from, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return ec.collectLogs(apply), nil
+}
+
+// collectLogs applies the function and returns logs emitted while it was
+// applied.
+func (ec *emulatedContract) collectLogs(apply func()) []*types.Log {
+	ec.logs = nil
+	apply()
+
+	logs := ec.logs
+	ec.logs = nil
+
+	return logs
+}
+
+// emit emits the contract event with the given argument values, in order of
+// the event inputs.
+func (ec *emulatedContract) emit(eventName string, values ...interface{}) {
+	event, ok := ec.abi.Events[eventName]
+	if !ok {
+		panic(fmt.Sprintf("unknown event [%s]", eventName))
+	}
+
+	if len(values) != len(event.Inputs) {
+		panic(fmt.Sprintf("invalid number of [%s] event values", eventName))
+	}
+
+	topics := []common.Hash{event.ID()}
+	nonIndexedValues := make([]interface{}, 0)
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			nonIndexedValues = append(nonIndexedValues, values[i])
+			continue
+		}
+
+		topic, err := indexedTopic(values[i])
+		if err != nil {
+			panic(fmt.Sprintf("invalid [%s] event topic: [%v]", eventName, err))
+		}
+		topics = append(topics, topic)
+	}
+
+	data, err := event.Inputs.NonIndexed().Pack(nonIndexedValues...)
+	if err != nil {
+		panic(fmt.Sprintf("could not pack [%s] event data: [%v]", eventName, err))
+	}
+
+	ec.logs = append(ec.logs, &types.Log{
+		Address: ec.address,
+		Topics:  topics,
+		Data:    data,
+	})
+}
+
+func indexedTopic(value interface{}) (common.Hash, error) {
+	switch v := value.(type) {
+	case common.Address:
+		return common.BytesToHash(v.Bytes()), nil
+	case [32]byte:
+		return common.Hash(v), nil
+	case common.Hash:
+		return v, nil
+	default:
+		return common.Hash{}, fmt.Errorf("unsupported indexed value type [%T]", value)
+	}
+}
@@ -0,0 +1,786 @@
+package ethtest
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxGroupSize is the maximum number of keep members.
+const maxGroupSize = 16
+
+var (
+	// DefaultMinimumStake is the minimum stake of operators eligible to join
+	// signers' pools unless changed with SetMinimumStake.
+	DefaultMinimumStake = big.NewInt(1000)
+
+	secp256k1HalfOrder = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+)
+
+type keepStatus int
+
+const (
+	active keepStatus = iota
+	closed
+	terminated
+)
+
+// factory emulates BondedECDSAKeepFactory contract with sortition pools
+// reduced to lists of registered operators. All fields are guarded by
+// the server mutex.
+type factory struct {
+	*emulatedContract
+
+	server  *Server
+	keepABI string
+
+	// executionHeader is the header of the block in which the transaction
+	// is being executed. For calls, the header of the latest block is used.
+	executionHeader  *types.Header
+	createdContracts uint64
+
+	keeps          []*keep
+	keepsByAddress map[common.Address]*keep
+
+	pools          map[common.Address]common.Address
+	registrations  map[common.Address][]common.Address
+	authorizations map[common.Address]bool
+	stakes         map[common.Address]*big.Int
+	minimumStake   *big.Int
+}
+
+func newFactory(server *Server, factoryABI string, keepABI string) (*factory, error) {
+	address := common.BytesToAddress(crypto.Keccak256([]byte("BondedECDSAKeepFactory")))
+
+	contract, err := newEmulatedContract(address, factoryABI)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &factory{
+		emulatedContract: contract,
+		server:           server,
+		keepABI:          keepABI,
+		keepsByAddress:   make(map[common.Address]*keep),
+		pools:            make(map[common.Address]common.Address),
+		registrations:    make(map[common.Address][]common.Address),
+		authorizations:   make(map[common.Address]bool),
+		stakes:           make(map[common.Address]*big.Int),
+		minimumStake:     DefaultMinimumStake,
+	}
+
+	// The application of the server has a signers' pool from the start.
+	f.pools[server.Application()] = f.nextContractAddress()
+
+	f.handleTransaction("createSortitionPool", f.createSortitionPool)
+	f.handleTransaction("registerMemberCandidate", f.registerMemberCandidate)
+	f.handleTransaction("updateOperatorStatus", f.updateOperatorStatus)
+	f.handleTransaction("openKeep", f.openKeep)
+	f.handleCall("getSortitionPool", f.getSortitionPool)
+	f.handleCall("isOperatorRegistered", f.isOperatorRegistered)
+	f.handleCall("isOperatorEligible", f.isOperatorEligible)
+	f.handleCall("isOperatorUpToDate", f.isOperatorUpToDate)
+	f.handleCall("isOperatorAuthorized", f.isOperatorAuthorized)
+	f.handleCall("hasMinimumStake", f.hasMinimumStake)
+	f.handleCall("balanceOf", f.balanceOf)
+	f.handleCall("getKeepCount", f.getKeepCount)
+	f.handleCall("getKeepAtIndex", f.getKeepAtIndex)
+	f.handleCall("getKeepOpenedTimestamp", f.getKeepOpenedTimestamp)
+
+	server.contracts[address] = f.emulatedContract
+
+	return f, nil
+}
+
+func (f *factory) nextContractAddress() common.Address {
+	address := crypto.CreateAddress(f.address, f.createdContracts)
+	f.createdContracts++
+
+	return address
+}
+
+func (f *factory) currentHeader() *types.Header {
+	if f.executionHeader != nil {
+		return f.executionHeader
+	}
+
+	return f.server.latestBlock().Header()
+}
+
+func (f *factory) isEligible(operator common.Address) bool {
+	stake, ok := f.stakes[operator]
+	return f.authorizations[operator] && ok && stake.Cmp(f.minimumStake) >= 0
+}
+
+func (f *factory) isRegistered(operator common.Address, application common.Address) bool {
+	return containsAddress(f.registrations[application], operator)
+}
+
+func (f *factory) createSortitionPool(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	application := args[0].(common.Address)
+
+	if _, ok := f.pools[application]; ok {
+		return nil, revertError{"Sortition pool already exists"}
+	}
+
+	return func() {
+		pool := f.nextContractAddress()
+		f.pools[application] = pool
+		f.emit("SortitionPoolCreated", application, pool)
+	}, nil
+}
+
+func (f *factory) registerMemberCandidate(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	application := args[0].(common.Address)
+
+	if _, ok := f.pools[application]; !ok {
+		return nil, revertError{"No pool found for the application"}
+	}
+
+	if f.isRegistered(from, application) {
+		return func() {}, nil
+	}
+
+	if !f.isEligible(from) {
+		return nil, revertError{"Operator not eligible"}
+	}
+
+	return func() {
+		f.registrations[application] = append(f.registrations[application], from)
+	}, nil
+}
+
+func (f *factory) updateOperatorStatus(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	operator := args[0].(common.Address)
+	application := args[1].(common.Address)
+
+	if _, ok := f.pools[application]; !ok {
+		return nil, revertError{"No pool found for the application"}
+	}
+
+	if !f.isRegistered(operator, application) {
+		return nil, revertError{"Operator is not registered in the pool"}
+	}
+
+	return func() {
+		if f.isEligible(operator) {
+			return
+		}
+
+		registrations := f.registrations[application]
+		for i, registered := range registrations {
+			if registered == operator {
+				f.registrations[application] = append(
+					registrations[:i:i],
+					registrations[i+1:]...,
+				)
+				break
+			}
+		}
+	}, nil
+}
+
+// openKeep opens a keep for the calling application. Members are selected
+// from eligible operators of the application's pool in order of their
+// registration.
+func (f *factory) openKeep(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	groupSize := args[0].(*big.Int)
+	honestThreshold := args[1].(*big.Int)
+	owner := args[2].(common.Address)
+
+	if groupSize.Sign() <= 0 {
+		return nil, revertError{"Minimum signing group size is 1"}
+	}
+	if groupSize.Cmp(big.NewInt(maxGroupSize)) > 0 {
+		return nil, revertError{"Maximum signing group size is 16"}
+	}
+	if honestThreshold.Sign() <= 0 {
+		return nil, revertError{"Honest threshold must be greater than 0"}
+	}
+	if honestThreshold.Cmp(groupSize) > 0 {
+		return nil, revertError{
+			"Honest threshold must be less or equal the group size",
+		}
+	}
+	if _, ok := f.pools[from]; !ok {
+		return nil, revertError{"No signer pool for this application"}
+	}
+
+	members := make([]common.Address, 0)
+	for _, operator := range f.registrations[from] {
+		if len(members) == int(groupSize.Int64()) {
+			break
+		}
+		if f.isEligible(operator) {
+			members = append(members, operator)
+		}
+	}
+	if len(members) < int(groupSize.Int64()) {
+		return nil, revertError{"Not enough operators in pool"}
+	}
+
+	return func() {
+		keep, err := newKeep(
+			f,
+			f.nextContractAddress(),
+			owner,
+			members,
+			honestThreshold.Uint64(),
+			f.currentHeader().Time,
+		)
+		if err != nil {
+			panic(fmt.Sprintf("could not create keep: [%v]", err))
+		}
+
+		f.keeps = append(f.keeps, keep)
+		f.keepsByAddress[keep.address] = keep
+
+		f.emit(
+			"BondedECDSAKeepCreated",
+			keep.address,
+			members,
+			owner,
+			from,
+			new(big.Int).Set(honestThreshold),
+		)
+	}, nil
+}
+
+func (f *factory) getSortitionPool(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	pool, ok := f.pools[args[0].(common.Address)]
+	if !ok {
+		return nil, revertError{"No pool found for the application"}
+	}
+
+	return []interface{}{pool}, nil
+}
+
+func (f *factory) isOperatorRegistered(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	operator := args[0].(common.Address)
+	application := args[1].(common.Address)
+
+	return []interface{}{f.isRegistered(operator, application)}, nil
+}
+
+func (f *factory) isOperatorEligible(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	operator := args[0].(common.Address)
+	application := args[1].(common.Address)
+
+	if _, ok := f.pools[application]; !ok {
+		return []interface{}{false}, nil
+	}
+
+	return []interface{}{f.isEligible(operator)}, nil
+}
+
+func (f *factory) isOperatorUpToDate(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	operator := args[0].(common.Address)
+	application := args[1].(common.Address)
+
+	if _, ok := f.pools[application]; !ok {
+		return nil, revertError{"No pool found for the application"}
+	}
+
+	return []interface{}{
+		f.isRegistered(operator, application) == f.isEligible(operator),
+	}, nil
+}
+
+func (f *factory) isOperatorAuthorized(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{f.authorizations[args[0].(common.Address)]}, nil
+}
+
+func (f *factory) hasMinimumStake(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	stake, ok := f.stakes[args[0].(common.Address)]
+	return []interface{}{ok && stake.Cmp(f.minimumStake) >= 0}, nil
+}
+
+func (f *factory) balanceOf(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	stake, ok := f.stakes[args[0].(common.Address)]
+	if !ok {
+		stake = big.NewInt(0)
+	}
+
+	return []interface{}{new(big.Int).Set(stake)}, nil
+}
+
+func (f *factory) getKeepCount(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{big.NewInt(int64(len(f.keeps)))}, nil
+}
+
+func (f *factory) getKeepAtIndex(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	index := args[0].(*big.Int)
+	if !index.IsInt64() || index.Int64() >= int64(len(f.keeps)) {
+		return nil, revertError{"Out of bounds."}
+	}
+
+	return []interface{}{f.keeps[index.Int64()].address}, nil
+}
+
+func (f *factory) getKeepOpenedTimestamp(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	keep, ok := f.keepsByAddress[args[0].(common.Address)]
+	if !ok {
+		return []interface{}{big.NewInt(0)}, nil
+	}
+
+	return []interface{}{new(big.Int).SetUint64(keep.openedTimestamp)}, nil
+}
+
+// keep emulates BondedECDSAKeep contract without bonds and rewards. All
+// fields are guarded by the server mutex.
+type keep struct {
+	*emulatedContract
+
+	owner           common.Address
+	members         []common.Address
+	honestThreshold uint64
+	openedTimestamp uint64
+	status          keepStatus
+
+	publicKey           []byte
+	submittedPublicKeys map[common.Address][]byte
+
+	digest    [32]byte
+	digests   map[[32]byte]uint64
+	isSigning bool
+
+	factory *factory
+}
+
+func newKeep(
+	factory *factory,
+	address common.Address,
+	owner common.Address,
+	members []common.Address,
+	honestThreshold uint64,
+	openedTimestamp uint64,
+) (*keep, error) {
+	contract, err := newEmulatedContract(address, factory.keepABI)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &keep{
+		emulatedContract:    contract,
+		owner:               owner,
+		members:             members,
+		honestThreshold:     honestThreshold,
+		openedTimestamp:     openedTimestamp,
+		submittedPublicKeys: make(map[common.Address][]byte),
+		digests:             make(map[[32]byte]uint64),
+		factory:             factory,
+	}
+
+	k.handleTransaction("submitPublicKey", k.submitPublicKey)
+	k.handleTransaction("sign", k.sign)
+	k.handleTransaction("submitSignature", k.submitSignature)
+	k.handleTransaction("closeKeep", k.closeKeep)
+	k.handleTransaction("seizeSignerBonds", k.seizeSignerBonds)
+	k.handleCall("getOwner", k.getOwner)
+	k.handleCall("getMembers", k.getMembers)
+	k.handleCall("honestThreshold", k.getHonestThreshold)
+	k.handleCall("getOpenedTimestamp", k.getOpenedTimestamp)
+	k.handleCall("getPublicKey", k.getPublicKey)
+	k.handleCall("isAwaitingSignature", k.isAwaitingSignature)
+	k.handleCall("digest", k.getDigest)
+	k.handleCall("digests", k.getDigests)
+	k.handleCall("isActive", k.isStatus(active))
+	k.handleCall("isClosed", k.isStatus(closed))
+	k.handleCall("isTerminated", k.isStatus(terminated))
+
+	factory.server.contracts[address] = contract
+
+	return k, nil
+}
+
+func (k *keep) checkOwner(from common.Address) error {
+	if from != k.owner {
+		return revertError{"Caller is not the keep owner"}
+	}
+
+	return nil
+}
+
+func (k *keep) checkMember(from common.Address) error {
+	if !containsAddress(k.members, from) {
+		return revertError{"Caller is not the keep member"}
+	}
+
+	return nil
+}
+
+func (k *keep) checkActive() error {
+	if k.status != active {
+		return revertError{"Keep is not active"}
+	}
+
+	return nil
+}
+
+func (k *keep) submitPublicKey(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	publicKey := args[0].([]byte)
+
+	if err := k.checkMember(from); err != nil {
+		return nil, err
+	}
+	if _, ok := k.submittedPublicKeys[from]; ok {
+		return nil, revertError{"Member already submitted a public key"}
+	}
+	if len(publicKey) != 64 {
+		return nil, revertError{"Public key must be 64 bytes long"}
+	}
+
+	return func() {
+		k.submittedPublicKeys[from] = publicKey
+
+		matchingPublicKeysCount := 0
+		for _, member := range k.members {
+			submittedPublicKey, ok := k.submittedPublicKeys[member]
+			if ok && string(submittedPublicKey) == string(publicKey) {
+				matchingPublicKeysCount++
+				continue
+			}
+
+			// The same way as the contract, a conflict is reported with
+			// the public key of the other member.
+			if ok {
+				k.emit("ConflictingPublicKeySubmitted", from, submittedPublicKey)
+			}
+		}
+
+		if matchingPublicKeysCount != len(k.members) {
+			return
+		}
+
+		k.publicKey = publicKey
+		k.emit("PublicKeyPublished", publicKey)
+	}, nil
+}
+
+func (k *keep) sign(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	digest := args[0].([32]byte)
+
+	if err := k.checkOwner(from); err != nil {
+		return nil, err
+	}
+	if err := k.checkActive(); err != nil {
+		return nil, err
+	}
+	if len(k.publicKey) == 0 {
+		return nil, revertError{"Public key was not set yet"}
+	}
+	if k.isSigning {
+		return nil, revertError{"Signer is busy"}
+	}
+
+	return func() {
+		k.digest = digest
+		k.digests[digest] = k.factory.currentHeader().Number.Uint64()
+		k.isSigning = true
+
+		k.emit("SignatureRequested", digest)
+	}, nil
+}
+
+func (k *keep) submitSignature(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	r := args[0].([32]byte)
+	s := args[1].([32]byte)
+	recoveryID := args[2].(uint8)
+
+	if err := k.checkMember(from); err != nil {
+		return nil, err
+	}
+	if !k.isSigning {
+		return nil, revertError{"Not awaiting a signature"}
+	}
+	if recoveryID >= 4 {
+		return nil, revertError{"Recovery ID must be one of {0, 1, 2, 3}"}
+	}
+	if new(big.Int).SetBytes(s[:]).Cmp(secp256k1HalfOrder) > 0 {
+		return nil, revertError{
+			"Malleable signature - s should be in the low half of " +
+				"secp256k1 curve's order",
+		}
+	}
+
+	signature := append(append(r[:], s[:]...), recoveryID)
+	recoveredPublicKey, err := crypto.Ecrecover(k.digest[:], signature)
+	if err != nil || string(recoveredPublicKey[1:]) != string(k.publicKey) {
+		return nil, revertError{"Invalid signature"}
+	}
+
+	return func() {
+		k.isSigning = false
+
+		k.emit("SignatureSubmitted", k.digest, r, s, recoveryID)
+	}, nil
+}
+
+func (k *keep) closeKeep(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	if err := k.checkOwner(from); err != nil {
+		return nil, err
+	}
+	if err := k.checkActive(); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		k.status = closed
+		k.isSigning = false
+
+		k.emit("KeepClosed")
+	}, nil
+}
+
+func (k *keep) seizeSignerBonds(
+	from common.Address,
+	args []interface{},
+) (func(), error) {
+	if err := k.checkOwner(from); err != nil {
+		return nil, err
+	}
+	if err := k.checkActive(); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		k.status = terminated
+		k.isSigning = false
+
+		k.emit("KeepTerminated")
+	}, nil
+}
+
+func (k *keep) getOwner(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{k.owner}, nil
+}
+
+func (k *keep) getMembers(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{k.members}, nil
+}
+
+func (k *keep) getHonestThreshold(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{new(big.Int).SetUint64(k.honestThreshold)}, nil
+}
+
+func (k *keep) getOpenedTimestamp(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{new(big.Int).SetUint64(k.openedTimestamp)}, nil
+}
+
+func (k *keep) getPublicKey(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{k.publicKey}, nil
+}
+
+func (k *keep) isAwaitingSignature(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	digest := args[0].([32]byte)
+
+	return []interface{}{k.isSigning && k.digest == digest}, nil
+}
+
+func (k *keep) getDigest(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	return []interface{}{k.digest}, nil
+}
+
+func (k *keep) getDigests(
+	from common.Address,
+	args []interface{},
+) ([]interface{}, error) {
+	digest := args[0].([32]byte)
+
+	return []interface{}{new(big.Int).SetUint64(k.digests[digest])}, nil
+}
+
+func (k *keep) isStatus(status keepStatus) callHandler {
+	return func(from common.Address, args []interface{}) ([]interface{}, error) {
+		return []interface{}{k.status == status}, nil
+	}
+}
+
+// AuthorizeOperator authorizes the factory to operate on the operator's
+// stake.
+func (s *Server) AuthorizeOperator(operator common.Address) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.factory.authorizations[operator] = true
+}
+
+// SetStake sets the stake of the operator. Operators which are authorized and
+// have at least the minimum stake are eligible to join signers' pools.
+func (s *Server) SetStake(operator common.Address, stake *big.Int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.factory.stakes[operator] = new(big.Int).Set(stake)
+}
+
+// SetMinimumStake sets the minimum stake of eligible operators.
+func (s *Server) SetMinimumStake(minimumStake *big.Int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.factory.minimumStake = new(big.Int).Set(minimumStake)
+}
+
+// FactoryAddress returns the address of the emulated BondedECDSAKeepFactory
+// contract.
+func (s *Server) FactoryAddress() common.Address {
+	return s.factory.address
+}
+
+// OpenKeep opens a keep of the application of the server. Keep members are
+// selected from eligible operators registered for the application.
+func (s *Server) OpenKeep(
+	groupSize uint64,
+	honestThreshold uint64,
+) (common.Address, error) {
+	_, err := s.transactAsApplication(
+		s.factory.emulatedContract,
+		"openKeep",
+		new(big.Int).SetUint64(groupSize),
+		new(big.Int).SetUint64(honestThreshold),
+		s.Application(),
+		big.NewInt(1),
+		big.NewInt(0),
+	)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("could not open keep: [%v]", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.factory.keeps[len(s.factory.keeps)-1].address, nil
+}
+
+// RequestSignature requests a signature over the digest from the keep.
+func (s *Server) RequestSignature(keepAddress common.Address, digest [32]byte) error {
+	return s.transactAsKeepOwner(keepAddress, "sign", digest)
+}
+
+// CloseKeep closes the keep.
+func (s *Server) CloseKeep(keepAddress common.Address) error {
+	return s.transactAsKeepOwner(keepAddress, "closeKeep")
+}
+
+// TerminateKeep terminates the keep, seizing bonds of its members.
+func (s *Server) TerminateKeep(keepAddress common.Address) error {
+	return s.transactAsKeepOwner(keepAddress, "seizeSignerBonds")
+}
+
+func (s *Server) transactAsKeepOwner(
+	keepAddress common.Address,
+	method string,
+	args ...interface{},
+) error {
+	s.mutex.Lock()
+	keep, ok := s.factory.keepsByAddress[keepAddress]
+	s.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown keep [%s]", keepAddress.String())
+	}
+
+	if _, err := s.transactAsApplication(
+		keep.emulatedContract,
+		method,
+		args...,
+	); err != nil {
+		return fmt.Errorf(
+			"could not call [%s] of keep [%s]: [%v]",
+			method,
+			keepAddress.String(),
+			err,
+		)
+	}
+
+	return nil
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+
+	return false
+}
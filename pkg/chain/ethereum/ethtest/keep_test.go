@@ -0,0 +1,321 @@
+package ethtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+func TestKeepSigning(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	memberKeys := registerOperators(ctx, t, server, client, 2)
+
+	keepAddress, err := server.OpenKeep(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep := bindContract(t, client, keepAddress, testKeepABI)
+
+	signingKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := crypto.FromECDSAPub(&signingKey.PublicKey)[1:]
+
+	digest := [32]byte{0x1, 0x2}
+	if err := server.RequestSignature(keepAddress, digest); err == nil ||
+		!strings.Contains(err.Error(), "Public key was not set yet") {
+		t.Errorf("unexpected signature request error: [%v]", err)
+	}
+
+	for _, memberKey := range memberKeys {
+		assertTransactionStatus(
+			ctx,
+			t,
+			client,
+			keep,
+			memberKey,
+			types.ReceiptStatusSuccessful,
+			"submitPublicKey",
+			publicKey,
+		)
+	}
+
+	var publishedPublicKey []byte
+	if err := keep.Call(nil, &publishedPublicKey, "getPublicKey"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicKey, publishedPublicKey) {
+		t.Errorf("unexpected published public key [%x]", publishedPublicKey)
+	}
+
+	if err := server.RequestSignature(keepAddress, digest); err != nil {
+		t.Fatal(err)
+	}
+	assertAwaitingSignature(t, keep, digest, true)
+
+	signature, err := crypto.Sign(digest[:], signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r, s [32]byte
+	copy(r[:], signature[:32])
+	copy(s[:], signature[32:64])
+
+	// Signature over a different digest is rejected.
+	otherSignature, err := crypto.Sign(crypto.Keccak256([]byte("other")), signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var otherR, otherS [32]byte
+	copy(otherR[:], otherSignature[:32])
+	copy(otherS[:], otherSignature[32:64])
+	assertTransactionStatus(
+		ctx,
+		t,
+		client,
+		keep,
+		memberKeys[0],
+		types.ReceiptStatusFailed,
+		"submitSignature",
+		otherR,
+		otherS,
+		otherSignature[64],
+	)
+	assertAwaitingSignature(t, keep, digest, true)
+
+	assertTransactionStatus(
+		ctx,
+		t,
+		client,
+		keep,
+		memberKeys[0],
+		types.ReceiptStatusSuccessful,
+		"submitSignature",
+		r,
+		s,
+		signature[64],
+	)
+	assertAwaitingSignature(t, keep, digest, false)
+}
+
+func TestConflictingPublicKeySubmitted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	memberKeys := registerOperators(ctx, t, server, client, 2)
+
+	keepAddress, err := server.OpenKeep(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep := bindContract(t, client, keepAddress, testKeepABI)
+
+	firstPublicKey := bytes.Repeat([]byte{0x1}, 64)
+	secondPublicKey := bytes.Repeat([]byte{0x2}, 64)
+
+	assertTransactionStatus(
+		ctx,
+		t,
+		client,
+		keep,
+		memberKeys[0],
+		types.ReceiptStatusSuccessful,
+		"submitPublicKey",
+		firstPublicKey,
+	)
+	receipt := assertTransactionStatus(
+		ctx,
+		t,
+		client,
+		keep,
+		memberKeys[1],
+		types.ReceiptStatusSuccessful,
+		"submitPublicKey",
+		secondPublicKey,
+	)
+
+	if len(receipt.Logs) != 1 {
+		t.Fatalf("unexpected number of logs [%v]", len(receipt.Logs))
+	}
+
+	// The conflict is reported with the public key submitted by the other
+	// member.
+	var event struct {
+		SubmittingMember     common.Address
+		ConflictingPublicKey []byte
+	}
+	if err := keep.UnpackLog(
+		&event,
+		"ConflictingPublicKeySubmitted",
+		*receipt.Logs[0],
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(firstPublicKey, event.ConflictingPublicKey) {
+		t.Errorf("unexpected conflicting public key [%x]", event.ConflictingPublicKey)
+	}
+
+	var publishedPublicKey []byte
+	if err := keep.Call(nil, &publishedPublicKey, "getPublicKey"); err != nil {
+		t.Fatal(err)
+	}
+	if len(publishedPublicKey) != 0 {
+		t.Errorf("public key has been published")
+	}
+}
+
+func TestCloseAndTerminateKeep(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	registerOperators(ctx, t, server, client, 1)
+
+	closedKeepAddress, err := server.OpenKeep(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminatedKeepAddress, err := server.OpenKeep(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.CloseKeep(closedKeepAddress); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.TerminateKeep(terminatedKeepAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keepAddress := range []common.Address{
+		closedKeepAddress,
+		terminatedKeepAddress,
+	} {
+		var isActive bool
+		keep := bindContract(t, client, keepAddress, testKeepABI)
+		if err := keep.Call(nil, &isActive, "isActive"); err != nil {
+			t.Fatal(err)
+		}
+		if isActive {
+			t.Errorf("keep [%s] is active", keepAddress.String())
+		}
+
+		if err := server.CloseKeep(keepAddress); err == nil ||
+			!strings.Contains(err.Error(), "Keep is not active") {
+			t.Errorf("unexpected error closing inactive keep: [%v]", err)
+		}
+	}
+}
+
+// registerOperators registers new eligible operators for the application of
+// the server and returns their keys in order of registration.
+func registerOperators(
+	ctx context.Context,
+	t *testing.T,
+	server *Server,
+	client *ethclient.Client,
+	count int,
+) []*ecdsa.PrivateKey {
+	factory := bindContract(t, client, server.FactoryAddress(), testFactoryABI)
+
+	operatorKeys := make([]*ecdsa.PrivateKey, count)
+	for i := range operatorKeys {
+		operatorKeys[i] = eligibleOperator(t, server)
+
+		assertTransactionStatus(
+			ctx,
+			t,
+			client,
+			factory,
+			operatorKeys[i],
+			types.ReceiptStatusSuccessful,
+			"registerMemberCandidate",
+			server.Application(),
+		)
+	}
+
+	return operatorKeys
+}
+
+// assertTransactionStatus submits the transaction with a gas limit set, so
+// that failing transactions are mined as well, and checks the status of its
+// receipt.
+func assertTransactionStatus(
+	ctx context.Context,
+	t *testing.T,
+	client *ethclient.Client,
+	contract *bind.BoundContract,
+	senderKey *ecdsa.PrivateKey,
+	expectedStatus uint64,
+	method string,
+	args ...interface{},
+) *types.Receipt {
+	transactor := bind.NewKeyedTransactor(senderKey)
+	transactor.GasLimit = 300000
+
+	tx, err := contract.Transact(transactor, method, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receipt.Status != expectedStatus {
+		t.Fatalf(
+			"unexpected status of [%s] transaction\nexpected: [%v]\nactual:   [%v]",
+			method,
+			expectedStatus,
+			receipt.Status,
+		)
+	}
+
+	return receipt
+}
+
+func assertAwaitingSignature(
+	t *testing.T,
+	keep *bind.BoundContract,
+	digest [32]byte,
+	expected bool,
+) {
+	var isAwaitingSignature bool
+	if err := keep.Call(
+		nil,
+		&isAwaitingSignature,
+		"isAwaitingSignature",
+		digest,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if isAwaitingSignature != expected {
+		t.Errorf(
+			"unexpected awaiting signature state\nexpected: [%v]\nactual:   [%v]",
+			expected,
+			isAwaitingSignature,
+		)
+	}
+}
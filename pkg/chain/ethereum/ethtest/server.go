@@ -0,0 +1,628 @@
+// Package ethtest provides an in-process stand-in of an Ethereum node for
+// tests of the Ethereum chain implementation. The server speaks JSON-RPC over
+// HTTP and websockets, emulates BondedECDSAKeepFactory and BondedECDSAKeep
+// contracts, and lets tests inject RPC errors, drop connections together with
+// their subscriptions, and leave transactions stuck until they are resubmitted
+// with a higher gas price.
+package ethtest
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ipfs/go-log"
+
+	"github.com/keep-network/keep-ecdsa/pkg/chain/gen/abi"
+)
+
+var logger = log.Logger("keep-chain-eth-ethtest")
+
+const (
+	blockGasLimit      = 8000000
+	transactionGasUsed = 21000
+)
+
+var (
+	// DefaultChainID is the chain ID reported by the server.
+	DefaultChainID = big.NewInt(1101)
+
+	// DefaultGasPrice is the gas price suggested by the server unless
+	// changed with SetGasPrice.
+	DefaultGasPrice = big.NewInt(20000000000) // 20 Gwei
+)
+
+// Server is an in-process Ethereum node emulating keep contracts. All state
+// queries are answered against the latest state, regardless of the block
+// they were requested for.
+type Server struct {
+	mutex sync.Mutex
+
+	blocks       []*types.Block
+	transactions map[common.Hash]*types.Transaction
+	receipts     map[common.Hash]*types.Receipt
+	senders      map[common.Hash]common.Address
+	logs         []*types.Log
+	nonces       map[common.Address]uint64
+	pending      []*types.Transaction
+
+	contracts map[common.Address]*emulatedContract
+	factory   *factory
+
+	gasPrice        *big.Int
+	minimumGasPrice *big.Int
+	autoMine        bool
+	failures        map[string]int
+
+	// miningMutex serializes mining, so notifications about mined blocks
+	// are delivered in order.
+	miningMutex sync.Mutex
+	headFeed    event.Feed
+	logsFeed    event.Feed
+
+	applicationKey *ecdsa.PrivateKey
+
+	rpcServer        *rpc.Server
+	httpServer       *httptest.Server
+	connectionsMutex sync.Mutex
+	connections      map[net.Conn]bool
+}
+
+// NewServer starts a server emulating keep contracts described by
+// the generated contract bindings.
+func NewServer() (*Server, error) {
+	return newServer(abi.BondedECDSAKeepFactoryABI, abi.BondedECDSAKeepABI)
+}
+
+func newServer(factoryABI string, keepABI string) (*Server, error) {
+	applicationKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate application key: [%v]", err)
+	}
+
+	server := &Server{
+		transactions:    make(map[common.Hash]*types.Transaction),
+		receipts:        make(map[common.Hash]*types.Receipt),
+		senders:         make(map[common.Hash]common.Address),
+		nonces:          make(map[common.Address]uint64),
+		contracts:       make(map[common.Address]*emulatedContract),
+		gasPrice:        DefaultGasPrice,
+		minimumGasPrice: big.NewInt(0),
+		autoMine:        true,
+		failures:        make(map[string]int),
+		applicationKey:  applicationKey,
+		connections:     make(map[net.Conn]bool),
+	}
+
+	server.blocks = []*types.Block{types.NewBlock(
+		&types.Header{
+			Number:     big.NewInt(0),
+			Difficulty: big.NewInt(1),
+			GasLimit:   blockGasLimit,
+			Time:       uint64(time.Now().Unix()),
+		},
+		nil,
+		nil,
+		nil,
+	)}
+
+	server.factory, err = newFactory(server, factoryABI, keepABI)
+	if err != nil {
+		return nil, err
+	}
+
+	server.rpcServer = rpc.NewServer()
+	if err := server.rpcServer.RegisterName("eth", &ethAPI{server}); err != nil {
+		return nil, fmt.Errorf("failed to register eth api: [%v]", err)
+	}
+	if err := server.rpcServer.RegisterName("net", &netAPI{server}); err != nil {
+		return nil, fmt.Errorf("failed to register net api: [%v]", err)
+	}
+
+	websocketHandler := server.rpcServer.WebsocketHandler([]string{"*"})
+	server.httpServer = httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				websocketHandler.ServeHTTP(w, r)
+				return
+			}
+
+			server.rpcServer.ServeHTTP(w, r)
+		},
+	))
+	server.httpServer.Config.ConnState = server.trackConnection
+	server.httpServer.Start()
+
+	return server, nil
+}
+
+// URL returns the websocket URL of the server.
+func (s *Server) URL() string {
+	return "ws://" + s.httpServer.Listener.Addr().String()
+}
+
+// HTTPURL returns the HTTP URL of the server. Subscriptions are not available
+// over HTTP.
+func (s *Server) HTTPURL() string {
+	return s.httpServer.URL
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() {
+	s.rpcServer.Stop()
+	s.DropConnections()
+	s.httpServer.Close()
+}
+
+// trackConnection keeps connections hijacked by websocket handlers, so that
+// they can be dropped. Connections served over HTTP are managed by the HTTP
+// server.
+func (s *Server) trackConnection(conn net.Conn, state http.ConnState) {
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+
+	switch state {
+	case http.StateHijacked:
+		s.connections[conn] = true
+	case http.StateClosed:
+		delete(s.connections, conn)
+	}
+}
+
+// DropConnections closes all websocket connections to the server. All
+// subscriptions of the clients are interrupted with an error. Clients
+// reconnect on their next request.
+func (s *Server) DropConnections() {
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+
+	for conn := range s.connections {
+		if err := conn.Close(); err != nil {
+			logger.Debugf("could not close connection: [%v]", err)
+		}
+		delete(s.connections, conn)
+	}
+}
+
+// FailRequests makes the next count calls of the JSON-RPC method, e.g.
+// eth_sendRawTransaction or eth_subscribe, fail with an error.
+func (s *Server) FailRequests(method string, count int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures[method] = count
+}
+
+// injectedFailure returns an error if a failure of the method has been
+// requested.
+func (s *Server) injectedFailure(method string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failures[method] == 0 {
+		return nil
+	}
+
+	s.failures[method]--
+	return fmt.Errorf("injected failure of [%s]", method)
+}
+
+// SetGasPrice sets the gas price suggested by the server.
+func (s *Server) SetGasPrice(gasPrice *big.Int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.gasPrice = gasPrice
+}
+
+// SetMinimumGasPrice sets the minimum gas price of mined transactions.
+// Transactions with a lower gas price stay pending until they are replaced
+// with transactions of the same nonce and a sufficient gas price.
+func (s *Server) SetMinimumGasPrice(gasPrice *big.Int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.minimumGasPrice = gasPrice
+}
+
+// SetAutoMine enables or disables mining of a new block whenever
+// a transaction which can be mined is submitted. Auto-mining is enabled
+// by default.
+func (s *Server) SetAutoMine(autoMine bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.autoMine = autoMine
+}
+
+// Mine mines a new block with all pending transactions which can be mined.
+// A block is mined even if there are no such transactions.
+func (s *Server) Mine() {
+	s.mine(true)
+}
+
+// BlockNumber returns the number of the latest block.
+func (s *Server) BlockNumber() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.latestBlock().NumberU64()
+}
+
+// Receipt returns the receipt of the mined transaction or nil if
+// the transaction has not been mined.
+func (s *Server) Receipt(hash common.Hash) *types.Receipt {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.receipts[hash]
+}
+
+// PendingTransactions returns transactions waiting to be mined.
+func (s *Server) PendingTransactions() []*types.Transaction {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending := make([]*types.Transaction, len(s.pending))
+	copy(pending, s.pending)
+
+	return pending
+}
+
+func (s *Server) latestBlock() *types.Block {
+	return s.blocks[len(s.blocks)-1]
+}
+
+func (s *Server) blockByNumber(number rpc.BlockNumber) *types.Block {
+	if number < 0 {
+		return s.latestBlock()
+	}
+
+	if int(number) >= len(s.blocks) {
+		return nil
+	}
+
+	return s.blocks[number]
+}
+
+func (s *Server) signer(tx *types.Transaction) types.Signer {
+	if tx.Protected() {
+		return types.NewEIP155Signer(tx.ChainId())
+	}
+
+	return types.HomesteadSigner{}
+}
+
+// pendingNonce returns the nonce of the next transaction of the account,
+// taking into account its pending transactions.
+func (s *Server) pendingNonce(account common.Address) uint64 {
+	nonce := s.nonces[account]
+	for {
+		if s.pendingTransaction(account, nonce) == nil {
+			return nonce
+		}
+		nonce++
+	}
+}
+
+func (s *Server) pendingTransaction(
+	account common.Address,
+	nonce uint64,
+) *types.Transaction {
+	for _, tx := range s.pending {
+		if s.senders[tx.Hash()] == account && tx.Nonce() == nonce {
+			return tx
+		}
+	}
+
+	return nil
+}
+
+// submit adds the signed transaction to the pending ones. A pending
+// transaction of the same sender and nonce is replaced if the new one offers
+// a higher gas price.
+func (s *Server) submit(tx *types.Transaction) error {
+	s.mutex.Lock()
+
+	sender, err := types.Sender(s.signer(tx), tx)
+	if err != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("invalid sender: [%v]", err)
+	}
+
+	if tx.To() == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("contract creation is not supported")
+	}
+
+	if tx.Gas() > blockGasLimit {
+		s.mutex.Unlock()
+		return fmt.Errorf("exceeds block gas limit")
+	}
+
+	if _, ok := s.transactions[tx.Hash()]; ok {
+		s.mutex.Unlock()
+		return fmt.Errorf("known transaction: %x", tx.Hash())
+	}
+
+	if tx.Nonce() < s.nonces[sender] {
+		s.mutex.Unlock()
+		return fmt.Errorf("nonce too low")
+	}
+
+	if replaced := s.pendingTransaction(sender, tx.Nonce()); replaced != nil {
+		if tx.GasPrice().Cmp(replaced.GasPrice()) <= 0 {
+			s.mutex.Unlock()
+			return fmt.Errorf("replacement transaction underpriced")
+		}
+
+		logger.Debugf(
+			"replacing transaction [%v] with [%v]",
+			replaced.Hash().TerminalString(),
+			tx.Hash().TerminalString(),
+		)
+		s.removePending(replaced)
+	}
+
+	s.senders[tx.Hash()] = sender
+	s.transactions[tx.Hash()] = tx
+	s.pending = append(s.pending, tx)
+
+	shouldMine := s.autoMine && len(s.executableTransactions()) > 0
+	s.mutex.Unlock()
+
+	if shouldMine {
+		s.mine(false)
+	}
+
+	return nil
+}
+
+func (s *Server) removePending(tx *types.Transaction) {
+	for i, pendingTx := range s.pending {
+		if pendingTx == tx {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			break
+		}
+	}
+
+	delete(s.transactions, tx.Hash())
+	delete(s.senders, tx.Hash())
+}
+
+// executableTransactions returns pending transactions which can be mined in
+// the next block, in order of their nonces.
+func (s *Server) executableTransactions() []*types.Transaction {
+	nonces := make(map[common.Address]uint64)
+	executable := make([]*types.Transaction, 0)
+
+	for {
+		found := false
+		for _, tx := range s.pending {
+			sender := s.senders[tx.Hash()]
+
+			nonce, ok := nonces[sender]
+			if !ok {
+				nonce = s.nonces[sender]
+			}
+
+			if tx.Nonce() != nonce || tx.GasPrice().Cmp(s.minimumGasPrice) < 0 {
+				continue
+			}
+
+			executable = append(executable, tx)
+			nonces[sender] = nonce + 1
+			found = true
+		}
+
+		if !found {
+			return executable
+		}
+	}
+}
+
+// mine mines a new block with executable pending transactions and notifies
+// subscribers about the block and its logs. Unless forced, no block is mined
+// if there are no executable transactions.
+func (s *Server) mine(force bool) {
+	s.miningMutex.Lock()
+	defer s.miningMutex.Unlock()
+
+	s.mutex.Lock()
+
+	transactions := s.executableTransactions()
+	if len(transactions) == 0 && !force {
+		s.mutex.Unlock()
+		return
+	}
+
+	parent := s.latestBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), big.NewInt(1)),
+		Difficulty: big.NewInt(1),
+		GasLimit:   blockGasLimit,
+		Time:       uint64(time.Now().Unix()),
+	}
+	if header.Time <= parent.Time() {
+		header.Time = parent.Time() + 1
+	}
+
+	receipts := make([]*types.Receipt, len(transactions))
+	blockLogs := make([]*types.Log, 0)
+	for i, tx := range transactions {
+		sender := s.senders[tx.Hash()]
+		s.nonces[sender] = tx.Nonce() + 1
+		s.removePending(tx)
+		s.transactions[tx.Hash()] = tx
+		s.senders[tx.Hash()] = sender
+
+		receipt := &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i+1) * transactionGasUsed,
+			TxHash:            tx.Hash(),
+			GasUsed:           transactionGasUsed,
+			TransactionIndex:  uint(i),
+			Logs:              []*types.Log{},
+		}
+
+		logs, err := s.execute(header, sender, tx)
+		if err != nil {
+			logger.Debugf(
+				"transaction [%v] failed: [%v]",
+				tx.Hash().TerminalString(),
+				err,
+			)
+			receipt.Status = types.ReceiptStatusFailed
+		} else {
+			for _, log := range logs {
+				log.TxHash = tx.Hash()
+				log.TxIndex = uint(i)
+				log.Index = uint(len(blockLogs))
+				blockLogs = append(blockLogs, log)
+			}
+			receipt.Logs = append(receipt.Logs, logs...)
+		}
+
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts[i] = receipt
+	}
+	header.GasUsed = uint64(len(transactions)) * transactionGasUsed
+
+	block := types.NewBlock(header, transactions, nil, receipts)
+	for _, receipt := range receipts {
+		receipt.BlockHash = block.Hash()
+		receipt.BlockNumber = block.Number()
+		s.receipts[receipt.TxHash] = receipt
+	}
+	for _, log := range blockLogs {
+		log.BlockHash = block.Hash()
+		log.BlockNumber = block.NumberU64()
+	}
+
+	s.blocks = append(s.blocks, block)
+	s.logs = append(s.logs, blockLogs...)
+
+	s.mutex.Unlock()
+
+	logger.Debugf(
+		"mined block [%v] with [%v] transactions",
+		block.NumberU64(),
+		len(transactions),
+	)
+
+	s.headFeed.Send(block.Header())
+	if len(blockLogs) > 0 {
+		s.logsFeed.Send(blockLogs)
+	}
+}
+
+// execute applies the transaction to the emulated contracts and returns
+// emitted logs. Value transfers to addresses without a contract always
+// succeed.
+func (s *Server) execute(
+	header *types.Header,
+	sender common.Address,
+	tx *types.Transaction,
+) ([]*types.Log, error) {
+	contract, ok := s.contracts[*tx.To()]
+	if !ok {
+		return []*types.Log{}, nil
+	}
+
+	s.factory.executionHeader = header
+	defer func() { s.factory.executionHeader = nil }()
+
+	return contract.transact(sender, tx.Data())
+}
+
+// call executes the call against the latest state of emulated contracts.
+func (s *Server) call(args callArgs) ([]byte, error) {
+	if args.To == nil {
+		return nil, fmt.Errorf("contract creation is not supported")
+	}
+
+	contract, ok := s.contracts[*args.To]
+	if !ok {
+		return []byte{}, nil
+	}
+
+	var from common.Address
+	if args.From != nil {
+		from = *args.From
+	}
+
+	return contract.call(from, args.Data)
+}
+
+// transactAsApplication submits a transaction of the application owning
+// keeps and makes sure it is mined. The transaction is validated first, so
+// the reason of a failure is returned.
+func (s *Server) transactAsApplication(
+	contract *emulatedContract,
+	method string,
+	args ...interface{},
+) (*types.Receipt, error) {
+	input, err := contract.abi.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack [%s] call: [%v]", method, err)
+	}
+
+	application := s.Application()
+	to := contract.address
+
+	s.mutex.Lock()
+	_, err = s.call(callArgs{From: &application, To: &to, Data: input})
+	nonce := s.pendingNonce(application)
+	gasPrice := s.gasPrice
+	if gasPrice.Cmp(s.minimumGasPrice) < 0 {
+		gasPrice = s.minimumGasPrice
+	}
+	s.mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := types.SignTx(
+		types.NewTransaction(nonce, to, big.NewInt(0), blockGasLimit, gasPrice, input),
+		types.HomesteadSigner{},
+		s.applicationKey,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: [%v]", err)
+	}
+
+	if err := s.submit(tx); err != nil {
+		return nil, err
+	}
+
+	receipt := s.Receipt(tx.Hash())
+	if receipt == nil {
+		s.Mine()
+		receipt = s.Receipt(tx.Hash())
+	}
+
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction [%x] has not been mined", tx.Hash())
+	}
+
+	return receipt, nil
+}
+
+// Application returns the address of the application owning keeps opened
+// by the server.
+func (s *Server) Application() common.Address {
+	return crypto.PubkeyToAddress(s.applicationKey.PublicKey)
+}
@@ -0,0 +1,473 @@
+package ethtest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	goethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+)
+
+// testFactoryABI and testKeepABI describe the subset of contract methods and
+// events exercised by tests, so that the server can be tested without
+// generated contract bindings.
+const (
+	testFactoryABI = `[
+		{"type":"function","name":"registerMemberCandidate","stateMutability":"nonpayable","inputs":[{"name":"_application","type":"address"}],"outputs":[]},
+		{"type":"function","name":"openKeep","stateMutability":"payable","payable":true,"inputs":[{"name":"_groupSize","type":"uint256"},{"name":"_honestThreshold","type":"uint256"},{"name":"_owner","type":"address"},{"name":"_bond","type":"uint256"},{"name":"_stakeLockDuration","type":"uint256"}],"outputs":[{"name":"keepAddress","type":"address"}]},
+		{"type":"function","name":"isOperatorRegistered","stateMutability":"view","constant":true,"inputs":[{"name":"_operator","type":"address"},{"name":"_application","type":"address"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"getKeepCount","stateMutability":"view","constant":true,"inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"getKeepAtIndex","stateMutability":"view","constant":true,"inputs":[{"name":"index","type":"uint256"}],"outputs":[{"name":"","type":"address"}]},
+		{"type":"event","name":"SortitionPoolCreated","anonymous":false,"inputs":[{"name":"application","type":"address","indexed":true},{"name":"sortitionPool","type":"address","indexed":false}]},
+		{"type":"event","name":"BondedECDSAKeepCreated","anonymous":false,"inputs":[{"name":"keepAddress","type":"address","indexed":true},{"name":"members","type":"address[]","indexed":false},{"name":"owner","type":"address","indexed":true},{"name":"application","type":"address","indexed":true},{"name":"honestThreshold","type":"uint256","indexed":false}]}
+	]`
+
+	testKeepABI = `[
+		{"type":"function","name":"submitPublicKey","stateMutability":"nonpayable","inputs":[{"name":"_publicKey","type":"bytes"}],"outputs":[]},
+		{"type":"function","name":"sign","stateMutability":"nonpayable","inputs":[{"name":"_digest","type":"bytes32"}],"outputs":[]},
+		{"type":"function","name":"submitSignature","stateMutability":"nonpayable","inputs":[{"name":"_r","type":"bytes32"},{"name":"_s","type":"bytes32"},{"name":"_recoveryID","type":"uint8"}],"outputs":[]},
+		{"type":"function","name":"closeKeep","stateMutability":"nonpayable","inputs":[],"outputs":[]},
+		{"type":"function","name":"seizeSignerBonds","stateMutability":"nonpayable","inputs":[],"outputs":[]},
+		{"type":"function","name":"getPublicKey","stateMutability":"view","constant":true,"inputs":[],"outputs":[{"name":"","type":"bytes"}]},
+		{"type":"function","name":"getMembers","stateMutability":"view","constant":true,"inputs":[],"outputs":[{"name":"","type":"address[]"}]},
+		{"type":"function","name":"isAwaitingSignature","stateMutability":"view","constant":true,"inputs":[{"name":"_digest","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"isActive","stateMutability":"view","constant":true,"inputs":[],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"event","name":"SignatureRequested","anonymous":false,"inputs":[{"name":"digest","type":"bytes32","indexed":true}]},
+		{"type":"event","name":"ConflictingPublicKeySubmitted","anonymous":false,"inputs":[{"name":"submittingMember","type":"address","indexed":true},{"name":"conflictingPublicKey","type":"bytes","indexed":false}]},
+		{"type":"event","name":"PublicKeyPublished","anonymous":false,"inputs":[{"name":"publicKey","type":"bytes","indexed":false}]},
+		{"type":"event","name":"KeepClosed","anonymous":false,"inputs":[]},
+		{"type":"event","name":"KeepTerminated","anonymous":false,"inputs":[]},
+		{"type":"event","name":"SignatureSubmitted","anonymous":false,"inputs":[{"name":"digest","type":"bytes32","indexed":true},{"name":"r","type":"bytes32","indexed":false},{"name":"s","type":"bytes32","indexed":false},{"name":"recoveryID","type":"uint8","indexed":false}]}
+	]`
+)
+
+func TestNewHeads(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	headers := make(chan *types.Header, 10)
+	subscription, err := client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	server.Mine()
+
+	header := receiveHeader(ctx, t, headers)
+	if header.Number.Uint64() != 1 {
+		t.Errorf("unexpected block number [%v]", header.Number)
+	}
+
+	block, err := client.BlockByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Hash() != header.Hash() {
+		t.Errorf(
+			"unexpected latest block\nexpected: [%x]\nactual:   [%x]",
+			header.Hash(),
+			block.Hash(),
+		)
+	}
+
+	genesis, err := client.HeaderByNumber(ctx, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesis.Hash() != block.ParentHash() {
+		t.Errorf("latest block is not a child of the genesis block")
+	}
+}
+
+func TestTransactionReceiptAndLogs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	factory := bindContract(t, client, server.FactoryAddress(), testFactoryABI)
+	operatorKey := eligibleOperator(t, server)
+
+	logs := make(chan types.Log, 10)
+	subscription, err := client.SubscribeFilterLogs(
+		ctx,
+		filterQuery(t, server.FactoryAddress(), testFactoryABI, "BondedECDSAKeepCreated"),
+		logs,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	tx, err := factory.Transact(
+		bind.NewKeyedTransactor(operatorKey),
+		"registerMemberCandidate",
+		server.Application(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction failed")
+	}
+
+	var isRegistered bool
+	if err := factory.Call(
+		&bind.CallOpts{Context: ctx},
+		&isRegistered,
+		"isOperatorRegistered",
+		crypto.PubkeyToAddress(operatorKey.PublicKey),
+		server.Application(),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if !isRegistered {
+		t.Errorf("operator is not registered")
+	}
+
+	keepAddress, err := server.OpenKeep(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case log := <-logs:
+		if common.BytesToAddress(log.Topics[1].Bytes()) != keepAddress {
+			t.Errorf("unexpected keep address in log topics")
+		}
+	case <-ctx.Done():
+		t.Fatal("keep created log has not been received")
+	}
+
+	pastLogs, err := client.FilterLogs(
+		ctx,
+		filterQuery(t, server.FactoryAddress(), testFactoryABI, "BondedECDSAKeepCreated"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pastLogs) != 1 {
+		t.Fatalf("unexpected number of past logs [%v]", len(pastLogs))
+	}
+
+	var keepAtIndex common.Address
+	if err := factory.Call(
+		&bind.CallOpts{Context: ctx},
+		&keepAtIndex,
+		"getKeepAtIndex",
+		big.NewInt(0),
+	); err != nil {
+		t.Fatal(err)
+	}
+	if keepAtIndex != keepAddress {
+		t.Errorf("unexpected keep at index [%s]", keepAtIndex.String())
+	}
+}
+
+func TestRevertedTransaction(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	factory := bindContract(t, client, server.FactoryAddress(), testFactoryABI)
+
+	// The operator has no stake, so it is not eligible to join the pool.
+	operatorKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = factory.Transact(
+		bind.NewKeyedTransactor(operatorKey),
+		"registerMemberCandidate",
+		server.Application(),
+	)
+	if err == nil {
+		t.Fatal("expected gas estimation failure")
+	}
+
+	// The failure reason is available with a call, the same way as on
+	// Ethereum nodes.
+	factoryABI, err := abi.JSON(strings.NewReader(testFactoryABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	factoryAddress := server.FactoryAddress()
+	resolvedErr := ethutil.NewErrorResolver(
+		client,
+		&factoryABI,
+		&factoryAddress,
+	).ResolveError(
+		err,
+		crypto.PubkeyToAddress(operatorKey.PublicKey),
+		nil,
+		"registerMemberCandidate",
+		server.Application(),
+	)
+	if !strings.Contains(resolvedErr.Error(), "Operator not eligible") {
+		t.Errorf("unexpected resolved error [%v]", resolvedErr)
+	}
+
+	// With the gas limit set, the transaction is mined and fails.
+	transactor := bind.NewKeyedTransactor(operatorKey)
+	transactor.GasLimit = 100000
+	tx, err := factory.Transact(
+		transactor,
+		"registerMemberCandidate",
+		server.Application(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("unexpected receipt status [%v]", receipt.Status)
+	}
+}
+
+func TestInjectedFailures(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	server.FailRequests("eth_getBlockByNumber", 2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.HeaderByNumber(ctx, nil); err == nil {
+			t.Fatalf("expected injected failure of request [%v]", i)
+		}
+	}
+
+	if _, err := client.HeaderByNumber(ctx, nil); err != nil {
+		t.Fatalf("unexpected error after injected failures: [%v]", err)
+	}
+}
+
+func TestDropConnections(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, client := startServer(t)
+	defer server.Close()
+
+	headers := make(chan *types.Header, 10)
+	subscription, err := client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.DropConnections()
+
+	select {
+	case <-subscription.Err():
+	case <-ctx.Done():
+		t.Fatal("subscription has not been interrupted")
+	}
+	subscription.Unsubscribe()
+
+	// The client reconnects once it notices the connection has been closed,
+	// so the subscription is retried the same way as by the block counter
+	// and contract event watchers.
+	for {
+		subscription, err = client.SubscribeNewHead(ctx, headers)
+		if err == nil {
+			break
+		}
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatalf("could not resubscribe: [%v]", err)
+		}
+	}
+	defer subscription.Unsubscribe()
+
+	server.Mine()
+
+	if header := receiveHeader(ctx, t, headers); header.Number.Uint64() != 1 {
+		t.Errorf("unexpected block number [%v]", header.Number)
+	}
+}
+
+func TestStuckTransaction(t *testing.T) {
+	server, client := startServer(t)
+	defer server.Close()
+
+	senderKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Transactions with the suggested gas price get stuck until they are
+	// resubmitted with a gas price at least 20% higher.
+	minimumGasPrice := new(big.Int).Div(
+		new(big.Int).Mul(DefaultGasPrice, big.NewInt(6)),
+		big.NewInt(5),
+	)
+	server.SetMinimumGasPrice(minimumGasPrice)
+
+	submit := func(gasPrice *big.Int) (*types.Transaction, error) {
+		tx, err := types.SignTx(
+			types.NewTransaction(
+				0,
+				common.Address{0x1},
+				big.NewInt(0),
+				transactionGasUsed,
+				gasPrice,
+				nil,
+			),
+			types.HomesteadSigner{},
+			senderKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return tx, client.SendTransaction(context.Background(), tx)
+	}
+
+	tx, err := submit(DefaultGasPrice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pending := server.PendingTransactions(); len(pending) != 1 {
+		t.Fatalf("unexpected number of pending transactions [%v]", len(pending))
+	}
+
+	// A forced block does not include the stuck transaction.
+	server.Mine()
+	if server.Receipt(tx.Hash()) != nil {
+		t.Fatal("stuck transaction has been mined")
+	}
+
+	miningWaiter := ethutil.NewMiningWaiter(
+		client,
+		time.Second,
+		new(big.Int).Mul(DefaultGasPrice, big.NewInt(2)),
+	)
+	miningWaiter.ForceMining(tx, submit)
+
+	if server.Receipt(tx.Hash()) != nil {
+		t.Error("original transaction has been mined")
+	}
+	if pending := server.PendingTransactions(); len(pending) != 0 {
+		t.Errorf("unexpected number of pending transactions [%v]", len(pending))
+	}
+
+	nonce, err := client.NonceAt(
+		context.Background(),
+		crypto.PubkeyToAddress(senderKey.PublicKey),
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 1 {
+		t.Errorf("unexpected nonce [%v]", nonce)
+	}
+}
+
+func startServer(t *testing.T) (*Server, *ethclient.Client) {
+	server, err := newServer(testFactoryABI, testKeepABI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := ethclient.Dial(server.URL())
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return server, client
+}
+
+func bindContract(
+	t *testing.T,
+	client *ethclient.Client,
+	address common.Address,
+	contractABI string,
+) *bind.BoundContract {
+	parsedABI, err := abi.JSON(strings.NewReader(contractABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bind.NewBoundContract(address, parsedABI, client, client, client)
+}
+
+func filterQuery(
+	t *testing.T,
+	address common.Address,
+	contractABI string,
+	eventName string,
+) goethereum.FilterQuery {
+	parsedABI, err := abi.JSON(strings.NewReader(contractABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return goethereum.FilterQuery{
+		Addresses: []common.Address{address},
+		Topics:    [][]common.Hash{{parsedABI.Events[eventName].ID()}},
+	}
+}
+
+// eligibleOperator returns the key of a new operator eligible to join
+// signers' pools.
+func eligibleOperator(t *testing.T, server *Server) *ecdsa.PrivateKey {
+	operatorKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operator := crypto.PubkeyToAddress(operatorKey.PublicKey)
+	server.AuthorizeOperator(operator)
+	server.SetStake(operator, DefaultMinimumStake)
+
+	return operatorKey
+}
+
+func receiveHeader(
+	ctx context.Context,
+	t *testing.T,
+	headers chan *types.Header,
+) *types.Header {
+	select {
+	case header := <-headers:
+		return header
+	case <-ctx.Done():
+		t.Fatal("new head has not been received")
+		return nil
+	}
+}
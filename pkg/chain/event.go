@@ -8,7 +8,10 @@ import (
 type BondedECDSAKeepCreatedEvent struct {
 	KeepAddress     common.Address   // keep contract address
 	Members         []common.Address // keep members addresses
+	Application     common.Address   // application which requested the keep
 	HonestThreshold uint64
+	BlockNumber     uint64
+	BlockHash       common.Hash
 }
 
 // ConflictingPublicKeySubmittedEvent is an event emitted each time when one of
@@ -27,19 +30,27 @@ type PublicKeyPublishedEvent struct {
 
 // SignatureRequestedEvent is an event emitted when a user requests
 // a digest to be signed.
+//
+// Block hash of events delivered by live subscriptions may be empty if it is
+// not provided by the subscription. It is always set for past events.
 type SignatureRequestedEvent struct {
 	Digest      [32]byte
 	BlockNumber uint64
+	BlockHash   common.Hash
 }
 
 // KeepClosedEvent is an event emitted when a keep has been closed.
 type KeepClosedEvent struct {
+	KeepAddress common.Address
 	BlockNumber uint64
+	BlockHash   common.Hash
 }
 
 // KeepTerminatedEvent is an event emitted when a keep has been terminated.
 type KeepTerminatedEvent struct {
+	KeepAddress common.Address
 	BlockNumber uint64
+	BlockHash   common.Hash
 }
 
 // IsMember checks if list of members contains the given address.
@@ -1,10 +1,16 @@
 package local
 
 import (
+	cecdsa "crypto/ecdsa"
 	"fmt"
+	"math/big"
+	"math/rand"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-common/pkg/subscription"
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
 )
 
 type keepStatus int
@@ -16,11 +22,47 @@ const (
 )
 
 type localKeep struct {
-	publicKey [64]byte
-	members   []common.Address
-	status    keepStatus
+	publicKey       [64]byte
+	members         []common.Address
+	honestThreshold uint64
+	status          keepStatus
+	openedBlock     uint64
 
-	signatureRequestedHandlers map[int]func(event *eth.SignatureRequestedEvent)
+	// submittedPublicKeys holds public keys submitted by members until all
+	// members submitted the same key and the key has been published.
+	submittedPublicKeys map[common.Address][64]byte
+
+	latestDigest      [32]byte
+	awaitingSignature bool
+
+	signatureRequestedHandlers   map[int]func(event *eth.SignatureRequestedEvent)
+	publicKeyPublishedHandlers   map[int]func(event *eth.PublicKeyPublishedEvent)
+	conflictingPublicKeyHandlers map[int]func(event *eth.ConflictingPublicKeySubmittedEvent)
+	keepClosedHandlers           map[int]func(event *eth.KeepClosedEvent)
+	keepTerminatedHandlers       map[int]func(event *eth.KeepTerminatedEvent)
+	signatureRequestedEvents     []*eth.SignatureRequestedEvent
+}
+
+func newLocalKeep(
+	members []common.Address,
+	honestThreshold uint64,
+	openedBlock uint64,
+) *localKeep {
+	return &localKeep{
+		members:                      members,
+		honestThreshold:              honestThreshold,
+		openedBlock:                  openedBlock,
+		submittedPublicKeys:          make(map[common.Address][64]byte),
+		signatureRequestedHandlers:   make(map[int]func(event *eth.SignatureRequestedEvent)),
+		publicKeyPublishedHandlers:   make(map[int]func(event *eth.PublicKeyPublishedEvent)),
+		conflictingPublicKeyHandlers: make(map[int]func(event *eth.ConflictingPublicKeySubmittedEvent)),
+		keepClosedHandlers:           make(map[int]func(event *eth.KeepClosedEvent)),
+		keepTerminatedHandlers:       make(map[int]func(event *eth.KeepTerminatedEvent)),
+	}
+}
+
+func (lk *localKeep) isMember(address common.Address) bool {
+	return containsAddress(lk.members, address)
 }
 
 func (c *localChain) requestSignature(keepAddress common.Address, digest [32]byte) error {
@@ -35,10 +77,34 @@ func (c *localChain) requestSignature(keepAddress common.Address, digest [32]byt
 		)
 	}
 
+	if keep.status != active {
+		return fmt.Errorf("keep [%s] is not active", keepAddress.String())
+	}
+
+	// The same way as the keep contract, a keep signs one digest at a time.
+	if keep.awaitingSignature {
+		return fmt.Errorf(
+			"keep [%s] is awaiting signature for digest [%x]",
+			keepAddress.String(),
+			keep.latestDigest,
+		)
+	}
+
+	keep.latestDigest = digest
+	keep.awaitingSignature = true
+
+	blockNumber := c.pendingBlock()
 	signatureRequestedEvent := &eth.SignatureRequestedEvent{
-		Digest: digest,
+		Digest:      digest,
+		BlockNumber: blockNumber,
+		BlockHash:   c.blockHash(blockNumber),
 	}
 
+	keep.signatureRequestedEvents = append(
+		keep.signatureRequestedEvents,
+		signatureRequestedEvent,
+	)
+
 	for _, handler := range keep.signatureRequestedHandlers {
 		go func(handler func(event *eth.SignatureRequestedEvent), signatureRequestedEvent *eth.SignatureRequestedEvent) {
 			handler(signatureRequestedEvent)
@@ -47,3 +113,177 @@ func (c *localChain) requestSignature(keepAddress common.Address, digest [32]byt
 
 	return nil
 }
+
+// submitKeepPublicKey records the public key submitted by the member.
+// Once all members submitted the same key, the key is published. Each
+// submission not matching keys submitted so far by other members is reported
+// as a conflicting one.
+func (c *localChain) submitKeepPublicKey(
+	member common.Address,
+	keepAddress common.Address,
+	publicKey [64]byte,
+) error {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	keep, ok := c.keeps[keepAddress]
+	if !ok {
+		return fmt.Errorf(
+			"failed to find keep with address: [%s]",
+			keepAddress.String(),
+		)
+	}
+
+	if keep.publicKey != [64]byte{} {
+		return fmt.Errorf(
+			"public key already submitted for keep [%s]",
+			keepAddress.String(),
+		)
+	}
+
+	if !keep.isMember(member) {
+		return fmt.Errorf(
+			"[%s] is not a member of keep [%s]",
+			member.String(),
+			keepAddress.String(),
+		)
+	}
+
+	if _, ok := keep.submittedPublicKeys[member]; ok {
+		return fmt.Errorf(
+			"member [%s] already submitted public key for keep [%s]",
+			member.String(),
+			keepAddress.String(),
+		)
+	}
+
+	isConflicting := false
+	for _, submittedPublicKey := range keep.submittedPublicKeys {
+		if submittedPublicKey != publicKey {
+			isConflicting = true
+		}
+	}
+
+	keep.submittedPublicKeys[member] = publicKey
+
+	if isConflicting {
+		conflictingPublicKeyEvent := &eth.ConflictingPublicKeySubmittedEvent{
+			SubmittingMember:     member,
+			ConflictingPublicKey: publicKey[:],
+		}
+
+		for _, handler := range keep.conflictingPublicKeyHandlers {
+			go func(handler func(event *eth.ConflictingPublicKeySubmittedEvent), conflictingPublicKeyEvent *eth.ConflictingPublicKeySubmittedEvent) {
+				handler(conflictingPublicKeyEvent)
+			}(handler, conflictingPublicKeyEvent)
+		}
+
+		return nil
+	}
+
+	if len(keep.submittedPublicKeys) < len(keep.members) {
+		return nil
+	}
+
+	keep.publicKey = publicKey
+	keep.submittedPublicKeys = make(map[common.Address][64]byte)
+
+	publicKeyPublishedEvent := &eth.PublicKeyPublishedEvent{
+		PublicKey: publicKey[:],
+	}
+
+	for _, handler := range keep.publicKeyPublishedHandlers {
+		go func(handler func(event *eth.PublicKeyPublishedEvent), publicKeyPublishedEvent *eth.PublicKeyPublishedEvent) {
+			handler(publicKeyPublishedEvent)
+		}(handler, publicKeyPublishedEvent)
+	}
+
+	return nil
+}
+
+// submitSignature accepts the signature submitted by the member if the keep
+// awaits a signature. If the keep public key has been already published, the
+// signature is verified against the latest digest and the public key.
+func (c *localChain) submitSignature(
+	member common.Address,
+	keepAddress common.Address,
+	signature *ecdsa.Signature,
+) error {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	keep, ok := c.keeps[keepAddress]
+	if !ok {
+		return fmt.Errorf(
+			"failed to find keep with address: [%s]",
+			keepAddress.String(),
+		)
+	}
+
+	if !keep.isMember(member) {
+		return fmt.Errorf(
+			"[%s] is not a member of keep [%s]",
+			member.String(),
+			keepAddress.String(),
+		)
+	}
+
+	if !keep.awaitingSignature {
+		return fmt.Errorf(
+			"keep [%s] is not awaiting a signature",
+			keepAddress.String(),
+		)
+	}
+
+	if keep.publicKey != [64]byte{} {
+		publicKey := &cecdsa.PublicKey{
+			Curve: crypto.S256(),
+			X:     new(big.Int).SetBytes(keep.publicKey[:32]),
+			Y:     new(big.Int).SetBytes(keep.publicKey[32:]),
+		}
+
+		if !cecdsa.Verify(
+			publicKey,
+			keep.latestDigest[:],
+			signature.R,
+			signature.S,
+		) {
+			return fmt.Errorf(
+				"invalid signature for digest [%x] of keep [%s]",
+				keep.latestDigest,
+				keepAddress.String(),
+			)
+		}
+	}
+
+	keep.awaitingSignature = false
+
+	return nil
+}
+
+func (c *localChain) subscribeKeep(
+	keepAddress common.Address,
+	subscribe func(keep *localKeep, handlerID int),
+	unsubscribe func(keep *localKeep, handlerID int),
+) (subscription.EventSubscription, error) {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	keep, ok := c.keeps[keepAddress]
+	if !ok {
+		return nil, fmt.Errorf(
+			"failed to find keep with address: [%s]",
+			keepAddress.String(),
+		)
+	}
+
+	handlerID := rand.Int()
+	subscribe(keep, handlerID)
+
+	return subscription.NewEventSubscription(func() {
+		c.handlerMutex.Lock()
+		defer c.handlerMutex.Unlock()
+
+		unsubscribe(keep, handlerID)
+	}), nil
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/keep-network/keep-ecdsa/pkg/chain"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
 )
 
 func (c *localChain) createKeep(keepAddress common.Address) error {
@@ -18,10 +18,12 @@ func (c *localChain) createKeep(keepAddress common.Address) error {
 		)
 	}
 
-	localKeep := &localKeep{
-		signatureRequestedHandlers: make(map[int]func(event *eth.SignatureRequestedEvent)),
-		publicKey:                  [64]byte{},
-	}
+	// The keep is created with the client as its only member.
+	localKeep := newLocalKeep(
+		[]common.Address{c.clientAddress},
+		1,
+		c.pendingBlock(),
+	)
 	c.keeps[keepAddress] = localKeep
 
 	keepCreatedEvent := &eth.BondedECDSAKeepCreatedEvent{
@@ -36,3 +38,112 @@ func (c *localChain) createKeep(keepAddress common.Address) error {
 
 	return nil
 }
+
+// isEligible checks if the operator is eligible to join the signers' pool.
+// The same way as on-chain, the operator has to be authorized and has to have
+// the minimum stake.
+func (c *localChain) isEligible(operator common.Address) (bool, error) {
+	if !c.authorizations[operator] {
+		return false, nil
+	}
+
+	return c.stakeMonitor.HasMinimumStake(operator.String())
+}
+
+func (c *localChain) registerAsMemberCandidate(
+	operator common.Address,
+	application common.Address,
+) error {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	if c.registrations[application][operator] {
+		return nil
+	}
+
+	isEligible, err := c.isEligible(operator)
+	if err != nil {
+		return err
+	}
+	if !isEligible {
+		return fmt.Errorf(
+			"operator [%s] is not eligible for application [%s]",
+			operator.String(),
+			application.String(),
+		)
+	}
+
+	if _, ok := c.registrations[application]; !ok {
+		c.registrations[application] = make(map[common.Address]bool)
+	}
+	c.registrations[application][operator] = true
+
+	return nil
+}
+
+func (c *localChain) isRegisteredForApplication(
+	operator common.Address,
+	application common.Address,
+) (bool, error) {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	return c.registrations[application][operator], nil
+}
+
+func (c *localChain) isEligibleForApplication(
+	operator common.Address,
+	application common.Address,
+) (bool, error) {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	return c.isEligible(operator)
+}
+
+// isStatusUpToDateForApplication checks if the operator's presence in the
+// signers' pool reflects its current eligibility. A registered operator which
+// is no longer eligible is out of date until its status is updated.
+func (c *localChain) isStatusUpToDateForApplication(
+	operator common.Address,
+	application common.Address,
+) (bool, error) {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	isEligible, err := c.isEligible(operator)
+	if err != nil {
+		return false, err
+	}
+
+	return c.registrations[application][operator] == isEligible, nil
+}
+
+// updateStatusForApplication removes the registered operator from the
+// signers' pool if it is no longer eligible.
+func (c *localChain) updateStatusForApplication(
+	operator common.Address,
+	application common.Address,
+) error {
+	c.handlerMutex.Lock()
+	defer c.handlerMutex.Unlock()
+
+	if !c.registrations[application][operator] {
+		return fmt.Errorf(
+			"operator [%s] is not registered for application [%s]",
+			operator.String(),
+			application.String(),
+		)
+	}
+
+	isEligible, err := c.isEligible(operator)
+	if err != nil {
+		return err
+	}
+
+	if !isEligible {
+		delete(c.registrations[application], operator)
+	}
+
+	return nil
+}
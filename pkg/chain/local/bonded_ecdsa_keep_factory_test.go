@@ -52,3 +52,61 @@ func TestCreateKeep(t *testing.T) {
 		)
 	}
 }
+
+func TestRegisterAsMemberCandidate(t *testing.T) {
+	chain := initializeLocalChain()
+	operator := chain.ConnectOperator(common.BytesToAddress([]byte{2}))
+	application := common.BytesToAddress([]byte{3})
+
+	if err := operator.RegisterAsMemberCandidate(application); err == nil {
+		t.Errorf("expected error when registering ineligible operator")
+	}
+
+	chain.AuthorizeOperator(operator.Address())
+	if err := chain.stakeMonitor.StakeTokens(operator.Address().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	if isEligible, _ := operator.IsEligibleForApplication(application); !isEligible {
+		t.Fatal("operator should be eligible")
+	}
+
+	if err := operator.RegisterAsMemberCandidate(application); err != nil {
+		t.Fatal(err)
+	}
+
+	if isRegistered, _ := operator.IsRegisteredForApplication(application); !isRegistered {
+		t.Errorf("operator should be registered")
+	}
+	if isRegistered, _ := chain.IsRegisteredForApplication(application); isRegistered {
+		t.Errorf("client should not be registered")
+	}
+	if isUpToDate, _ := operator.IsStatusUpToDateForApplication(application); !isUpToDate {
+		t.Errorf("operator status should be up to date")
+	}
+
+	// The operator is no longer eligible once the stake is withdrawn. It is
+	// removed from the pool when its status is updated.
+	if err := chain.stakeMonitor.UnstakeTokens(operator.Address().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	if isUpToDate, _ := operator.IsStatusUpToDateForApplication(application); isUpToDate {
+		t.Errorf("operator status should not be up to date")
+	}
+
+	if err := operator.UpdateStatusForApplication(application); err != nil {
+		t.Fatal(err)
+	}
+
+	if isRegistered, _ := operator.IsRegisteredForApplication(application); isRegistered {
+		t.Errorf("operator should not be registered")
+	}
+	if isUpToDate, _ := operator.IsStatusUpToDateForApplication(application); !isUpToDate {
+		t.Errorf("operator status should be up to date")
+	}
+
+	if err := operator.UpdateStatusForApplication(application); err == nil {
+		t.Errorf("expected error when updating status of unregistered operator")
+	}
+}
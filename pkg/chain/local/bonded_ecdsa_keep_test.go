@@ -3,13 +3,18 @@ package local
 import (
 	"bytes"
 	"context"
+	cecdsa "crypto/ecdsa"
+	"crypto/rand"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
 )
 
 func TestRequestSignatureNonexistentKeep(t *testing.T) {
@@ -81,3 +86,314 @@ func TestRequestSignature(t *testing.T) {
 		t.Fatal(ctx.Err())
 	}
 }
+
+func TestPublicKeyPublished(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	chain := initializeLocalChain()
+	otherMember := chain.ConnectOperator(common.BytesToAddress([]byte{2}))
+	keepAddress := common.BytesToAddress([]byte{1})
+	publicKey := [64]byte{11, 12, 13}
+
+	chain.OpenKeep(
+		keepAddress,
+		[]common.Address{chain.Address(), otherMember.Address()},
+	)
+
+	publishedEvents := make(chan *eth.PublicKeyPublishedEvent, 1)
+	subscription, err := chain.OnPublicKeyPublished(
+		keepAddress,
+		func(event *eth.PublicKeyPublishedEvent) {
+			publishedEvents <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	if err := chain.SubmitKeepPublicKey(keepAddress, publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// The key is not published until all members submitted it.
+	if key, _ := chain.GetPublicKey(keepAddress); len(key) != 0 {
+		t.Errorf("unexpected public key: [%x]", key)
+	}
+
+	expectedError := fmt.Errorf(
+		"member [%s] already submitted public key for keep [%s]",
+		chain.Address().String(),
+		keepAddress.String(),
+	)
+	err = chain.SubmitKeepPublicKey(keepAddress, publicKey)
+	if !reflect.DeepEqual(expectedError, err) {
+		t.Errorf(
+			"unexpected error\nexpected: [%v]\nactual:   [%v]",
+			expectedError,
+			err,
+		)
+	}
+
+	if err := otherMember.SubmitKeepPublicKey(keepAddress, publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-publishedEvents:
+		if !bytes.Equal(publicKey[:], event.PublicKey) {
+			t.Errorf(
+				"unexpected published public key\nexpected: [%x]\nactual:   [%x]",
+				publicKey,
+				event.PublicKey,
+			)
+		}
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	key, err := chain.GetPublicKey(keepAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(publicKey[:], key) {
+		t.Errorf(
+			"unexpected keep public key\nexpected: [%x]\nactual:   [%x]",
+			publicKey,
+			key,
+		)
+	}
+}
+
+func TestConflictingPublicKeySubmitted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	chain := initializeLocalChain()
+	otherMember := chain.ConnectOperator(common.BytesToAddress([]byte{2}))
+	keepAddress := common.BytesToAddress([]byte{1})
+	conflictingPublicKey := [64]byte{21, 22, 23}
+
+	chain.OpenKeep(
+		keepAddress,
+		[]common.Address{chain.Address(), otherMember.Address()},
+	)
+
+	conflictEvents := make(chan *eth.ConflictingPublicKeySubmittedEvent, 1)
+	subscription, err := chain.OnConflictingPublicKeySubmitted(
+		keepAddress,
+		func(event *eth.ConflictingPublicKeySubmittedEvent) {
+			conflictEvents <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	if err := chain.SubmitKeepPublicKey(keepAddress, [64]byte{11, 12, 13}); err != nil {
+		t.Fatal(err)
+	}
+	if err := otherMember.SubmitKeepPublicKey(keepAddress, conflictingPublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedEvent := &eth.ConflictingPublicKeySubmittedEvent{
+		SubmittingMember:     otherMember.Address(),
+		ConflictingPublicKey: conflictingPublicKey[:],
+	}
+
+	select {
+	case event := <-conflictEvents:
+		if !reflect.DeepEqual(expectedEvent, event) {
+			t.Errorf(
+				"unexpected conflicting public key event\nexpected: [%+v]\nactual:   [%+v]",
+				expectedEvent,
+				event,
+			)
+		}
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	if key, _ := chain.GetPublicKey(keepAddress); len(key) != 0 {
+		t.Errorf("unexpected public key: [%x]", key)
+	}
+}
+
+func TestSubmitSignature(t *testing.T) {
+	chain := initializeLocalChain()
+	keepAddress := common.BytesToAddress([]byte{1})
+	digest := [32]byte{1, 2, 3}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := eth.SerializePublicKey(
+		(*ecdsa.PublicKey)(&privateKey.PublicKey),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain.OpenKeep(keepAddress, []common.Address{chain.Address()})
+	if err := chain.SubmitKeepPublicKey(keepAddress, publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.RequestSignature(keepAddress, digest); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.RequestSignature(keepAddress, [32]byte{4}); err == nil {
+		t.Errorf("expected error when requesting signature from busy keep")
+	}
+
+	latestDigest, err := chain.LatestDigest(keepAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latestDigest != digest {
+		t.Errorf(
+			"unexpected latest digest\nexpected: [%x]\nactual:   [%x]",
+			digest,
+			latestDigest,
+		)
+	}
+
+	assertAwaitingSignature(t, chain, keepAddress, digest, true)
+
+	invalidSignature := &ecdsa.Signature{R: big.NewInt(1), S: big.NewInt(2)}
+	if err := chain.SubmitSignature(keepAddress, invalidSignature); err == nil {
+		t.Errorf("expected error when submitting invalid signature")
+	}
+
+	assertAwaitingSignature(t, chain, keepAddress, digest, true)
+
+	r, s, err := cecdsa.Sign(rand.Reader, privateKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.SubmitSignature(
+		keepAddress,
+		&ecdsa.Signature{R: r, S: s},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	assertAwaitingSignature(t, chain, keepAddress, digest, false)
+
+	if err := chain.SubmitSignature(
+		keepAddress,
+		&ecdsa.Signature{R: r, S: s},
+	); err == nil {
+		t.Errorf("expected error when keep is not awaiting a signature")
+	}
+}
+
+func TestCloseAndTerminateKeep(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	chain := initializeLocalChain()
+	closedKeepAddress := common.BytesToAddress([]byte{1})
+	terminatedKeepAddress := common.BytesToAddress([]byte{2})
+
+	chain.OpenKeep(closedKeepAddress, []common.Address{chain.Address()})
+	chain.OpenKeep(terminatedKeepAddress, []common.Address{chain.Address()})
+
+	closedEvents := make(chan *eth.KeepClosedEvent, 1)
+	closedSubscription, err := chain.OnKeepClosed(
+		closedKeepAddress,
+		func(event *eth.KeepClosedEvent) {
+			closedEvents <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closedSubscription.Unsubscribe()
+
+	terminatedEvents := make(chan *eth.KeepTerminatedEvent, 1)
+	terminatedSubscription, err := chain.OnKeepTerminated(
+		terminatedKeepAddress,
+		func(event *eth.KeepTerminatedEvent) {
+			terminatedEvents <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer terminatedSubscription.Unsubscribe()
+
+	if err := chain.RequestSignature(closedKeepAddress, [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.CloseKeep(closedKeepAddress); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.TerminateKeep(terminatedKeepAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-closedEvents:
+		if event.KeepAddress != closedKeepAddress {
+			t.Errorf("unexpected closed keep [%s]", event.KeepAddress.String())
+		}
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	select {
+	case event := <-terminatedEvents:
+		if event.KeepAddress != terminatedKeepAddress {
+			t.Errorf("unexpected terminated keep [%s]", event.KeepAddress.String())
+		}
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	for _, keepAddress := range []common.Address{
+		closedKeepAddress,
+		terminatedKeepAddress,
+	} {
+		if isActive, _ := chain.IsActive(keepAddress); isActive {
+			t.Errorf("keep [%s] should not be active", keepAddress.String())
+		}
+
+		if err := chain.RequestSignature(keepAddress, [32]byte{2}); err == nil {
+			t.Errorf("expected error when requesting signature from inactive keep")
+		}
+
+		if err := chain.CloseKeep(keepAddress); err == nil {
+			t.Errorf("expected error when closing inactive keep")
+		}
+	}
+
+	assertAwaitingSignature(t, chain, closedKeepAddress, [32]byte{1}, false)
+}
+
+func assertAwaitingSignature(
+	t *testing.T,
+	chain *localChain,
+	keepAddress common.Address,
+	digest [32]byte,
+	expected bool,
+) {
+	isAwaitingSignature, err := chain.IsAwaitingSignature(keepAddress, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isAwaitingSignature != expected {
+		t.Errorf(
+			"unexpected awaiting signature state\nexpected: [%v]\nactual:   [%v]",
+			expected,
+			isAwaitingSignature,
+		)
+	}
+}
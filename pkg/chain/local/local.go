@@ -8,12 +8,22 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/keep-network/keep-common/pkg/subscription"
 	"github.com/keep-network/keep-core/pkg/chain"
+	corelocal "github.com/keep-network/keep-core/pkg/chain/local"
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
 )
 
+// localBlockTime is the interval in which blocks are mined by the local block
+// counter.
+const localBlockTime = 500 * time.Millisecond
+
+// localMinimumStake is the minimum stake operators of the local chain have to
+// have to be eligible for joining signers' pools.
+var localMinimumStake = big.NewInt(1000)
+
 // Chain is an extention of eth.Handle interface which exposes
 // additional functions useful for testing.
 type Chain interface {
@@ -21,7 +31,11 @@ type Chain interface {
 
 	OpenKeep(keepAddress common.Address, members []common.Address)
 	CloseKeep(keepAddress common.Address) error
+	TerminateKeep(keepAddress common.Address) error
+	RequestSignature(keepAddress common.Address, digest [32]byte) error
+	Reorg(fromBlock uint64)
 	AuthorizeOperator(operatorAddress common.Address)
+	ConnectOperator(operatorAddress common.Address) Chain
 }
 
 // localChain is an implementation of ethereum blockchain interface.
@@ -36,30 +50,127 @@ type localChain struct {
 
 	keepCreatedHandlers map[int]func(event *eth.BondedECDSAKeepCreatedEvent)
 
+	keepCreatedEvents    []*eth.BondedECDSAKeepCreatedEvent
+	keepClosedEvents     []*eth.KeepClosedEvent
+	keepTerminatedEvents []*eth.KeepTerminatedEvent
+
 	clientAddress common.Address
 
 	authorizations map[common.Address]bool
+	// registrations holds operators registered in signers' pools of
+	// applications.
+	registrations map[common.Address]map[common.Address]bool
+
+	stakeMonitor *corelocal.StakeMonitor
+	blockCounter chain.BlockCounter
+	// reorgs holds blocks from which the chain has been reorganized. Hash of
+	// each block depends on the number of reorganizations it went through.
+	reorgs []uint64
+
+	genesisTime time.Time
 }
 
 // Connect performs initialization for communication with Ethereum blockchain
 // based on provided config.
 func Connect() Chain {
+	blockCounter, err := corelocal.BlockCounter()
+	if err != nil {
+		panic(fmt.Sprintf("failed to create local block counter: [%v]", err))
+	}
+
 	return &localChain{
 		keeps:               make(map[common.Address]*localKeep),
 		keepCreatedHandlers: make(map[int]func(event *eth.BondedECDSAKeepCreatedEvent)),
 		clientAddress:       common.HexToAddress("6299496199d99941193Fdd2d717ef585F431eA05"),
 		authorizations:      make(map[common.Address]bool),
+		registrations:       make(map[common.Address]map[common.Address]bool),
+		stakeMonitor:        corelocal.NewStakeMonitor(localMinimumStake),
+		blockCounter:        blockCounter,
+		genesisTime:         time.Now(),
+	}
+}
+
+// pendingBlock returns the number of the block which is currently being
+// mined. Events emitted by the local chain are reported in this block, the
+// same way transactions submitted to a real chain are mined in one of the
+// next blocks.
+func (lc *localChain) pendingBlock() uint64 {
+	currentBlock, err := lc.blockCounter.CurrentBlock()
+	if err != nil {
+		panic(fmt.Sprintf("failed to get current block: [%v]", err))
 	}
+
+	return currentBlock + 1
+}
+
+// blockHash returns the hash of the block with the given number in the
+// current version of the chain.
+func (lc *localChain) blockHash(blockNumber uint64) common.Hash {
+	version := 0
+	for _, reorgBlock := range lc.reorgs {
+		if reorgBlock <= blockNumber {
+			version++
+		}
+	}
+
+	return crypto.Keccak256Hash(
+		new(big.Int).SetUint64(blockNumber).Bytes(),
+		big.NewInt(int64(version)).Bytes(),
+	)
+}
+
+// Reorg simulates a chain reorganization starting from the given block.
+// Hashes of the given block and all blocks after it change. Events emitted
+// in the reorganized blocks stay in the chain but with their original block
+// hashes, as if they were mined again in the same blocks of a new fork.
+func (lc *localChain) Reorg(fromBlock uint64) {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	lc.reorgs = append(lc.reorgs, fromBlock)
+}
+
+func (lc *localChain) BlockHash(blockNumber uint64) (common.Hash, error) {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	return lc.blockHash(blockNumber), nil
+}
+
+// blockTimestamp returns the timestamp of the block with the given number.
+// Local blocks are mined in equal intervals starting from the moment the local
+// chain has been connected.
+func (lc *localChain) blockTimestamp(blockNumber uint64) time.Time {
+	return lc.genesisTime.Add(time.Duration(blockNumber) * localBlockTime)
+}
+
+func (lc *localChain) BlockTimestamp(blockNumber uint64) (time.Time, error) {
+	return lc.blockTimestamp(blockNumber), nil
 }
 
 func (lc *localChain) OpenKeep(keepAddress common.Address, members []common.Address) {
 	lc.handlerMutex.Lock()
 	defer lc.handlerMutex.Unlock()
 
-	lc.keeps[keepAddress] = &localKeep{
-		members: members,
-	}
+	blockNumber := lc.pendingBlock()
+
+	lc.keeps[keepAddress] = newLocalKeep(
+		members,
+		uint64(len(members)),
+		blockNumber,
+	)
 	lc.keepAddresses = append(lc.keepAddresses, keepAddress)
+
+	lc.keepCreatedEvents = append(
+		lc.keepCreatedEvents,
+		&eth.BondedECDSAKeepCreatedEvent{
+			KeepAddress:     keepAddress,
+			Members:         members,
+			HonestThreshold: uint64(len(members)),
+			BlockNumber:     blockNumber,
+			BlockHash:       lc.blockHash(blockNumber),
+		},
+	)
 }
 
 func (lc *localChain) CloseKeep(keepAddress common.Address) error {
@@ -70,7 +181,68 @@ func (lc *localChain) CloseKeep(keepAddress common.Address) error {
 	if !ok {
 		return fmt.Errorf("no keep with address [%v]", keepAddress)
 	}
+
+	if keep.status != active {
+		return fmt.Errorf("keep [%v] is not active", keepAddress)
+	}
+
 	keep.status = closed
+	keep.awaitingSignature = false
+
+	blockNumber := lc.pendingBlock()
+	keepClosedEvent := &eth.KeepClosedEvent{
+		KeepAddress: keepAddress,
+		BlockNumber: blockNumber,
+		BlockHash:   lc.blockHash(blockNumber),
+	}
+	lc.keepClosedEvents = append(lc.keepClosedEvents, keepClosedEvent)
+
+	for _, handler := range keep.keepClosedHandlers {
+		go func(handler func(event *eth.KeepClosedEvent), keepClosedEvent *eth.KeepClosedEvent) {
+			handler(keepClosedEvent)
+		}(handler, keepClosedEvent)
+	}
+
+	return nil
+}
+
+func (lc *localChain) RequestSignature(
+	keepAddress common.Address,
+	digest [32]byte,
+) error {
+	return lc.requestSignature(keepAddress, digest)
+}
+
+func (lc *localChain) TerminateKeep(keepAddress common.Address) error {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	if keep.status != active {
+		return fmt.Errorf("keep [%v] is not active", keepAddress)
+	}
+
+	keep.status = terminated
+	keep.awaitingSignature = false
+
+	blockNumber := lc.pendingBlock()
+	keepTerminatedEvent := &eth.KeepTerminatedEvent{
+		KeepAddress: keepAddress,
+		BlockNumber: blockNumber,
+		BlockHash:   lc.blockHash(blockNumber),
+	}
+	lc.keepTerminatedEvents = append(lc.keepTerminatedEvents, keepTerminatedEvent)
+
+	for _, handler := range keep.keepTerminatedHandlers {
+		go func(handler func(event *eth.KeepTerminatedEvent), keepTerminatedEvent *eth.KeepTerminatedEvent) {
+			handler(keepTerminatedEvent)
+		}(handler, keepTerminatedEvent)
+	}
+
 	return nil
 }
 
@@ -86,14 +258,26 @@ func (lc *localChain) Address() common.Address {
 	return lc.clientAddress
 }
 
+// ConnectOperator returns a handle to the same local chain used by
+// the operator with the given address. Clients of all members of a keep can
+// share the local chain this way.
+func (lc *localChain) ConnectOperator(operatorAddress common.Address) Chain {
+	return &operatorChain{
+		localChain: lc,
+		address:    operatorAddress,
+	}
+}
+
+// StakeMonitor returns a local stake monitor. Operators have to stake
+// the minimum stake with the monitor to be eligible for signers' pools.
 func (lc *localChain) StakeMonitor() (chain.StakeMonitor, error) {
-	return nil, nil // not implemented.
+	return lc.stakeMonitor, nil
 }
 
 // RegisterAsMemberCandidate registers client as a candidate to be selected
 // to a keep.
 func (lc *localChain) RegisterAsMemberCandidate(application common.Address) error {
-	return nil
+	return lc.registerAsMemberCandidate(lc.clientAddress, application)
 }
 
 // OnBondedECDSAKeepCreated is a callback that is invoked when an on-chain
@@ -122,53 +306,24 @@ func (lc *localChain) OnSignatureRequested(
 	keepAddress common.Address,
 	handler func(event *eth.SignatureRequestedEvent),
 ) (subscription.EventSubscription, error) {
-	lc.handlerMutex.Lock()
-	defer lc.handlerMutex.Unlock()
-
-	handlerID := rand.Int()
-
-	keep, ok := lc.keeps[keepAddress]
-	if !ok {
-		return nil, fmt.Errorf(
-			"failed to find keep with address: [%s]",
-			keepAddress.String(),
-		)
-	}
-
-	keep.signatureRequestedHandlers[handlerID] = handler
-
-	return subscription.NewEventSubscription(func() {
-		lc.handlerMutex.Lock()
-		defer lc.handlerMutex.Unlock()
-
-		delete(keep.signatureRequestedHandlers, handlerID)
-	}), nil
-}
-
-// SubmitKeepPublicKey checks if public key has been already submitted for given
-// keep address, if not it stores the key in a map.
+	return lc.subscribeKeep(
+		keepAddress,
+		func(keep *localKeep, handlerID int) {
+			keep.signatureRequestedHandlers[handlerID] = handler
+		},
+		func(keep *localKeep, handlerID int) {
+			delete(keep.signatureRequestedHandlers, handlerID)
+		},
+	)
+}
+
+// SubmitKeepPublicKey submits the public key calculated by the client to
+// the keep. The key is published once all members submitted the same key.
 func (lc *localChain) SubmitKeepPublicKey(
 	keepAddress common.Address,
 	publicKey [64]byte,
 ) error {
-	keep, ok := lc.keeps[keepAddress]
-	if !ok {
-		return fmt.Errorf(
-			"failed to find keep with address: [%s]",
-			keepAddress.String(),
-		)
-	}
-
-	if keep.publicKey != [64]byte{} {
-		return fmt.Errorf(
-			"public key already submitted for keep [%s]",
-			keepAddress.String(),
-		)
-	}
-
-	keep.publicKey = publicKey
-
-	return nil
+	return lc.submitKeepPublicKey(lc.clientAddress, keepAddress, publicKey)
 }
 
 // SubmitSignature submits a signature to a keep contract deployed under a
@@ -177,7 +332,7 @@ func (lc *localChain) SubmitSignature(
 	keepAddress common.Address,
 	signature *ecdsa.Signature,
 ) error {
-	return nil
+	return lc.submitSignature(lc.clientAddress, keepAddress, signature)
 }
 
 // IsAwaitingSignature checks if the keep is waiting for a signature to be
@@ -186,7 +341,15 @@ func (lc *localChain) IsAwaitingSignature(
 	keepAddress common.Address,
 	digest [32]byte,
 ) (bool, error) {
-	panic("implement")
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return false, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	return keep.awaitingSignature && keep.latestDigest == digest, nil
 }
 
 // IsActive checks for current state of a keep on-chain.
@@ -203,23 +366,23 @@ func (lc *localChain) IsActive(keepAddress common.Address) (bool, error) {
 }
 
 func (lc *localChain) BlockCounter() chain.BlockCounter {
-	panic("implement")
+	return lc.blockCounter
 }
 
 func (lc *localChain) IsRegisteredForApplication(application common.Address) (bool, error) {
-	panic("implement")
+	return lc.isRegisteredForApplication(lc.clientAddress, application)
 }
 
 func (lc *localChain) IsEligibleForApplication(application common.Address) (bool, error) {
-	panic("implement")
+	return lc.isEligibleForApplication(lc.clientAddress, application)
 }
 
 func (lc *localChain) IsStatusUpToDateForApplication(application common.Address) (bool, error) {
-	panic("implement")
+	return lc.isStatusUpToDateForApplication(lc.clientAddress, application)
 }
 
 func (lc *localChain) UpdateStatusForApplication(application common.Address) error {
-	panic("implement")
+	return lc.updateStatusForApplication(lc.clientAddress, application)
 }
 
 func (lc *localChain) IsOperatorAuthorized(operator common.Address) (bool, error) {
@@ -255,43 +418,112 @@ func (lc *localChain) OnKeepClosed(
 	keepAddress common.Address,
 	handler func(event *eth.KeepClosedEvent),
 ) (subscription.EventSubscription, error) {
-	panic("implement")
+	return lc.subscribeKeep(
+		keepAddress,
+		func(keep *localKeep, handlerID int) {
+			keep.keepClosedHandlers[handlerID] = handler
+		},
+		func(keep *localKeep, handlerID int) {
+			delete(keep.keepClosedHandlers, handlerID)
+		},
+	)
 }
 
 func (lc *localChain) OnKeepTerminated(
 	keepAddress common.Address,
 	handler func(event *eth.KeepTerminatedEvent),
 ) (subscription.EventSubscription, error) {
-	panic("implement")
+	return lc.subscribeKeep(
+		keepAddress,
+		func(keep *localKeep, handlerID int) {
+			keep.keepTerminatedHandlers[handlerID] = handler
+		},
+		func(keep *localKeep, handlerID int) {
+			delete(keep.keepTerminatedHandlers, handlerID)
+		},
+	)
 }
 
 func (lc *localChain) OnConflictingPublicKeySubmitted(
 	keepAddress common.Address,
 	handler func(event *eth.ConflictingPublicKeySubmittedEvent),
 ) (subscription.EventSubscription, error) {
-	panic("implement")
+	return lc.subscribeKeep(
+		keepAddress,
+		func(keep *localKeep, handlerID int) {
+			keep.conflictingPublicKeyHandlers[handlerID] = handler
+		},
+		func(keep *localKeep, handlerID int) {
+			delete(keep.conflictingPublicKeyHandlers, handlerID)
+		},
+	)
 }
 
 func (lc *localChain) OnPublicKeyPublished(
 	keepAddress common.Address,
 	handler func(event *eth.PublicKeyPublishedEvent),
 ) (subscription.EventSubscription, error) {
-	panic("implement")
+	return lc.subscribeKeep(
+		keepAddress,
+		func(keep *localKeep, handlerID int) {
+			keep.publicKeyPublishedHandlers[handlerID] = handler
+		},
+		func(keep *localKeep, handlerID int) {
+			delete(keep.publicKeyPublishedHandlers, handlerID)
+		},
+	)
 }
 
 func (lc *localChain) LatestDigest(keepAddress common.Address) ([32]byte, error) {
-	panic("implement")
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return [32]byte{}, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	return keep.latestDigest, nil
 }
 
 func (lc *localChain) SignatureRequestedBlock(
 	keepAddress common.Address,
 	digest [32]byte,
 ) (uint64, error) {
-	panic("implement")
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return 0, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	// The same digest may be requested more than once. The contract keeps
+	// the block of the latest request.
+	for i := len(keep.signatureRequestedEvents) - 1; i >= 0; i-- {
+		if keep.signatureRequestedEvents[i].Digest == digest {
+			return keep.signatureRequestedEvents[i].BlockNumber, nil
+		}
+	}
+
+	return 0, nil
 }
 
 func (lc *localChain) GetPublicKey(keepAddress common.Address) ([]uint8, error) {
-	panic("implement")
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return nil, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	if keep.publicKey == [64]byte{} {
+		return []uint8{}, nil
+	}
+
+	publicKey := keep.publicKey
+	return publicKey[:], nil
 }
 
 func (lc *localChain) GetMembers(
@@ -310,9 +542,122 @@ func (lc *localChain) GetMembers(
 func (lc *localChain) GetHonestThreshold(
 	keepAddress common.Address,
 ) (uint64, error) {
-	panic("implement")
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return 0, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	return keep.honestThreshold, nil
 }
 
 func (lc *localChain) GetOpenedTimestamp(keepAddress common.Address) (time.Time, error) {
-	panic("implement")
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return time.Time{}, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	return lc.blockTimestamp(keep.openedBlock), nil
+}
+
+func (lc *localChain) PastBondedECDSAKeepCreatedEvents(
+	startBlock uint64,
+	endBlock uint64,
+) ([]*eth.BondedECDSAKeepCreatedEvent, error) {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	events := make([]*eth.BondedECDSAKeepCreatedEvent, 0)
+	for _, event := range lc.keepCreatedEvents {
+		if event.BlockNumber >= startBlock && event.BlockNumber <= endBlock {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (lc *localChain) PastSignatureRequestedEvents(
+	keepAddress common.Address,
+	startBlock uint64,
+	endBlock uint64,
+) ([]*eth.SignatureRequestedEvent, error) {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	keep, ok := lc.keeps[keepAddress]
+	if !ok {
+		return nil, fmt.Errorf("no keep with address [%v]", keepAddress)
+	}
+
+	events := make([]*eth.SignatureRequestedEvent, 0)
+	for _, event := range keep.signatureRequestedEvents {
+		if event.BlockNumber >= startBlock && event.BlockNumber <= endBlock {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (lc *localChain) PastKeepClosedEvents(
+	startBlock uint64,
+	endBlock uint64,
+	keepAddresses ...common.Address,
+) ([]*eth.KeepClosedEvent, error) {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	events := make([]*eth.KeepClosedEvent, 0)
+	for _, event := range lc.keepClosedEvents {
+		if event.BlockNumber < startBlock || event.BlockNumber > endBlock {
+			continue
+		}
+
+		if len(keepAddresses) > 0 && !containsAddress(keepAddresses, event.KeepAddress) {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (lc *localChain) PastKeepTerminatedEvents(
+	startBlock uint64,
+	endBlock uint64,
+	keepAddresses ...common.Address,
+) ([]*eth.KeepTerminatedEvent, error) {
+	lc.handlerMutex.Lock()
+	defer lc.handlerMutex.Unlock()
+
+	events := make([]*eth.KeepTerminatedEvent, 0)
+	for _, event := range lc.keepTerminatedEvents {
+		if event.BlockNumber < startBlock || event.BlockNumber > endBlock {
+			continue
+		}
+
+		if len(keepAddresses) > 0 && !containsAddress(keepAddresses, event.KeepAddress) {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
 }
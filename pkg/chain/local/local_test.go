@@ -76,13 +76,20 @@ func TestOnSignatureRequested(t *testing.T) {
 	}
 	defer subscription.Unsubscribe()
 
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	err = chain.requestSignature(keepAddress, digest)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	expectedEvent := &eth.SignatureRequestedEvent{
-		Digest: digest,
+		Digest:      digest,
+		BlockNumber: currentBlock + 1,
+		BlockHash:   chain.blockHash(currentBlock + 1),
 	}
 
 	select {
@@ -145,3 +152,72 @@ func TestSubmitKeepPublicKey(t *testing.T) {
 func initializeLocalChain() *localChain {
 	return Connect().(*localChain)
 }
+
+func TestBlockHashReorg(t *testing.T) {
+	chain := initializeLocalChain()
+
+	hash1, _ := chain.BlockHash(1)
+	hash2, _ := chain.BlockHash(2)
+	hash3, _ := chain.BlockHash(3)
+
+	if hash1 == hash2 || hash2 == hash3 {
+		t.Fatal("blocks should have different hashes")
+	}
+
+	chain.Reorg(2)
+
+	if hash, _ := chain.BlockHash(1); hash != hash1 {
+		t.Errorf("hash of block before reorg should not change")
+	}
+	if hash, _ := chain.BlockHash(2); hash == hash2 {
+		t.Errorf("hash of reorganized block should change")
+	}
+	if hash, _ := chain.BlockHash(3); hash == hash3 {
+		t.Errorf("hash of block after reorganized block should change")
+	}
+}
+
+func TestKeepTimestamps(t *testing.T) {
+	chain := initializeLocalChain()
+	keepAddress := common.BytesToAddress([]byte{1})
+	digest := [32]byte{1}
+
+	chain.OpenKeep(keepAddress, []common.Address{chain.Address()})
+
+	openedTimestamp, err := chain.GetOpenedTimestamp(keepAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openedBlockTimestamp, _ := chain.BlockTimestamp(chain.keeps[keepAddress].openedBlock)
+	if !openedTimestamp.Equal(openedBlockTimestamp) {
+		t.Errorf(
+			"unexpected opened timestamp\nexpected: [%v]\nactual:   [%v]",
+			openedBlockTimestamp,
+			openedTimestamp,
+		)
+	}
+
+	if err := chain.RequestSignature(keepAddress, digest); err != nil {
+		t.Fatal(err)
+	}
+
+	requestBlock, err := chain.SignatureRequestedBlock(keepAddress, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedRequestBlock := chain.keeps[keepAddress].signatureRequestedEvents[0].BlockNumber
+	if requestBlock != expectedRequestBlock {
+		t.Errorf(
+			"unexpected signature requested block\nexpected: [%v]\nactual:   [%v]",
+			expectedRequestBlock,
+			requestBlock,
+		)
+	}
+
+	requestTimestamp, _ := chain.BlockTimestamp(requestBlock)
+	if requestTimestamp.Before(openedTimestamp) {
+		t.Errorf("signature requested before the keep was opened")
+	}
+}
@@ -0,0 +1,54 @@
+package local
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
+)
+
+// operatorChain is a handle to the local chain used by an operator other than
+// the client the chain has been connected for. Transactions submitted with
+// the handle are submitted by the operator.
+type operatorChain struct {
+	*localChain
+
+	address common.Address
+}
+
+// Address returns operator's ethereum address.
+func (oc *operatorChain) Address() common.Address {
+	return oc.address
+}
+
+func (oc *operatorChain) RegisterAsMemberCandidate(application common.Address) error {
+	return oc.registerAsMemberCandidate(oc.address, application)
+}
+
+func (oc *operatorChain) IsRegisteredForApplication(application common.Address) (bool, error) {
+	return oc.isRegisteredForApplication(oc.address, application)
+}
+
+func (oc *operatorChain) IsEligibleForApplication(application common.Address) (bool, error) {
+	return oc.isEligibleForApplication(oc.address, application)
+}
+
+func (oc *operatorChain) IsStatusUpToDateForApplication(application common.Address) (bool, error) {
+	return oc.isStatusUpToDateForApplication(oc.address, application)
+}
+
+func (oc *operatorChain) UpdateStatusForApplication(application common.Address) error {
+	return oc.updateStatusForApplication(oc.address, application)
+}
+
+func (oc *operatorChain) SubmitKeepPublicKey(
+	keepAddress common.Address,
+	publicKey [64]byte,
+) error {
+	return oc.submitKeepPublicKey(oc.address, keepAddress, publicKey)
+}
+
+func (oc *operatorChain) SubmitSignature(
+	keepAddress common.Address,
+	signature *ecdsa.Signature,
+) error {
+	return oc.submitSignature(oc.address, keepAddress, signature)
+}
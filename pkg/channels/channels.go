@@ -0,0 +1,453 @@
+// Package channels manages lifetime of network channels. Broadcast and unicast
+// channels are opened by owners, such as protocol sessions of a keep, and are
+// torn down once no owner uses them anymore. All owners of a keep can be
+// released at once when the keep is closed.
+package channels
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/keep-core/pkg/net"
+)
+
+var logger = log.Logger("keep-channels")
+
+// Closer is implemented by network providers able to release resources of
+// channels which are no longer used, e.g. to leave the pubsub topic of
+// a broadcast channel. Providers wrapping other providers should implement it
+// and forward closing to the wrapped provider.
+//
+// The libp2p provider of keep-core does not implement Closer yet. Its unicast
+// channels open a stream for each message, so they hold no resources once
+// handlers of their owners are removed, but its broadcast channels stay
+// subscribed to their topics until the provider is stopped. Leaving topics of
+// unused broadcast channels requires closing support in keep-core.
+type Closer interface {
+	CloseBroadcastChannel(name string) error
+	CloseUnicastChannel(peerID net.TransportIdentifier) error
+}
+
+// Manager counts owners of broadcast and unicast channels of the network
+// provider. Channels are opened with the provider once and shared by owners
+// until the last owner releases them; then the channel is closed if
+// the provider supports it. Handlers registered and messages sent by
+// an owner are removed and no longer retransmitted once the owner is released,
+// even if the channel is still used by other owners.
+//
+// Reference counts are kept per manager, so all users of the provider should
+// share the same manager.
+type Manager struct {
+	provider net.Provider
+
+	broadcastChannels *references
+	unicastChannels   *references
+
+	mutex sync.Mutex
+	// owners are owners of channels grouped by the name of the group they
+	// open channels for, e.g. the keep address.
+	owners map[string]map[*Owner]bool
+}
+
+// NewManager creates a manager of channels of the provided network provider.
+func NewManager(provider net.Provider) *Manager {
+	return &Manager{
+		provider:          provider,
+		broadcastChannels: newReferences(),
+		unicastChannels:   newReferences(),
+		owners:            make(map[string]map[*Owner]bool),
+	}
+}
+
+// NewOwner creates an owner of channels opened for the given group. Channels
+// are held by the owner until it is released or until all owners of the group
+// are released.
+func (m *Manager) NewOwner(group string) *Owner {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	owner := &Owner{
+		Provider:          m.provider,
+		manager:           m,
+		group:             group,
+		ctx:               ctx,
+		cancel:            cancel,
+		broadcastChannels: make(map[string]*broadcastChannel),
+		unicastChannels:   make(map[string]*unicastChannel),
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.owners[group]; !ok {
+		m.owners[group] = make(map[*Owner]bool)
+	}
+	m.owners[group][owner] = true
+
+	return owner
+}
+
+// ReleaseGroup releases channels held by all owners of the given group.
+// It is safe to call on a nil manager.
+func (m *Manager) ReleaseGroup(group string) {
+	if m == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	owners := m.owners[group]
+	delete(m.owners, group)
+	m.mutex.Unlock()
+
+	if len(owners) == 0 {
+		return
+	}
+
+	logger.Debugf("releasing channels of [%d] owners of [%s]", len(owners), group)
+
+	for owner := range owners {
+		owner.Release()
+	}
+}
+
+func (m *Manager) acquireBroadcastChannel(name string) (net.BroadcastChannel, error) {
+	channel, err := m.broadcastChannels.acquire(name, func() (interface{}, error) {
+		return m.provider.BroadcastChannelFor(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	broadcastChannel, _ := channel.(net.BroadcastChannel)
+	return broadcastChannel, nil
+}
+
+func (m *Manager) releaseBroadcastChannel(name string) {
+	m.broadcastChannels.release(name, func(interface{}) {
+		logger.Debugf("broadcast channel [%s] is no longer used", name)
+
+		closer, ok := m.provider.(Closer)
+		if !ok {
+			return
+		}
+
+		if err := closer.CloseBroadcastChannel(name); err != nil {
+			logger.Warningf("failed to close broadcast channel [%s]: [%v]", name, err)
+		}
+	})
+}
+
+func (m *Manager) acquireUnicastChannel(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	channel, err := m.unicastChannels.acquire(
+		peerID.String(),
+		func() (interface{}, error) {
+			return m.provider.UnicastChannelWith(peerID)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	unicastChannel, _ := channel.(net.UnicastChannel)
+	return unicastChannel, nil
+}
+
+func (m *Manager) releaseUnicastChannel(peerID net.TransportIdentifier) {
+	m.unicastChannels.release(peerID.String(), func(interface{}) {
+		logger.Debugf("unicast channel with [%s] is no longer used", peerID.String())
+
+		closer, ok := m.provider.(Closer)
+		if !ok {
+			return
+		}
+
+		if err := closer.CloseUnicastChannel(peerID); err != nil {
+			logger.Warningf(
+				"failed to close unicast channel with [%s]: [%v]",
+				peerID.String(),
+				err,
+			)
+		}
+	})
+}
+
+// references counts owners of channels identified by keys. Channels are
+// opened and closed with the network provider without holding the mutex, so
+// that network operations on one channel do not block other channels.
+// Opening and closing of the same channel wait for each other.
+type references struct {
+	mutex   sync.Mutex
+	entries map[string]*reference
+}
+
+type reference struct {
+	channel interface{}
+	count   int
+	// pending is set while the channel is being opened or closed with
+	// the provider and is closed once the operation completes.
+	pending chan struct{}
+}
+
+func newReferences() *references {
+	return &references{
+		entries: make(map[string]*reference),
+	}
+}
+
+// acquire returns the channel with the given key, opening it with the open
+// function if no owner holds it yet.
+func (r *references) acquire(
+	key string,
+	open func() (interface{}, error),
+) (interface{}, error) {
+	r.mutex.Lock()
+	for {
+		entry, ok := r.entries[key]
+		if !ok {
+			break
+		}
+
+		if entry.pending == nil {
+			entry.count++
+			r.mutex.Unlock()
+			return entry.channel, nil
+		}
+
+		pending := entry.pending
+		r.mutex.Unlock()
+		<-pending
+		r.mutex.Lock()
+	}
+
+	entry := &reference{pending: make(chan struct{})}
+	r.entries[key] = entry
+	r.mutex.Unlock()
+
+	channel, err := open()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	close(entry.pending)
+	entry.pending = nil
+
+	if err != nil {
+		delete(r.entries, key)
+		return nil, err
+	}
+
+	entry.channel = channel
+	entry.count = 1
+
+	return channel, nil
+}
+
+// release decrements the number of owners of the channel with the given key
+// and closes the channel with the provided function once no owner holds it.
+func (r *references) release(key string, closeChannel func(channel interface{})) {
+	r.mutex.Lock()
+
+	entry, ok := r.entries[key]
+	if !ok || entry.pending != nil {
+		r.mutex.Unlock()
+		return
+	}
+
+	entry.count--
+	if entry.count > 0 {
+		r.mutex.Unlock()
+		return
+	}
+
+	pending := make(chan struct{})
+	entry.pending = pending
+	r.mutex.Unlock()
+
+	closeChannel(entry.channel)
+
+	r.mutex.Lock()
+	delete(r.entries, key)
+	close(pending)
+	r.mutex.Unlock()
+}
+
+func (m *Manager) removeOwner(owner *Owner) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	owners, ok := m.owners[owner.group]
+	if !ok {
+		return
+	}
+
+	delete(owners, owner)
+	if len(owners) == 0 {
+		delete(m.owners, owner.group)
+	}
+}
+
+// Owner is a network provider holding channels it opened until it is
+// released. It can be passed to code opening channels with the network
+// provider, e.g. to a protocol session.
+type Owner struct {
+	net.Provider
+
+	manager *Manager
+	group   string
+
+	// ctx is done when the owner is released. Handlers registered and
+	// messages sent with channels of the owner are bound to it.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex             sync.Mutex
+	broadcastChannels map[string]*broadcastChannel
+	unicastChannels   map[string]*unicastChannel
+	isReleased        bool
+}
+
+// BroadcastChannelFor provides a broadcast channel held by the owner.
+func (o *Owner) BroadcastChannelFor(name string) (net.BroadcastChannel, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.isReleased {
+		return nil, fmt.Errorf("channels of [%s] have been released", o.group)
+	}
+
+	if channel, ok := o.broadcastChannels[name]; ok {
+		return channel, nil
+	}
+
+	channel, err := o.manager.acquireBroadcastChannel(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ownedChannel := &broadcastChannel{
+		BroadcastChannel: channel,
+		ownerCtx:         o.ctx,
+	}
+	o.broadcastChannels[name] = ownedChannel
+
+	return ownedChannel, nil
+}
+
+// UnicastChannelWith provides a unicast channel with the peer held by
+// the owner.
+func (o *Owner) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.isReleased {
+		return nil, fmt.Errorf("channels of [%s] have been released", o.group)
+	}
+
+	if channel, ok := o.unicastChannels[peerID.String()]; ok {
+		return channel, nil
+	}
+
+	channel, err := o.manager.acquireUnicastChannel(peerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownedChannel := &unicastChannel{
+		UnicastChannel: channel,
+		peerID:         peerID,
+		ownerCtx:       o.ctx,
+	}
+	o.unicastChannels[peerID.String()] = ownedChannel
+
+	return ownedChannel, nil
+}
+
+// Release releases all channels held by the owner. Handlers registered with
+// the channels by the owner are removed and messages sent by the owner are no
+// longer retransmitted. Channels can not be opened with a released owner.
+func (o *Owner) Release() {
+	o.mutex.Lock()
+	if o.isReleased {
+		o.mutex.Unlock()
+		return
+	}
+	o.isReleased = true
+	o.cancel()
+
+	broadcastChannels := o.broadcastChannels
+	unicastChannels := o.unicastChannels
+	o.broadcastChannels = nil
+	o.unicastChannels = nil
+	o.mutex.Unlock()
+
+	for name := range broadcastChannels {
+		o.manager.releaseBroadcastChannel(name)
+	}
+	for _, channel := range unicastChannels {
+		o.manager.releaseUnicastChannel(channel.peerID)
+	}
+
+	o.manager.removeOwner(o)
+}
+
+type broadcastChannel struct {
+	net.BroadcastChannel
+
+	ownerCtx context.Context
+}
+
+func (bc *broadcastChannel) Send(ctx context.Context, m net.TaggedMarshaler) error {
+	if bc.ownerCtx.Err() != nil {
+		return fmt.Errorf("broadcast channel [%s] has been released", bc.Name())
+	}
+
+	return bc.BroadcastChannel.Send(bound(ctx, bc.ownerCtx), m)
+}
+
+func (bc *broadcastChannel) Recv(ctx context.Context, handler func(m net.Message)) {
+	bc.BroadcastChannel.Recv(bound(ctx, bc.ownerCtx), handler)
+}
+
+type unicastChannel struct {
+	net.UnicastChannel
+
+	peerID   net.TransportIdentifier
+	ownerCtx context.Context
+}
+
+func (uc *unicastChannel) Send(m net.TaggedMarshaler) error {
+	if uc.ownerCtx.Err() != nil {
+		return fmt.Errorf(
+			"unicast channel with [%s] has been released",
+			uc.peerID.String(),
+		)
+	}
+
+	return uc.UnicastChannel.Send(m)
+}
+
+func (uc *unicastChannel) Recv(ctx context.Context, handler func(m net.Message)) {
+	uc.UnicastChannel.Recv(bound(ctx, uc.ownerCtx), handler)
+}
+
+// bound returns a context which is done when the provided context or
+// the owner's context is done.
+func bound(ctx context.Context, ownerCtx context.Context) context.Context {
+	boundCtx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+
+		select {
+		case <-ownerCtx.Done():
+		case <-boundCtx.Done():
+		}
+	}()
+
+	return boundCtx
+}
@@ -0,0 +1,351 @@
+package channels
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+)
+
+func TestOwnersShareChannels(t *testing.T) {
+	provider := newTestProvider()
+	manager := NewManager(provider)
+
+	owner1 := manager.NewOwner("keep")
+	owner2 := manager.NewOwner("keep")
+
+	if _, err := owner1.BroadcastChannelFor("keep"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owner1.BroadcastChannelFor("keep"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owner2.BroadcastChannelFor("keep"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owner1.UnicastChannelWith(testTransportID("peer")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := owner2.UnicastChannelWith(testTransportID("peer")); err != nil {
+		t.Fatal(err)
+	}
+
+	if provider.openedBroadcastChannels != 1 {
+		t.Errorf(
+			"unexpected number of opened broadcast channels\nexpected: [%v]\nactual:   [%v]",
+			1,
+			provider.openedBroadcastChannels,
+		)
+	}
+	if provider.openedUnicastChannels != 1 {
+		t.Errorf(
+			"unexpected number of opened unicast channels\nexpected: [%v]\nactual:   [%v]",
+			1,
+			provider.openedUnicastChannels,
+		)
+	}
+
+	owner1.Release()
+	assertClosedChannels(t, provider, nil, nil)
+
+	owner2.Release()
+	assertClosedChannels(t, provider, []string{"keep"}, []string{"peer"})
+}
+
+func TestReleasedOwnerStopsHandlersAndRetransmissions(t *testing.T) {
+	provider := newTestProvider()
+	manager := NewManager(provider)
+
+	owner1 := manager.NewOwner("keep")
+	owner2 := manager.NewOwner("keep")
+
+	broadcastChannel1, err := owner1.BroadcastChannelFor("keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broadcastChannel2, err := owner2.BroadcastChannelFor("keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unicastChannel1, err := owner1.UnicastChannelWith(testTransportID("peer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	broadcastChannel1.Recv(ctx, func(net.Message) {})
+	if err := broadcastChannel1.Send(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	unicastChannel1.Recv(ctx, func(net.Message) {})
+	broadcastChannel2.Recv(ctx, func(net.Message) {})
+
+	owner1.Release()
+
+	channel := provider.broadcastChannels["keep"]
+	// Receive and send contexts of the first owner are done.
+	releasedContexts := []context.Context{
+		channel.contexts[0],
+		channel.contexts[1],
+		provider.unicastContexts[0],
+	}
+	for _, ctx := range releasedContexts {
+		<-ctx.Done()
+	}
+	if channel.contexts[2].Err() != nil {
+		t.Errorf("handler of the second owner has been removed")
+	}
+
+	if err := broadcastChannel1.Send(ctx, nil); err == nil {
+		t.Errorf("message has been sent with the released owner")
+	}
+	if err := unicastChannel1.Send(nil); err == nil {
+		t.Errorf("message has been sent with the released owner")
+	}
+	if _, err := owner1.BroadcastChannelFor("keep"); err == nil {
+		t.Errorf("channel has been opened with the released owner")
+	}
+}
+
+func TestReleaseGroup(t *testing.T) {
+	provider := newTestProvider()
+	manager := NewManager(provider)
+
+	keep1Owner1 := manager.NewOwner("keep-1")
+	keep1Owner2 := manager.NewOwner("keep-1")
+	keep2Owner := manager.NewOwner("keep-2")
+
+	for _, owner := range []*Owner{keep1Owner1, keep1Owner2} {
+		if _, err := owner.BroadcastChannelFor("keep-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := keep2Owner.BroadcastChannelFor("keep-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	manager.ReleaseGroup("keep-1")
+	assertClosedChannels(t, provider, []string{"keep-1"}, nil)
+
+	if _, ok := manager.owners["keep-1"]; ok {
+		t.Errorf("owners of the released group are still tracked")
+	}
+
+	keep2Owner.Release()
+	assertClosedChannels(t, provider, []string{"keep-1", "keep-2"}, nil)
+
+	if len(manager.owners) != 0 {
+		t.Errorf("released owners are still tracked")
+	}
+}
+
+func TestOpeningChannelDoesNotBlockOtherChannels(t *testing.T) {
+	provider := &blockingProvider{
+		testProvider: newTestProvider(),
+		blockedName:  "slow",
+		unblock:      make(chan struct{}),
+	}
+	manager := NewManager(provider)
+
+	slowOpened := make(chan error, 1)
+	go func() {
+		_, err := manager.NewOwner("keep-1").BroadcastChannelFor("slow")
+		slowOpened <- err
+	}()
+
+	fastOpened := make(chan error, 1)
+	go func() {
+		_, err := manager.NewOwner("keep-2").BroadcastChannelFor("fast")
+		fastOpened <- err
+	}()
+
+	select {
+	case err := <-fastOpened:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel should be opened while other channel is being opened")
+	}
+
+	// The second owner of the slow channel waits until it is opened and
+	// shares it with the first one.
+	sharedOpened := make(chan error, 1)
+	go func() {
+		_, err := manager.NewOwner("keep-1").BroadcastChannelFor("slow")
+		sharedOpened <- err
+	}()
+
+	close(provider.unblock)
+
+	for _, opened := range []chan error{slowOpened, sharedOpened} {
+		select {
+		case err := <-opened:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("channel should be opened once the provider returns")
+		}
+	}
+
+	if provider.openedBroadcastChannels != 2 {
+		t.Errorf(
+			"unexpected number of opened broadcast channels\nexpected: [%v]\nactual:   [%v]",
+			2,
+			provider.openedBroadcastChannels,
+		)
+	}
+}
+
+func assertClosedChannels(
+	t *testing.T,
+	provider *testProvider,
+	expectedBroadcastChannels []string,
+	expectedUnicastChannels []string,
+) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if !reflect.DeepEqual(
+		expectedBroadcastChannels,
+		provider.closedBroadcastChannels,
+	) {
+		t.Errorf(
+			"unexpected closed broadcast channels\nexpected: [%v]\nactual:   [%v]",
+			expectedBroadcastChannels,
+			provider.closedBroadcastChannels,
+		)
+	}
+
+	if !reflect.DeepEqual(
+		expectedUnicastChannels,
+		provider.closedUnicastChannels,
+	) {
+		t.Errorf(
+			"unexpected closed unicast channels\nexpected: [%v]\nactual:   [%v]",
+			expectedUnicastChannels,
+			provider.closedUnicastChannels,
+		)
+	}
+}
+
+type testTransportID string
+
+func (id testTransportID) String() string {
+	return string(id)
+}
+
+type testProvider struct {
+	net.Provider
+
+	mutex                   sync.Mutex
+	openedBroadcastChannels int
+	openedUnicastChannels   int
+	broadcastChannels       map[string]*testBroadcastChannel
+	unicastContexts         []context.Context
+	closedBroadcastChannels []string
+	closedUnicastChannels   []string
+}
+
+func newTestProvider() *testProvider {
+	return &testProvider{
+		broadcastChannels: make(map[string]*testBroadcastChannel),
+	}
+}
+
+func (tp *testProvider) BroadcastChannelFor(name string) (net.BroadcastChannel, error) {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+
+	tp.openedBroadcastChannels++
+
+	channel := &testBroadcastChannel{name: name}
+	tp.broadcastChannels[name] = channel
+
+	return channel, nil
+}
+
+func (tp *testProvider) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+
+	tp.openedUnicastChannels++
+
+	return &testUnicastChannel{provider: tp}, nil
+}
+
+func (tp *testProvider) CloseBroadcastChannel(name string) error {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+
+	tp.closedBroadcastChannels = append(tp.closedBroadcastChannels, name)
+	return nil
+}
+
+func (tp *testProvider) CloseUnicastChannel(peerID net.TransportIdentifier) error {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+
+	tp.closedUnicastChannels = append(tp.closedUnicastChannels, peerID.String())
+	return nil
+}
+
+// blockingProvider blocks opening of the broadcast channel with the given
+// name until unblocked.
+type blockingProvider struct {
+	*testProvider
+
+	blockedName string
+	unblock     chan struct{}
+}
+
+func (bp *blockingProvider) BroadcastChannelFor(name string) (net.BroadcastChannel, error) {
+	if name == bp.blockedName {
+		<-bp.unblock
+	}
+
+	return bp.testProvider.BroadcastChannelFor(name)
+}
+
+// testBroadcastChannel records contexts of handlers and sent messages.
+type testBroadcastChannel struct {
+	net.BroadcastChannel
+
+	name     string
+	contexts []context.Context
+}
+
+func (tbc *testBroadcastChannel) Name() string {
+	return tbc.name
+}
+
+func (tbc *testBroadcastChannel) Send(ctx context.Context, m net.TaggedMarshaler) error {
+	tbc.contexts = append(tbc.contexts, ctx)
+	return nil
+}
+
+func (tbc *testBroadcastChannel) Recv(ctx context.Context, handler func(m net.Message)) {
+	tbc.contexts = append(tbc.contexts, ctx)
+}
+
+type testUnicastChannel struct {
+	net.UnicastChannel
+
+	provider *testProvider
+}
+
+func (tuc *testUnicastChannel) Send(m net.TaggedMarshaler) error {
+	return nil
+}
+
+func (tuc *testUnicastChannel) Recv(ctx context.Context, handler func(m net.Message)) {
+	tuc.provider.unicastContexts = append(tuc.provider.unicastContexts, ctx)
+}
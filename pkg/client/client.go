@@ -4,7 +4,6 @@ package client
 import (
 	"context"
 	"fmt"
-	"math/big"
 	"sync"
 	"time"
 
@@ -16,49 +15,249 @@ import (
 	"github.com/keep-network/keep-core/pkg/net"
 	"github.com/keep-network/keep-core/pkg/operator"
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+	"github.com/keep-network/keep-ecdsa/pkg/confirmation"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
 	"github.com/keep-network/keep-ecdsa/pkg/node"
+	"github.com/keep-network/keep-ecdsa/pkg/policy"
 	"github.com/keep-network/keep-ecdsa/pkg/registry"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
 var logger = log.Logger("keep-ecdsa")
 
 const (
-	awaitingKeyGenerationLookback = 24 * time.Hour
-	keyGenerationTimeout          = 120 * time.Minute
-	signingTimeout                = 90 * time.Minute
-	blockConfirmations            = 12
+	// keyGenerationTimeout is the time, counted from the on-chain keep opening
+	// timestamp, after which the keep owner may consider key generation as
+	// failed and the public key is no longer expected.
+	keyGenerationTimeout = 120 * time.Minute
+	// signingTimeout is the time, counted from the timestamp of the block in
+	// which a signature has been requested, after which the keep owner may
+	// consider signing as failed and the signature is no longer expected.
+	signingTimeout = 90 * time.Minute
 )
 
-// Initialize initializes the ECDSA client with rules related to events handling.
-// Expects a slice of sanctioned applications selected by the operator for which
-// operator will be registered as a member candidate.
-func Initialize(
-	ctx context.Context,
-	operatorPublicKey *operator.PublicKey,
-	ethereumChain eth.Handle,
-	networkProvider net.Provider,
-	persistence persistence.Handle,
-	sanctionedApplications []common.Address,
-	tssConfig *tss.Config,
-) {
-	keepsRegistry := registry.NewKeepsRegistry(persistence)
+// Options contains everything needed to run the ECDSA client.
+type Options struct {
+	// OperatorPublicKey is the public key of the operator running the client.
+	OperatorPublicKey *operator.PublicKey
+	// EthereumChain is the handle to the chain the client operates on.
+	EthereumChain eth.Handle
+	// NetworkProvider is the provider of the network used to communicate
+	// with other keep members.
+	NetworkProvider net.Provider
+	// Scheduler bounds the number of protocols executed at the same time.
+	// If not set, a scheduler with default limits is used.
+	Scheduler *node.Scheduler
+	// PreParamsPool is the pool of TSS pre-parameters used for key
+	// generation. Clients of operators running in the same process should
+	// share it. If not set, the client generates pre-parameters on its own.
+	PreParamsPool *node.PreParamsPool
+	// Custodian holds key shares of the operator outside of the client
+	// process and executes protocols with them. If set, the client does not
+	// generate TSS pre-parameters and persists signers without key shares.
+	Custodian node.Custodian
+	// PeerMonitor records heartbeats of peer members of active keeps.
+	// Clients of operators running in the same process should share it.
+	// If not set, the client uses a monitor with default settings.
+	PeerMonitor *node.PeerMonitor
+	// ChannelManager manages lifetime of network channels opened for keeps.
+	// Clients of operators running in the same process should share it.
+	// If not set, the client uses a manager of its own.
+	ChannelManager *channels.Manager
+	// ReliabilityLedger records reliability of co-signer operators. Clients
+	// of operators running in the same process should share it. If not set,
+	// the client uses a ledger held in memory only.
+	ReliabilityLedger *node.ReliabilityLedger
+	// Persistence is the handle used to store keys material.
+	Persistence persistence.Handle
+	// KeepsRegistry holds keeps of the operator read from the persistence.
+	// It is set when the registry has already been kept up to date by a node
+	// standing by for the lease. If not set, the client creates a registry
+	// of its own. The registry is reloaded from the persistence on start.
+	KeepsRegistry *registry.Keeps
+	// KeepsIndex is the index in which keeps awaiting key generation are
+	// looked up.
+	KeepsIndex *index.Keeps
+	// SanctionedApplications is a list of applications selected by the
+	// operator for which the operator will be registered as a member
+	// candidate.
+	SanctionedApplications []common.Address
+
+	TSSConfig          *tss.Config
+	ConfirmationConfig *confirmation.Config
+	RetryConfig        *retry.Config
+
+	// SigningPolicy contains operator's hooks executed before and after
+	// signing. If not set, no hooks are executed.
+	SigningPolicy *policy.Policy
+
+	// Hooks are notified about keep lifecycle events.
+	Hooks Hooks
+}
+
+// Hooks are callbacks notified about keep lifecycle events. All hooks are
+// optional. Hooks are called synchronously from the goroutine handling the
+// event so they should return quickly.
+type Hooks struct {
+	// OnKeepStateChanged is called when the keep transitions from one state
+	// to another.
+	OnKeepStateChanged func(keepAddress common.Address, from, to KeepState)
+	// OnSignatureCompleted is called when the signing protocol for the given
+	// digest completes. The error is nil if the signature has been calculated
+	// and published successfully.
+	OnSignatureCompleted func(keepAddress common.Address, digest [32]byte, err error)
+}
+
+// KeepStatus describes a keep the client is a member of.
+type KeepStatus struct {
+	Address common.Address
+	State   KeepState
+	// Peers describe when peer members of the keep have been last seen.
+	// They are known only for active keeps with a generated key.
+	Peers []node.PeerStatus
+}
 
-	tssNode := node.NewNode(ethereumChain, networkProvider, tssConfig)
+// PendingSignature describes a signature being calculated by the client.
+type PendingSignature struct {
+	KeepAddress common.Address
+	Digest      [32]byte
+}
+
+// Client is the ECDSA keep client. It handles keep events, generates keys
+// and calculates signatures for keeps in which the operator is a member.
+type Client struct {
+	options *Options
 
-	tssNode.InitializeTSSPreParamsPool()
+	keepsRegistry *registry.Keeps
+	keepStates    *keepStates
+	tssNode       *node.Node
+	goroutines    *goroutines
 
-	requestedSigners := &requestedSignersTrack{
-		data:  make(map[string]bool),
-		mutex: &sync.Mutex{},
+	mutex     sync.Mutex
+	isStarted bool
+	cancel    context.CancelFunc
+}
+
+// New creates a new ECDSA client. The client does not handle any events until
+// it is started.
+func New(options *Options) (*Client, error) {
+	if options == nil {
+		return nil, fmt.Errorf("options are required")
+	}
+	if options.OperatorPublicKey == nil {
+		return nil, fmt.Errorf("operator public key is required")
+	}
+	if options.EthereumChain == nil {
+		return nil, fmt.Errorf("ethereum chain is required")
 	}
-	requestedSignatures := &requestedSignaturesTrack{
-		data:  make(map[string]map[string]bool),
-		mutex: &sync.Mutex{},
+	if options.NetworkProvider == nil {
+		return nil, fmt.Errorf("network provider is required")
+	}
+	if options.Persistence == nil {
+		return nil, fmt.Errorf("persistence is required")
+	}
+	if options.KeepsIndex == nil {
+		return nil, fmt.Errorf("keeps index is required")
+	}
+
+	clientOptions := *options
+	if clientOptions.Scheduler == nil {
+		clientOptions.Scheduler = node.NewScheduler(nil)
+	}
+	if clientOptions.PeerMonitor == nil {
+		clientOptions.PeerMonitor = node.NewPeerMonitor(nil)
+	}
+	if clientOptions.ChannelManager == nil {
+		clientOptions.ChannelManager = channels.NewManager(
+			clientOptions.NetworkProvider,
+		)
+	}
+	if clientOptions.ReliabilityLedger == nil {
+		clientOptions.ReliabilityLedger = node.NewReliabilityLedger(nil)
+	}
+	if clientOptions.TSSConfig == nil {
+		clientOptions.TSSConfig = &tss.Config{}
+	}
+	if clientOptions.ConfirmationConfig == nil {
+		clientOptions.ConfirmationConfig = &confirmation.Config{}
+	}
+	if clientOptions.RetryConfig == nil {
+		clientOptions.RetryConfig = &retry.Config{}
+	}
+
+	keepsRegistry := clientOptions.KeepsRegistry
+	if keepsRegistry == nil {
+		keepsRegistry = registry.NewKeepsRegistry(clientOptions.Persistence)
+	}
+
+	return &Client{
+		options:       &clientOptions,
+		keepsRegistry: keepsRegistry,
+		keepStates: newKeepStates(
+			keepsRegistry,
+			clientOptions.ChannelManager,
+			&clientOptions.Hooks,
+		),
+		tssNode: node.NewNode(
+			clientOptions.EthereumChain,
+			clientOptions.NetworkProvider,
+			clientOptions.TSSConfig,
+			clientOptions.RetryConfig,
+			clientOptions.Scheduler,
+		),
+		goroutines: newGoroutines(),
+	}, nil
+}
+
+// Start starts handling events related to keeps. The client runs until the
+// provided context is done or until it is stopped. A client can be started
+// only once.
+func (c *Client) Start(ctx context.Context) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.isStarted {
+		return fmt.Errorf("client has already been started")
+	}
+	c.isStarted = true
+
+	ctx, c.cancel = context.WithCancel(ctx)
+
+	ethereumChain := c.options.EthereumChain
+	operatorPublicKey := c.options.OperatorPublicKey
+	keepsRegistry := c.keepsRegistry
+	keepStates := c.keepStates
+	tssNode := c.tssNode
+	goroutines := c.goroutines
+
+	tssNode.UseChannelManager(c.options.ChannelManager)
+	tssNode.UsePeerMonitor(c.options.PeerMonitor)
+	tssNode.UseReliabilityLedger(c.options.ReliabilityLedger)
+
+	if c.options.Custodian != nil {
+		tssNode.UseCustodian(c.options.Custodian)
+	} else if c.options.PreParamsPool != nil {
+		tssNode.UseTSSPreParamsPool(c.options.PreParamsPool)
+	} else {
+		tssNode.InitializeTSSPreParamsPool(ctx)
 	}
 
 	// Load current keeps' signers from storage and register for signing events.
 	keepsRegistry.LoadExistingKeeps()
+	keepStates.load()
+
+	signingPolicy := &signingPolicy{
+		policy:     c.options.SigningPolicy,
+		keepsIndex: c.options.KeepsIndex,
+	}
+
+	backfill := newEventsBackfill(ethereumChain, keepsRegistry, goroutines)
+	confirmer := confirmation.NewConfirmer(
+		ethereumChain,
+		c.options.ConfirmationConfig,
+	)
 
 	confirmIsInactive := func(keepAddress common.Address) bool {
 		currentBlock, err := ethereumChain.BlockCounter().CurrentBlock()
@@ -67,11 +266,13 @@ func Initialize(
 			return false
 		}
 
-		isKeepActive, err := waitForChainConfirmation(
-			ethereumChain,
+		isKeepInactive, err := confirmer.Confirm(
+			confirmation.KeepClosed,
 			currentBlock,
+			common.Hash{},
 			func() (bool, error) {
-				return ethereumChain.IsActive(keepAddress)
+				isActive, err := ethereumChain.IsActive(keepAddress)
+				return !isActive, err
 			},
 		)
 		if err != nil {
@@ -83,11 +284,50 @@ func Initialize(
 			return false
 		}
 
-		return !isKeepActive
+		return isKeepInactive
 	}
 
-	for _, keepAddress := range keepsRegistry.GetKeepsAddresses() {
-		go func(keepAddress common.Address) {
+	for _, keepAddress := range keepStates.addresses() {
+		keepAddress := keepAddress
+		goroutines.start(func() {
+			switch keepStates.state(keepAddress) {
+			case KeepClosed, KeepTerminated:
+				// The client has been stopped before the keep got archived.
+				// The state is checked again before archiving, since a deep
+				// chain reorganization could make the keep active again.
+				isActive, err := ethereumChain.IsActive(keepAddress)
+				if err != nil {
+					logger.Errorf(
+						"failed to verify if keep [%s] is still active: [%v]; "+
+							"archiving postponed",
+						keepAddress.String(),
+						err,
+					)
+					return
+				}
+
+				if !isActive || !keepsRegistry.HasSigner(keepAddress) {
+					logger.Infof(
+						"keep [%s] is no longer active; archiving",
+						keepAddress.String(),
+					)
+					if err := keepStates.archive(keepAddress); err != nil {
+						logger.Errorf("failed to archive keep: [%v]", err)
+					}
+					return
+				}
+
+				logger.Warningf(
+					"keep [%s] is active again after it has been closed; "+
+						"reopening",
+					keepAddress.String(),
+				)
+				if err := keepStates.transition(keepAddress, eventKeepReopened); err != nil {
+					logger.Errorf("failed to reopen keep: [%v]", err)
+					return
+				}
+			}
+
 			isActive, err := ethereumChain.IsActive(keepAddress)
 			if err != nil {
 				logger.Errorf(
@@ -109,7 +349,13 @@ func Initialize(
 						"confirmed that keep [%s] is no longer active; archiving",
 						keepAddress.String(),
 					)
-					keepsRegistry.UnregisterKeep(keepAddress)
+					if err := keepStates.transition(keepAddress, eventKeepClosed); err != nil {
+						logger.Errorf("failed to close keep: [%v]", err)
+						return
+					}
+					if err := keepStates.archive(keepAddress); err != nil {
+						logger.Errorf("failed to archive keep: [%v]", err)
+					}
 					return
 				}
 				logger.Warningf("keep [%s] is still active", keepAddress.String())
@@ -127,52 +373,39 @@ func Initialize(
 				return
 			}
 
-			for _, signer := range signers {
-				subscriptionOnSignatureRequested, err := monitorSigningRequests(
-					ethereumChain,
-					tssNode,
-					keepAddress,
-					signer,
-					requestedSignatures,
-				)
-				if err != nil {
-					logger.Errorf(
-						"failed registering for requested signature event for keep [%s]: [%v]",
-						keepAddress.String(),
-						err,
-					)
-					// In case of an error we want to avoid subscribing to keep
-					// closed events. Something is wrong and we should stop
-					// further processing.
-					return
-				}
-				go monitorKeepClosedEvents(
-					ethereumChain,
-					keepAddress,
-					keepsRegistry,
-					subscriptionOnSignatureRequested,
-				)
-				go monitorKeepTerminatedEvent(
-					ethereumChain,
-					keepAddress,
-					keepsRegistry,
-					subscriptionOnSignatureRequested,
-				)
-			}
-		}(keepAddress)
+			monitorKeep(
+				ctx,
+				goroutines,
+				ethereumChain,
+				backfill,
+				confirmer,
+				tssNode,
+				keepStates,
+				signingPolicy,
+				keepAddress,
+				signers,
+			)
+		})
 	}
 
-	go checkAwaitingKeyGeneration(
-		ctx,
-		ethereumChain,
-		tssNode,
-		operatorPublicKey,
-		keepsRegistry,
-		requestedSignatures,
-	)
+	goroutines.start(func() {
+		checkAwaitingKeyGeneration(
+			ctx,
+			goroutines,
+			ethereumChain,
+			backfill,
+			confirmer,
+			tssNode,
+			operatorPublicKey,
+			keepsRegistry,
+			c.options.KeepsIndex,
+			keepStates,
+			signingPolicy,
+		)
+	})
 
 	// Watch for new keeps creation.
-	ethereumChain.OnBondedECDSAKeepCreated(func(event *eth.BondedECDSAKeepCreatedEvent) {
+	subscriptionOnKeepCreated, err := ethereumChain.OnBondedECDSAKeepCreated(func(event *eth.BondedECDSAKeepCreatedEvent) {
 		logger.Infof(
 			"new keep [%s] created with members: [%x]\n",
 			event.KeepAddress.String(),
@@ -180,106 +413,180 @@ func Initialize(
 		)
 
 		if event.IsMember(ethereumChain.Address()) {
-			go func(event *eth.BondedECDSAKeepCreatedEvent) {
-				if ok := requestedSigners.add(event.KeepAddress); !ok {
-					logger.Errorf(
-						"keep creation event for keep [%s] already registered",
-						event.KeepAddress.String(),
-					)
-					return
-				}
-				defer requestedSigners.remove(event.KeepAddress)
-
+			goroutines.start(func() {
 				generateKeyForKeep(
 					ctx,
+					goroutines,
 					ethereumChain,
+					backfill,
+					confirmer,
 					tssNode,
 					operatorPublicKey,
 					keepsRegistry,
-					requestedSignatures,
+					keepStates,
+					signingPolicy,
 					event.KeepAddress,
 					event.Members,
 					event.HonestThreshold,
 				)
-			}(event)
+			})
 		}
 	})
+	if err != nil {
+		c.cancel()
+		return fmt.Errorf(
+			"failed on registering for keep created event: [%v]",
+			err,
+		)
+	}
+
+	goroutines.start(func() {
+		<-ctx.Done()
+		subscriptionOnKeepCreated.Unsubscribe()
+	})
+
+	for _, application := range c.options.SanctionedApplications {
+		application := application
+		goroutines.start(func() {
+			checkStatusAndRegisterForApplication(
+				ctx,
+				ethereumChain,
+				confirmer,
+				c.options.RetryConfig.Policy(retry.ChainCall),
+				application,
+			)
+		})
+	}
+
+	return nil
+}
+
+// Stop stops handling events and cancels all protocols executed by the
+// client. It returns once all goroutines started by the client returned.
+func (c *Client) Stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	c.goroutines.stop()
+}
 
-	for _, application := range sanctionedApplications {
-		go checkStatusAndRegisterForApplication(ctx, ethereumChain, application)
+// Keeps returns all keeps the client is a member of along with their
+// lifecycle states and statuses of peer members.
+func (c *Client) Keeps() []KeepStatus {
+	states := c.keepStates.snapshot()
+
+	keeps := make([]KeepStatus, 0, len(states))
+	for keepAddress, state := range states {
+		keeps = append(keeps, KeepStatus{
+			Address: keepAddress,
+			State:   state,
+			Peers:   c.options.PeerMonitor.Peers(keepAddress),
+		})
 	}
+
+	return keeps
+}
+
+// PendingSignatures returns signatures which are currently being calculated.
+func (c *Client) PendingSignatures() []PendingSignature {
+	var pendingSignatures []PendingSignature
+	for keepAddress, digests := range c.keepStates.pendingSignatures() {
+		for _, digest := range digests {
+			pendingSignatures = append(pendingSignatures, PendingSignature{
+				KeepAddress: keepAddress,
+				Digest:      digest,
+			})
+		}
+	}
+
+	return pendingSignatures
+}
+
+// PoolStatus returns the state of the TSS pre-parameters pool.
+func (c *Client) PoolStatus() node.PreParamsPoolStatus {
+	return c.tssNode.PreParamsPoolStatus()
 }
 
 func checkAwaitingKeyGeneration(
 	ctx context.Context,
+	goroutines *goroutines,
 	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
 	tssNode *node.Node,
 	operatorPublicKey *operator.PublicKey,
 	keepsRegistry *registry.Keeps,
-	requestedSignatures *requestedSignaturesTrack,
+	keepsIndex *index.Keeps,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
 ) {
-	keepCount, err := ethereumChain.GetKeepCount()
-	if err != nil {
-		logger.Warningf("could not get keep count: [%v]", err)
-		return
-	}
-
-	zero := big.NewInt(0)
-	one := big.NewInt(1)
-
-	lastIndex := new(big.Int).Sub(keepCount, one)
-
-	// Iterate through keeps starting from the end.
-	for keepIndex := new(big.Int).Set(lastIndex); keepIndex.Cmp(zero) != -1; keepIndex.Sub(keepIndex, one) {
-		logger.Debugf(
-			"checking awaiting key generation for keep at index [%v]",
-			keepIndex.String(),
-		)
+	// Active keeps are ordered starting from the most recently created ones.
+	// Keeps of other operators are skipped before reading anything from
+	// the chain.
+	for _, keep := range keepsIndex.ActiveKeeps() {
+		if !keep.IsMember(ethereumChain.Address()) {
+			continue
+		}
 
-		keep, err := ethereumChain.GetKeepAtIndex(keepIndex)
+		deadline, err := keyGenerationDeadline(ethereumChain, keep.Address)
 		if err != nil {
 			logger.Warningf(
-				"could not get keep at index [%v]: [%v]",
-				keepIndex,
+				"could not get key generation deadline for keep [%s]: [%v]",
+				keep.Address.String(),
 				err,
 			)
 			continue
 		}
 
-		keepOpenedTimestamp, err := ethereumChain.GetOpenedTimestamp(keep)
+		isPassed, err := isDeadlinePassed(ethereumChain, deadline)
 		if err != nil {
 			logger.Warningf(
-				"could not check opening timestamp for keep [%s]: [%v]",
-				keep.String(),
+				"could not check key generation deadline for keep [%s]: [%v]",
+				keep.Address.String(),
 				err,
 			)
 			continue
 		}
 
-		// If a keep was opened before the defined lookback duration there is no
-		// sense to continue because the next keep was created earlier.
-		if keepOpenedTimestamp.Add(awaitingKeyGenerationLookback).Before(time.Now()) {
+		// If key generation deadline of a keep has passed there is no sense
+		// to continue because the next keep was created earlier.
+		if isPassed {
 			logger.Debugf(
-				"stopping awaiting key generation check with keep at index [%s] opened at [%s]",
-				keepIndex,
-				keepOpenedTimestamp,
+				"stopping awaiting key generation check with keep [%s] "+
+					"opened at block [%v]; key generation deadline [%v] passed",
+				keep.Address.String(),
+				keep.CreationBlock,
+				deadline,
 			)
 			break
 		}
 
+		logger.Debugf(
+			"checking awaiting key generation for keep [%s]",
+			keep.Address.String(),
+		)
+
 		err = checkAwaitingKeyGenerationForKeep(
 			ctx,
+			goroutines,
 			ethereumChain,
+			backfill,
+			confirmer,
 			tssNode,
 			operatorPublicKey,
 			keepsRegistry,
-			requestedSignatures,
+			keepStates,
+			signingPolicy,
 			keep,
 		)
 		if err != nil {
 			logger.Warningf(
 				"could not check awaiting key generation for keep [%s]: [%v]",
-				keep.String(),
+				keep.Address.String(),
 				err,
 			)
 		}
@@ -288,14 +595,18 @@ func checkAwaitingKeyGeneration(
 
 func checkAwaitingKeyGenerationForKeep(
 	ctx context.Context,
+	goroutines *goroutines,
 	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
 	tssNode *node.Node,
 	operatorPublicKey *operator.PublicKey,
 	keepsRegistry *registry.Keeps,
-	requestedSignatures *requestedSignaturesTrack,
-	keep common.Address,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
+	keep *index.Keep,
 ) error {
-	publicKey, err := ethereumChain.GetPublicKey(keep)
+	publicKey, err := ethereumChain.GetPublicKey(keep.Address)
 	if err != nil {
 		return err
 	}
@@ -304,60 +615,62 @@ func checkAwaitingKeyGenerationForKeep(
 		return nil
 	}
 
-	// If the key material is stored in the registry it means that the key
-	// generation succeeded and public key transaction has been submitted.
+	// If the key has been generated it means that the key material is stored
+	// in the registry and public key transaction has been submitted.
 	// There are two scenarios possible:
 	// - public key submission transactions are still mining,
 	// - conflicting public key has been submitted.
 	// In both cases, the client should not attempt to generate the key again.
-	if keepsRegistry.HasSigner(keep) {
+	switch keepStates.state(keep.Address) {
+	case KeepAwaitingKeyGeneration:
+	case KeepGeneratingKey:
+		logger.Debugf(
+			"key generation for keep [%s] already in progress",
+			keep.Address.String(),
+		)
+		return nil
+	default:
 		logger.Warningf(
 			"keep public key is not registered on-chain but key material "+
 				"is stored on disk; skipping key generation; PLEASE INSPECT "+
 				"PUBLIC KEY SUBMISSION TRANSACTION FOR KEEP [%v]",
-			keep.String(),
+			keep.Address.String(),
 		)
 		return nil
 	}
 
-	members, err := ethereumChain.GetMembers(keep)
-	if err != nil {
-		return err
-	}
-
-	honestThreshold, err := ethereumChain.GetHonestThreshold(keep)
-	if err != nil {
-		return err
-	}
-
-	for _, member := range members {
-		if ethereumChain.Address() == member {
-			go generateKeyForKeep(
-				ctx,
-				ethereumChain,
-				tssNode,
-				operatorPublicKey,
-				keepsRegistry,
-				requestedSignatures,
-				keep,
-				members,
-				honestThreshold,
-			)
-
-			break
-		}
-	}
+	goroutines.start(func() {
+		generateKeyForKeep(
+			ctx,
+			goroutines,
+			ethereumChain,
+			backfill,
+			confirmer,
+			tssNode,
+			operatorPublicKey,
+			keepsRegistry,
+			keepStates,
+			signingPolicy,
+			keep.Address,
+			keep.Members,
+			keep.HonestThreshold,
+		)
+	})
 
 	return nil
 }
 
 func generateKeyForKeep(
 	ctx context.Context,
+	goroutines *goroutines,
 	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
 	tssNode *node.Node,
 	operatorPublicKey *operator.PublicKey,
 	keepsRegistry *registry.Keeps,
-	requestedSignatures *requestedSignaturesTrack,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
 	keepAddress common.Address,
 	members []common.Address,
 	honestThreshold uint64,
@@ -384,14 +697,44 @@ func generateKeyForKeep(
 		return
 	}
 
+	if err := keepStates.transition(keepAddress, eventKeyGenerationStarted); err != nil {
+		logger.Errorf(
+			"could not start signer generation for keep [%s]: [%v]",
+			keepAddress.String(),
+			err,
+		)
+		return
+	}
+
 	logger.Infof(
 		"member [%s] is starting signer generation for keep [%s]...",
 		ethereumChain.Address().String(),
 		keepAddress.String(),
 	)
 
-	signer, err := generateSignerForKeep(
+	// Connections with peer members are established while key generation
+	// waits for its turn and for other members to announce themselves.
+	goroutines.start(func() {
+		unreachable := tssNode.CheckConnectivity(
+			ctx,
+			operatorPublicKey,
+			keepAddress,
+			members,
+		)
+		if len(unreachable) > 0 {
+			logger.Warningf(
+				"[%d] peer members of keep [%s] are not reachable; "+
+					"key generation may fail: [%v]",
+				len(unreachable),
+				keepAddress.String(),
+				unreachable,
+			)
+		}
+	})
+
+	signers, err := generateSignersForKeep(
 		ctx,
+		ethereumChain,
 		tssNode,
 		operatorPublicKey,
 		keepAddress,
@@ -403,91 +746,202 @@ func generateKeyForKeep(
 			keepAddress.String(),
 			err,
 		)
+
+		if err := keepStates.transition(keepAddress, eventKeyGenerationFailed); err != nil {
+			logger.Errorf("failed to reset key generation: [%v]", err)
+		}
 		return
 	}
 
-	logger.Infof("initialized signer for keep [%s]", keepAddress.String())
+	logger.Infof(
+		"initialized [%d] signer(s) for keep [%s]",
+		len(signers),
+		keepAddress.String(),
+	)
 
-	err = keepsRegistry.RegisterSigner(keepAddress, signer)
-	if err != nil {
-		logger.Errorf(
-			"failed to register threshold signer for keep [%s]: [%v]",
-			keepAddress.String(),
-			err,
-		)
+	for _, signer := range signers {
+		err = keepsRegistry.RegisterSigner(keepAddress, signer)
+		if err != nil {
+			logger.Errorf(
+				"failed to register threshold signer for keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+		}
 	}
 
-	subscriptionOnSignatureRequested, err := monitorSigningRequests(
-		ethereumChain,
-		tssNode,
-		keepAddress,
-		signer,
-		requestedSignatures,
-	)
-	if err != nil {
+	if err := keepStates.transition(keepAddress, eventKeyGenerated); err != nil {
 		logger.Errorf(
-			"failed on registering for requested signature event "+
-				"for keep [%s]: [%v]",
+			"failed to complete signer generation for keep [%s]: [%v]",
 			keepAddress.String(),
 			err,
 		)
-
-		// In case of an error we want to avoid subscribing to keep
-		// closed events. Something is wrong and we should stop
-		// further processing.
 		return
 	}
 
-	go monitorKeepClosedEvents(
-		ethereumChain,
-		keepAddress,
-		keepsRegistry,
-		subscriptionOnSignatureRequested,
-	)
-	go monitorKeepTerminatedEvent(
+	monitorKeep(
+		ctx,
+		goroutines,
 		ethereumChain,
+		backfill,
+		confirmer,
+		tssNode,
+		keepStates,
+		signingPolicy,
 		keepAddress,
-		keepsRegistry,
-		subscriptionOnSignatureRequested,
+		signers,
 	)
 }
 
-func generateSignerForKeep(
+func generateSignersForKeep(
 	ctx context.Context,
+	ethereumChain eth.Handle,
 	tssNode *node.Node,
 	operatorPublicKey *operator.PublicKey,
 	keepAddress common.Address,
 	members []common.Address,
-) (*tss.ThresholdSigner, error) {
-	keygenCtx, cancel := context.WithTimeout(ctx, keyGenerationTimeout)
+) ([]*tss.ThresholdSigner, error) {
+	deadline, err := keyGenerationDeadline(ethereumChain, keepAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	keygenCtx, cancel := withChainDeadline(ctx, ethereumChain, deadline)
 	defer cancel()
 
-	return tssNode.GenerateSignerForKeep(
+	return tssNode.GenerateSignersForKeep(
 		keygenCtx,
+		deadline,
 		operatorPublicKey,
 		keepAddress,
 		members,
 	)
 }
 
-// monitorSigningRequests registers for signature requested events emitted by
-// specific keep contract.
-func monitorSigningRequests(
+// monitorKeep registers for signature requested events and for keep closed
+// and terminated events. Signatures are calculated with all the given signers,
+// one for each seat the operator holds in the keep. Subscriptions are created
+// only once for the given keep and only if the keep is active. Subscriptions
+// are cancelled when the context is done.
+func monitorKeep(
+	ctx context.Context,
+	goroutines *goroutines,
 	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
 	tssNode *node.Node,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
 	keepAddress common.Address,
-	signer *tss.ThresholdSigner,
-	requestedSignatures *requestedSignaturesTrack,
-) (subscription.EventSubscription, error) {
-	go checkAwaitingSignature(
+	signers []*tss.ThresholdSigner,
+) {
+	if !keepStates.startMonitoring(keepAddress) {
+		logger.Warningf(
+			"keep [%s] in state [%v] is already monitored or not active",
+			keepAddress.String(),
+			keepStates.state(keepAddress),
+		)
+		return
+	}
+
+	subscriptionOnSignatureRequested, err := monitorSigningRequests(
+		ctx,
+		goroutines,
 		ethereumChain,
+		backfill,
+		confirmer,
 		tssNode,
+		keepStates,
+		signingPolicy,
 		keepAddress,
-		signer,
-		requestedSignatures,
+		signers,
 	)
+	if err != nil {
+		logger.Errorf(
+			"failed on registering for requested signature event "+
+				"for keep [%s]: [%v]",
+			keepAddress.String(),
+			err,
+		)
 
-	return ethereumChain.OnSignatureRequested(
+		// In case of an error we want to avoid subscribing to keep
+		// closed events. Something is wrong and we should stop
+		// further processing.
+		return
+	}
+
+	// Heartbeats are exchanged with peer members as long as the keep is
+	// monitored, so they stop along with signing requests subscription.
+	heartbeatCtx, stopHeartbeats := context.WithCancel(ctx)
+	goroutines.start(func() {
+		if err := tssNode.MonitorPeers(heartbeatCtx, keepAddress, signers); err != nil {
+			logger.Errorf(
+				"failed to monitor peer members of keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+		}
+	})
+
+	signingSubscription := subscriptionOnSignatureRequested
+	subscriptionOnSignatureRequested = subscription.NewEventSubscription(func() {
+		signingSubscription.Unsubscribe()
+		stopHeartbeats()
+	})
+
+	goroutines.start(func() {
+		monitorKeepClosedEvents(
+			ctx,
+			ethereumChain,
+			backfill,
+			confirmer,
+			keepAddress,
+			keepStates,
+			subscriptionOnSignatureRequested,
+		)
+	})
+	goroutines.start(func() {
+		monitorKeepTerminatedEvent(
+			ctx,
+			ethereumChain,
+			backfill,
+			confirmer,
+			keepAddress,
+			keepStates,
+			subscriptionOnSignatureRequested,
+		)
+	})
+}
+
+// monitorSigningRequests registers for signature requested events emitted by
+// specific keep contract. Signatures are calculated with all the given signers
+// in a single protocol execution.
+func monitorSigningRequests(
+	ctx context.Context,
+	goroutines *goroutines,
+	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
+	tssNode *node.Node,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
+	keepAddress common.Address,
+	signers []*tss.ThresholdSigner,
+) (subscription.EventSubscription, error) {
+	goroutines.start(func() {
+		checkAwaitingSignature(
+			ctx,
+			ethereumChain,
+			confirmer,
+			tssNode,
+			keepStates,
+			signingPolicy,
+			keepAddress,
+			signers,
+		)
+	})
+
+	return backfill.OnSignatureRequested(
 		keepAddress,
 		func(event *eth.SignatureRequestedEvent) {
 			logger.Infof(
@@ -497,20 +951,21 @@ func monitorSigningRequests(
 				event.BlockNumber,
 			)
 
-			go func(event *eth.SignatureRequestedEvent) {
-				if ok := requestedSignatures.add(keepAddress, event.Digest); !ok {
+			goroutines.start(func() {
+				if err := keepStates.startSigning(keepAddress, event.Digest); err != nil {
 					logger.Errorf(
-						"signature requested event for keep [%s] and digest [%x] already registered",
+						"could not start signing for keep [%s]: [%v]",
 						keepAddress.String(),
-						event.Digest,
+						err,
 					)
 					return
 				}
-				defer requestedSignatures.remove(keepAddress, event.Digest)
+				defer keepStates.finishSigning(keepAddress, event.Digest)
 
-				isAwaitingSignature, err := waitForChainConfirmation(
-					ethereumChain,
+				isAwaitingSignature, err := confirmer.Confirm(
+					confirmation.SignatureRequested,
 					event.BlockNumber,
+					event.BlockHash,
 					func() (bool, error) {
 						return ethereumChain.IsAwaitingSignature(keepAddress, event.Digest)
 					},
@@ -534,18 +989,31 @@ func monitorSigningRequests(
 					return
 				}
 
-				generateSignatureForKeep(tssNode, keepAddress, signer, event.Digest)
-			}(event)
+				generateSignatureForKeep(
+					ctx,
+					ethereumChain,
+					tssNode,
+					keepStates,
+					signingPolicy,
+					keepAddress,
+					signers,
+					event.Digest,
+					event.BlockNumber,
+				)
+			})
 		},
 	)
 }
 
 func checkAwaitingSignature(
+	ctx context.Context,
 	ethereumChain eth.Handle,
+	confirmer *confirmation.Confirmer,
 	tssNode *node.Node,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
 	keepAddress common.Address,
-	signer *tss.ThresholdSigner,
-	requestedSignatures *requestedSignaturesTrack,
+	signers []*tss.ThresholdSigner,
 ) {
 	logger.Debugf("checking awaiting signature for keep [%s]", keepAddress.String())
 
@@ -573,15 +1041,15 @@ func checkAwaitingSignature(
 			latestDigest,
 		)
 
-		if ok := requestedSignatures.add(keepAddress, latestDigest); !ok {
+		if err := keepStates.startSigning(keepAddress, latestDigest); err != nil {
 			logger.Errorf(
-				"signature requested event for keep [%s] and digest [%x] already registered",
+				"could not start signing for keep [%s]: [%v]",
 				keepAddress.String(),
-				latestDigest,
+				err,
 			)
 			return
 		}
-		defer requestedSignatures.remove(keepAddress, latestDigest)
+		defer keepStates.finishSigning(keepAddress, latestDigest)
 
 		startBlock, err := ethereumChain.SignatureRequestedBlock(keepAddress, latestDigest)
 		if err != nil {
@@ -594,9 +1062,10 @@ func checkAwaitingSignature(
 			return
 		}
 
-		isStillAwaitingSignature, err := waitForChainConfirmation(
-			ethereumChain,
+		isStillAwaitingSignature, err := confirmer.Confirm(
+			confirmation.SignatureRequested,
 			startBlock,
+			common.Hash{},
 			func() (bool, error) {
 				isAwaitingSignature, err := ethereumChain.IsAwaitingSignature(keepAddress, latestDigest)
 				if err != nil {
@@ -630,44 +1099,90 @@ func checkAwaitingSignature(
 			return
 		}
 
-		generateSignatureForKeep(tssNode, keepAddress, signer, latestDigest)
+		generateSignatureForKeep(
+			ctx,
+			ethereumChain,
+			tssNode,
+			keepStates,
+			signingPolicy,
+			keepAddress,
+			signers,
+			latestDigest,
+			startBlock,
+		)
 	}
 }
 
 func generateSignatureForKeep(
+	ctx context.Context,
+	ethereumChain eth.Handle,
 	tssNode *node.Node,
+	keepStates *keepStates,
+	signingPolicy *signingPolicy,
 	keepAddress common.Address,
-	signer *tss.ThresholdSigner,
+	signers []*tss.ThresholdSigner,
 	digest [32]byte,
+	requestBlock uint64,
 ) {
-	signingCtx, cancel := context.WithTimeout(context.Background(), signingTimeout)
+	signingRequest := signingPolicy.newRequest(keepAddress, digest, requestBlock)
+
+	if err := signingPolicy.beforeSigning(ctx, signingRequest); err != nil {
+		logger.Errorf(
+			"signing for keep [%s] not approved by signing policy: [%v]",
+			keepAddress.String(),
+			err,
+		)
+		keepStates.signatureCompleted(keepAddress, digest, err)
+		return
+	}
+
+	deadline, err := signingDeadline(ethereumChain, requestBlock)
+	if err != nil {
+		logger.Errorf(
+			"failed to get signing deadline for keep [%s]: [%v]",
+			keepAddress.String(),
+			err,
+		)
+		signingPolicy.afterSigning(ctx, signingRequest, err)
+		keepStates.signatureCompleted(keepAddress, digest, err)
+		return
+	}
+
+	signingCtx, cancel := withChainDeadline(ctx, ethereumChain, deadline)
 	defer cancel()
 
-	if err := tssNode.CalculateSignature(
+	err = tssNode.CalculateSignature(
 		signingCtx,
-		signer,
+		deadline,
+		signers,
 		digest,
-	); err != nil {
+	)
+	if err != nil {
 		logger.Errorf(
 			"signature calculation failed for keep [%s]: [%v]",
 			keepAddress.String(),
 			err,
 		)
 	}
+
+	signingPolicy.afterSigning(ctx, signingRequest, err)
+	keepStates.signatureCompleted(keepAddress, digest, err)
 }
 
 // monitorKeepClosedEvent monitors KeepClosed event and if that event happens
-// unsubscribes from signing event for the given keep and unregisters it from
-// the keep registry.
+// unsubscribes from signing events for the given keep and archives it.
 func monitorKeepClosedEvents(
+	ctx context.Context,
 	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
 	keepAddress common.Address,
-	keepsRegistry *registry.Keeps,
+	keepStates *keepStates,
 	subscriptionOnSignatureRequested subscription.EventSubscription,
 ) {
-	keepClosed := make(chan *eth.KeepClosedEvent)
+	keepClosed := make(chan *eth.KeepClosedEvent, 1)
 
-	subscriptionOnKeepClosed, err := ethereumChain.OnKeepClosed(
+	subscriptionOnKeepClosed, err := backfill.OnKeepClosed(
 		keepAddress,
 		func(event *eth.KeepClosedEvent) {
 			logger.Infof(
@@ -676,11 +1191,13 @@ func monitorKeepClosedEvents(
 				event.BlockNumber,
 			)
 
-			isKeepActive, err := waitForChainConfirmation(
-				ethereumChain,
+			isKeepInactive, err := confirmer.Confirm(
+				confirmation.KeepClosed,
 				event.BlockNumber,
+				event.BlockHash,
 				func() (bool, error) {
-					return ethereumChain.IsActive(keepAddress)
+					isActive, err := ethereumChain.IsActive(keepAddress)
+					return !isActive, err
 				},
 			)
 			if err != nil {
@@ -692,12 +1209,19 @@ func monitorKeepClosedEvents(
 				return
 			}
 
-			if isKeepActive {
+			if !isKeepInactive {
 				logger.Warningf("keep [%s] has not been closed", keepAddress.String())
 				return
 			}
 
-			keepsRegistry.UnregisterKeep(keepAddress)
+			if err := keepStates.transition(keepAddress, eventKeepClosed); err != nil {
+				logger.Errorf("failed to update keep state: [%v]", err)
+				return
+			}
+			if err := keepStates.archive(keepAddress); err != nil {
+				logger.Errorf("failed to archive keep: [%v]", err)
+			}
+
 			keepClosed <- event
 		},
 	)
@@ -714,23 +1238,31 @@ func monitorKeepClosedEvents(
 	defer subscriptionOnKeepClosed.Unsubscribe()
 	defer subscriptionOnSignatureRequested.Unsubscribe()
 
-	<-keepClosed
-
-	logger.Info("unsubscribing from events on keep closed")
+	select {
+	case <-keepClosed:
+		logger.Info("unsubscribing from events on keep closed")
+	case <-ctx.Done():
+		logger.Debugf(
+			"unsubscribing from events of keep [%s] on client stop",
+			keepAddress.String(),
+		)
+	}
 }
 
 // monitorKeepTerminatedEvent monitors KeepTerminated event and if that event
-// happens unsubscribes from signing event for the given keep and unregisters it
-// from the keep registry.
+// happens unsubscribes from signing events for the given keep and archives it.
 func monitorKeepTerminatedEvent(
+	ctx context.Context,
 	ethereumChain eth.Handle,
+	backfill *eventsBackfill,
+	confirmer *confirmation.Confirmer,
 	keepAddress common.Address,
-	keepsRegistry *registry.Keeps,
+	keepStates *keepStates,
 	subscriptionOnSignatureRequested subscription.EventSubscription,
 ) {
-	keepTerminated := make(chan *eth.KeepTerminatedEvent)
+	keepTerminated := make(chan *eth.KeepTerminatedEvent, 1)
 
-	subscriptionOnKeepTerminated, err := ethereumChain.OnKeepTerminated(
+	subscriptionOnKeepTerminated, err := backfill.OnKeepTerminated(
 		keepAddress,
 		func(event *eth.KeepTerminatedEvent) {
 			logger.Warningf(
@@ -739,11 +1271,13 @@ func monitorKeepTerminatedEvent(
 				event.BlockNumber,
 			)
 
-			isKeepActive, err := waitForChainConfirmation(
-				ethereumChain,
+			isKeepInactive, err := confirmer.Confirm(
+				confirmation.KeepTerminated,
 				event.BlockNumber,
+				event.BlockHash,
 				func() (bool, error) {
-					return ethereumChain.IsActive(keepAddress)
+					isActive, err := ethereumChain.IsActive(keepAddress)
+					return !isActive, err
 				},
 			)
 			if err != nil {
@@ -755,12 +1289,19 @@ func monitorKeepTerminatedEvent(
 				return
 			}
 
-			if isKeepActive {
+			if !isKeepInactive {
 				logger.Warningf("keep [%s] has not been terminated", keepAddress.String())
 				return
 			}
 
-			keepsRegistry.UnregisterKeep(keepAddress)
+			if err := keepStates.transition(keepAddress, eventKeepTerminated); err != nil {
+				logger.Errorf("failed to update keep state: [%v]", err)
+				return
+			}
+			if err := keepStates.archive(keepAddress); err != nil {
+				logger.Errorf("failed to archive keep: [%v]", err)
+			}
+
 			keepTerminated <- event
 		},
 	)
@@ -777,33 +1318,13 @@ func monitorKeepTerminatedEvent(
 	defer subscriptionOnKeepTerminated.Unsubscribe()
 	defer subscriptionOnSignatureRequested.Unsubscribe()
 
-	<-keepTerminated
-
-	logger.Info("unsubscribing from events on keep terminated")
-}
-
-// waitForChainConfirmation ensures that after receiving specific number of block
-// confirmations the state of the chain is actually as expected. It waits for
-// predefined number of blocks since the start block number provided. After the
-// required block number is reached it performs a check of the chain state with
-// a provided function returning a boolean value.
-func waitForChainConfirmation(
-	ethereumChain eth.Handle,
-	startBlockNumber uint64,
-	stateCheck func() (bool, error),
-) (bool, error) {
-	blockHeight := startBlockNumber + blockConfirmations
-	logger.Infof("waiting for block [%d] to confirm chain state", blockHeight)
-
-	err := ethereumChain.BlockCounter().WaitForBlockHeight(blockHeight)
-	if err != nil {
-		return false, fmt.Errorf("failed to wait for block height: [%v]", err)
-	}
-
-	result, err := stateCheck()
-	if err != nil {
-		return false, fmt.Errorf("failed to get chain state confirmation: [%v]", err)
+	select {
+	case <-keepTerminated:
+		logger.Info("unsubscribing from events on keep terminated")
+	case <-ctx.Done():
+		logger.Debugf(
+			"unsubscribing from events of keep [%s] on client stop",
+			keepAddress.String(),
+		)
 	}
-
-	return result, nil
 }
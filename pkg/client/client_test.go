@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/keep-network/keep-core/pkg/net/key"
+	netlocal "github.com/keep-network/keep-core/pkg/net/local"
+	"github.com/keep-network/keep-core/pkg/operator"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+)
+
+func TestNewRequiresOptions(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Errorf("expected error for missing options")
+	}
+
+	options := newTestOptions(t)
+	options.EthereumChain = nil
+
+	if _, err := New(options); err == nil {
+		t.Errorf("expected error for missing ethereum chain")
+	}
+}
+
+func TestClientStartStop(t *testing.T) {
+	ecdsaClient, err := New(newTestOptions(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ecdsaClient.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ecdsaClient.Stop()
+
+	if err := ecdsaClient.Start(ctx); err == nil {
+		t.Errorf("expected error when starting client twice")
+	}
+
+	if keeps := ecdsaClient.Keeps(); len(keeps) != 0 {
+		t.Errorf("unexpected keeps: [%v]", keeps)
+	}
+
+	if pendingSignatures := ecdsaClient.PendingSignatures(); len(pendingSignatures) != 0 {
+		t.Errorf("unexpected pending signatures: [%v]", pendingSignatures)
+	}
+
+	if poolStatus := ecdsaClient.PoolStatus(); poolStatus.Capacity == 0 {
+		t.Errorf("pre-parameters pool should be initialized")
+	}
+}
+
+func TestClientUsesProvidedKeepsRegistry(t *testing.T) {
+	options := newTestOptions(t)
+	options.KeepsRegistry = registry.NewKeepsRegistry(options.Persistence)
+
+	ecdsaClient, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ecdsaClient.keepsRegistry != options.KeepsRegistry {
+		t.Errorf("client should use the provided keeps registry")
+	}
+}
+
+func TestCheckAwaitingKeyGenerationSkipsKeepsOfOtherOperators(t *testing.T) {
+	chain := &openedTimestampCountingChain{
+		Chain:                local.Connect(),
+		openedTimestampMutex: &sync.Mutex{},
+	}
+
+	otherOperator := common.HexToAddress("0x65ea55c1f10491038425725dc00dffeab2a1e28a")
+	chain.OpenKeep(
+		common.HexToAddress("0x770a9E2F2Aa1eC2d3Ca916Fc3e6A55058A898632"),
+		[]common.Address{otherOperator},
+	)
+	chain.OpenKeep(
+		common.HexToAddress("0x8B3BccB3A3994681A1C1584DE4b4E8b23ed1Ed6d"),
+		[]common.Address{otherOperator},
+	)
+
+	keepsIndex := index.NewKeepsIndex(
+		chain,
+		newMemoryPersistence(),
+		&index.Config{},
+		0,
+	)
+
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.BlockCounter().WaitForBlockHeight(currentBlock + 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := keepsIndex.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if keeps := keepsIndex.ActiveKeeps(); len(keeps) != 2 {
+		t.Fatalf("unexpected number of indexed keeps: [%d]", len(keeps))
+	}
+
+	checkAwaitingKeyGeneration(
+		context.Background(),
+		newGoroutines(),
+		chain,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		keepsIndex,
+		nil,
+		nil,
+	)
+
+	if chain.openedTimestampCalls != 0 {
+		t.Errorf(
+			"unexpected opened timestamp calls\nexpected: [%v]\nactual:   [%v]",
+			0,
+			chain.openedTimestampCalls,
+		)
+	}
+}
+
+// openedTimestampCountingChain counts calls to GetOpenedTimestamp.
+type openedTimestampCountingChain struct {
+	local.Chain
+
+	openedTimestampMutex *sync.Mutex
+	openedTimestampCalls int
+}
+
+func (otcc *openedTimestampCountingChain) GetOpenedTimestamp(
+	keepAddress common.Address,
+) (time.Time, error) {
+	otcc.openedTimestampMutex.Lock()
+	otcc.openedTimestampCalls++
+	otcc.openedTimestampMutex.Unlock()
+
+	return otcc.Chain.GetOpenedTimestamp(keepAddress)
+}
+
+func newTestOptions(t *testing.T) *Options {
+	operatorPrivateKey, operatorPublicKey, err := operator.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, networkPublicKey := key.OperatorKeyToNetworkKey(
+		operatorPrivateKey,
+		operatorPublicKey,
+	)
+
+	chain := local.Connect()
+
+	return &Options{
+		OperatorPublicKey: operatorPublicKey,
+		EthereumChain:     chain,
+		NetworkProvider:   netlocal.ConnectWithKey(networkPublicKey),
+		Persistence:       newMemoryPersistence(),
+		KeepsIndex: index.NewKeepsIndex(
+			chain,
+			newMemoryPersistence(),
+			&index.Config{},
+			0,
+		),
+	}
+}
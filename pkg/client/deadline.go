@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+)
+
+// keyGenerationDeadline returns the time after which the keep will no longer
+// accept a public key. Key generation starts at the on-chain keep opening
+// timestamp.
+func keyGenerationDeadline(
+	ethereumChain eth.Handle,
+	keepAddress common.Address,
+) (time.Time, error) {
+	openedTimestamp, err := ethereumChain.GetOpenedTimestamp(keepAddress)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"failed to get opened timestamp of keep [%s]: [%v]",
+			keepAddress.String(),
+			err,
+		)
+	}
+
+	return openedTimestamp.Add(keyGenerationTimeout), nil
+}
+
+// signingDeadline returns the time after which the keep will no longer accept
+// a signature requested at the given block. Signing starts at the timestamp of
+// the block in which the signature was requested.
+func signingDeadline(
+	ethereumChain eth.Handle,
+	requestBlock uint64,
+) (time.Time, error) {
+	requestTimestamp, err := ethereumChain.BlockTimestamp(requestBlock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"failed to get timestamp of block [%v]: [%v]",
+			requestBlock,
+			err,
+		)
+	}
+
+	return requestTimestamp.Add(signingTimeout), nil
+}
+
+// isDeadlinePassed checks if the current block has been mined after the given
+// deadline.
+func isDeadlinePassed(ethereumChain eth.Handle, deadline time.Time) (bool, error) {
+	currentBlock, err := ethereumChain.BlockCounter().CurrentBlock()
+	if err != nil {
+		return false, fmt.Errorf("failed to get current block: [%v]", err)
+	}
+
+	return isBlockAfterDeadline(ethereumChain, currentBlock, deadline)
+}
+
+func isBlockAfterDeadline(
+	ethereumChain eth.Handle,
+	blockNumber uint64,
+	deadline time.Time,
+) (bool, error) {
+	blockTimestamp, err := ethereumChain.BlockTimestamp(blockNumber)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to get timestamp of block [%v]: [%v]",
+			blockNumber,
+			err,
+		)
+	}
+
+	return blockTimestamp.After(deadline), nil
+}
+
+// withChainDeadline returns a copy of the parent context which is cancelled
+// as soon as a block with a timestamp after the given deadline is mined. Such
+// block is the first one in which the contract no longer accepts the result
+// of the protocol, so there is no point to continue the work. Contrary to
+// a wall-clock deadline, it does not depend on the local clock and on delays
+// between the chain and the client.
+func withChainDeadline(
+	parent context.Context,
+	ethereumChain eth.Handle,
+	deadline time.Time,
+) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	blocks := ethereumChain.BlockCounter().WatchBlocks(ctx)
+
+	checkBlock := func(blockNumber uint64) bool {
+		isAfterDeadline, err := isBlockAfterDeadline(
+			ethereumChain,
+			blockNumber,
+			deadline,
+		)
+		if err != nil {
+			logger.Warningf("could not check deadline: [%v]", err)
+			return false
+		}
+
+		if isAfterDeadline {
+			logger.Infof(
+				"block [%v] has been mined after deadline [%v]",
+				blockNumber,
+				deadline,
+			)
+			cancel()
+		}
+
+		return isAfterDeadline
+	}
+
+	go func() {
+		currentBlock, err := ethereumChain.BlockCounter().CurrentBlock()
+		if err != nil {
+			logger.Warningf("could not get current block: [%v]", err)
+		} else if checkBlock(currentBlock) {
+			return
+		}
+
+		for {
+			select {
+			case blockNumber, ok := <-blocks:
+				if !ok {
+					return
+				}
+
+				if checkBlock(blockNumber) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
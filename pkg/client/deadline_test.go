@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+)
+
+func TestWithChainDeadline(t *testing.T) {
+	chain := local.Connect()
+
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deadline falls between the timestamps of the next two blocks, so the
+	// context should be cancelled once the block after the next one is mined.
+	deadlineBlock := currentBlock + 1
+	deadlineBlockTimestamp, err := chain.BlockTimestamp(deadlineBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := deadlineBlockTimestamp.Add(time.Millisecond)
+
+	ctx, cancel := withChainDeadline(context.Background(), chain, deadline)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context should be cancelled after deadline")
+	}
+
+	cancelledAtBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cancelledAtBlock <= deadlineBlock {
+		t.Errorf(
+			"context cancelled at block [%v] before deadline block [%v] ended",
+			cancelledAtBlock,
+			deadlineBlock,
+		)
+	}
+}
+
+func TestWithChainDeadlinePassed(t *testing.T) {
+	chain := local.Connect()
+
+	waitForNextBlock(t, chain)
+
+	ctx, cancel := withChainDeadline(
+		context.Background(),
+		chain,
+		time.Now().Add(-time.Hour),
+	)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("context should be cancelled immediately")
+	}
+}
+
+func TestSigningDeadline(t *testing.T) {
+	chain := local.Connect()
+
+	requestTimestamp, err := chain.BlockTimestamp(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline, err := signingDeadline(chain, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDeadline := requestTimestamp.Add(signingTimeout)
+	if !deadline.Equal(expectedDeadline) {
+		t.Errorf(
+			"unexpected deadline\nexpected: [%v]\nactual:   [%v]",
+			expectedDeadline,
+			deadline,
+		)
+	}
+
+	isPassed, err := isDeadlinePassed(chain, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isPassed {
+		t.Errorf("deadline should not be passed")
+	}
+}
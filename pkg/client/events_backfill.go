@@ -0,0 +1,466 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/keep-network/keep-common/pkg/subscription"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+)
+
+const (
+	// eventsBackfillPeriod is the number of blocks between two consecutive
+	// scans for keep events missed by live subscriptions.
+	eventsBackfillPeriod = 20
+	// eventsBackfillBatchSize is the maximum number of blocks scanned for
+	// past events in a single query.
+	eventsBackfillBatchSize = 5000
+)
+
+// eventsBackfill wraps live keep event subscriptions so that events emitted
+// when the subscription was broken, e.g. because of a dropped connection to
+// the Ethereum node or because the client was not running, are not lost.
+//
+// For each keep with at least one subscription, events emitted by the keep
+// are periodically fetched from the chain starting from the block after
+// the last processed one. The last processed block is stored in the keeps
+// registry so the scan continues from the same place after a restart.
+// Events fetched this way are passed to the same handlers as live events.
+// Each handler receives the given event only once, no matter if it was
+// delivered by the live subscription, the backfill or both.
+type eventsBackfill struct {
+	ethereumChain eth.Handle
+	keepsRegistry *registry.Keeps
+	goroutines    *goroutines
+
+	keepsMutex *sync.Mutex
+	keeps      map[common.Address]*keepEventsBackfill
+}
+
+type keepEventsBackfill struct {
+	cancel   context.CancelFunc
+	handlers map[int]*backfillHandler
+}
+
+// backfillHandler is a deduplicated handler of a single subscription. Exactly
+// one of the handler functions is set.
+type backfillHandler struct {
+	deduplicator *eventsDeduplicator
+
+	onSignatureRequested func(event *eth.SignatureRequestedEvent)
+	onKeepClosed         func(event *eth.KeepClosedEvent)
+	onKeepTerminated     func(event *eth.KeepTerminatedEvent)
+}
+
+func newEventsBackfill(
+	ethereumChain eth.Handle,
+	keepsRegistry *registry.Keeps,
+	goroutines *goroutines,
+) *eventsBackfill {
+	return &eventsBackfill{
+		ethereumChain: ethereumChain,
+		keepsRegistry: keepsRegistry,
+		goroutines:    goroutines,
+		keepsMutex:    &sync.Mutex{},
+		keeps:         make(map[common.Address]*keepEventsBackfill),
+	}
+}
+
+// OnSignatureRequested registers a handler for signature requested events
+// emitted by the given keep, including events missed by the live
+// subscription.
+func (eb *eventsBackfill) OnSignatureRequested(
+	keepAddress common.Address,
+	handler func(event *eth.SignatureRequestedEvent),
+) (subscription.EventSubscription, error) {
+	deduplicator := newEventsDeduplicator()
+
+	deduplicatedHandler := func(event *eth.SignatureRequestedEvent) {
+		if deduplicator.isNew(event.BlockNumber, event.Digest) {
+			handler(event)
+		}
+	}
+
+	liveSubscription, err := eb.ethereumChain.OnSignatureRequested(
+		keepAddress,
+		deduplicatedHandler,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return eb.register(
+		keepAddress,
+		liveSubscription,
+		&backfillHandler{
+			deduplicator:         deduplicator,
+			onSignatureRequested: deduplicatedHandler,
+		},
+	), nil
+}
+
+// OnKeepClosed registers a handler for the keep closed event emitted by
+// the given keep, including the event missed by the live subscription.
+func (eb *eventsBackfill) OnKeepClosed(
+	keepAddress common.Address,
+	handler func(event *eth.KeepClosedEvent),
+) (subscription.EventSubscription, error) {
+	deduplicator := newEventsDeduplicator()
+
+	deduplicatedHandler := func(event *eth.KeepClosedEvent) {
+		if deduplicator.isNew(event.BlockNumber, [32]byte{}) {
+			handler(event)
+		}
+	}
+
+	liveSubscription, err := eb.ethereumChain.OnKeepClosed(
+		keepAddress,
+		deduplicatedHandler,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return eb.register(
+		keepAddress,
+		liveSubscription,
+		&backfillHandler{
+			deduplicator: deduplicator,
+			onKeepClosed: deduplicatedHandler,
+		},
+	), nil
+}
+
+// OnKeepTerminated registers a handler for the keep terminated event emitted
+// by the given keep, including the event missed by the live subscription.
+func (eb *eventsBackfill) OnKeepTerminated(
+	keepAddress common.Address,
+	handler func(event *eth.KeepTerminatedEvent),
+) (subscription.EventSubscription, error) {
+	deduplicator := newEventsDeduplicator()
+
+	deduplicatedHandler := func(event *eth.KeepTerminatedEvent) {
+		if deduplicator.isNew(event.BlockNumber, [32]byte{}) {
+			handler(event)
+		}
+	}
+
+	liveSubscription, err := eb.ethereumChain.OnKeepTerminated(
+		keepAddress,
+		deduplicatedHandler,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return eb.register(
+		keepAddress,
+		liveSubscription,
+		&backfillHandler{
+			deduplicator:     deduplicator,
+			onKeepTerminated: deduplicatedHandler,
+		},
+	), nil
+}
+
+// register adds the handler to the backfill of the given keep and starts
+// the backfill if it is the first handler registered for the keep. Returned
+// subscription cancels both the live subscription and the backfill for
+// the handler.
+func (eb *eventsBackfill) register(
+	keepAddress common.Address,
+	liveSubscription subscription.EventSubscription,
+	handler *backfillHandler,
+) subscription.EventSubscription {
+	eb.keepsMutex.Lock()
+	defer eb.keepsMutex.Unlock()
+
+	keep, ok := eb.keeps[keepAddress]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		keep = &keepEventsBackfill{
+			cancel:   cancel,
+			handlers: make(map[int]*backfillHandler),
+		}
+		eb.keeps[keepAddress] = keep
+
+		go eb.monitor(ctx, keepAddress)
+	}
+
+	handlerID := rand.Int()
+	keep.handlers[handlerID] = handler
+
+	return subscription.NewEventSubscription(func() {
+		liveSubscription.Unsubscribe()
+
+		eb.keepsMutex.Lock()
+		defer eb.keepsMutex.Unlock()
+
+		delete(keep.handlers, handlerID)
+
+		if len(keep.handlers) == 0 {
+			keep.cancel()
+			delete(eb.keeps, keepAddress)
+		}
+	})
+}
+
+// monitor backfills events of the given keep on start and then every
+// eventsBackfillPeriod blocks, until the context is done.
+func (eb *eventsBackfill) monitor(ctx context.Context, keepAddress common.Address) {
+	blockCounter := eb.ethereumChain.BlockCounter()
+
+	startBlock, err := blockCounter.CurrentBlock()
+	if err != nil {
+		logger.Errorf(
+			"failed to start events backfill for keep [%s]: [%v]",
+			keepAddress.String(),
+			err,
+		)
+		return
+	}
+
+	// If the keep has not been monitored before, there is nothing to
+	// backfill. Only events emitted from now on are of interest.
+	_, hasLastProcessedBlock := eb.keepsRegistry.LastProcessedBlock(keepAddress)
+	if !hasLastProcessedBlock && eb.keepsRegistry.HasSigner(keepAddress) {
+		if err := eb.keepsRegistry.UpdateLastProcessedBlock(
+			keepAddress,
+			startBlock,
+		); err != nil {
+			logger.Errorf(
+				"failed to record events backfill start for keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+		}
+	}
+
+	eb.backfill(keepAddress)
+
+	lastBackfillBlock := startBlock
+	newBlockChan := blockCounter.WatchBlocks(ctx)
+
+	for {
+		select {
+		case block, ok := <-newBlockChan:
+			if !ok {
+				return
+			}
+
+			if block < lastBackfillBlock+eventsBackfillPeriod {
+				continue
+			}
+
+			eb.backfill(keepAddress)
+			lastBackfillBlock = block
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// backfill fetches events emitted by the given keep since the last processed
+// block up to the current block and passes them to registered handlers.
+func (eb *eventsBackfill) backfill(keepAddress common.Address) {
+	// Keep is no longer registered, e.g. it has been closed and archived.
+	// There is no point in looking for its events.
+	if !eb.keepsRegistry.HasSigner(keepAddress) {
+		return
+	}
+
+	lastProcessedBlock, ok := eb.keepsRegistry.LastProcessedBlock(keepAddress)
+	if !ok {
+		return
+	}
+
+	currentBlock, err := eb.ethereumChain.BlockCounter().CurrentBlock()
+	if err != nil {
+		logger.Errorf("failed to get current block: [%v]", err)
+		return
+	}
+
+	for startBlock := lastProcessedBlock + 1; startBlock <= currentBlock; {
+		endBlock := startBlock + eventsBackfillBatchSize - 1
+		if endBlock > currentBlock {
+			endBlock = currentBlock
+		}
+
+		if err := eb.backfillRange(keepAddress, startBlock, endBlock); err != nil {
+			logger.Errorf(
+				"failed to backfill events of keep [%s] from blocks [%v-%v]: [%v]",
+				keepAddress.String(),
+				startBlock,
+				endBlock,
+				err,
+			)
+			return
+		}
+
+		if err := eb.keepsRegistry.UpdateLastProcessedBlock(
+			keepAddress,
+			endBlock,
+		); err != nil {
+			logger.Errorf(
+				"failed to update last processed block of keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+			return
+		}
+
+		startBlock = endBlock + 1
+	}
+}
+
+func (eb *eventsBackfill) backfillRange(
+	keepAddress common.Address,
+	startBlock uint64,
+	endBlock uint64,
+) error {
+	handlers := eb.handlers(keepAddress)
+
+	var (
+		hasSignatureRequestedHandler bool
+		hasKeepClosedHandler         bool
+		hasKeepTerminatedHandler     bool
+	)
+	for _, handler := range handlers {
+		hasSignatureRequestedHandler =
+			hasSignatureRequestedHandler || handler.onSignatureRequested != nil
+		hasKeepClosedHandler =
+			hasKeepClosedHandler || handler.onKeepClosed != nil
+		hasKeepTerminatedHandler =
+			hasKeepTerminatedHandler || handler.onKeepTerminated != nil
+	}
+
+	var (
+		signatureRequestedEvents []*eth.SignatureRequestedEvent
+		keepClosedEvents         []*eth.KeepClosedEvent
+		keepTerminatedEvents     []*eth.KeepTerminatedEvent
+		err                      error
+	)
+
+	if hasSignatureRequestedHandler {
+		signatureRequestedEvents, err = eb.ethereumChain.PastSignatureRequestedEvents(
+			keepAddress,
+			startBlock,
+			endBlock,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasKeepClosedHandler {
+		keepClosedEvents, err = eb.ethereumChain.PastKeepClosedEvents(
+			startBlock,
+			endBlock,
+			keepAddress,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasKeepTerminatedHandler {
+		keepTerminatedEvents, err = eb.ethereumChain.PastKeepTerminatedEvents(
+			startBlock,
+			endBlock,
+			keepAddress,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Handlers are executed in separate goroutines, the same way as for live
+	// subscriptions, so that a long running handler does not block the backfill.
+	for _, handler := range handlers {
+		handler := handler
+
+		if handler.onSignatureRequested != nil {
+			for _, event := range signatureRequestedEvents {
+				event := event
+				eb.goroutines.start(func() { handler.onSignatureRequested(event) })
+			}
+		}
+		if handler.onKeepClosed != nil {
+			for _, event := range keepClosedEvents {
+				event := event
+				eb.goroutines.start(func() { handler.onKeepClosed(event) })
+			}
+		}
+		if handler.onKeepTerminated != nil {
+			for _, event := range keepTerminatedEvents {
+				event := event
+				eb.goroutines.start(func() { handler.onKeepTerminated(event) })
+			}
+		}
+	}
+
+	return nil
+}
+
+func (eb *eventsBackfill) handlers(keepAddress common.Address) []*backfillHandler {
+	eb.keepsMutex.Lock()
+	defer eb.keepsMutex.Unlock()
+
+	keep, ok := eb.keeps[keepAddress]
+	if !ok {
+		return nil
+	}
+
+	handlers := make([]*backfillHandler, 0, len(keep.handlers))
+	for _, handler := range keep.handlers {
+		handlers = append(handlers, handler)
+	}
+
+	return handlers
+}
+
+// eventsDeduplicator remembers events already passed to a handler. Events are
+// identified by the block in which they were emitted and the digest, if
+// the event has one.
+//
+// Seen events are never forgotten. Live subscriptions may deliver an event
+// with an arbitrary delay, long after the backfill processed its block, so
+// there is no block below which an event can no longer arrive. The
+// deduplicator lives only as long as the subscription of a single keep and
+// a keep emits few events, so the set stays small.
+type eventsDeduplicator struct {
+	mutex *sync.Mutex
+	seen  map[eventKey]bool
+}
+
+type eventKey struct {
+	blockNumber uint64
+	digest      [32]byte
+}
+
+func newEventsDeduplicator() *eventsDeduplicator {
+	return &eventsDeduplicator{
+		mutex: &sync.Mutex{},
+		seen:  make(map[eventKey]bool),
+	}
+}
+
+// isNew returns true if the event has not been seen before and notes it as
+// seen.
+func (ed *eventsDeduplicator) isNew(blockNumber uint64, digest [32]byte) bool {
+	ed.mutex.Lock()
+	defer ed.mutex.Unlock()
+
+	key := eventKey{blockNumber, digest}
+	if ed.seen[key] {
+		return false
+	}
+
+	ed.seen[key] = true
+	return true
+}
@@ -0,0 +1,418 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/keep-network/keep-common/pkg/persistence"
+	"github.com/keep-network/keep-common/pkg/subscription"
+	"github.com/keep-network/keep-core/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/internal/testdata"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/gen/pb"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+)
+
+var backfillKeepAddress = common.HexToAddress("0x770a9E2F2Aa1eC2d3Ca916Fc3e6A55058A898632")
+
+func TestEventsBackfillDeliversMissedSignatureRequest(t *testing.T) {
+	// Live subscriptions of this chain never deliver any event, the same way
+	// as a subscription of a broken websocket connection.
+	chain := &brokenSubscriptionsChain{local.Connect()}
+	keepsRegistry := newTestKeepsRegistry(t, chain)
+
+	backfill := newEventsBackfill(chain, keepsRegistry, newGoroutines())
+
+	events := make(chan *eth.SignatureRequestedEvent, 10)
+	subscription, err := backfill.OnSignatureRequested(
+		backfillKeepAddress,
+		func(event *eth.SignatureRequestedEvent) {
+			events <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	digest := [32]byte{1, 2, 3}
+	if err := chain.RequestSignature(backfillKeepAddress, digest); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForNextBlock(t, chain)
+	backfill.backfill(backfillKeepAddress)
+
+	event := expectEvent(t, events)
+	if event.Digest != digest {
+		t.Errorf(
+			"unexpected digest\nexpected: [%x]\nactual:   [%x]",
+			digest,
+			event.Digest,
+		)
+	}
+
+	lastProcessedBlock, _ := keepsRegistry.LastProcessedBlock(backfillKeepAddress)
+	if lastProcessedBlock < event.BlockNumber {
+		t.Errorf(
+			"last processed block [%v] is lower than the event block [%v]",
+			lastProcessedBlock,
+			event.BlockNumber,
+		)
+	}
+
+	backfill.backfill(backfillKeepAddress)
+	expectNoEvent(t, events)
+}
+
+func TestEventsBackfillDeduplicatesLiveEvents(t *testing.T) {
+	chain := local.Connect()
+	keepsRegistry := newTestKeepsRegistry(t, chain)
+
+	backfill := newEventsBackfill(chain, keepsRegistry, newGoroutines())
+
+	events := make(chan *eth.SignatureRequestedEvent, 10)
+	subscription, err := backfill.OnSignatureRequested(
+		backfillKeepAddress,
+		func(event *eth.SignatureRequestedEvent) {
+			events <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	if err := chain.RequestSignature(backfillKeepAddress, [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Live event.
+	expectEvent(t, events)
+
+	waitForNextBlock(t, chain)
+	backfill.backfill(backfillKeepAddress)
+
+	expectNoEvent(t, events)
+}
+
+func TestEventsBackfillDeduplicatesLateLiveEvents(t *testing.T) {
+	chain := &delayedSubscriptionsChain{Chain: local.Connect()}
+	keepsRegistry := newTestKeepsRegistry(t, chain)
+
+	backfill := newEventsBackfill(chain, keepsRegistry, newGoroutines())
+
+	events := make(chan *eth.SignatureRequestedEvent, 10)
+	subscription, err := backfill.OnSignatureRequested(
+		backfillKeepAddress,
+		func(event *eth.SignatureRequestedEvent) {
+			events <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	if err := chain.RequestSignature(backfillKeepAddress, [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForNextBlock(t, chain)
+	backfill.backfill(backfillKeepAddress)
+	event := expectEvent(t, events)
+
+	// The chain advanced far beyond the event block before the live
+	// subscription delivered the event.
+	atomic.StoreUint64(&chain.blocksAhead, 1000)
+	backfill.backfill(backfillKeepAddress)
+	chain.deliverSignatureRequested(event)
+
+	expectNoEvent(t, events)
+}
+
+func TestEventsBackfillResumesFromLastProcessedBlock(t *testing.T) {
+	chain := &brokenSubscriptionsChain{local.Connect()}
+	keepsRegistry := newTestKeepsRegistry(t, chain)
+
+	// Signature requested while the client was not running.
+	if err := chain.RequestSignature(backfillKeepAddress, [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForNextBlock(t, chain)
+
+	backfill := newEventsBackfill(chain, keepsRegistry, newGoroutines())
+
+	events := make(chan *eth.SignatureRequestedEvent, 10)
+	subscription, err := backfill.OnSignatureRequested(
+		backfillKeepAddress,
+		func(event *eth.SignatureRequestedEvent) {
+			events <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	// Backfill is executed on start.
+	expectEvent(t, events)
+}
+
+func TestEventsBackfillDeliversMissedKeepClosed(t *testing.T) {
+	chain := &brokenSubscriptionsChain{local.Connect()}
+	keepsRegistry := newTestKeepsRegistry(t, chain)
+
+	backfill := newEventsBackfill(chain, keepsRegistry, newGoroutines())
+
+	events := make(chan *eth.KeepClosedEvent, 10)
+	subscription, err := backfill.OnKeepClosed(
+		backfillKeepAddress,
+		func(event *eth.KeepClosedEvent) {
+			events <- event
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscription.Unsubscribe()
+
+	if err := chain.CloseKeep(backfillKeepAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForNextBlock(t, chain)
+	backfill.backfill(backfillKeepAddress)
+
+	select {
+	case event := <-events:
+		if event.KeepAddress != backfillKeepAddress {
+			t.Errorf(
+				"unexpected keep address\nexpected: [%v]\nactual:   [%v]",
+				backfillKeepAddress.String(),
+				event.KeepAddress.String(),
+			)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected keep closed event")
+	}
+}
+
+func TestEventsDeduplicator(t *testing.T) {
+	deduplicator := newEventsDeduplicator()
+
+	if !deduplicator.isNew(10, [32]byte{1}) {
+		t.Error("event should be new")
+	}
+	if deduplicator.isNew(10, [32]byte{1}) {
+		t.Error("event should not be new")
+	}
+	if !deduplicator.isNew(10, [32]byte{2}) {
+		t.Error("event with a different digest should be new")
+	}
+	if !deduplicator.isNew(11, [32]byte{1}) {
+		t.Error("event from a different block should be new")
+	}
+}
+
+type brokenSubscriptionsChain struct {
+	local.Chain
+}
+
+func (bsc *brokenSubscriptionsChain) OnSignatureRequested(
+	keepAddress common.Address,
+	handler func(event *eth.SignatureRequestedEvent),
+) (subscription.EventSubscription, error) {
+	return subscription.NewEventSubscription(func() {}), nil
+}
+
+func (bsc *brokenSubscriptionsChain) OnKeepClosed(
+	keepAddress common.Address,
+	handler func(event *eth.KeepClosedEvent),
+) (subscription.EventSubscription, error) {
+	return subscription.NewEventSubscription(func() {}), nil
+}
+
+// delayedSubscriptionsChain holds events of live subscriptions until they
+// are delivered explicitly. The current block reported by the chain can be
+// moved ahead of the local chain to simulate a long delay.
+type delayedSubscriptionsChain struct {
+	local.Chain
+
+	blocksAhead uint64
+
+	handlersMutex              sync.Mutex
+	signatureRequestedHandlers []func(event *eth.SignatureRequestedEvent)
+}
+
+func (dsc *delayedSubscriptionsChain) OnSignatureRequested(
+	keepAddress common.Address,
+	handler func(event *eth.SignatureRequestedEvent),
+) (subscription.EventSubscription, error) {
+	dsc.handlersMutex.Lock()
+	defer dsc.handlersMutex.Unlock()
+
+	dsc.signatureRequestedHandlers = append(
+		dsc.signatureRequestedHandlers,
+		handler,
+	)
+
+	return subscription.NewEventSubscription(func() {}), nil
+}
+
+func (dsc *delayedSubscriptionsChain) deliverSignatureRequested(
+	event *eth.SignatureRequestedEvent,
+) {
+	dsc.handlersMutex.Lock()
+	defer dsc.handlersMutex.Unlock()
+
+	for _, handler := range dsc.signatureRequestedHandlers {
+		handler(event)
+	}
+}
+
+func (dsc *delayedSubscriptionsChain) BlockCounter() chain.BlockCounter {
+	return &shiftedBlockCounter{
+		dsc.Chain.BlockCounter(),
+		atomic.LoadUint64(&dsc.blocksAhead),
+	}
+}
+
+type shiftedBlockCounter struct {
+	chain.BlockCounter
+
+	blocksAhead uint64
+}
+
+func (sbc *shiftedBlockCounter) CurrentBlock() (uint64, error) {
+	currentBlock, err := sbc.BlockCounter.CurrentBlock()
+	return currentBlock + sbc.blocksAhead, err
+}
+
+func expectEvent(
+	t *testing.T,
+	events <-chan *eth.SignatureRequestedEvent,
+) *eth.SignatureRequestedEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("expected signature requested event")
+		return nil
+	}
+}
+
+func expectNoEvent(t *testing.T, events <-chan *eth.SignatureRequestedEvent) {
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected signature requested event: [%+v]", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func waitForNextBlock(t *testing.T, chain local.Chain) {
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.BlockCounter().WaitForBlockHeight(currentBlock + 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestKeepsRegistry opens a keep on the local chain and registers a signer
+// for it in a new keeps registry with the current block recorded as the last
+// processed one.
+func newTestKeepsRegistry(t *testing.T, chain local.Chain) *registry.Keeps {
+	chain.OpenKeep(
+		backfillKeepAddress,
+		[]common.Address{chain.Address(), common.BytesToAddress([]byte{1})},
+	)
+
+	signer, err := newTestSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keepsRegistry := registry.NewKeepsRegistry(&persistenceHandleMock{})
+	if err := keepsRegistry.RegisterSigner(backfillKeepAddress, signer); err != nil {
+		t.Fatal(err)
+	}
+
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keepsRegistry.UpdateLastProcessedBlock(
+		backfillKeepAddress,
+		currentBlock,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	return keepsRegistry
+}
+
+func newTestSigner() (*tss.ThresholdSigner, error) {
+	testData, err := testdata.LoadKeygenTestFixtures(1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key gen test fixtures: [%v]", err)
+	}
+
+	thresholdKey := tss.ThresholdKey(testData[0])
+	thresholdKeyBytes, err := thresholdKey.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal threshold key: [%v]", err)
+	}
+
+	groupMemberIDs := [][]byte{[]byte("member-1"), []byte("member-2")}
+
+	bytes, err := proto.Marshal(&pb.ThresholdSigner{
+		GroupInfo: &pb.ThresholdSigner_GroupInfo{
+			GroupID:            "test-group-1",
+			MemberID:           groupMemberIDs[0],
+			GroupMemberIDs:     groupMemberIDs,
+			DishonestThreshold: 1,
+		},
+		ThresholdKey: thresholdKeyBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signer: [%v]", err)
+	}
+
+	signer := &tss.ThresholdSigner{}
+	if err := signer.Unmarshal(bytes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signer: [%v]", err)
+	}
+
+	return signer, nil
+}
+
+type persistenceHandleMock struct{}
+
+func (phm *persistenceHandleMock) Save(data []byte, directory string, name string) error {
+	return nil
+}
+
+func (phm *persistenceHandleMock) ReadAll() (<-chan persistence.DataDescriptor, <-chan error) {
+	dataChannel := make(chan persistence.DataDescriptor)
+	errorChannel := make(chan error)
+	close(dataChannel)
+	close(errorChannel)
+	return dataChannel, errorChannel
+}
+
+func (phm *persistenceHandleMock) Archive(directory string) error {
+	return nil
+}
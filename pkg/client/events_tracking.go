@@ -7,37 +7,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-// requestedSignersTrack is used to track signers generation started after keep
-// creation event is received. It is used to ensure that the process execution
-// is not duplicated, e.g. when the client receives the same event multiple times.
-// When event is received, it should be noted in this struct. When the signer
-// generation process completes (no matter if it succeeded or failed), it should
-// be removed from this struct.
-type requestedSignersTrack struct {
-	data  map[string]bool // <keep, bool>
-	mutex *sync.Mutex
-}
-
-func (rst *requestedSignersTrack) add(keepAddress common.Address) bool {
-	rst.mutex.Lock()
-	defer rst.mutex.Unlock()
-
-	if rst.data[keepAddress.String()] == true {
-		return false
-	}
-
-	rst.data[keepAddress.String()] = true
-
-	return true
-}
-
-func (rst *requestedSignersTrack) remove(keepAddress common.Address) {
-	rst.mutex.Lock()
-	defer rst.mutex.Unlock()
-
-	delete(rst.data, keepAddress.String())
-}
-
 // requestedSignaturesTrack is used to track signature calculation started after
 // signature request event is received. It is used to ensure that the process execution
 // is not duplicated, e.g. when the client receives the same event multiple times.
@@ -82,3 +51,33 @@ func (rst *requestedSignaturesTrack) remove(keepAddress common.Address, digest [
 		}
 	}
 }
+
+func (rst *requestedSignaturesTrack) count(keepAddress common.Address) int {
+	rst.mutex.Lock()
+	defer rst.mutex.Unlock()
+
+	return len(rst.data[keepAddress.String()])
+}
+
+func (rst *requestedSignaturesTrack) digests() map[common.Address][][32]byte {
+	rst.mutex.Lock()
+	defer rst.mutex.Unlock()
+
+	digests := make(map[common.Address][][32]byte, len(rst.data))
+	for keepAddress, keepSignatures := range rst.data {
+		for digestString := range keepSignatures {
+			digestBytes, err := hex.DecodeString(digestString)
+			if err != nil {
+				continue
+			}
+
+			var digest [32]byte
+			copy(digest[:], digestBytes)
+
+			address := common.HexToAddress(keepAddress)
+			digests[address] = append(digests[address], digest)
+		}
+	}
+
+	return digests
+}
@@ -7,75 +7,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-func TestRequestedSignersTrackAdd(t *testing.T) {
-	keepAddress1 := common.BytesToAddress([]byte{1})
-	keepAddress2 := common.BytesToAddress([]byte{2})
-
-	rs := &requestedSignersTrack{
-		data:  make(map[string]bool),
-		mutex: &sync.Mutex{},
-	}
-
-	if !rs.add(keepAddress1) {
-		t.Error("event wasn't emitted before; should be added successfully")
-	}
-
-	if !rs.add(keepAddress2) {
-		t.Error("event wasn't emitted before; should be added successfully")
-	}
-}
-
-func TestRequestedSignersTrackAdd_Duplicate(t *testing.T) {
-	keepAddress := common.BytesToAddress([]byte{1})
-
-	rs := &requestedSignersTrack{
-		data:  make(map[string]bool),
-		mutex: &sync.Mutex{},
-	}
-
-	if !rs.add(keepAddress) {
-		t.Error("event wasn't emitted before; should be added successfully")
-	}
-
-	if rs.add(keepAddress) {
-		t.Error("event was emitted before; it should not be added")
-	}
-}
-
-func TestRequestedSignersTrackRemove(t *testing.T) {
-	keepAddress := common.BytesToAddress([]byte{1})
-
-	rs := &requestedSignersTrack{
-		data:  make(map[string]bool),
-		mutex: &sync.Mutex{},
-	}
-
-	if !rs.add(keepAddress) {
-		t.Error("event wasn't emitted before; should be added successfully")
-	}
-
-	rs.remove(keepAddress)
-
-	if !rs.add(keepAddress) {
-		t.Error("event was removed from tracking; should be added successfully")
-	}
-}
-
-func TestRequestedSignersTrackRemove_WhenEmpty(t *testing.T) {
-	keepAddress := common.BytesToAddress([]byte{1})
-
-	rs := &requestedSignersTrack{
-		data:  make(map[string]bool),
-		mutex: &sync.Mutex{},
-	}
-
-	rs.remove(keepAddress)
-
-	if !rs.add(keepAddress) {
-		t.Error("event wasn't emitted before; should be added successfully")
-	}
-}
-
 func TestRequestedSignaturesTrackAdd_SameKeep(t *testing.T) {
 	keepAddress := common.BytesToAddress([]byte{1})
 
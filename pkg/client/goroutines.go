@@ -0,0 +1,46 @@
+package client
+
+import (
+	"sync"
+)
+
+// goroutines tracks goroutines started by the client so that stopping
+// the client can wait until all of them return. Once the client is stopping,
+// no new goroutines are started; event handlers called by the chain after
+// the client has been stopped do not start any work.
+type goroutines struct {
+	mutex     sync.Mutex
+	isStopped bool
+	waitGroup sync.WaitGroup
+}
+
+func newGoroutines() *goroutines {
+	return &goroutines{}
+}
+
+// start runs the function in a new tracked goroutine, unless the client is
+// stopping.
+func (g *goroutines) start(function func()) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.isStopped {
+		return
+	}
+
+	g.waitGroup.Add(1)
+	go func() {
+		defer g.waitGroup.Done()
+		function()
+	}()
+}
+
+// stop prevents new goroutines from being started and waits until all
+// tracked goroutines return.
+func (g *goroutines) stop() {
+	g.mutex.Lock()
+	g.isStopped = true
+	g.mutex.Unlock()
+
+	g.waitGroup.Wait()
+}
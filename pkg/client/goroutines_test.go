@@ -0,0 +1,59 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGoroutinesStopWaits(t *testing.T) {
+	goroutines := newGoroutines()
+
+	proceed := make(chan struct{})
+	returned := make(chan struct{})
+	goroutines.start(func() {
+		<-proceed
+		close(returned)
+	})
+
+	stopped := make(chan struct{})
+	go func() {
+		goroutines.stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop should wait for running goroutines")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(proceed)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop should return once goroutines returned")
+	}
+
+	select {
+	case <-returned:
+	default:
+		t.Error("goroutine should have returned before stop")
+	}
+}
+
+func TestGoroutinesNotStartedAfterStop(t *testing.T) {
+	goroutines := newGoroutines()
+	goroutines.stop()
+
+	started := make(chan struct{}, 1)
+	goroutines.start(func() {
+		started <- struct{}{}
+	})
+
+	select {
+	case <-started:
+		t.Error("goroutine should not be started after stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
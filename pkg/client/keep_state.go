@@ -0,0 +1,496 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+)
+
+// KeepState is a state of the keep lifecycle as seen by the client.
+type KeepState int
+
+// States of the keep lifecycle. A keep the client knows nothing about is
+// awaiting key generation.
+const (
+	// KeepAwaitingKeyGeneration means the key has not been generated yet and
+	// key generation has not been started.
+	KeepAwaitingKeyGeneration KeepState = iota
+	// KeepGeneratingKey means key generation protocol is in progress.
+	KeepGeneratingKey
+	// KeepActive means the key has been generated, stored and submitted to
+	// the chain and the client awaits signature requests.
+	KeepActive
+	// KeepSigning means at least one signing protocol is in progress.
+	KeepSigning
+	// KeepClosed means the keep has been closed on-chain.
+	KeepClosed
+	// KeepTerminated means the keep has been terminated on-chain.
+	KeepTerminated
+	// KeepArchived means key material of the closed or terminated keep has
+	// been archived. This is the final state.
+	KeepArchived
+)
+
+var keepStateNames = map[KeepState]string{
+	KeepAwaitingKeyGeneration: "awaiting_key_generation",
+	KeepGeneratingKey:         "generating_key",
+	KeepActive:                "active",
+	KeepSigning:               "signing",
+	KeepClosed:                "closed",
+	KeepTerminated:            "terminated",
+	KeepArchived:              "archived",
+}
+
+func (ks KeepState) String() string {
+	if name, ok := keepStateNames[ks]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+func parseKeepState(name string) (KeepState, error) {
+	for state, stateName := range keepStateNames {
+		if stateName == name {
+			return state, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown keep state [%v]", name)
+}
+
+// isPersisted determines if the state should be persisted in the registry.
+// Key generation states are not persisted since there is no key material
+// stored for the keep before the key is generated and interrupted key
+// generation has to be started from scratch anyway. The archived state is not
+// persisted since the keep directory is archived.
+func (ks KeepState) isPersisted() bool {
+	switch ks {
+	case KeepActive, KeepSigning, KeepClosed, KeepTerminated:
+		return true
+	default:
+		return false
+	}
+}
+
+// releasesChannels determines if network channels of the keep should be
+// released when the keep enters the state. Channels are not needed by closed
+// and terminated keeps and keeps awaiting key generation open them again once
+// key generation is started.
+func (ks KeepState) releasesChannels() bool {
+	switch ks {
+	case KeepAwaitingKeyGeneration, KeepClosed, KeepTerminated:
+		return true
+	default:
+		return false
+	}
+}
+
+// keepEvent is a chain event or a protocol result changing the keep state.
+type keepEvent int
+
+const (
+	eventKeyGenerationStarted keepEvent = iota
+	eventKeyGenerationFailed
+	eventKeyGenerated
+	eventSigningStarted
+	eventSigningCompleted
+	eventKeepClosed
+	eventKeepTerminated
+	eventKeepReopened
+	eventKeepArchived
+)
+
+var keepEventNames = map[keepEvent]string{
+	eventKeyGenerationStarted: "key generation started",
+	eventKeyGenerationFailed:  "key generation failed",
+	eventKeyGenerated:         "key generated",
+	eventSigningStarted:       "signing started",
+	eventSigningCompleted:     "signing completed",
+	eventKeepClosed:           "keep closed",
+	eventKeepTerminated:       "keep terminated",
+	eventKeepReopened:         "keep reopened",
+	eventKeepArchived:         "keep archived",
+}
+
+func (ke keepEvent) String() string {
+	if name, ok := keepEventNames[ke]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// keepTransitions defines all legal transitions of the keep state. Any event
+// not listed for the given state is rejected.
+//
+// A closed or terminated keep is reopened if a chain reorganization deeper
+// than the confirmation depth made it active again. This is possible only
+// until the keep is archived; archived key material is not restored.
+var keepTransitions = map[KeepState]map[keepEvent]KeepState{
+	KeepAwaitingKeyGeneration: {
+		eventKeyGenerationStarted: KeepGeneratingKey,
+		eventKeepClosed:           KeepClosed,
+		eventKeepTerminated:       KeepTerminated,
+	},
+	KeepGeneratingKey: {
+		eventKeyGenerationFailed: KeepAwaitingKeyGeneration,
+		eventKeyGenerated:        KeepActive,
+		eventKeepClosed:          KeepClosed,
+		eventKeepTerminated:      KeepTerminated,
+	},
+	KeepActive: {
+		eventSigningStarted: KeepSigning,
+		eventKeepClosed:     KeepClosed,
+		eventKeepTerminated: KeepTerminated,
+	},
+	KeepSigning: {
+		eventSigningStarted:   KeepSigning,
+		eventSigningCompleted: KeepActive,
+		eventKeepClosed:       KeepClosed,
+		eventKeepTerminated:   KeepTerminated,
+	},
+	KeepClosed: {
+		eventKeepReopened: KeepActive,
+		eventKeepArchived: KeepArchived,
+	},
+	KeepTerminated: {
+		eventKeepReopened: KeepActive,
+		eventKeepArchived: KeepArchived,
+	},
+}
+
+type keepLifecycle struct {
+	state       KeepState
+	isMonitored bool
+}
+
+type stateChange struct {
+	keepAddress common.Address
+	from        KeepState
+	to          KeepState
+}
+
+// keepStates tracks lifecycle states of all keeps the client is a member of.
+// It is the single source of truth about what the client should do with the
+// given keep; protocols are started and event subscriptions are created only
+// if the keep state allows for it. States of keeps holding key material are
+// persisted in the keeps registry. Network channels of keeps which no longer
+// need them are released with the channel manager.
+type keepStates struct {
+	mutex sync.Mutex
+	keeps map[common.Address]*keepLifecycle
+
+	// stateChanges collects state changes made with the mutex locked so
+	// that hooks are notified about them once the mutex is unlocked.
+	stateChanges []stateChange
+
+	requestedSignatures *requestedSignaturesTrack
+
+	keepsRegistry  *registry.Keeps
+	channelManager *channels.Manager
+	hooks          *Hooks
+}
+
+func newKeepStates(
+	keepsRegistry *registry.Keeps,
+	channelManager *channels.Manager,
+	hooks *Hooks,
+) *keepStates {
+	if hooks == nil {
+		hooks = &Hooks{}
+	}
+
+	return &keepStates{
+		keeps: make(map[common.Address]*keepLifecycle),
+		requestedSignatures: &requestedSignaturesTrack{
+			data:  make(map[string]map[string]bool),
+			mutex: &sync.Mutex{},
+		},
+		keepsRegistry:  keepsRegistry,
+		channelManager: channelManager,
+		hooks:          hooks,
+	}
+}
+
+// unlock unlocks the mutex, releases channels of keeps which no longer need
+// them and notifies hooks about state changes made while the mutex was locked.
+func (ks *keepStates) unlock() {
+	stateChanges := ks.stateChanges
+	ks.stateChanges = nil
+
+	ks.mutex.Unlock()
+
+	for _, change := range stateChanges {
+		if change.to.releasesChannels() {
+			ks.channelManager.ReleaseGroup(change.keepAddress.Hex())
+		}
+	}
+
+	if ks.hooks.OnKeepStateChanged == nil {
+		return
+	}
+
+	for _, change := range stateChanges {
+		ks.hooks.OnKeepStateChanged(change.keepAddress, change.from, change.to)
+	}
+}
+
+// load restores states of keeps loaded to the registry. Keeps with key
+// material but with no state stored have been created by a previous version
+// of the client and are considered active. Signing interrupted by the client
+// restart is not in progress anymore so the keep is considered active as well.
+func (ks *keepStates) load() {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	states := make(map[common.Address]KeepState)
+
+	for _, keepAddress := range ks.keepsRegistry.GetKeepsAddresses() {
+		states[keepAddress] = KeepActive
+	}
+
+	for keepAddress, storedState := range ks.keepsRegistry.GetKeepStates() {
+		state, err := parseKeepState(string(storedState))
+		if err != nil {
+			logger.Errorf(
+				"could not load state of keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+			continue
+		}
+
+		if state == KeepSigning {
+			state = KeepActive
+		}
+
+		states[keepAddress] = state
+	}
+
+	for keepAddress, state := range states {
+		logger.Debugf(
+			"loaded keep [%s] in state [%v]",
+			keepAddress.String(),
+			state,
+		)
+
+		ks.keeps[keepAddress] = &keepLifecycle{state: state}
+	}
+}
+
+// addresses returns addresses of all keeps with known state.
+func (ks *keepStates) addresses() []common.Address {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	addresses := make([]common.Address, 0, len(ks.keeps))
+	for keepAddress := range ks.keeps {
+		addresses = append(addresses, keepAddress)
+	}
+
+	return addresses
+}
+
+// snapshot returns current states of all tracked keeps.
+func (ks *keepStates) snapshot() map[common.Address]KeepState {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	states := make(map[common.Address]KeepState, len(ks.keeps))
+	for keepAddress, keep := range ks.keeps {
+		states[keepAddress] = keep.state
+	}
+
+	return states
+}
+
+// pendingSignatures returns digests which are currently being signed, grouped
+// by keep.
+func (ks *keepStates) pendingSignatures() map[common.Address][][32]byte {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	return ks.requestedSignatures.digests()
+}
+
+// state returns the current state of the given keep.
+func (ks *keepStates) state(keepAddress common.Address) KeepState {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	if keep, ok := ks.keeps[keepAddress]; ok {
+		return keep.state
+	}
+
+	return KeepAwaitingKeyGeneration
+}
+
+// transition changes the state of the given keep as a result of the given
+// event. An error is returned if the transition is not legal in the current
+// keep state.
+func (ks *keepStates) transition(keepAddress common.Address, event keepEvent) error {
+	ks.mutex.Lock()
+	defer ks.unlock()
+
+	return ks.transitionLocked(keepAddress, event)
+}
+
+func (ks *keepStates) transitionLocked(
+	keepAddress common.Address,
+	event keepEvent,
+) error {
+	keep, ok := ks.keeps[keepAddress]
+	if !ok {
+		keep = &keepLifecycle{state: KeepAwaitingKeyGeneration}
+	}
+
+	newState, ok := keepTransitions[keep.state][event]
+	if !ok {
+		return fmt.Errorf(
+			"illegal transition of keep [%s] in state [%v] on [%v]",
+			keepAddress.String(),
+			keep.state,
+			event,
+		)
+	}
+
+	if newState.isPersisted() && newState != keep.state {
+		err := ks.keepsRegistry.SaveKeepState(
+			keepAddress,
+			[]byte(newState.String()),
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"could not persist state of keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+		}
+	}
+
+	logger.Debugf(
+		"keep [%s] transitioned from [%v] to [%v] on [%v]",
+		keepAddress.String(),
+		keep.state,
+		newState,
+		event,
+	)
+
+	if newState != keep.state {
+		ks.stateChanges = append(ks.stateChanges, stateChange{
+			keepAddress: keepAddress,
+			from:        keep.state,
+			to:          newState,
+		})
+	}
+
+	keep.state = newState
+
+	switch newState {
+	case KeepAwaitingKeyGeneration, KeepArchived:
+		// Both states are equivalent to not tracking the keep at all.
+		delete(ks.keeps, keepAddress)
+	default:
+		ks.keeps[keepAddress] = keep
+	}
+
+	return nil
+}
+
+// startSigning registers that signing of the given digest has been started
+// for the given keep. An error is returned if the keep can not sign or if
+// signing of the digest is already in progress.
+func (ks *keepStates) startSigning(keepAddress common.Address, digest [32]byte) error {
+	ks.mutex.Lock()
+	defer ks.unlock()
+
+	if ok := ks.requestedSignatures.add(keepAddress, digest); !ok {
+		return fmt.Errorf(
+			"signing of digest [%x] for keep [%s] already in progress",
+			digest,
+			keepAddress.String(),
+		)
+	}
+
+	if err := ks.transitionLocked(keepAddress, eventSigningStarted); err != nil {
+		ks.requestedSignatures.remove(keepAddress, digest)
+		return err
+	}
+
+	return nil
+}
+
+// finishSigning registers that signing of the given digest for the given keep
+// has been completed, no matter if it succeeded or failed. The keep becomes
+// active again once all signings are completed.
+func (ks *keepStates) finishSigning(keepAddress common.Address, digest [32]byte) {
+	ks.mutex.Lock()
+	defer ks.unlock()
+
+	ks.requestedSignatures.remove(keepAddress, digest)
+
+	keep, ok := ks.keeps[keepAddress]
+	if !ok || keep.state != KeepSigning {
+		// The keep has been closed or terminated in the meantime.
+		return
+	}
+
+	if ks.requestedSignatures.count(keepAddress) > 0 {
+		return
+	}
+
+	if err := ks.transitionLocked(keepAddress, eventSigningCompleted); err != nil {
+		logger.Errorf("failed to complete signing: [%v]", err)
+	}
+}
+
+// signatureCompleted notifies hooks that signing of the given digest for the
+// given keep has completed.
+func (ks *keepStates) signatureCompleted(
+	keepAddress common.Address,
+	digest [32]byte,
+	err error,
+) {
+	if ks.hooks.OnSignatureCompleted != nil {
+		ks.hooks.OnSignatureCompleted(keepAddress, digest, err)
+	}
+}
+
+// startMonitoring returns true if the client should subscribe for events of
+// the given keep. It returns true only once for the keep and only if the key
+// has been generated and the keep is still active.
+func (ks *keepStates) startMonitoring(keepAddress common.Address) bool {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+
+	keep, ok := ks.keeps[keepAddress]
+	if !ok || keep.isMonitored {
+		return false
+	}
+
+	if keep.state != KeepActive && keep.state != KeepSigning {
+		return false
+	}
+
+	keep.isMonitored = true
+	return true
+}
+
+// archive archives key material of the given keep. Only closed or terminated
+// keeps can be archived.
+func (ks *keepStates) archive(keepAddress common.Address) error {
+	ks.mutex.Lock()
+	defer ks.unlock()
+
+	if err := ks.transitionLocked(keepAddress, eventKeepArchived); err != nil {
+		return err
+	}
+
+	ks.keepsRegistry.UnregisterKeep(keepAddress)
+
+	return nil
+}
@@ -0,0 +1,408 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-common/pkg/persistence"
+	"github.com/keep-network/keep-core/pkg/net/local"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+)
+
+func TestKeepStatesLifecycle(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+	digest := [32]byte{1}
+
+	keepStates := newKeepStates(registry.NewKeepsRegistry(newMemoryPersistence()), nil, nil)
+
+	assertState := func(expected KeepState) {
+		if actual := keepStates.state(keepAddress); actual != expected {
+			t.Fatalf(
+				"unexpected keep state\nexpected: [%v]\nactual:   [%v]",
+				expected,
+				actual,
+			)
+		}
+	}
+
+	assertState(KeepAwaitingKeyGeneration)
+
+	if err := keepStates.transition(keepAddress, eventKeyGenerationStarted); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepGeneratingKey)
+
+	if err := keepStates.transition(keepAddress, eventKeyGenerated); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepActive)
+
+	if err := keepStates.startSigning(keepAddress, digest); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepSigning)
+
+	keepStates.finishSigning(keepAddress, digest)
+	assertState(KeepActive)
+
+	if err := keepStates.transition(keepAddress, eventKeepClosed); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepClosed)
+
+	// Closure reverted by a deep chain reorganization.
+	if err := keepStates.transition(keepAddress, eventKeepReopened); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepActive)
+
+	if err := keepStates.transition(keepAddress, eventKeepClosed); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepClosed)
+
+	if err := keepStates.archive(keepAddress); err != nil {
+		t.Fatal(err)
+	}
+	assertState(KeepAwaitingKeyGeneration)
+
+	if len(keepStates.addresses()) != 0 {
+		t.Errorf("archived keep should not be tracked")
+	}
+}
+
+func TestKeepStatesIllegalTransitions(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+
+	var tests = map[string]struct {
+		events        []keepEvent
+		illegalEvent  keepEvent
+		expectedState KeepState
+	}{
+		"duplicate key generation": {
+			events:        []keepEvent{eventKeyGenerationStarted},
+			illegalEvent:  eventKeyGenerationStarted,
+			expectedState: KeepGeneratingKey,
+		},
+		"key generation for active keep": {
+			events: []keepEvent{
+				eventKeyGenerationStarted,
+				eventKeyGenerated,
+			},
+			illegalEvent:  eventKeyGenerationStarted,
+			expectedState: KeepActive,
+		},
+		"signing before key generation": {
+			illegalEvent:  eventSigningStarted,
+			expectedState: KeepAwaitingKeyGeneration,
+		},
+		"archiving active keep": {
+			events: []keepEvent{
+				eventKeyGenerationStarted,
+				eventKeyGenerated,
+			},
+			illegalEvent:  eventKeepArchived,
+			expectedState: KeepActive,
+		},
+		"signing for closed keep": {
+			events: []keepEvent{
+				eventKeyGenerationStarted,
+				eventKeyGenerated,
+				eventKeepClosed,
+			},
+			illegalEvent:  eventSigningStarted,
+			expectedState: KeepClosed,
+		},
+		"reopening active keep": {
+			events: []keepEvent{
+				eventKeyGenerationStarted,
+				eventKeyGenerated,
+			},
+			illegalEvent:  eventKeepReopened,
+			expectedState: KeepActive,
+		},
+		"terminating closed keep": {
+			events: []keepEvent{
+				eventKeyGenerationStarted,
+				eventKeyGenerated,
+				eventKeepClosed,
+			},
+			illegalEvent:  eventKeepTerminated,
+			expectedState: KeepClosed,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			keepStates := newKeepStates(
+				registry.NewKeepsRegistry(newMemoryPersistence()),
+				nil,
+				nil,
+			)
+
+			for _, event := range test.events {
+				if err := keepStates.transition(keepAddress, event); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := keepStates.transition(keepAddress, test.illegalEvent); err == nil {
+				t.Errorf("expected illegal transition error")
+			}
+
+			if state := keepStates.state(keepAddress); state != test.expectedState {
+				t.Errorf(
+					"unexpected keep state\nexpected: [%v]\nactual:   [%v]",
+					test.expectedState,
+					state,
+				)
+			}
+		})
+	}
+}
+
+func TestKeepStatesArchiveActiveKeep(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+
+	persistence := newMemoryPersistence()
+	keepStates := newKeepStates(registry.NewKeepsRegistry(persistence), nil, nil)
+
+	keepStates.transition(keepAddress, eventKeyGenerationStarted)
+	keepStates.transition(keepAddress, eventKeyGenerated)
+
+	if err := keepStates.archive(keepAddress); err == nil {
+		t.Errorf("expected active keep archiving to fail")
+	}
+
+	if len(persistence.archived) != 0 {
+		t.Errorf("active keep should not be archived")
+	}
+}
+
+func TestKeepStatesSigning(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+	digest1 := [32]byte{1}
+	digest2 := [32]byte{2}
+
+	keepStates := newKeepStates(registry.NewKeepsRegistry(newMemoryPersistence()), nil, nil)
+
+	keepStates.transition(keepAddress, eventKeyGenerationStarted)
+	keepStates.transition(keepAddress, eventKeyGenerated)
+
+	if err := keepStates.startSigning(keepAddress, digest1); err != nil {
+		t.Fatal(err)
+	}
+	if err := keepStates.startSigning(keepAddress, digest1); err == nil {
+		t.Errorf("expected duplicate signing to fail")
+	}
+	if err := keepStates.startSigning(keepAddress, digest2); err != nil {
+		t.Fatal(err)
+	}
+
+	keepStates.finishSigning(keepAddress, digest1)
+	if state := keepStates.state(keepAddress); state != KeepSigning {
+		t.Errorf("keep should be signing until all signings complete")
+	}
+
+	keepStates.finishSigning(keepAddress, digest2)
+	if state := keepStates.state(keepAddress); state != KeepActive {
+		t.Errorf("keep should be active once all signings complete")
+	}
+}
+
+func TestKeepStatesMonitorOnce(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+
+	keepStates := newKeepStates(registry.NewKeepsRegistry(newMemoryPersistence()), nil, nil)
+
+	if keepStates.startMonitoring(keepAddress) {
+		t.Errorf("keep without key should not be monitored")
+	}
+
+	keepStates.transition(keepAddress, eventKeyGenerationStarted)
+	keepStates.transition(keepAddress, eventKeyGenerated)
+
+	if !keepStates.startMonitoring(keepAddress) {
+		t.Errorf("active keep should be monitored")
+	}
+	if keepStates.startMonitoring(keepAddress) {
+		t.Errorf("keep should be monitored only once")
+	}
+}
+
+func TestKeepStatesStateChangedHook(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+
+	var stateChanges []KeepState
+	keepStates := newKeepStates(
+		registry.NewKeepsRegistry(newMemoryPersistence()),
+		nil,
+		&Hooks{
+			OnKeepStateChanged: func(
+				changedKeepAddress common.Address,
+				from, to KeepState,
+			) {
+				if changedKeepAddress != keepAddress {
+					t.Errorf("unexpected keep [%s]", changedKeepAddress.String())
+				}
+				stateChanges = append(stateChanges, to)
+			},
+		},
+	)
+
+	keepStates.transition(keepAddress, eventKeyGenerationStarted)
+	keepStates.transition(keepAddress, eventKeyGenerated)
+	keepStates.startSigning(keepAddress, [32]byte{1})
+	keepStates.startSigning(keepAddress, [32]byte{2})
+	keepStates.finishSigning(keepAddress, [32]byte{1})
+	keepStates.finishSigning(keepAddress, [32]byte{2})
+
+	expectedStateChanges := []KeepState{
+		KeepGeneratingKey,
+		KeepActive,
+		KeepSigning,
+		KeepActive,
+	}
+	if !reflect.DeepEqual(expectedStateChanges, stateChanges) {
+		t.Errorf(
+			"unexpected state changes\nexpected: [%v]\nactual:   [%v]",
+			expectedStateChanges,
+			stateChanges,
+		)
+	}
+}
+
+func TestKeepStatesReleaseChannels(t *testing.T) {
+	keepAddress := common.BytesToAddress([]byte{1})
+
+	channelManager := channels.NewManager(local.Connect())
+	keepStates := newKeepStates(
+		registry.NewKeepsRegistry(newMemoryPersistence()),
+		channelManager,
+		nil,
+	)
+
+	keepStates.transition(keepAddress, eventKeyGenerationStarted)
+	keepStates.transition(keepAddress, eventKeyGenerated)
+
+	keepChannels := channelManager.NewOwner(keepAddress.Hex())
+	if _, err := keepChannels.BroadcastChannelFor(keepAddress.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	keepStates.startSigning(keepAddress, [32]byte{1})
+	keepStates.finishSigning(keepAddress, [32]byte{1})
+
+	if _, err := keepChannels.BroadcastChannelFor(keepAddress.Hex()); err != nil {
+		t.Errorf("channels of the active keep have been released")
+	}
+
+	keepStates.transition(keepAddress, eventKeepClosed)
+
+	if _, err := keepChannels.BroadcastChannelFor(keepAddress.Hex()); err == nil {
+		t.Errorf("channels of the closed keep have not been released")
+	}
+}
+
+func TestKeepStatesLoad(t *testing.T) {
+	signingKeep := common.BytesToAddress([]byte{1})
+	closedKeep := common.BytesToAddress([]byte{2})
+	generatingKeep := common.BytesToAddress([]byte{3})
+
+	persistence := newMemoryPersistence()
+	keepStates := newKeepStates(registry.NewKeepsRegistry(persistence), nil, nil)
+
+	keepStates.transition(signingKeep, eventKeyGenerationStarted)
+	keepStates.transition(signingKeep, eventKeyGenerated)
+	keepStates.startSigning(signingKeep, [32]byte{1})
+
+	keepStates.transition(closedKeep, eventKeyGenerationStarted)
+	keepStates.transition(closedKeep, eventKeyGenerated)
+	keepStates.transition(closedKeep, eventKeepClosed)
+
+	keepStates.transition(generatingKeep, eventKeyGenerationStarted)
+
+	keepsRegistry := registry.NewKeepsRegistry(persistence)
+	keepsRegistry.LoadExistingKeeps()
+
+	loadedKeepStates := newKeepStates(keepsRegistry, nil, nil)
+	loadedKeepStates.load()
+
+	expectedStates := map[common.Address]KeepState{
+		signingKeep:    KeepActive,
+		closedKeep:     KeepClosed,
+		generatingKeep: KeepAwaitingKeyGeneration,
+	}
+	for keepAddress, expectedState := range expectedStates {
+		if state := loadedKeepStates.state(keepAddress); state != expectedState {
+			t.Errorf(
+				"unexpected state of keep [%s]\nexpected: [%v]\nactual:   [%v]",
+				keepAddress.String(),
+				expectedState,
+				state,
+			)
+		}
+	}
+}
+
+type memoryPersistence struct {
+	files    map[string]map[string][]byte
+	archived []string
+}
+
+func newMemoryPersistence() *memoryPersistence {
+	return &memoryPersistence{
+		files: make(map[string]map[string][]byte),
+	}
+}
+
+func (mp *memoryPersistence) Save(data []byte, directory string, name string) error {
+	if _, ok := mp.files[directory]; !ok {
+		mp.files[directory] = make(map[string][]byte)
+	}
+
+	mp.files[directory][name] = data
+	return nil
+}
+
+func (mp *memoryPersistence) ReadAll() (<-chan persistence.DataDescriptor, <-chan error) {
+	dataChannel := make(chan persistence.DataDescriptor, 100)
+	errorChannel := make(chan error)
+
+	for directory, files := range mp.files {
+		for name, data := range files {
+			dataChannel <- &memoryDataDescriptor{name, directory, data}
+		}
+	}
+
+	close(dataChannel)
+	close(errorChannel)
+
+	return dataChannel, errorChannel
+}
+
+func (mp *memoryPersistence) Archive(directory string) error {
+	delete(mp.files, directory)
+	mp.archived = append(mp.archived, directory)
+	return nil
+}
+
+type memoryDataDescriptor struct {
+	name      string
+	directory string
+	content   []byte
+}
+
+func (mdd *memoryDataDescriptor) Name() string {
+	return mdd.name
+}
+
+func (mdd *memoryDataDescriptor) Directory() string {
+	return mdd.directory
+}
+
+func (mdd *memoryDataDescriptor) Content() ([]byte, error) {
+	return mdd.content, nil
+}
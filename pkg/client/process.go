@@ -0,0 +1,666 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/keep-network/keep-common/pkg/chain/ethereum/ethutil"
+	"github.com/keep-network/keep-common/pkg/persistence"
+	"github.com/keep-network/keep-core/pkg/chain"
+	"github.com/keep-network/keep-core/pkg/metrics"
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/net/libp2p"
+	"github.com/keep-network/keep-core/pkg/net/retransmission"
+	"github.com/keep-network/keep-core/pkg/operator"
+
+	"github.com/keep-network/keep-ecdsa/internal/config"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/ethereum"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+	"github.com/keep-network/keep-ecdsa/pkg/confirmation"
+	"github.com/keep-network/keep-ecdsa/pkg/custody"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/firewall"
+	"github.com/keep-network/keep-ecdsa/pkg/identity"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
+	"github.com/keep-network/keep-ecdsa/pkg/lease"
+	"github.com/keep-network/keep-ecdsa/pkg/node"
+	"github.com/keep-network/keep-ecdsa/pkg/policy"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+)
+
+// Constants related with network.
+//
+// In order to communicate, nodes in the network should have a connection
+// between them. Basically a node can:
+//   - receive a connection from another peer
+//   - automatically open a connection to another peer
+//     during core bootstrap round
+//   - automatically open a connection to another peer
+//     after routing table refresh (DHT bootstrap round)
+//
+// Ideally, each node in the network should have a connection with all
+// other nodes or at least be aware of their existence. This strongly depends
+// on the actual network topology but some parameters can be fine-tuned
+// in order to improve the behavior of the network.
+const (
+	// routingTableRefreshPeriod determines the frequency of routing table
+	// refreshes. Routing table is actually a structure which contains
+	// transport identifiers of other network peers along with their
+	// addresses. A refresh of the routing table is basically a query
+	// sent to connected peers asking about new entries from their routing
+	// tables. If the node receives an information about new peers it will
+	// try to connect them automatically.
+	//
+	// The refresh period should be set to a value which will
+	// allow to keep the routing table up to date with the actual
+	// network state. Bear in mind a smaller value may not have sense
+	// as changes in the network need some time to propagate and frequent
+	// refreshes can increase resource consumption and network congestion.
+	routingTableRefreshPeriod = 5 * time.Minute
+)
+
+// Process runs clients of all operator accounts configured for a single
+// client process. Clients share the network host, the keeps index, the TSS
+// pre-parameters pool, the protocol scheduler and other process-wide
+// components.
+type Process struct {
+	ctx             context.Context
+	isLeaseEnabled  bool
+	networkProvider net.Provider
+	clients         []*Client
+}
+
+// StartProcess connects operator accounts from the config, acquires the lease
+// if it is enabled, connects to the network and starts clients of all
+// operators. If the lease is enabled, the call blocks until the node acquires
+// it. The process runs until the provided context is done or until the node
+// stops being the leader.
+func StartProcess(ctx context.Context, config *config.Config) (*Process, error) {
+	accounts := config.Accounts()
+	operatorAccounts := make([]*operatorAccount, len(accounts))
+	operatorPrivateKeys := make([]*operator.PrivateKey, len(accounts))
+	operatorAddresses := make(map[common.Address]bool, len(accounts))
+	for i, account := range accounts {
+		operatorAccount, err := connectOperatorAccount(
+			config,
+			account.KeyFile,
+			account.KeyFilePassword,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if operatorAddresses[operatorAccount.address] {
+			return nil, fmt.Errorf(
+				"operator [%s] is configured more than once",
+				operatorAccount.address.String(),
+			)
+		}
+		operatorAddresses[operatorAccount.address] = true
+
+		operatorAccounts[i] = operatorAccount
+		operatorPrivateKeys[i] = operatorAccount.privateKey
+	}
+
+	persistences := make([]persistence.Handle, len(operatorAccounts))
+	keepsRegistries := make([]*registry.Keeps, len(operatorAccounts))
+	for i, operatorAccount := range operatorAccounts {
+		handle, err := initializePersistence(config, operatorAccount, i == 0)
+		if err != nil {
+			return nil, err
+		}
+
+		persistences[i] = handle
+		keepsRegistries[i] = registry.NewKeepsRegistry(handle)
+	}
+
+	if config.Lease.IsEnabled() {
+		leaseCtx, err := acquireLease(
+			ctx,
+			config,
+			operatorAccounts,
+			keepsRegistries,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lease: [%v]", err)
+		}
+		ctx = leaseCtx
+	}
+
+	// The main operator account is used to identify the network host and
+	// to keep the index of keeps shared by all operators up to date.
+	mainAccount := operatorAccounts[0]
+	ethereumChain := mainAccount.ethereumChain
+	stakeMonitor := mainAccount.stakeMonitor
+
+	keepsIndex, err := initializeKeepsIndex(ctx, config, ethereumChain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize keeps index: [%v]", err)
+	}
+
+	networkPrivateKey, _ := key.OperatorKeyToNetworkKey(
+		mainAccount.privateKey, mainAccount.publicKey,
+	)
+
+	libp2pProvider, err := libp2p.Connect(
+		ctx,
+		config.LibP2P,
+		networkPrivateKey,
+		libp2p.ProtocolECDSA,
+		firewall.NewStakeOrActiveKeepPolicy(
+			ethereumChain,
+			stakeMonitor,
+			keepsIndex,
+		),
+		retransmission.NewTimeTicker(ctx, 1*time.Second),
+		libp2p.WithRoutingTableRefreshPeriod(routingTableRefreshPeriod),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	networkProvider, err := identity.NewProvider(
+		ctx,
+		libp2pProvider,
+		mainAccount.publicKey,
+		operatorPrivateKeys,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize network provider: [%v]", err)
+	}
+
+	sanctionedApplications, err := config.SanctionedApplications.Addresses()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sanctioned applications addresses: [%v]", err)
+	}
+
+	channelManager := channels.NewManager(networkProvider)
+	scheduler := node.NewScheduler(&config.Scheduler)
+	peerMonitor := node.NewPeerMonitor(&config.Heartbeat)
+
+	reliabilityLedger, err := OpenReliabilityLedger(config)
+	if err != nil {
+		return nil, err
+	}
+
+	signingPolicy, err := policy.NewFromConfig(&config.SigningPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize signing policy: [%v]", err)
+	}
+
+	// With the custody daemon, key shares and pre-parameters are held by
+	// the daemon and the client only relays protocol messages.
+	var preParamsPool *node.PreParamsPool
+	var custodian node.Custodian
+	if config.Custody.IsEnabled() {
+		custodyClient, err := connectCustody(ctx, config, networkProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to custody daemon: [%v]", err)
+		}
+		custodian = custodyClient
+	} else {
+		preParamsPool = node.NewPreParamsPool(ctx, &config.TSS)
+	}
+
+	clients := make([]*Client, len(operatorAccounts))
+	for i, operatorAccount := range operatorAccounts {
+		ecdsaClient, err := New(&Options{
+			OperatorPublicKey:      operatorAccount.publicKey,
+			EthereumChain:          operatorAccount.ethereumChain,
+			NetworkProvider:        networkProvider,
+			ChannelManager:         channelManager,
+			Scheduler:              scheduler,
+			PeerMonitor:            peerMonitor,
+			ReliabilityLedger:      reliabilityLedger,
+			PreParamsPool:          preParamsPool,
+			Custodian:              custodian,
+			Persistence:            persistences[i],
+			KeepsRegistry:          keepsRegistries[i],
+			KeepsIndex:             keepsIndex,
+			SanctionedApplications: sanctionedApplications,
+			TSSConfig:              &config.TSS,
+			ConfirmationConfig:     &config.Confirmations,
+			RetryConfig:            &config.Retry,
+			SigningPolicy:          signingPolicy,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client: [%v]", err)
+		}
+
+		if err := ecdsaClient.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start client: [%v]", err)
+		}
+		clients[i] = ecdsaClient
+
+		logger.Debugf(
+			"initialized operator with address: [%s]",
+			operatorAccount.address.String(),
+		)
+	}
+
+	initializeMetrics(
+		ctx,
+		config,
+		networkProvider,
+		stakeMonitor,
+		scheduler,
+		peerMonitor,
+		reliabilityLedger,
+	)
+
+	return &Process{
+		ctx:             ctx,
+		isLeaseEnabled:  config.Lease.IsEnabled(),
+		networkProvider: networkProvider,
+		clients:         clients,
+	}, nil
+}
+
+// NetworkAddresses returns addresses under which the network host of
+// the process is reachable.
+func (p *Process) NetworkAddresses() []string {
+	return p.networkProvider.ConnectionManager().AddrStrings()
+}
+
+// Wait blocks until the process stops running and returns the reason.
+func (p *Process) Wait() error {
+	<-p.ctx.Done()
+
+	if p.isLeaseEnabled {
+		// Exiting guarantees no protocol is executed by this node once
+		// another node may have taken the lease over.
+		return fmt.Errorf("node is no longer the leader")
+	}
+
+	return fmt.Errorf("unexpected context cancellation")
+}
+
+// Stop stops clients of all operators. It returns once all of them stopped.
+func (p *Process) Stop() {
+	for _, client := range p.clients {
+		client.Stop()
+	}
+}
+
+// operatorAccount is an operator account run by the client along with
+// the chain handle used to act on its behalf.
+type operatorAccount struct {
+	address       common.Address
+	password      string
+	privateKey    *operator.PrivateKey
+	publicKey     *operator.PublicKey
+	ethereumChain eth.Handle
+	stakeMonitor  chain.StakeMonitor
+}
+
+func connectOperatorAccount(
+	config *config.Config,
+	keyFile string,
+	keyFilePassword string,
+) (*operatorAccount, error) {
+	ethereumKey, err := ethutil.DecryptKeyFile(keyFile, keyFilePassword)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to read key file [%s]: [%v]",
+			keyFile,
+			err,
+		)
+	}
+
+	ethereumChain, err := ethereum.Connect(
+		ethereumKey,
+		&config.Ethereum,
+		&config.Retry,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ethereum node: [%v]", err)
+	}
+
+	stakeMonitor, err := ethereumChain.StakeMonitor()
+	if err != nil {
+		return nil, fmt.Errorf("error obtaining stake monitor handle: [%v]", err)
+	}
+	hasMinimumStake, err := stakeMonitor.HasMinimumStake(
+		ethereumKey.Address.Hex(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not check the stake: [%v]", err)
+	}
+	if !hasMinimumStake {
+		return nil, fmt.Errorf(
+			"no minimum KEEP stake or operator [%s] is not authorized to use it; "+
+				"please make sure the operator address in the configuration "+
+				"is correct and it has KEEP tokens delegated and the operator "+
+				"contract has been authorized to operate on the stake",
+			ethereumKey.Address.String(),
+		)
+	}
+
+	operatorPrivateKey, operatorPublicKey := operator.EthereumKeyToOperatorKey(ethereumKey)
+
+	return &operatorAccount{
+		address:       ethereumKey.Address,
+		password:      keyFilePassword,
+		privateKey:    operatorPrivateKey,
+		publicKey:     operatorPublicKey,
+		ethereumChain: ethereumChain,
+		stakeMonitor:  stakeMonitor,
+	}, nil
+}
+
+func connectCustody(
+	ctx context.Context,
+	config *config.Config,
+	networkProvider net.Provider,
+) (*custody.Client, error) {
+	token, err := custody.ReadToken(config.Custody.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	custodyClient := custody.NewClient(
+		config.Custody.Socket,
+		token,
+		networkProvider,
+	)
+	if err := custodyClient.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("connected to custody daemon at [%s]", config.Custody.Socket)
+
+	return custodyClient, nil
+}
+
+// standbyRefreshPeriod determines how often a node standing by reloads keeps
+// of the operators from the shared storage.
+const standbyRefreshPeriod = 1 * time.Minute
+
+// acquireLease blocks until the node acquires the lease. Until then, the node
+// stands by and keeps the provided registries of the operators up to date with
+// keeps persisted by the leader, without modifying them. Once the lease is
+// acquired, the registries are no longer refreshed and can be handed over to
+// clients of the operators. The returned context is done when the node stops
+// being the leader.
+func acquireLease(
+	ctx context.Context,
+	config *config.Config,
+	operatorAccounts []*operatorAccount,
+	keepsRegistries []*registry.Keeps,
+) (context.Context, error) {
+	nodeLease, err := lease.New(
+		lease.NewFileStorage(config.Lease.File),
+		&config.Lease,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("node [%s] stands by until it acquires the lease", nodeLease.NodeID())
+
+	followCtx, cancelFollow := context.WithCancel(ctx)
+	followDone := make(chan struct{})
+	go func() {
+		defer close(followDone)
+		followKeeps(followCtx, operatorAccounts, keepsRegistries)
+	}()
+
+	leaderCtx, err := nodeLease.Acquire(ctx)
+
+	// Registries may be handed over only once they are no longer refreshed.
+	cancelFollow()
+	<-followDone
+
+	return leaderCtx, err
+}
+
+// followKeeps periodically reloads keeps of the operators from the storage
+// into the provided registries until the context is done. Keeps are only read
+// so that the node standing by does not interfere with the leader.
+func followKeeps(
+	ctx context.Context,
+	operatorAccounts []*operatorAccount,
+	keepsRegistries []*registry.Keeps,
+) {
+	ticker := time.NewTicker(standbyRefreshPeriod)
+	defer ticker.Stop()
+
+	for {
+		for i, operatorAccount := range operatorAccounts {
+			keepsRegistry := keepsRegistries[i]
+			keepsRegistry.LoadExistingKeeps()
+
+			logger.Infof(
+				"standing by for operator [%s] being a member of [%d] keeps",
+				operatorAccount.address.String(),
+				len(keepsRegistry.GetKeepsAddresses()),
+			)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// operatorsDataDir is the name of the directory inside the storage data
+// directory in which key material of additional operator accounts is
+// persisted. Each additional operator has its own subdirectory named after
+// the operator address. Key material of the main operator is persisted
+// directly in the storage data directory.
+const operatorsDataDir = "operators"
+
+func initializePersistence(
+	config *config.Config,
+	operatorAccount *operatorAccount,
+	isMainAccount bool,
+) (persistence.Handle, error) {
+	dataDir := config.Storage.DataDir
+	if !isMainAccount {
+		dataDir = filepath.Join(
+			dataDir,
+			operatorsDataDir,
+			operatorAccount.address.Hex(),
+		)
+		if err := os.MkdirAll(dataDir, 0700); err != nil {
+			return nil, fmt.Errorf(
+				"failed to create operator data directory [%s]: [%v]",
+				dataDir,
+				err,
+			)
+		}
+	}
+
+	handle, err := persistence.NewDiskHandle(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed while creating a storage disk handler: [%v]", err)
+	}
+
+	return persistence.NewEncryptedPersistence(
+		handle,
+		operatorAccount.password,
+	), nil
+}
+
+// indexDataDir is the name of the directory inside the storage data directory
+// in which the keeps index is persisted. The index is kept apart from the
+// key material because it contains only public on-chain data and does not
+// have to be encrypted.
+const indexDataDir = "index"
+
+func initializeKeepsIndex(
+	ctx context.Context,
+	config *config.Config,
+	ethereumChain eth.Handle,
+) (*index.Keeps, error) {
+	indexPath := filepath.Join(config.Storage.DataDir, indexDataDir)
+	if err := os.MkdirAll(indexPath, 0700); err != nil {
+		return nil, fmt.Errorf(
+			"failed to create keeps index directory [%s]: [%v]",
+			indexPath,
+			err,
+		)
+	}
+
+	handle, err := persistence.NewDiskHandle(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed while creating a keeps index disk handler: [%v]",
+			err,
+		)
+	}
+
+	keepsIndex := index.NewKeepsIndex(
+		ethereumChain,
+		handle,
+		&config.Index,
+		config.Confirmations.Depth(confirmation.KeepCreated),
+	)
+
+	if err := keepsIndex.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load keeps index: [%v]", err)
+	}
+
+	logger.Info("synchronizing keeps index with the chain...")
+	if err := keepsIndex.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to synchronize keeps index: [%v]", err)
+	}
+
+	go keepsIndex.Run(ctx)
+
+	return keepsIndex, nil
+}
+
+// reliabilityDataDir is the name of the directory inside the storage data
+// directory in which the reliability ledger of co-signers is persisted.
+// Similarly to the keeps index, the ledger contains only public data and
+// does not have to be encrypted.
+const reliabilityDataDir = "reliability"
+
+// OpenReliabilityLedger loads the reliability ledger of co-signer operators
+// persisted in the storage data directory.
+func OpenReliabilityLedger(
+	config *config.Config,
+) (*node.ReliabilityLedger, error) {
+	ledgerPath := filepath.Join(config.Storage.DataDir, reliabilityDataDir)
+	if err := os.MkdirAll(ledgerPath, 0700); err != nil {
+		return nil, fmt.Errorf(
+			"failed to create reliability ledger directory [%s]: [%v]",
+			ledgerPath,
+			err,
+		)
+	}
+
+	handle, err := persistence.NewDiskHandle(ledgerPath)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed while creating a reliability ledger disk handler: [%v]",
+			err,
+		)
+	}
+
+	reliabilityLedger := node.NewReliabilityLedger(handle)
+
+	if err := reliabilityLedger.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load reliability ledger: [%v]", err)
+	}
+
+	return reliabilityLedger, nil
+}
+
+// defaultSchedulerMetricsTick is the default duration of the observation tick
+// for scheduler metrics.
+const defaultSchedulerMetricsTick = 10 * time.Second
+
+func initializeMetrics(
+	ctx context.Context,
+	config *config.Config,
+	netProvider net.Provider,
+	stakeMonitor chain.StakeMonitor,
+	scheduler *node.Scheduler,
+	peerMonitor *node.PeerMonitor,
+	reliabilityLedger *node.ReliabilityLedger,
+) {
+	registry, isConfigured := metrics.Initialize(
+		config.Metrics.Port,
+	)
+	if !isConfigured {
+		logger.Infof("metrics are not configured")
+		return
+	}
+
+	logger.Infof(
+		"enabled metrics on port [%v]",
+		config.Metrics.Port,
+	)
+
+	metrics.ObserveConnectedPeersCount(
+		ctx,
+		registry,
+		netProvider,
+		time.Duration(config.Metrics.NetworkMetricsTick)*time.Second,
+	)
+
+	metrics.ObserveConnectedBootstrapCount(
+		ctx,
+		registry,
+		netProvider,
+		config.LibP2P.Peers,
+		time.Duration(config.Metrics.NetworkMetricsTick)*time.Second,
+	)
+
+	metrics.ObserveEthConnectivity(
+		ctx,
+		registry,
+		stakeMonitor,
+		config.Ethereum.Account.Address,
+		time.Duration(config.Metrics.EthereumMetricsTick)*time.Second,
+	)
+
+	metrics.ExposeLibP2PInfo(
+		registry,
+		netProvider,
+	)
+
+	schedulerMetricsTick := defaultSchedulerMetricsTick
+	if config.Metrics.SchedulerMetricsTick > 0 {
+		schedulerMetricsTick = time.Duration(
+			config.Metrics.SchedulerMetricsTick,
+		) * time.Second
+	}
+
+	scheduler.ObserveQueueDepth(ctx, registry, schedulerMetricsTick)
+
+	peerMonitor.ObserveSilentPeers(
+		ctx,
+		registry,
+		time.Duration(config.Metrics.NetworkMetricsTick)*time.Second,
+	)
+
+	reliabilityLedger.ObserveUnreliableOperators(
+		ctx,
+		registry,
+		time.Duration(config.Metrics.NetworkMetricsTick)*time.Second,
+	)
+
+	tss.ObserveMessagePipeline(
+		ctx,
+		registry,
+		time.Duration(config.Metrics.NetworkMetricsTick)*time.Second,
+	)
+
+	tss.ObserveKeyGenerationTraffic(
+		ctx,
+		registry,
+		time.Duration(config.Metrics.NetworkMetricsTick)*time.Second,
+	)
+}
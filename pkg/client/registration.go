@@ -3,14 +3,14 @@ package client
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/confirmation"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
 const statusCheckIntervalBlocks = 100
-const retryDelay = 1 * time.Second
 
 // checkStatusAndRegisterForApplication checks whether the operator is
 // registered as a member candidate for keep for the given application.
@@ -21,11 +21,16 @@ const retryDelay = 1 * time.Second
 // process to keep the operator's status up to date in the pool.
 // If operator status in the pool cannot be monitored, e.g. when operator is
 // removed from the pool it triggers the registration process from the begining.
+// Failed chain calls are retried according to the provided retry policy.
 func checkStatusAndRegisterForApplication(
 	ctx context.Context,
 	ethereumChain eth.Handle,
+	confirmer *confirmation.Confirmer,
+	retryPolicy *retry.Policy,
 	application common.Address,
 ) {
+	backoff := retry.NewBackoff(retryPolicy)
+
 RegistrationLoop:
 	for {
 		select {
@@ -39,28 +44,66 @@ RegistrationLoop:
 					application.String(),
 					err,
 				)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				if !waitForRetry(ctx, backoff, application) {
+					return
+				}
 				continue RegistrationLoop
 			}
 
+			backoff.Reset()
+
 			if !isRegistered {
 				// if the operator is not registered, we need to register it and
 				// wait until registration is confirmed
-				registerAsMemberCandidate(ctx, ethereumChain, application)
-				waitUntilRegistered(ctx, ethereumChain, application)
+				registerAsMemberCandidate(
+					ctx,
+					ethereumChain,
+					retryPolicy,
+					application,
+				)
+				waitUntilRegistered(ctx, ethereumChain, retryPolicy, application)
 			}
 
 			// once the registration is confirmed or if the client is already
 			// registered, we can start to monitor the status
-			if err := monitorSignerPoolStatus(ctx, ethereumChain, application); err != nil {
+			if err := monitorSignerPoolStatus(
+				ctx,
+				ethereumChain,
+				confirmer,
+				application,
+			); err != nil {
 				logger.Errorf("failed on signer pool status monitoring: [%v]", err)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				if !waitForRetry(ctx, backoff, application) {
+					return
+				}
 				continue RegistrationLoop
 			}
 		}
 	}
 }
 
+// waitForRetry waits for the next attempt according to the backoff. It returns
+// false if no more attempts should be made.
+func waitForRetry(
+	ctx context.Context,
+	backoff *retry.Backoff,
+	application common.Address,
+) bool {
+	if err := backoff.Wait(ctx); err != nil {
+		if err == retry.ErrExhausted {
+			logger.Errorf(
+				"giving up on registration for application [%s] "+
+					"after [%v] attempts",
+				application.String(),
+				backoff.Attempts(),
+			)
+		}
+		return false
+	}
+
+	return true
+}
+
 // registerAsMemberCandidate checks current operator's eligibility to become
 // keep member candidate for the given application and if it is positive,
 // registers the operator as a keep member candidate for the given application.
@@ -69,6 +112,7 @@ RegistrationLoop:
 func registerAsMemberCandidate(
 	parentCtx context.Context,
 	ethereumChain eth.Handle,
+	retryPolicy *retry.Policy,
 	application common.Address,
 ) {
 	// If the operator is eligible right now for registering as a member
@@ -103,7 +147,12 @@ func registerAsMemberCandidate(
 	// We do the same in case the registration of eligible operator failed for
 	// some reason. As soon as the operator is eligible, we will proceed with
 	// the registration.
-	registerAsMemberCandidateWhenEligible(parentCtx, ethereumChain, application)
+	registerAsMemberCandidateWhenEligible(
+		parentCtx,
+		ethereumChain,
+		retryPolicy,
+		application,
+	)
 }
 
 // registerAsMemberCandidateWhenEligible for each new block checks the operator's
@@ -112,11 +161,14 @@ func registerAsMemberCandidate(
 func registerAsMemberCandidateWhenEligible(
 	parentCtx context.Context,
 	ethereumChain eth.Handle,
+	retryPolicy *retry.Policy,
 	application common.Address,
 ) {
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
+	backoff := retry.NewBackoff(retryPolicy)
+
 	newBlockChan := ethereumChain.BlockCounter().WatchBlocks(ctx)
 	for {
 		select {
@@ -128,7 +180,9 @@ func registerAsMemberCandidateWhenEligible(
 					application.String(),
 					err,
 				)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				if !waitForRetry(ctx, backoff, application) {
+					return
+				}
 				continue
 			}
 
@@ -139,7 +193,7 @@ func registerAsMemberCandidateWhenEligible(
 					"operator is not eligible for application [%s]",
 					application.String(),
 				)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				backoff.Reset()
 				continue
 			}
 
@@ -155,7 +209,9 @@ func registerAsMemberCandidateWhenEligible(
 					application.String(),
 					err,
 				)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				if !waitForRetry(ctx, backoff, application) {
+					return
+				}
 				continue
 			}
 
@@ -173,8 +229,11 @@ func registerAsMemberCandidateWhenEligible(
 func waitUntilRegistered(
 	ctx context.Context,
 	ethereumChain eth.Handle,
+	retryPolicy *retry.Policy,
 	application common.Address,
 ) {
+	backoff := retry.NewBackoff(retryPolicy)
+
 	newBlockChan := ethereumChain.BlockCounter().WatchBlocks(ctx)
 
 	for {
@@ -187,7 +246,9 @@ func waitUntilRegistered(
 					application.String(),
 					err,
 				)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				if !waitForRetry(ctx, backoff, application) {
+					return
+				}
 				continue
 			}
 
@@ -214,6 +275,7 @@ func waitUntilRegistered(
 func monitorSignerPoolStatus(
 	ctx context.Context,
 	ethereumChain eth.Handle,
+	confirmer *confirmation.Confirmer,
 	application common.Address,
 ) error {
 	logger.Debugf(
@@ -274,9 +336,10 @@ func monitorSignerPoolStatus(
 					)
 				}
 
-				isRegistered, err := waitForChainConfirmation(
-					ethereumChain,
+				isRegistered, err := confirmer.Confirm(
+					confirmation.OperatorStatusUpdated,
 					statusCheckBlock,
+					common.Hash{},
 					func() (bool, error) {
 						return ethereumChain.IsRegisteredForApplication(
 							application,
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
+	"github.com/keep-network/keep-ecdsa/pkg/policy"
+)
+
+// signingPolicy executes operator's signing hooks around signatures
+// calculated by the client.
+type signingPolicy struct {
+	policy     *policy.Policy
+	keepsIndex *index.Keeps
+}
+
+func (sp *signingPolicy) newRequest(
+	keepAddress common.Address,
+	digest [32]byte,
+	requestBlock uint64,
+) *policy.SigningRequest {
+	request := &policy.SigningRequest{
+		KeepAddress:  keepAddress,
+		Digest:       digest,
+		RequestBlock: requestBlock,
+	}
+
+	if keep, ok := sp.keepsIndex.Keep(keepAddress); ok {
+		request.Application = keep.Application
+	}
+
+	return request
+}
+
+func (sp *signingPolicy) beforeSigning(
+	ctx context.Context,
+	request *policy.SigningRequest,
+) error {
+	return sp.policy.BeforeSigning(ctx, request)
+}
+
+func (sp *signingPolicy) afterSigning(
+	ctx context.Context,
+	request *policy.SigningRequest,
+	signingErr error,
+) {
+	sp.policy.AfterSigning(ctx, request, signingErr)
+}
@@ -0,0 +1,212 @@
+// Package confirmation provides a reorganization-aware confirmation of chain
+// events. Before a client acts on an event, e.g. archives a closed keep or
+// starts signing, it waits until the block in which the event was emitted
+// gets enough confirmations, makes sure the block has not been reorganized
+// out of the chain in the meantime and checks the chain state.
+//
+// Confirmation does not protect against reorganizations deeper than
+// the configured depth. If such a reorganization makes a keep active again
+// after its closure or termination has been confirmed, the client reopens
+// the keep only if it has not been archived yet. Archived key material is not
+// restored automatically and has to be moved back from the archive directory
+// by the operator.
+package confirmation
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-log"
+
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+)
+
+var logger = log.Logger("keep-confirmation")
+
+// DefaultDepth is the number of block confirmations required for events for
+// which no depth has been configured.
+const DefaultDepth = 12
+
+// maxReorgRetries is the maximum number of times the confirmation is restarted
+// after the event block has been reorganized out of the chain. When it is
+// exceeded, the event is left unconfirmed and the caller does not act on it.
+const maxReorgRetries = 5
+
+// Event identifies a type of chain event which requires a confirmation.
+type Event string
+
+// Event types requiring confirmation.
+const (
+	SignatureRequested    Event = "SignatureRequested"
+	KeepClosed            Event = "KeepClosed"
+	KeepTerminated        Event = "KeepTerminated"
+	OperatorStatusUpdated Event = "OperatorStatusUpdated"
+	KeepCreated           Event = "KeepCreated"
+)
+
+// Config contains the number of block confirmations required for each event
+// type. Zero value means that the default depth should be used.
+type Config struct {
+	// Depth used for all event types without their own depth configured.
+	// If not set, DefaultDepth is used.
+	Default               uint64
+	SignatureRequested    uint64
+	KeepClosed            uint64
+	KeepTerminated        uint64
+	OperatorStatusUpdated uint64
+	KeepCreated           uint64
+}
+
+// Depth returns the number of block confirmations required for the given
+// event type.
+func (c *Config) Depth(event Event) uint64 {
+	var depth uint64
+
+	switch event {
+	case SignatureRequested:
+		depth = c.SignatureRequested
+	case KeepClosed:
+		depth = c.KeepClosed
+	case KeepTerminated:
+		depth = c.KeepTerminated
+	case OperatorStatusUpdated:
+		depth = c.OperatorStatusUpdated
+	case KeepCreated:
+		depth = c.KeepCreated
+	}
+
+	if depth != 0 {
+		return depth
+	}
+
+	if c.Default != 0 {
+		return c.Default
+	}
+
+	return DefaultDepth
+}
+
+// Confirmer confirms chain events.
+type Confirmer struct {
+	chain  eth.Handle
+	config *Config
+}
+
+// NewConfirmer creates a new event confirmer with confirmation depths defined
+// in the provided config.
+func NewConfirmer(chain eth.Handle, config *Config) *Confirmer {
+	if config == nil {
+		config = &Config{}
+	}
+
+	return &Confirmer{
+		chain:  chain,
+		config: config,
+	}
+}
+
+// Confirm waits until the block with the given number gets the number of
+// confirmations required for the given event type and then performs a check of
+// the chain state with the provided function. The function should return true
+// if the chain state confirms the event, e.g. the keep is no longer active
+// for the keep closed event.
+//
+// The block hash identifies the block in which the event was emitted. If it
+// is empty, the hash of the block with the given number at the time of the
+// call is used instead. When the required number of confirmations is reached,
+// the block hash is compared with the hash of the block with the same number
+// which is currently in the chain. If they differ, the event block has been
+// reorganized out of the chain and the decision can not be made yet. In such
+// case, the state is checked again and, if it is still as expected, the
+// confirmation is restarted from the current block, since the event could have
+// been mined again in one of the new blocks.
+func (c *Confirmer) Confirm(
+	event Event,
+	blockNumber uint64,
+	blockHash common.Hash,
+	stateCheck func() (bool, error),
+) (bool, error) {
+	depth := c.config.Depth(event)
+
+	for attempt := 0; ; attempt++ {
+		if blockHash == (common.Hash{}) {
+			hash, err := c.chain.BlockHash(blockNumber)
+			if err != nil {
+				return false, fmt.Errorf(
+					"failed to get hash of block [%v]: [%v]",
+					blockNumber,
+					err,
+				)
+			}
+			blockHash = hash
+		}
+
+		confirmationBlock := blockNumber + depth
+		logger.Infof(
+			"waiting for block [%d] to confirm [%v] event from block [%d]",
+			confirmationBlock,
+			event,
+			blockNumber,
+		)
+
+		err := c.chain.BlockCounter().WaitForBlockHeight(confirmationBlock)
+		if err != nil {
+			return false, fmt.Errorf("failed to wait for block height: [%v]", err)
+		}
+
+		canonicalHash, err := c.chain.BlockHash(blockNumber)
+		if err != nil {
+			return false, fmt.Errorf(
+				"failed to get hash of block [%v]: [%v]",
+				blockNumber,
+				err,
+			)
+		}
+
+		result, err := stateCheck()
+		if err != nil {
+			return false, fmt.Errorf(
+				"failed to get chain state confirmation: [%v]",
+				err,
+			)
+		}
+
+		if canonicalHash == blockHash {
+			return result, nil
+		}
+
+		logger.Warningf(
+			"block [%d] with [%v] event has been reorganized out of the chain; "+
+				"expected hash [%s], actual hash [%s]",
+			blockNumber,
+			event,
+			blockHash.Hex(),
+			canonicalHash.Hex(),
+		)
+
+		// The state no longer confirms the event so there is no point in
+		// waiting for it any longer.
+		if !result {
+			return false, nil
+		}
+
+		if attempt >= maxReorgRetries {
+			return false, fmt.Errorf(
+				"[%v] event could not be confirmed after [%v] reorganizations",
+				event,
+				attempt+1,
+			)
+		}
+
+		// The state still confirms the event so it must have been included in
+		// one of the new blocks. Since we do not know which one, we wait for
+		// confirmations counted from the current block.
+		currentBlock, err := c.chain.BlockCounter().CurrentBlock()
+		if err != nil {
+			return false, fmt.Errorf("failed to get current block: [%v]", err)
+		}
+
+		blockNumber = currentBlock
+		blockHash = common.Hash{}
+	}
+}
@@ -0,0 +1,179 @@
+package confirmation
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+)
+
+func TestDepth(t *testing.T) {
+	var tests = map[string]struct {
+		config        *Config
+		event         Event
+		expectedDepth uint64
+	}{
+		"no depth configured": {
+			config:        &Config{},
+			event:         KeepClosed,
+			expectedDepth: DefaultDepth,
+		},
+		"default depth configured": {
+			config:        &Config{Default: 30},
+			event:         KeepClosed,
+			expectedDepth: 30,
+		},
+		"event depth configured": {
+			config:        &Config{Default: 30, KeepClosed: 50},
+			event:         KeepClosed,
+			expectedDepth: 50,
+		},
+		"other event depth configured": {
+			config:        &Config{Default: 30, KeepTerminated: 50},
+			event:         KeepClosed,
+			expectedDepth: 30,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			depth := test.config.Depth(test.event)
+			if depth != test.expectedDepth {
+				t.Errorf(
+					"unexpected depth\nexpected: [%v]\nactual:   [%v]",
+					test.expectedDepth,
+					depth,
+				)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	chain := local.Connect()
+	confirmer := NewConfirmer(chain, &Config{KeepClosed: 2})
+
+	startBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := confirmer.Confirm(
+		KeepClosed,
+		startBlock,
+		common.Hash{},
+		func() (bool, error) { return true, nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result {
+		t.Errorf("event should be confirmed")
+	}
+
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if currentBlock < startBlock+2 {
+		t.Errorf(
+			"confirmation returned before block [%v]; current block [%v]",
+			startBlock+2,
+			currentBlock,
+		)
+	}
+}
+
+func TestConfirmStateNotConfirmed(t *testing.T) {
+	chain := local.Connect()
+	confirmer := NewConfirmer(chain, &Config{KeepClosed: 1})
+
+	startBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := confirmer.Confirm(
+		KeepClosed,
+		startBlock,
+		common.Hash{},
+		func() (bool, error) { return false, nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result {
+		t.Errorf("event should not be confirmed")
+	}
+}
+
+func TestConfirmReorgedEvent(t *testing.T) {
+	var tests = map[string]struct {
+		stateConfirmed      bool
+		expectedResult      bool
+		expectedStateChecks int32
+	}{
+		"state no longer confirms the event": {
+			stateConfirmed:      false,
+			expectedResult:      false,
+			expectedStateChecks: 1,
+		},
+		"state still confirms the event": {
+			stateConfirmed:      true,
+			expectedResult:      true,
+			expectedStateChecks: 2,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			chain := local.Connect()
+			confirmer := NewConfirmer(chain, &Config{KeepClosed: 1})
+
+			eventBlock, err := chain.BlockCounter().CurrentBlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			eventBlockHash, err := chain.BlockHash(eventBlock)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			chain.Reorg(eventBlock)
+
+			var stateChecks int32
+			result, err := confirmer.Confirm(
+				KeepClosed,
+				eventBlock,
+				eventBlockHash,
+				func() (bool, error) {
+					atomic.AddInt32(&stateChecks, 1)
+					return test.stateConfirmed, nil
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result != test.expectedResult {
+				t.Errorf(
+					"unexpected result\nexpected: [%v]\nactual:   [%v]",
+					test.expectedResult,
+					result,
+				)
+			}
+
+			if stateChecks != test.expectedStateChecks {
+				t.Errorf(
+					"unexpected number of state checks\nexpected: [%v]\nactual:   [%v]",
+					test.expectedStateChecks,
+					stateChecks,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package custody
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/operator"
+
+	"github.com/keep-network/keep-ecdsa/pkg/custody/gen/pb"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+)
+
+// filterQueryTimeout is the maximum time the node waits for the daemon to
+// evaluate a broadcast channel filter. Messages are rejected if the daemon
+// does not answer in time.
+const filterQueryTimeout = 5 * time.Second
+
+// networkBridge executes channel operations requested by the daemon with
+// the network provider of the node and passes messages received on the opened
+// channels to the daemon. Messages are relayed as bytes; the node does not
+// need to understand them.
+type networkBridge struct {
+	conn     *conn
+	provider net.Provider
+
+	mutex             sync.Mutex
+	transportIDs      map[string]net.TransportIdentifier
+	broadcastChannels map[string]net.BroadcastChannel
+	unicastChannels   map[string]net.UnicastChannel
+}
+
+func newNetworkBridge(conn *conn, provider net.Provider) *networkBridge {
+	return &networkBridge{
+		conn:              conn,
+		provider:          provider,
+		transportIDs:      make(map[string]net.TransportIdentifier),
+		broadcastChannels: make(map[string]net.BroadcastChannel),
+		unicastChannels:   make(map[string]net.UnicastChannel),
+	}
+}
+
+func (nb *networkBridge) handle(ctx context.Context, frame *pb.Frame) ([]byte, error) {
+	request := &pb.ChannelRequest{}
+	if err := request.Unmarshal(frame.Payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: [%v]", err)
+	}
+
+	switch frame.Kind {
+	case kindTransport:
+		return nb.createTransportIdentifier(request)
+	case kindBroadcastOpen:
+		return nil, nb.openBroadcastChannel(request)
+	case kindBroadcastRegister:
+		return nil, nb.registerBroadcastType(request)
+	case kindBroadcastFilter:
+		return nil, nb.setBroadcastFilter(request)
+	case kindBroadcastSend:
+		return nil, nb.broadcast(ctx, request)
+	case kindUnicastOpen:
+		return nil, nb.openUnicastChannel(request)
+	case kindUnicastRegister:
+		return nil, nb.registerUnicastType(request)
+	case kindUnicastSend:
+		return nil, nb.sendTo(request)
+	default:
+		return nil, fmt.Errorf("unknown request [%s]", frame.Kind)
+	}
+}
+
+func (nb *networkBridge) createTransportIdentifier(
+	request *pb.ChannelRequest,
+) ([]byte, error) {
+	publicKey, err := operator.Unmarshal(request.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal public key: [%v]", err)
+	}
+
+	transportID, err := nb.provider.CreateTransportIdentifier(*publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nb.mutex.Lock()
+	nb.transportIDs[transportID.String()] = transportID
+	nb.mutex.Unlock()
+
+	return []byte(transportID.String()), nil
+}
+
+func (nb *networkBridge) openBroadcastChannel(request *pb.ChannelRequest) error {
+	nb.mutex.Lock()
+	defer nb.mutex.Unlock()
+
+	if _, ok := nb.broadcastChannels[request.Channel]; ok {
+		return nil
+	}
+
+	channel, err := nb.provider.BroadcastChannelFor(request.Channel)
+	if err != nil {
+		return err
+	}
+
+	channel.Recv(nb.conn.ctx, func(message net.Message) {
+		nb.forward(&pb.NetworkMessage{Channel: request.Channel}, message)
+	})
+
+	nb.broadcastChannels[request.Channel] = channel
+
+	return nil
+}
+
+func (nb *networkBridge) registerBroadcastType(request *pb.ChannelRequest) error {
+	channel, err := nb.broadcastChannel(request.Channel)
+	if err != nil {
+		return err
+	}
+
+	return channel.RegisterUnmarshaler(nodeUnmarshaler(request.MessageType))
+}
+
+func (nb *networkBridge) setBroadcastFilter(request *pb.ChannelRequest) error {
+	channel, err := nb.broadcastChannel(request.Channel)
+	if err != nil {
+		return err
+	}
+
+	return channel.SetFilter(func(authorPublicKey *operator.PublicKey) bool {
+		ctx, cancel := context.WithTimeout(nb.conn.ctx, filterQueryTimeout)
+		defer cancel()
+
+		response, err := nb.conn.request(ctx, kindFilterQuery, &pb.ChannelRequest{
+			Channel: request.Channel,
+			Payload: operator.Marshal(authorPublicKey),
+		})
+		if err != nil {
+			logger.Warningf(
+				"rejecting message on channel [%s]; "+
+					"failed to evaluate filter: [%v]",
+				request.Channel,
+				err,
+			)
+			return false
+		}
+
+		return len(response) == 1 && response[0] == 1
+	})
+}
+
+func (nb *networkBridge) broadcast(
+	ctx context.Context,
+	request *pb.ChannelRequest,
+) error {
+	channel, err := nb.broadcastChannel(request.Channel)
+	if err != nil {
+		return err
+	}
+
+	return channel.Send(ctx, &rawMessage{
+		messageType: request.MessageType,
+		payload:     request.Payload,
+	})
+}
+
+func (nb *networkBridge) broadcastChannel(name string) (net.BroadcastChannel, error) {
+	nb.mutex.Lock()
+	defer nb.mutex.Unlock()
+
+	channel, ok := nb.broadcastChannels[name]
+	if !ok {
+		return nil, fmt.Errorf("broadcast channel [%s] is not open", name)
+	}
+
+	return channel, nil
+}
+
+func (nb *networkBridge) openUnicastChannel(request *pb.ChannelRequest) error {
+	nb.mutex.Lock()
+	defer nb.mutex.Unlock()
+
+	if _, ok := nb.unicastChannels[request.Peer]; ok {
+		return nil
+	}
+
+	transportID, ok := nb.transportIDs[request.Peer]
+	if !ok {
+		return fmt.Errorf("unknown peer [%s]", request.Peer)
+	}
+
+	channel, err := nb.provider.UnicastChannelWith(transportID)
+	if err != nil {
+		return err
+	}
+
+	channel.Recv(nb.conn.ctx, func(message net.Message) {
+		nb.forward(&pb.NetworkMessage{Peer: request.Peer}, message)
+	})
+
+	nb.unicastChannels[request.Peer] = channel
+
+	return nil
+}
+
+func (nb *networkBridge) registerUnicastType(request *pb.ChannelRequest) error {
+	channel, err := nb.unicastChannel(request.Peer)
+	if err != nil {
+		return err
+	}
+
+	channel.SetUnmarshaler(nodeUnmarshaler(request.MessageType))
+
+	return nil
+}
+
+func (nb *networkBridge) sendTo(request *pb.ChannelRequest) error {
+	channel, err := nb.unicastChannel(request.Peer)
+	if err != nil {
+		return err
+	}
+
+	return channel.Send(&rawMessage{
+		messageType: request.MessageType,
+		payload:     request.Payload,
+	})
+}
+
+func (nb *networkBridge) unicastChannel(peer string) (net.UnicastChannel, error) {
+	nb.mutex.Lock()
+	defer nb.mutex.Unlock()
+
+	channel, ok := nb.unicastChannels[peer]
+	if !ok {
+		return nil, fmt.Errorf("unicast channel with [%s] is not open", peer)
+	}
+
+	return channel, nil
+}
+
+// forward passes the message received on the channel described by the
+// provided network message to the daemon.
+func (nb *networkBridge) forward(
+	networkMessage *pb.NetworkMessage,
+	message net.Message,
+) {
+	payload, ok := message.Payload().(net.TaggedMarshaler)
+	if !ok {
+		return
+	}
+
+	payloadBytes, err := payload.Marshal()
+	if err != nil {
+		logger.Warningf("failed to marshal message for the daemon: [%v]", err)
+		return
+	}
+
+	networkMessage.SenderPublicKey = message.SenderPublicKey()
+	networkMessage.TransportSenderID = message.TransportSenderID().String()
+	networkMessage.MessageType = payload.Type()
+	networkMessage.Payload = payloadBytes
+	networkMessage.Seqno = message.Seqno()
+
+	if err := nb.conn.notify(kindMessage, networkMessage); err != nil {
+		logger.Warningf("failed to pass message to the daemon: [%v]", err)
+	}
+}
+
+// rawMessage is a network message of the given type which content is not
+// interpreted by the node.
+type rawMessage struct {
+	messageType string
+	payload     []byte
+}
+
+// nodeUnmarshaler returns the unmarshaler registered on the channel of the node
+// for messages of the given type. The node takes part in protocols of its own,
+// such as heartbeats, on the same channels, so messages the node understands
+// are unmarshaled as such and marshaled again before being passed to
+// the daemon. Other messages are not interpreted.
+func nodeUnmarshaler(messageType string) func() net.TaggedUnmarshaler {
+	for _, unmarshaler := range tss.Unmarshalers() {
+		if unmarshaler().Type() == messageType {
+			return unmarshaler
+		}
+	}
+
+	return rawUnmarshaler(messageType)
+}
+
+func rawUnmarshaler(messageType string) func() net.TaggedUnmarshaler {
+	return func() net.TaggedUnmarshaler {
+		return &rawMessage{messageType: messageType}
+	}
+}
+
+func (rm *rawMessage) Type() string {
+	return rm.messageType
+}
+
+func (rm *rawMessage) Marshal() ([]byte, error) {
+	return rm.payload, nil
+}
+
+func (rm *rawMessage) Unmarshal(bytes []byte) error {
+	rm.payload = append([]byte{}, bytes...)
+	return nil
+}
@@ -0,0 +1,195 @@
+package custody
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	stdnet "net"
+	"sync"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+
+	"github.com/keep-network/keep-ecdsa/pkg/custody/gen/pb"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+)
+
+// Client connects the node to the custody daemon. It implements the custodian
+// of the node: key generation and signing protocols are executed by the daemon
+// and the client passes protocol messages between the daemon and the network.
+// The client reconnects to the daemon if the connection has been lost.
+type Client struct {
+	socketPath string
+	token      []byte
+	provider   net.Provider
+
+	mutex sync.Mutex
+	conn  *conn
+}
+
+// NewClient creates a client of the daemon listening on the given socket.
+// Messages of protocols executed by the daemon are sent and received with
+// the provided network provider.
+func NewClient(socketPath string, token []byte, provider net.Provider) *Client {
+	return &Client{
+		socketPath: socketPath,
+		token:      token,
+		provider:   provider,
+	}
+}
+
+// Connect connects to the daemon unless the client is already connected.
+// The context bounds only the time of establishing the connection.
+func (c *Client) Connect(ctx context.Context) error {
+	_, err := c.connection(ctx)
+	return err
+}
+
+func (c *Client) connection(ctx context.Context) (*conn, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		select {
+		case <-c.conn.done():
+			logger.Warning("connection with the custody daemon lost; reconnecting")
+		default:
+			return c.conn, nil
+		}
+	}
+
+	var dialer stdnet.Dialer
+	netConn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the custody daemon: [%v]", err)
+	}
+
+	// The connection outlives requests; it is tied to the context of
+	// the client only.
+	conn := newConn(context.Background(), netConn)
+
+	netConn.SetDeadline(time.Now().Add(authenticationTimeout))
+	if err := conn.authenticateNode(c.token); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("failed to authenticate with the custody daemon: [%v]", err)
+	}
+	netConn.SetDeadline(time.Time{})
+
+	bridge := newNetworkBridge(conn, c.provider)
+	go conn.serve(bridge.handle)
+
+	c.conn = conn
+
+	return conn, nil
+}
+
+// Close closes the connection with the daemon.
+func (c *Client) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn != nil {
+		c.conn.close()
+	}
+}
+
+// GenerateThresholdSigners requests the daemon to execute the key generation
+// protocol for the given members of the group. Returned signers hold only
+// public data of the key; shares are kept by the daemon.
+func (c *Client) GenerateThresholdSigners(
+	ctx context.Context,
+	groupID string,
+	memberIDs []tss.MemberID,
+	groupMemberIDs []tss.MemberID,
+	dishonestThreshold uint,
+) ([]*tss.ThresholdSigner, error) {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err := conn.request(ctx, kindGenerate, &pb.GenerateRequest{
+		GroupID:            groupID,
+		MemberIDs:          fromMemberIDs(memberIDs),
+		GroupMemberIDs:     fromMemberIDs(groupMemberIDs),
+		DishonestThreshold: uint32(dishonestThreshold),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("custody daemon failed to generate key: [%v]", err)
+	}
+
+	response := &pb.GenerateResponse{}
+	if err := response.Unmarshal(responseBytes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: [%v]", err)
+	}
+
+	if len(response.Signers) != len(memberIDs) {
+		return nil, fmt.Errorf(
+			"custody daemon returned [%d] signers for [%d] members",
+			len(response.Signers),
+			len(memberIDs),
+		)
+	}
+
+	signers := make([]*tss.ThresholdSigner, len(response.Signers))
+	for i, signerBytes := range response.Signers {
+		signers[i] = &tss.ThresholdSigner{}
+		if err := signers[i].Unmarshal(signerBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return signers, nil
+}
+
+// CalculateSignature requests the daemon to execute the signing protocol with
+// key shares of the given signers.
+func (c *Client) CalculateSignature(
+	ctx context.Context,
+	digest []byte,
+	signers []*tss.ThresholdSigner,
+) (*ecdsa.Signature, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs := make([]tss.MemberID, len(signers))
+	for i, signer := range signers {
+		memberIDs[i] = signer.MemberID()
+	}
+
+	responseBytes, err := conn.request(ctx, kindSign, &pb.SignRequest{
+		GroupID:   signers[0].GroupID(),
+		MemberIDs: fromMemberIDs(memberIDs),
+		Digest:    digest,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("custody daemon failed to sign: [%v]", err)
+	}
+
+	response := &pb.SignResponse{}
+	if err := response.Unmarshal(responseBytes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: [%v]", err)
+	}
+
+	return &ecdsa.Signature{
+		R:          new(big.Int).SetBytes(response.R),
+		S:          new(big.Int).SetBytes(response.S),
+		RecoveryID: int(response.RecoveryID),
+	}, nil
+}
+
+func fromMemberIDs(memberIDs []tss.MemberID) [][]byte {
+	bytes := make([][]byte, len(memberIDs))
+	for i, memberID := range memberIDs {
+		bytes[i] = memberID
+	}
+
+	return bytes
+}
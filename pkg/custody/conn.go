@@ -0,0 +1,429 @@
+package custody
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	stdnet "net"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/keep-network/keep-ecdsa/pkg/custody/gen/pb"
+)
+
+const (
+	// maxFrameSize is the maximum size of a single frame. Frames carry
+	// protocol messages and signers which are far below this limit.
+	maxFrameSize = 64 * 1024 * 1024
+
+	nonceLength = 32
+
+	// Prefixes of data authenticated by each side of the connection so that
+	// a response of one side can not be replayed as a response of the other.
+	nodeAuthenticationPrefix   = "keep-ecdsa custody node:"
+	daemonAuthenticationPrefix = "keep-ecdsa custody daemon:"
+)
+
+// Kinds of frames exchanged during the authentication.
+const (
+	kindChallenge     = "challenge"
+	kindAuthenticate  = "authenticate"
+	kindAuthenticated = "authenticated"
+)
+
+// kindCancel is the kind of the notification cancelling the request with
+// the ID carried in the payload.
+const kindCancel = "cancel"
+
+// requestHandler handles a request or a notification received from the other
+// side of the connection. The returned payload is sent back for requests and
+// ignored for notifications. The context is done when the request has been
+// cancelled by the other side or when the connection is closed.
+type requestHandler func(ctx context.Context, frame *pb.Frame) ([]byte, error)
+
+// conn multiplexes requests sent in both directions over a single stream
+// connection. Each frame is preceded by its length.
+type conn struct {
+	netConn stdnet.Conn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	writeMutex sync.Mutex
+
+	requestsMutex   sync.Mutex
+	lastRequestID   uint64
+	pendingRequests map[uint64]chan *pb.Frame
+	servedRequests  map[uint64]context.CancelFunc
+}
+
+func newConn(ctx context.Context, netConn stdnet.Conn) *conn {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c := &conn{
+		netConn:         netConn,
+		ctx:             ctx,
+		cancel:          cancel,
+		pendingRequests: make(map[uint64]chan *pb.Frame),
+		servedRequests:  make(map[uint64]context.CancelFunc),
+	}
+
+	go func() {
+		<-ctx.Done()
+		netConn.Close()
+	}()
+
+	return c
+}
+
+// done returns a channel which is closed when the connection is closed.
+func (c *conn) done() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+func (c *conn) close() {
+	c.cancel()
+}
+
+// serve reads frames until the connection is closed. Responses are passed to
+// pending requests and other frames are passed to the handler.
+func (c *conn) serve(handler requestHandler) {
+	defer c.close()
+
+	for {
+		frame, err := c.readFrame()
+		if err != nil {
+			select {
+			case <-c.done():
+			default:
+				logger.Warningf("closing custody connection: [%v]", err)
+			}
+			return
+		}
+
+		switch {
+		case frame.IsResponse:
+			c.requestsMutex.Lock()
+			response, ok := c.pendingRequests[frame.RequestID]
+			delete(c.pendingRequests, frame.RequestID)
+			c.requestsMutex.Unlock()
+
+			if ok {
+				response <- frame
+			}
+		case frame.Kind == kindCancel:
+			if len(frame.Payload) != 8 {
+				continue
+			}
+			requestID := binary.BigEndian.Uint64(frame.Payload)
+
+			c.requestsMutex.Lock()
+			cancel, ok := c.servedRequests[requestID]
+			c.requestsMutex.Unlock()
+
+			if ok {
+				cancel()
+			}
+		default:
+			// The request is registered before it is handled so that
+			// a cancellation read right after it is not missed.
+			ctx, cancel := context.WithCancel(c.ctx)
+			if frame.RequestID != 0 {
+				c.requestsMutex.Lock()
+				c.servedRequests[frame.RequestID] = cancel
+				c.requestsMutex.Unlock()
+			}
+
+			go c.handle(ctx, cancel, handler, frame)
+		}
+	}
+}
+
+func (c *conn) handle(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	handler requestHandler,
+	frame *pb.Frame,
+) {
+	defer cancel()
+
+	if frame.RequestID != 0 {
+		defer func() {
+			c.requestsMutex.Lock()
+			delete(c.servedRequests, frame.RequestID)
+			c.requestsMutex.Unlock()
+		}()
+	}
+
+	payload, err := handler(ctx, frame)
+
+	if frame.RequestID == 0 {
+		if err != nil {
+			logger.Warningf(
+				"failed to handle custody notification [%s]: [%v]",
+				frame.Kind,
+				err,
+			)
+		}
+		return
+	}
+
+	response := &pb.Frame{
+		RequestID:  frame.RequestID,
+		IsResponse: true,
+		Kind:       frame.Kind,
+		Payload:    payload,
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+
+	if err := c.writeFrame(response); err != nil {
+		logger.Warningf("failed to respond to custody request: [%v]", err)
+	}
+}
+
+// request sends a request and waits for the response. If the context is done
+// before the response arrives, the other side is notified that the request
+// has been cancelled.
+func (c *conn) request(
+	ctx context.Context,
+	kind string,
+	payload proto.Marshaler,
+) ([]byte, error) {
+	payloadBytes, err := payload.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: [%v]", err)
+	}
+
+	response := make(chan *pb.Frame, 1)
+
+	c.requestsMutex.Lock()
+	c.lastRequestID++
+	requestID := c.lastRequestID
+	c.pendingRequests[requestID] = response
+	c.requestsMutex.Unlock()
+
+	removePending := func() {
+		c.requestsMutex.Lock()
+		delete(c.pendingRequests, requestID)
+		c.requestsMutex.Unlock()
+	}
+
+	if err := c.writeFrame(&pb.Frame{
+		RequestID: requestID,
+		Kind:      kind,
+		Payload:   payloadBytes,
+	}); err != nil {
+		removePending()
+		return nil, err
+	}
+
+	select {
+	case frame := <-response:
+		if frame.Error != "" {
+			return nil, fmt.Errorf("%s", frame.Error)
+		}
+		return frame.Payload, nil
+	case <-ctx.Done():
+		removePending()
+
+		cancelPayload := make([]byte, 8)
+		binary.BigEndian.PutUint64(cancelPayload, requestID)
+		if err := c.writeFrame(&pb.Frame{
+			Kind:    kindCancel,
+			Payload: cancelPayload,
+		}); err != nil {
+			logger.Warningf("failed to cancel custody request: [%v]", err)
+		}
+
+		return nil, ctx.Err()
+	case <-c.done():
+		removePending()
+		return nil, fmt.Errorf("custody connection closed")
+	}
+}
+
+// notify sends a notification which is not responded to.
+func (c *conn) notify(kind string, payload proto.Marshaler) error {
+	payloadBytes, err := payload.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: [%v]", err)
+	}
+
+	return c.writeFrame(&pb.Frame{
+		Kind:    kind,
+		Payload: payloadBytes,
+	})
+}
+
+func (c *conn) writeFrame(frame *pb.Frame) error {
+	bytes, err := frame.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal frame: [%v]", err)
+	}
+
+	if len(bytes) > maxFrameSize {
+		return fmt.Errorf("frame of [%d] bytes is too large", len(bytes))
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(bytes)))
+
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if _, err := c.netConn.Write(append(header, bytes...)); err != nil {
+		return fmt.Errorf("failed to write frame: [%v]", err)
+	}
+
+	return nil
+}
+
+func (c *conn) readFrame() (*pb.Frame, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.netConn, header); err != nil {
+		return nil, fmt.Errorf("failed to read frame header: [%v]", err)
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length > maxFrameSize {
+		return nil, fmt.Errorf("frame of [%d] bytes is too large", length)
+	}
+
+	bytes := make([]byte, length)
+	if _, err := io.ReadFull(c.netConn, bytes); err != nil {
+		return nil, fmt.Errorf("failed to read frame: [%v]", err)
+	}
+
+	frame := &pb.Frame{}
+	if err := frame.Unmarshal(bytes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal frame: [%v]", err)
+	}
+
+	return frame, nil
+}
+
+// authenticateDaemon is executed by the daemon before the connection is
+// served. Both sides prove they know the token by calculating a MAC over
+// a nonce chosen by the other side.
+func (c *conn) authenticateDaemon(token []byte) error {
+	daemonNonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	if err := c.writeFrame(&pb.Frame{
+		Kind:    kindChallenge,
+		Payload: daemonNonce,
+	}); err != nil {
+		return err
+	}
+
+	frame, err := c.readFrame()
+	if err != nil {
+		return err
+	}
+	if frame.Kind != kindAuthenticate {
+		return fmt.Errorf("unexpected frame [%s]", frame.Kind)
+	}
+
+	authentication := &pb.Authentication{}
+	if err := authentication.Unmarshal(frame.Payload); err != nil {
+		return fmt.Errorf("failed to unmarshal authentication: [%v]", err)
+	}
+
+	if !hmac.Equal(
+		authentication.Mac,
+		mac(token, nodeAuthenticationPrefix, daemonNonce),
+	) {
+		c.writeFrame(&pb.Frame{
+			Kind:  kindAuthenticated,
+			Error: "authentication failed",
+		})
+		return fmt.Errorf("node failed to authenticate")
+	}
+
+	if len(authentication.Nonce) != nonceLength {
+		return fmt.Errorf("invalid nonce length [%d]", len(authentication.Nonce))
+	}
+
+	return c.writeFrame(&pb.Frame{
+		Kind:    kindAuthenticated,
+		Payload: mac(token, daemonAuthenticationPrefix, authentication.Nonce),
+	})
+}
+
+// authenticateNode is executed by the node right after it connects to
+// the daemon.
+func (c *conn) authenticateNode(token []byte) error {
+	frame, err := c.readFrame()
+	if err != nil {
+		return err
+	}
+	if frame.Kind != kindChallenge || len(frame.Payload) != nonceLength {
+		return fmt.Errorf("unexpected frame [%s]", frame.Kind)
+	}
+
+	nodeNonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	authentication := &pb.Authentication{
+		Mac:   mac(token, nodeAuthenticationPrefix, frame.Payload),
+		Nonce: nodeNonce,
+	}
+	authenticationBytes, err := authentication.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal authentication: [%v]", err)
+	}
+
+	if err := c.writeFrame(&pb.Frame{
+		Kind:    kindAuthenticate,
+		Payload: authenticationBytes,
+	}); err != nil {
+		return err
+	}
+
+	frame, err = c.readFrame()
+	if err != nil {
+		return err
+	}
+	if frame.Kind != kindAuthenticated {
+		return fmt.Errorf("unexpected frame [%s]", frame.Kind)
+	}
+	if frame.Error != "" {
+		return fmt.Errorf("%s", frame.Error)
+	}
+
+	if !hmac.Equal(
+		frame.Payload,
+		mac(token, daemonAuthenticationPrefix, nodeNonce),
+	) {
+		return fmt.Errorf("daemon failed to authenticate")
+	}
+
+	return nil
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: [%v]", err)
+	}
+
+	return nonce, nil
+}
+
+func mac(token []byte, prefix string, nonce []byte) []byte {
+	hash := hmac.New(sha256.New, token)
+	hash.Write([]byte(prefix))
+	hash.Write(nonce)
+	return hash.Sum(nil)
+}
@@ -0,0 +1,104 @@
+// Package custody separates key shares of the operator from the node
+// connected to the network and to the chain. Shares are held by the custody
+// daemon which executes key generation and signing protocols. The node sends
+// protocol requests to the daemon over a local authenticated socket and
+// relays messages between the daemon and other keep members, so compromising
+// the node does not expose key shares.
+package custody
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("keep-custody")
+
+// tokenLength is the length of the token generated by the daemon.
+const tokenLength = 32
+
+// Config contains the configuration of the node connecting to the custody
+// daemon.
+type Config struct {
+	// Socket is the path of the Unix socket on which the daemon listens.
+	// If set, the node delegates key generation and signing to the daemon.
+	Socket string
+	// TokenFile is the path of the file with the token created by the daemon
+	// used to authenticate connections.
+	TokenFile string
+}
+
+// IsEnabled returns true if the socket of the daemon has been configured.
+func (c *Config) IsEnabled() bool {
+	return c != nil && c.Socket != ""
+}
+
+// DaemonConfig contains the configuration of the custody daemon. The daemon
+// is expected to run as a separate OS user, so that the node can not read
+// key shares and the secret encrypting them.
+type DaemonConfig struct {
+	// Socket is the path of the Unix socket on which the daemon listens.
+	Socket string
+	// TokenFile is the path of the file with the token used to authenticate
+	// connections. The daemon creates the file if it does not exist.
+	TokenFile string
+	// DataDir is the directory in which the daemon persists key shares.
+	DataDir string
+	// Password is the secret with which key shares are encrypted. It is
+	// expected to be provided as an environment variable of the daemon and
+	// must not be known to the node.
+	Password string
+}
+
+// ReadToken reads the token from the file created by the daemon.
+func ReadToken(tokenFile string) ([]byte, error) {
+	content, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: [%v]", err)
+	}
+
+	token, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token: [%v]", err)
+	}
+
+	if len(token) == 0 {
+		return nil, fmt.Errorf("token file [%s] is empty", tokenFile)
+	}
+
+	return token, nil
+}
+
+// ReadOrCreateToken reads the token from the file or, if the file does not
+// exist, generates a new token and writes it to the file writable by the user
+// running the daemon and readable by its group. The node is expected to run as
+// a different user belonging to that group.
+func ReadOrCreateToken(tokenFile string) ([]byte, error) {
+	if _, err := os.Stat(tokenFile); err == nil {
+		return ReadToken(tokenFile)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to check token file: [%v]", err)
+	}
+
+	token := make([]byte, tokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate token: [%v]", err)
+	}
+
+	if err := ioutil.WriteFile(
+		tokenFile,
+		[]byte(hex.EncodeToString(token)),
+		0640,
+	); err != nil {
+		return nil, fmt.Errorf("failed to write token file: [%v]", err)
+	}
+
+	logger.Infof("generated new custody token in [%s]", tokenFile)
+
+	return token, nil
+}
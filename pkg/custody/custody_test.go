@@ -0,0 +1,377 @@
+package custody
+
+import (
+	"context"
+	cecdsa "crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	stdnet "net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-common/pkg/persistence"
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/net/local"
+	"github.com/keep-network/keep-core/pkg/operator"
+
+	"github.com/keep-network/keep-ecdsa/internal/testdata"
+	"github.com/keep-network/keep-ecdsa/pkg/custody/gen/pb"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+var networkRetryPolicy = (&retry.Config{}).Policy(retry.Network)
+
+func TestAuthentication(t *testing.T) {
+	var tests = map[string]struct {
+		daemonToken []byte
+		nodeToken   []byte
+		expectError bool
+	}{
+		"same token": {
+			daemonToken: []byte("token"),
+			nodeToken:   []byte("token"),
+			expectError: false,
+		},
+		"different token": {
+			daemonToken: []byte("token"),
+			nodeToken:   []byte("another token"),
+			expectError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			daemonNetConn, nodeNetConn := stdnet.Pipe()
+
+			daemonConn := newConn(context.Background(), daemonNetConn)
+			defer daemonConn.close()
+			nodeConn := newConn(context.Background(), nodeNetConn)
+			defer nodeConn.close()
+
+			daemonErr := make(chan error, 1)
+			go func() {
+				daemonErr <- daemonConn.authenticateDaemon(test.daemonToken)
+			}()
+
+			nodeErr := nodeConn.authenticateNode(test.nodeToken)
+
+			if test.expectError != (nodeErr != nil) {
+				t.Errorf(
+					"unexpected node authentication result\n"+
+						"expected error: [%v]\nactual error:   [%v]",
+					test.expectError,
+					nodeErr,
+				)
+			}
+			if err := <-daemonErr; test.expectError != (err != nil) {
+				t.Errorf(
+					"unexpected daemon authentication result\n"+
+						"expected error: [%v]\nactual error:   [%v]",
+					test.expectError,
+					err,
+				)
+			}
+		})
+	}
+}
+
+func TestCancelSentRightAfterRequest(t *testing.T) {
+	daemonNetConn, nodeNetConn := stdnet.Pipe()
+
+	daemonConn := newConn(context.Background(), daemonNetConn)
+	defer daemonConn.close()
+	nodeConn := newConn(context.Background(), nodeNetConn)
+	defer nodeConn.close()
+
+	cancelled := make(chan uint64)
+	go daemonConn.serve(func(ctx context.Context, frame *pb.Frame) ([]byte, error) {
+		<-ctx.Done()
+		cancelled <- frame.RequestID
+		return nil, ctx.Err()
+	})
+	// Responses to cancelled requests are read and dropped by the node.
+	go nodeConn.serve(func(ctx context.Context, frame *pb.Frame) ([]byte, error) {
+		return nil, nil
+	})
+
+	for requestID := uint64(1); requestID <= 100; requestID++ {
+		if err := nodeConn.writeFrame(&pb.Frame{
+			RequestID: requestID,
+			Kind:      "test",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		cancelPayload := make([]byte, 8)
+		binary.BigEndian.PutUint64(cancelPayload, requestID)
+		if err := nodeConn.writeFrame(&pb.Frame{
+			Kind:    kindCancel,
+			Payload: cancelPayload,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case cancelledID := <-cancelled:
+			if cancelledID != requestID {
+				t.Fatalf(
+					"unexpected cancelled request\nexpected: [%v]\nactual:   [%v]",
+					requestID,
+					cancelledID,
+				)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("request [%v] has not been cancelled", requestID)
+		}
+	}
+}
+
+func TestConnectWithInvalidToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	socketPath, cleanup := newTestSocketPath(t)
+	defer cleanup()
+
+	server := &Server{token: []byte("token")}
+	listener, err := Listen(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ctx, listener)
+
+	client := NewClient(socketPath, []byte("another token"), nil)
+	if err := client.Connect(ctx); err == nil {
+		t.Fatal("expected authentication error")
+	}
+}
+
+func TestGenerateKeyAndSign(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	groupSize := 3
+	dishonestThreshold := uint(groupSize - 1)
+	// The daemon registers signers under the keep address equal to the group.
+	keepAddress := make([]byte, common.AddressLength)
+	rand.Read(keepAddress)
+	groupID := common.BytesToAddress(keepAddress).Hex()
+
+	testData, err := testdata.LoadKeygenTestFixtures(groupSize)
+	if err != nil {
+		t.Fatalf("failed to load test data: [%v]", err)
+	}
+
+	groupMemberIDs := make([]tss.MemberID, groupSize)
+	networkProviders := make([]net.Provider, groupSize)
+	for i := range groupMemberIDs {
+		_, publicKey, err := operator.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		groupMemberIDs[i] = tss.MemberIDFromPublicKey(publicKey)
+
+		networkPublicKey := key.NetworkPublic(*publicKey)
+		networkProviders[i] = local.ConnectWithKey(&networkPublicKey)
+	}
+
+	// Each member holds its share in its own custody daemon and relays
+	// protocol messages through its own network provider.
+	clients := make([]*Client, groupSize)
+	registries := make([]*registry.Keeps, groupSize)
+	for i := range clients {
+		var cleanup func()
+		clients[i], registries[i], cleanup = newTestCustody(
+			ctx,
+			t,
+			networkProviders[i],
+			&testData[i].LocalPreParams,
+		)
+		defer cleanup()
+	}
+
+	// Key generation.
+	signers := make([]*tss.ThresholdSigner, groupSize)
+	err = runMembers(groupSize, func(index int) error {
+		custodySigners, err := clients[index].GenerateThresholdSigners(
+			ctx,
+			groupID,
+			[]tss.MemberID{groupMemberIDs[index]},
+			groupMemberIDs,
+			dishonestThreshold,
+		)
+		if err != nil {
+			return err
+		}
+		signers[index] = custodySigners[0]
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error on key generation: [%v]", err)
+	}
+
+	publicKey := signers[0].PublicKey()
+	for i, signer := range signers {
+		if signer.HasShare() {
+			t.Errorf("signer of member [%d] returned by the daemon holds a key share", i)
+		}
+
+		if !reflect.DeepEqual(publicKey, signer.PublicKey()) {
+			t.Errorf(
+				"unexpected public key of member [%d]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				publicKey,
+				signer.PublicKey(),
+			)
+		}
+
+		custodySigners, err := registries[i].GetSigners(common.HexToAddress(groupID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(custodySigners) != 1 || !custodySigners[0].HasShare() {
+			t.Errorf("daemon of member [%d] has not registered the key share", i)
+		}
+	}
+
+	// Signing.
+	digest := sha256.Sum256([]byte("message to sign"))
+
+	signatures := make([]*ecdsa.Signature, groupSize)
+	err = runMembers(groupSize, func(index int) error {
+		signature, err := clients[index].CalculateSignature(
+			ctx,
+			digest[:],
+			[]*tss.ThresholdSigner{signers[index]},
+		)
+		signatures[index] = signature
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error on signing: [%v]", err)
+	}
+
+	for i, signature := range signatures {
+		if !reflect.DeepEqual(signatures[0], signature) {
+			t.Errorf(
+				"signature of member [%d] doesn't match\nexpected: [%v]\nactual:   [%v]",
+				i,
+				signatures[0],
+				signature,
+			)
+		}
+	}
+
+	if !cecdsa.Verify(
+		(*cecdsa.PublicKey)(publicKey),
+		digest[:],
+		signatures[0].R,
+		signatures[0].S,
+	) {
+		t.Errorf("invalid signature: [%+v]", signatures[0])
+	}
+}
+
+// newTestCustody starts a daemon generating keys with the given
+// pre-parameters and connects a client relaying messages through the given
+// network provider.
+func newTestCustody(
+	ctx context.Context,
+	t *testing.T,
+	networkProvider net.Provider,
+	preParams *keygen.LocalPreParams,
+) (*Client, *registry.Keeps, func()) {
+	dir, err := ioutil.TempDir("", "custody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	handle, err := persistence.NewDiskHandle(dir)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	keepsRegistry := registry.NewKeepsRegistry(
+		persistence.NewEncryptedPersistence(handle, "password"),
+	)
+
+	token := []byte("token")
+	server := &Server{
+		keepsRegistry: keepsRegistry,
+		preParamsPool: &testPreParamsSource{params: preParams},
+		retryPolicy:   networkRetryPolicy,
+		token:         token,
+	}
+
+	socketPath := filepath.Join(dir, "custody.sock")
+	listener, err := Listen(socketPath)
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	go server.Serve(ctx, listener)
+
+	client := NewClient(socketPath, token, networkProvider)
+	if err := client.Connect(ctx); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return client, keepsRegistry, func() {
+		client.Close()
+		cleanup()
+	}
+}
+
+// runMembers executes the function for each member concurrently and returns
+// the first error.
+func runMembers(groupSize int, run func(index int) error) error {
+	errs := make(chan error, groupSize)
+
+	var wg sync.WaitGroup
+	wg.Add(groupSize)
+	for i := 0; i < groupSize; i++ {
+		go func(index int) {
+			defer wg.Done()
+			if err := run(index); err != nil {
+				errs <- fmt.Errorf("member [%d]: [%v]", index, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+type testPreParamsSource struct {
+	params *keygen.LocalPreParams
+}
+
+func (tpps *testPreParamsSource) Get() *keygen.LocalPreParams {
+	return tpps.params
+}
+
+func newTestSocketPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "custody")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "custody.sock"), func() { os.RemoveAll(dir) }
+}
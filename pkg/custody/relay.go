@@ -0,0 +1,437 @@
+package custody
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/operator"
+
+	"github.com/keep-network/keep-ecdsa/pkg/custody/gen/pb"
+)
+
+// Kinds of requests sent by the daemon to let the node act on the network on
+// its behalf.
+const (
+	kindTransport         = "transport"
+	kindBroadcastOpen     = "broadcast.open"
+	kindBroadcastRegister = "broadcast.register"
+	kindBroadcastFilter   = "broadcast.filter"
+	kindBroadcastSend     = "broadcast.send"
+	kindUnicastOpen       = "unicast.open"
+	kindUnicastRegister   = "unicast.register"
+	kindUnicastSend       = "unicast.send"
+)
+
+// Kinds of frames sent by the node to pass messages received from
+// the network to the daemon.
+const (
+	// kindMessage is a notification with a message received from
+	// the network on one of the relayed channels.
+	kindMessage = "message"
+	// kindFilterQuery is a request asking whether the broadcast channel
+	// filter set by the daemon accepts messages of the given author.
+	kindFilterQuery = "filter"
+)
+
+// relayTransportID is a transport identifier of a peer as it is known to
+// the node.
+type relayTransportID string
+
+func (id relayTransportID) String() string {
+	return string(id)
+}
+
+// relayProvider is a network provider used by the daemon. It does not have
+// a network connection on its own; channel operations are sent to the node
+// which executes them with its network provider and passes received messages
+// back.
+type relayProvider struct {
+	conn *conn
+
+	channelsMutex     sync.Mutex
+	broadcastChannels map[string]*relayBroadcastChannel
+	unicastChannels   map[string]*relayUnicastChannel
+}
+
+func newRelayProvider(conn *conn) *relayProvider {
+	return &relayProvider{
+		conn:              conn,
+		broadcastChannels: make(map[string]*relayBroadcastChannel),
+		unicastChannels:   make(map[string]*relayUnicastChannel),
+	}
+}
+
+func (rp *relayProvider) ID() net.TransportIdentifier {
+	return relayTransportID("custody")
+}
+
+func (rp *relayProvider) Type() string {
+	return "custody"
+}
+
+func (rp *relayProvider) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	rp.channelsMutex.Lock()
+	defer rp.channelsMutex.Unlock()
+
+	peer := peerID.String()
+
+	if channel, ok := rp.unicastChannels[peer]; ok {
+		return channel, nil
+	}
+
+	if _, err := rp.conn.request(
+		rp.conn.ctx,
+		kindUnicastOpen,
+		&pb.ChannelRequest{Peer: peer},
+	); err != nil {
+		return nil, err
+	}
+
+	channel := &relayUnicastChannel{
+		relayHandlers: newRelayHandlers(),
+		conn:          rp.conn,
+		peer:          peer,
+	}
+	rp.unicastChannels[peer] = channel
+
+	return channel, nil
+}
+
+// OnUnicastChannelOpened is not supported; channels are opened by the daemon.
+func (rp *relayProvider) OnUnicastChannelOpened(
+	handler func(channel net.UnicastChannel),
+) {
+}
+
+func (rp *relayProvider) BroadcastChannelFor(
+	name string,
+) (net.BroadcastChannel, error) {
+	rp.channelsMutex.Lock()
+	defer rp.channelsMutex.Unlock()
+
+	if channel, ok := rp.broadcastChannels[name]; ok {
+		return channel, nil
+	}
+
+	if _, err := rp.conn.request(
+		rp.conn.ctx,
+		kindBroadcastOpen,
+		&pb.ChannelRequest{Channel: name},
+	); err != nil {
+		return nil, err
+	}
+
+	channel := &relayBroadcastChannel{
+		relayHandlers: newRelayHandlers(),
+		conn:          rp.conn,
+		name:          name,
+	}
+	rp.broadcastChannels[name] = channel
+
+	return channel, nil
+}
+
+// ConnectionManager is not available in the daemon.
+func (rp *relayProvider) ConnectionManager() net.ConnectionManager {
+	return nil
+}
+
+func (rp *relayProvider) CreateTransportIdentifier(
+	publicKey operator.PublicKey,
+) (net.TransportIdentifier, error) {
+	transportID, err := rp.conn.request(
+		rp.conn.ctx,
+		kindTransport,
+		&pb.ChannelRequest{Payload: operator.Marshal(&publicKey)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return relayTransportID(transportID), nil
+}
+
+// BroadcastChannelForwarderFor is not supported; the node decides which
+// channels it forwards.
+func (rp *relayProvider) BroadcastChannelForwarderFor(name string) {
+}
+
+// deliver passes the message received by the node to the channel on which
+// it has been received.
+func (rp *relayProvider) deliver(message *pb.NetworkMessage) error {
+	rp.channelsMutex.Lock()
+	var handlers *relayHandlers
+	if message.Channel != "" {
+		if channel, ok := rp.broadcastChannels[message.Channel]; ok {
+			handlers = channel.relayHandlers
+		}
+	} else {
+		if channel, ok := rp.unicastChannels[message.Peer]; ok {
+			handlers = channel.relayHandlers
+		}
+	}
+	rp.channelsMutex.Unlock()
+
+	if handlers == nil {
+		return fmt.Errorf("message received on unknown channel")
+	}
+
+	return handlers.deliver(message)
+}
+
+// acceptsAuthor evaluates the filter set on the broadcast channel for
+// the given author.
+func (rp *relayProvider) acceptsAuthor(
+	channelName string,
+	authorPublicKey []byte,
+) (bool, error) {
+	rp.channelsMutex.Lock()
+	channel, ok := rp.broadcastChannels[channelName]
+	rp.channelsMutex.Unlock()
+
+	if !ok {
+		return false, fmt.Errorf("unknown broadcast channel [%s]", channelName)
+	}
+
+	author, err := operator.Unmarshal(authorPublicKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal author public key: [%v]", err)
+	}
+
+	return channel.acceptsAuthor(author), nil
+}
+
+// relayHandlers holds unmarshalers and receive handlers of a relayed channel.
+type relayHandlers struct {
+	mutex        sync.Mutex
+	unmarshalers map[string]func() net.TaggedUnmarshaler
+	handlers     map[int]func(m net.Message)
+	nextHandler  int
+}
+
+func newRelayHandlers() *relayHandlers {
+	return &relayHandlers{
+		unmarshalers: make(map[string]func() net.TaggedUnmarshaler),
+		handlers:     make(map[int]func(m net.Message)),
+	}
+}
+
+func (rh *relayHandlers) addUnmarshaler(
+	unmarshaler func() net.TaggedUnmarshaler,
+) string {
+	messageType := unmarshaler().Type()
+
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+
+	rh.unmarshalers[messageType] = unmarshaler
+
+	return messageType
+}
+
+func (rh *relayHandlers) recv(ctx context.Context, handler func(m net.Message)) {
+	rh.mutex.Lock()
+	defer rh.mutex.Unlock()
+
+	id := rh.nextHandler
+	rh.nextHandler++
+	rh.handlers[id] = handler
+
+	go func() {
+		<-ctx.Done()
+
+		rh.mutex.Lock()
+		delete(rh.handlers, id)
+		rh.mutex.Unlock()
+	}()
+}
+
+func (rh *relayHandlers) deliver(message *pb.NetworkMessage) error {
+	rh.mutex.Lock()
+	unmarshaler, ok := rh.unmarshalers[message.MessageType]
+	handlers := make([]func(m net.Message), 0, len(rh.handlers))
+	for _, handler := range rh.handlers {
+		handlers = append(handlers, handler)
+	}
+	rh.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no unmarshaler for type [%s]", message.MessageType)
+	}
+
+	payload := unmarshaler()
+	if err := payload.Unmarshal(message.Payload); err != nil {
+		return fmt.Errorf("failed to unmarshal message: [%v]", err)
+	}
+
+	relayedMessage := &relayMessage{
+		transportSenderID: relayTransportID(message.TransportSenderID),
+		senderPublicKey:   message.SenderPublicKey,
+		payload:           payload,
+		messageType:       message.MessageType,
+		seqno:             message.Seqno,
+	}
+
+	for _, handler := range handlers {
+		handler(relayedMessage)
+	}
+
+	return nil
+}
+
+type relayBroadcastChannel struct {
+	*relayHandlers
+
+	conn *conn
+	name string
+
+	filterMutex sync.Mutex
+	filter      net.BroadcastChannelFilter
+}
+
+func (rbc *relayBroadcastChannel) Name() string {
+	return rbc.name
+}
+
+func (rbc *relayBroadcastChannel) Send(
+	ctx context.Context,
+	m net.TaggedMarshaler,
+) error {
+	payload, err := m.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: [%v]", err)
+	}
+
+	_, err = rbc.conn.request(ctx, kindBroadcastSend, &pb.ChannelRequest{
+		Channel:     rbc.name,
+		MessageType: m.Type(),
+		Payload:     payload,
+	})
+	return err
+}
+
+func (rbc *relayBroadcastChannel) Recv(
+	ctx context.Context,
+	handler func(m net.Message),
+) {
+	rbc.recv(ctx, handler)
+}
+
+func (rbc *relayBroadcastChannel) RegisterUnmarshaler(
+	unmarshaler func() net.TaggedUnmarshaler,
+) error {
+	messageType := rbc.addUnmarshaler(unmarshaler)
+
+	_, err := rbc.conn.request(rbc.conn.ctx, kindBroadcastRegister, &pb.ChannelRequest{
+		Channel:     rbc.name,
+		MessageType: messageType,
+	})
+	return err
+}
+
+// SetFilter sets the filter evaluated by the daemon when the node asks whether
+// a message of the given author should be accepted. The node evaluates
+// the filter before passing messages to the daemon so that network provider
+// extensions of the node, such as hosted operators, are respected.
+func (rbc *relayBroadcastChannel) SetFilter(
+	filter net.BroadcastChannelFilter,
+) error {
+	rbc.filterMutex.Lock()
+	rbc.filter = filter
+	rbc.filterMutex.Unlock()
+
+	_, err := rbc.conn.request(rbc.conn.ctx, kindBroadcastFilter, &pb.ChannelRequest{
+		Channel: rbc.name,
+	})
+	return err
+}
+
+func (rbc *relayBroadcastChannel) acceptsAuthor(author *operator.PublicKey) bool {
+	rbc.filterMutex.Lock()
+	filter := rbc.filter
+	rbc.filterMutex.Unlock()
+
+	if filter == nil {
+		return true
+	}
+
+	return filter(author)
+}
+
+type relayUnicastChannel struct {
+	*relayHandlers
+
+	conn *conn
+	peer string
+}
+
+func (ruc *relayUnicastChannel) Send(m net.TaggedMarshaler) error {
+	payload, err := m.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: [%v]", err)
+	}
+
+	_, err = ruc.conn.request(ruc.conn.ctx, kindUnicastSend, &pb.ChannelRequest{
+		Peer:        ruc.peer,
+		MessageType: m.Type(),
+		Payload:     payload,
+	})
+	return err
+}
+
+func (ruc *relayUnicastChannel) Recv(
+	ctx context.Context,
+	handler func(m net.Message),
+) {
+	ruc.recv(ctx, handler)
+}
+
+func (ruc *relayUnicastChannel) SetUnmarshaler(
+	unmarshaler func() net.TaggedUnmarshaler,
+) {
+	messageType := ruc.addUnmarshaler(unmarshaler)
+
+	if _, err := ruc.conn.request(ruc.conn.ctx, kindUnicastRegister, &pb.ChannelRequest{
+		Peer:        ruc.peer,
+		MessageType: messageType,
+	}); err != nil {
+		logger.Warningf(
+			"failed to register unmarshaler for peer [%s]: [%v]",
+			ruc.peer,
+			err,
+		)
+	}
+}
+
+// relayMessage is a message received by the node and passed to the daemon.
+type relayMessage struct {
+	transportSenderID net.TransportIdentifier
+	senderPublicKey   []byte
+	payload           interface{}
+	messageType       string
+	seqno             uint64
+}
+
+func (rm *relayMessage) TransportSenderID() net.TransportIdentifier {
+	return rm.transportSenderID
+}
+
+func (rm *relayMessage) SenderPublicKey() []byte {
+	return rm.senderPublicKey
+}
+
+func (rm *relayMessage) Payload() interface{} {
+	return rm.payload
+}
+
+func (rm *relayMessage) Type() string {
+	return rm.messageType
+}
+
+func (rm *relayMessage) Seqno() uint64 {
+	return rm.seqno
+}
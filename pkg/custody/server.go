@@ -0,0 +1,292 @@
+package custody
+
+import (
+	"context"
+	"fmt"
+	stdnet "net"
+	"os"
+	"time"
+
+	"github.com/binance-chain/tss-lib/ecdsa/keygen"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/keep-network/keep-ecdsa/pkg/custody/gen/pb"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/params"
+	"github.com/keep-network/keep-ecdsa/pkg/node"
+	"github.com/keep-network/keep-ecdsa/pkg/registry"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+// authenticationTimeout is the maximum time of the authentication of a newly
+// established connection.
+const authenticationTimeout = 10 * time.Second
+
+// Kinds of requests sent by the node to the daemon.
+const (
+	kindGenerate = "generate"
+	kindSign     = "sign"
+)
+
+// preParamsSource provides TSS pre-parameters for key generation.
+type preParamsSource interface {
+	Get() *keygen.LocalPreParams
+}
+
+// Server is the custody daemon. It holds key shares of the operator and
+// executes key generation and signing protocols requested by the node.
+// The node passes protocol messages between the daemon and other keep members.
+type Server struct {
+	keepsRegistry  *registry.Keeps
+	preParamsPool  preParamsSource
+	retryPolicy    *retry.Policy
+	messageLimiter *tss.MessageLimiter
+	token          []byte
+}
+
+// NewServer creates a custody daemon keeping signers in the provided registry
+// and generating keys with pre-parameters from the provided pool. Only nodes
+// knowing the token are served. Opening of network channels by the node is
+// retried according to the provided retry policy and messages received from
+// peer members are limited with the provided message limiter.
+func NewServer(
+	keepsRegistry *registry.Keeps,
+	preParamsPool *node.PreParamsPool,
+	retryPolicy *retry.Policy,
+	messageLimiter *tss.MessageLimiter,
+	token []byte,
+) *Server {
+	return &Server{
+		keepsRegistry:  keepsRegistry,
+		preParamsPool:  preParamsPool,
+		retryPolicy:    retryPolicy,
+		messageLimiter: messageLimiter,
+		token:          token,
+	}
+}
+
+// Listen creates a Unix socket listener at the given path. A stale socket
+// left by a previous daemon is removed. The socket is accessible only by
+// the user running the daemon and its group, to which the node belongs.
+func Listen(socketPath string) (stdnet.Listener, error) {
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: [%v]", err)
+	}
+
+	listener, err := stdnet.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on socket: [%v]", err)
+	}
+
+	if err := os.Chmod(socketPath, 0660); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: [%v]", err)
+	}
+
+	return listener, nil
+}
+
+// Serve accepts connections from nodes until the context is done.
+func (s *Server) Serve(ctx context.Context, listener stdnet.Listener) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		netConn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				return fmt.Errorf("failed to accept connection: [%v]", err)
+			}
+		}
+
+		go s.serveConn(ctx, netConn)
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, netConn stdnet.Conn) {
+	conn := newConn(ctx, netConn)
+
+	netConn.SetDeadline(time.Now().Add(authenticationTimeout))
+	if err := conn.authenticateDaemon(s.token); err != nil {
+		logger.Warningf("rejecting custody connection: [%v]", err)
+		conn.close()
+		return
+	}
+	netConn.SetDeadline(time.Time{})
+
+	logger.Info("node connected to the custody daemon")
+
+	relay := newRelayProvider(conn)
+
+	conn.serve(func(ctx context.Context, frame *pb.Frame) ([]byte, error) {
+		switch frame.Kind {
+		case kindGenerate:
+			return s.generate(ctx, relay, frame.Payload)
+		case kindSign:
+			return s.sign(ctx, relay, frame.Payload)
+		case kindMessage:
+			message := &pb.NetworkMessage{}
+			if err := message.Unmarshal(frame.Payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal message: [%v]", err)
+			}
+			return nil, relay.deliver(message)
+		case kindFilterQuery:
+			request := &pb.ChannelRequest{}
+			if err := request.Unmarshal(frame.Payload); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal request: [%v]", err)
+			}
+
+			accepted, err := relay.acceptsAuthor(request.Channel, request.Payload)
+			if err != nil {
+				return nil, err
+			}
+			if accepted {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		default:
+			return nil, fmt.Errorf("unknown request [%s]", frame.Kind)
+		}
+	})
+
+	logger.Info("node disconnected from the custody daemon")
+}
+
+func (s *Server) generate(
+	ctx context.Context,
+	relay *relayProvider,
+	payload []byte,
+) ([]byte, error) {
+	request := &pb.GenerateRequest{}
+	if err := request.Unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: [%v]", err)
+	}
+
+	if !common.IsHexAddress(request.GroupID) {
+		return nil, fmt.Errorf("group ID [%s] is not a keep address", request.GroupID)
+	}
+	keepAddress := common.HexToAddress(request.GroupID)
+
+	memberIDs := toMemberIDs(request.MemberIDs)
+
+	paramsBoxes := make([]*params.Box, len(memberIDs))
+	for i := range paramsBoxes {
+		paramsBoxes[i] = params.NewBox(s.preParamsPool.Get())
+	}
+
+	signers, err := tss.GenerateThresholdSigners(
+		ctx,
+		request.GroupID,
+		memberIDs,
+		toMemberIDs(request.GroupMemberIDs),
+		uint(request.DishonestThreshold),
+		relay,
+		s.retryPolicy,
+		s.messageLimiter,
+		paramsBoxes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.GenerateResponse{}
+	for _, signer := range signers {
+		if err := s.keepsRegistry.RegisterSigner(keepAddress, signer); err != nil {
+			return nil, fmt.Errorf(
+				"failed to register signer for keep [%s]: [%v]",
+				keepAddress.String(),
+				err,
+			)
+		}
+
+		signerBytes, err := signer.WithoutShare().Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal signer: [%v]", err)
+		}
+		response.Signers = append(response.Signers, signerBytes)
+	}
+
+	logger.Infof(
+		"generated [%d] signers for keep [%s]",
+		len(signers),
+		keepAddress.String(),
+	)
+
+	return response.Marshal()
+}
+
+func (s *Server) sign(
+	ctx context.Context,
+	relay *relayProvider,
+	payload []byte,
+) ([]byte, error) {
+	request := &pb.SignRequest{}
+	if err := request.Unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal request: [%v]", err)
+	}
+
+	keepAddress := common.HexToAddress(request.GroupID)
+
+	keepSigners, err := s.keepsRegistry.GetSigners(keepAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []*tss.ThresholdSigner
+	for _, memberID := range toMemberIDs(request.MemberIDs) {
+		signer := findSigner(keepSigners, memberID)
+		if signer == nil {
+			return nil, fmt.Errorf(
+				"no signer of member [%s] for keep [%s]",
+				memberID,
+				keepAddress.String(),
+			)
+		}
+		signers = append(signers, signer)
+	}
+
+	signature, err := tss.CalculateSignature(
+		ctx,
+		request.Digest,
+		signers,
+		relay,
+		s.retryPolicy,
+		s.messageLimiter,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return (&pb.SignResponse{
+		R:          signature.R.Bytes(),
+		S:          signature.S.Bytes(),
+		RecoveryID: int32(signature.RecoveryID),
+	}).Marshal()
+}
+
+func findSigner(
+	signers []*tss.ThresholdSigner,
+	memberID tss.MemberID,
+) *tss.ThresholdSigner {
+	for _, signer := range signers {
+		if signer.MemberID().Equal(memberID) {
+			return signer
+		}
+	}
+
+	return nil
+}
+
+func toMemberIDs(bytes [][]byte) []tss.MemberID {
+	memberIDs := make([]tss.MemberID, len(bytes))
+	for i, memberID := range bytes {
+		memberIDs[i] = memberID
+	}
+
+	return memberIDs
+}
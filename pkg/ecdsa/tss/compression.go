@@ -0,0 +1,71 @@
+package tss
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// compressPayload compresses the payload of a protocol message. Payloads
+// consist mostly of ciphertexts and proofs which do not compress well, so
+// the compressed payload is returned only if it is smaller than the original
+// one. The second return value is false if the payload should be sent as it is.
+func compressPayload(payload []byte) ([]byte, bool) {
+	var buffer bytes.Buffer
+
+	writer, err := flate.NewWriter(&buffer, flate.BestSpeed)
+	if err != nil {
+		logger.Warningf("could not initialize payload compression: [%v]", err)
+		return payload, false
+	}
+
+	if _, err := writer.Write(payload); err != nil {
+		logger.Warningf("could not compress payload: [%v]", err)
+		return payload, false
+	}
+
+	if err := writer.Close(); err != nil {
+		logger.Warningf("could not compress payload: [%v]", err)
+		return payload, false
+	}
+
+	if buffer.Len() >= len(payload) {
+		return payload, false
+	}
+
+	return buffer.Bytes(), true
+}
+
+// decompressPayload decompresses the payload of a protocol message. An error
+// is returned if the decompressed payload would exceed the maximum size, so
+// that a small compressed message can not exhaust memory of the receiver.
+func decompressPayload(payload []byte, maxSize int) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(payload))
+	defer reader.Close()
+
+	decompressed, err := ioutil.ReadAll(
+		io.LimitReader(reader, int64(maxSize)+1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: [%v]", err)
+	}
+
+	if len(decompressed) > maxSize {
+		return nil, oversizedPayloadError{maxSize}
+	}
+
+	return decompressed, nil
+}
+
+type oversizedPayloadError struct {
+	maxSize int
+}
+
+func (e oversizedPayloadError) Error() string {
+	return fmt.Sprintf(
+		"decompressed payload exceeds maximum size of [%d] bytes",
+		e.maxSize,
+	)
+}
@@ -0,0 +1,66 @@
+package tss
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestCompressPayload(t *testing.T) {
+	payload := bytes.Repeat([]byte("very important message"), 100)
+
+	compressed, ok := compressPayload(payload)
+	if !ok {
+		t.Fatal("compressible payload has not been compressed")
+	}
+	if len(compressed) >= len(payload) {
+		t.Errorf(
+			"compressed payload is not smaller\noriginal:   [%d]\ncompressed: [%d]",
+			len(payload),
+			len(compressed),
+		)
+	}
+
+	decompressed, err := decompressPayload(compressed, len(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload, decompressed) {
+		t.Errorf("unexpected decompressed payload")
+	}
+}
+
+func TestCompressIncompressiblePayload(t *testing.T) {
+	payload := make([]byte, 1024)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, ok := compressPayload(payload)
+	if ok {
+		t.Fatal("incompressible payload has been compressed")
+	}
+	if !bytes.Equal(payload, compressed) {
+		t.Errorf("unexpected payload")
+	}
+}
+
+func TestDecompressOversizedPayload(t *testing.T) {
+	payload := make([]byte, 10*1024)
+
+	compressed, ok := compressPayload(payload)
+	if !ok {
+		t.Fatal("compressible payload has not been compressed")
+	}
+
+	_, err := decompressPayload(compressed, len(payload)-1)
+	if _, ok := err.(oversizedPayloadError); !ok {
+		t.Fatalf("expected oversized payload error; has: [%v]", err)
+	}
+}
+
+func TestDecompressInvalidPayload(t *testing.T) {
+	if _, err := decompressPayload([]byte("not compressed"), 1024); err == nil {
+		t.Fatal("expected decompression error")
+	}
+}
@@ -8,6 +8,9 @@ import (
 type Config struct {
 	// Timeout for pre-parameters generation in tss-lib.
 	PreParamsGenerationTimeout duration
+	// Limits of messages received from peer members in a single protocol
+	// session.
+	MessageLimits MessageLimits
 }
 
 // We use BurntSushi/toml package to parse configuration file. Unfortunately it
@@ -6,6 +6,14 @@ import (
 	"time"
 )
 
+// Names of protocol stages which can time out waiting for peer members.
+const (
+	AnnounceStage      = "announce"
+	ReadinessStage     = "readiness"
+	KeyGenerationStage = "key generation"
+	SigningStage       = "signing"
+)
+
 type timeoutError struct {
 	timeout   time.Duration
 	stage     string
@@ -34,3 +42,26 @@ func (t timeoutError) Error() string {
 		t.stage,
 	)
 }
+
+// UnresponsiveMembers returns the stage of the protocol which timed out with
+// the given error along with members the stage was still waiting for.
+// False is returned if the error is not a protocol timeout error.
+func UnresponsiveMembers(err error) (string, []MemberID, bool) {
+	timeoutErr, ok := err.(timeoutError)
+	if !ok {
+		return "", nil, false
+	}
+
+	return timeoutErr.stage, timeoutErr.memberIDs, true
+}
+
+// wrapError annotates the error with the given description. Timeout errors
+// are returned as they are so that the caller can learn which members were
+// unresponsive.
+func wrapError(description string, err error) error {
+	if _, ok := err.(timeoutError); ok {
+		return err
+	}
+
+	return fmt.Errorf("%s: [%v]", description, err)
+}
@@ -0,0 +1,64 @@
+package tss
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestUnresponsiveMembers(t *testing.T) {
+	memberIDs := []MemberID{MemberID("member-1"), MemberID("member-2")}
+	timeoutErr := timeoutError{protocolReadyTimeout, ReadinessStage, memberIDs}
+
+	var tests = map[string]struct {
+		err               error
+		expectedStage     string
+		expectedMemberIDs []MemberID
+		expectedTimeout   bool
+	}{
+		"timeout error": {
+			err:               timeoutErr,
+			expectedStage:     ReadinessStage,
+			expectedMemberIDs: memberIDs,
+			expectedTimeout:   true,
+		},
+		"wrapped timeout error": {
+			err:               wrapError("readiness signaling protocol failed", timeoutErr),
+			expectedStage:     ReadinessStage,
+			expectedMemberIDs: memberIDs,
+			expectedTimeout:   true,
+		},
+		"other error": {
+			err:             wrapError("failed to sign", fmt.Errorf("failure")),
+			expectedTimeout: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			stage, memberIDs, ok := UnresponsiveMembers(test.err)
+
+			if ok != test.expectedTimeout {
+				t.Fatalf(
+					"unexpected timeout result\nexpected: [%v]\nactual:   [%v]",
+					test.expectedTimeout,
+					ok,
+				)
+			}
+			if stage != test.expectedStage {
+				t.Errorf(
+					"unexpected stage\nexpected: [%s]\nactual:   [%s]",
+					test.expectedStage,
+					stage,
+				)
+			}
+			if !reflect.DeepEqual(memberIDs, test.expectedMemberIDs) {
+				t.Errorf(
+					"unexpected members\nexpected: [%v]\nactual:   [%v]",
+					test.expectedMemberIDs,
+					memberIDs,
+				)
+			}
+		})
+	}
+}
@@ -112,7 +112,7 @@ func (s *member) generateKey(ctx context.Context) (*ThresholdSigner, error) {
 				}
 			}
 
-			return nil, timeoutError{KeyGenerationProtocolTimeout, "key generation", memberIDs}
+			return nil, timeoutError{KeyGenerationProtocolTimeout, KeyGenerationStage, memberIDs}
 		}
 	}
 }
@@ -0,0 +1,270 @@
+package tss
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/operator"
+)
+
+const (
+	// DefaultMaxMessageSize is the default maximum size in bytes of a single
+	// protocol message payload.
+	DefaultMaxMessageSize = 1024 * 1024
+	// DefaultSenderMessageRate is the default number of messages per second
+	// accepted from a single sender in a session.
+	DefaultSenderMessageRate = 100
+	// DefaultSenderMessageBurst is the default number of messages accepted
+	// from a single sender at once, above the sender message rate.
+	DefaultSenderMessageBurst = 1000
+	// DefaultMaxBufferedBytes is the default maximum total size in bytes of
+	// protocol messages waiting for processing in a session.
+	DefaultMaxBufferedBytes = 64 * 1024 * 1024
+)
+
+// Kinds of message limits violations.
+const (
+	MessageSizeViolation   = "message size"
+	MessageRateViolation   = "message rate"
+	BufferedBytesViolation = "buffered bytes"
+)
+
+// MessageLimits contains limits of messages received from peer members in
+// a single protocol session. Zero value means that the default should be used.
+type MessageLimits struct {
+	// MaxMessageSize is the maximum size in bytes of a single protocol
+	// message payload.
+	MaxMessageSize int
+	// SenderMessageRate is the number of messages per second accepted from
+	// a single sender.
+	SenderMessageRate float64
+	// SenderMessageBurst is the number of messages accepted from a single
+	// sender at once, above the sender message rate.
+	SenderMessageBurst int
+	// MaxBufferedBytes is the maximum total size in bytes of protocol messages
+	// waiting for processing.
+	MaxBufferedBytes int
+}
+
+// MisbehaviorHandler is notified about a member violating message limits.
+// The member is identified by the public key of the network message sender.
+type MisbehaviorHandler func(senderID MemberID, violation string)
+
+// MessageLimiter enforces message limits in protocol sessions. Messages
+// violating the limits are dropped and the sender is reported to
+// the misbehavior handler once per session and kind of violation.
+type MessageLimiter struct {
+	limits             MessageLimits
+	misbehaviorHandler MisbehaviorHandler
+}
+
+// NewMessageLimiter creates a limiter enforcing the provided limits.
+// If limits are nil, default limits are used. The misbehavior handler is
+// optional.
+func NewMessageLimiter(
+	limits *MessageLimits,
+	misbehaviorHandler MisbehaviorHandler,
+) *MessageLimiter {
+	limiter := &MessageLimiter{
+		limits: MessageLimits{
+			MaxMessageSize:     DefaultMaxMessageSize,
+			SenderMessageRate:  DefaultSenderMessageRate,
+			SenderMessageBurst: DefaultSenderMessageBurst,
+			MaxBufferedBytes:   DefaultMaxBufferedBytes,
+		},
+		misbehaviorHandler: misbehaviorHandler,
+	}
+
+	if limits != nil {
+		if limits.MaxMessageSize > 0 {
+			limiter.limits.MaxMessageSize = limits.MaxMessageSize
+		}
+		if limits.SenderMessageRate > 0 {
+			limiter.limits.SenderMessageRate = limits.SenderMessageRate
+		}
+		if limits.SenderMessageBurst > 0 {
+			limiter.limits.SenderMessageBurst = limits.SenderMessageBurst
+		}
+		if limits.MaxBufferedBytes > 0 {
+			limiter.limits.MaxBufferedBytes = limits.MaxBufferedBytes
+		}
+	}
+
+	return limiter
+}
+
+// session creates a limiter state for a single protocol session of the group
+// with the given number of members. Default limits are used if the limiter
+// is nil.
+func (ml *MessageLimiter) session(membersCount int) *sessionLimiter {
+	if ml == nil {
+		ml = NewMessageLimiter(nil, nil)
+	}
+
+	return &sessionLimiter{
+		limits:             ml.limits,
+		misbehaviorHandler: ml.misbehaviorHandler,
+		membersCount:       membersCount,
+		now:                time.Now,
+		senders:            make(map[string]*senderState),
+	}
+}
+
+type senderState struct {
+	tokens        float64
+	updatedAt     time.Time
+	bufferedBytes int
+	reported      map[string]bool
+}
+
+// sessionLimiter enforces message limits in a single protocol session.
+type sessionLimiter struct {
+	limits             MessageLimits
+	misbehaviorHandler MisbehaviorHandler
+	membersCount       int
+	now                func() time.Time
+
+	mutex         sync.Mutex
+	senders       map[string]*senderState
+	bufferedBytes int
+}
+
+// admit checks the size and the rate of messages of the sender. False is
+// returned if the message should be dropped.
+func (sl *sessionLimiter) admit(senderID MemberID, size int) bool {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	sender := sl.sender(senderID)
+
+	if size > sl.limits.MaxMessageSize {
+		sl.violation(senderID, sender, MessageSizeViolation)
+		return false
+	}
+
+	// Tokens of the sender are refilled with the message rate up to
+	// the burst.
+	now := sl.now()
+	sender.tokens += now.Sub(sender.updatedAt).Seconds() * sl.limits.SenderMessageRate
+	if burst := float64(sl.limits.SenderMessageBurst); sender.tokens > burst {
+		sender.tokens = burst
+	}
+	sender.updatedAt = now
+
+	if sender.tokens < 1 {
+		sl.violation(senderID, sender, MessageRateViolation)
+		return false
+	}
+	sender.tokens--
+
+	return true
+}
+
+// admitMessage checks the size and the rate of messages of the network sender
+// of the message. False is returned if the message should be dropped.
+func (sl *sessionLimiter) admitMessage(message net.Message, size int) bool {
+	senderID, err := messageSender(message)
+	if err != nil {
+		logger.Warningf("dropping message: [%v]", err)
+		return false
+	}
+
+	return sl.admit(senderID, size)
+}
+
+// reserve reserves space for a message of the sender waiting for processing.
+// If the total size of buffered messages would exceed the limit, false is
+// returned and the message should be dropped. The sender is reported only if
+// its messages take more than a fair share of the limit, so that members
+// are not blamed for messages of a flooding member.
+func (sl *sessionLimiter) reserve(senderID MemberID, size int) bool {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	sender := sl.sender(senderID)
+
+	if sl.bufferedBytes+size > sl.limits.MaxBufferedBytes {
+		fairShare := sl.limits.MaxBufferedBytes / sl.membersCount
+		if sender.bufferedBytes+size > fairShare {
+			sl.violation(senderID, sender, BufferedBytesViolation)
+		}
+		return false
+	}
+
+	sl.bufferedBytes += size
+	sender.bufferedBytes += size
+
+	return true
+}
+
+// maxMessageSize returns the maximum size in bytes of a single protocol
+// message payload.
+func (sl *sessionLimiter) maxMessageSize() int {
+	return sl.limits.MaxMessageSize
+}
+
+// oversized reports the sender of a message which exceeded the maximum size
+// once decompressed.
+func (sl *sessionLimiter) oversized(senderID MemberID) {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	sl.violation(senderID, sl.sender(senderID), MessageSizeViolation)
+}
+
+// release frees space reserved for a processed message of the sender.
+func (sl *sessionLimiter) release(senderID MemberID, size int) {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	sl.bufferedBytes -= size
+	sl.sender(senderID).bufferedBytes -= size
+}
+
+func (sl *sessionLimiter) sender(senderID MemberID) *senderState {
+	sender, ok := sl.senders[senderID.String()]
+	if !ok {
+		sender = &senderState{
+			tokens:    float64(sl.limits.SenderMessageBurst),
+			updatedAt: sl.now(),
+			reported:  make(map[string]bool),
+		}
+		sl.senders[senderID.String()] = sender
+	}
+
+	return sender
+}
+
+func (sl *sessionLimiter) violation(
+	senderID MemberID,
+	sender *senderState,
+	violation string,
+) {
+	if sender.reported[violation] {
+		return
+	}
+	sender.reported[violation] = true
+
+	logger.Warningf(
+		"dropping messages from [%s]; [%s] limit exceeded",
+		senderID,
+		violation,
+	)
+
+	if sl.misbehaviorHandler != nil {
+		go sl.misbehaviorHandler(senderID, violation)
+	}
+}
+
+// messageSender returns the member ID of the network sender of the message.
+// Several members may share the sender if they are hosted by the same node.
+func messageSender(message net.Message) (MemberID, error) {
+	publicKey, err := operator.Unmarshal(message.SenderPublicKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sender public key: [%v]", err)
+	}
+
+	return MemberIDFromPublicKey(publicKey), nil
+}
@@ -0,0 +1,168 @@
+package tss
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type misbehavior struct {
+	senderID  string
+	violation string
+}
+
+func newTestSessionLimiter(
+	limits *MessageLimits,
+	membersCount int,
+) (*sessionLimiter, chan misbehavior) {
+	misbehaviors := make(chan misbehavior, 10)
+	limiter := NewMessageLimiter(
+		limits,
+		func(senderID MemberID, violation string) {
+			misbehaviors <- misbehavior{senderID.String(), violation}
+		},
+	).session(membersCount)
+
+	return limiter, misbehaviors
+}
+
+func TestSessionLimiterMessageSize(t *testing.T) {
+	memberIDs, err := generateMemberKeys(2)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	limiter, misbehaviors := newTestSessionLimiter(
+		&MessageLimits{MaxMessageSize: 100},
+		len(memberIDs),
+	)
+
+	if !limiter.admit(memberIDs[0], 100) {
+		t.Errorf("message within the size limit has been dropped")
+	}
+	if limiter.admit(memberIDs[0], 101) {
+		t.Errorf("message exceeding the size limit has been admitted")
+	}
+	if limiter.admit(memberIDs[0], 101) {
+		t.Errorf("message exceeding the size limit has been admitted")
+	}
+	if !limiter.admit(memberIDs[1], 100) {
+		t.Errorf("message of another sender has been dropped")
+	}
+
+	assertMisbehaviors(
+		t,
+		[]misbehavior{{memberIDs[0].String(), MessageSizeViolation}},
+		misbehaviors,
+	)
+}
+
+func TestSessionLimiterMessageRate(t *testing.T) {
+	memberIDs, err := generateMemberKeys(2)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	limiter, misbehaviors := newTestSessionLimiter(
+		&MessageLimits{SenderMessageRate: 2, SenderMessageBurst: 3},
+		len(memberIDs),
+	)
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !limiter.admit(memberIDs[0], 1) {
+			t.Fatalf("message [%d] within the burst has been dropped", i)
+		}
+	}
+	if limiter.admit(memberIDs[0], 1) {
+		t.Errorf("message exceeding the burst has been admitted")
+	}
+	if !limiter.admit(memberIDs[1], 1) {
+		t.Errorf("message of another sender has been dropped")
+	}
+
+	// One message is allowed every half a second.
+	now = now.Add(500 * time.Millisecond)
+	if !limiter.admit(memberIDs[0], 1) {
+		t.Errorf("message within the rate has been dropped")
+	}
+	if limiter.admit(memberIDs[0], 1) {
+		t.Errorf("message exceeding the rate has been admitted")
+	}
+
+	assertMisbehaviors(
+		t,
+		[]misbehavior{{memberIDs[0].String(), MessageRateViolation}},
+		misbehaviors,
+	)
+}
+
+func TestSessionLimiterBufferedBytes(t *testing.T) {
+	memberIDs, err := generateMemberKeys(2)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	limiter, misbehaviors := newTestSessionLimiter(
+		&MessageLimits{MaxBufferedBytes: 100},
+		len(memberIDs),
+	)
+
+	if !limiter.reserve(memberIDs[0], 60) {
+		t.Fatalf("message within the limit has been dropped")
+	}
+	if !limiter.reserve(memberIDs[1], 30) {
+		t.Fatalf("message within the limit has been dropped")
+	}
+
+	// The second sender does not exceed its share of the limit, so it is
+	// not blamed for the dropped message.
+	if limiter.reserve(memberIDs[1], 20) {
+		t.Errorf("message exceeding the limit has been admitted")
+	}
+	if limiter.reserve(memberIDs[0], 20) {
+		t.Errorf("message exceeding the limit has been admitted")
+	}
+
+	limiter.release(memberIDs[0], 60)
+	if !limiter.reserve(memberIDs[1], 20) {
+		t.Errorf("message within the limit has been dropped after release")
+	}
+
+	assertMisbehaviors(
+		t,
+		[]misbehavior{{memberIDs[0].String(), BufferedBytesViolation}},
+		misbehaviors,
+	)
+}
+
+func assertMisbehaviors(
+	t *testing.T,
+	expected []misbehavior,
+	misbehaviors chan misbehavior,
+) {
+	var actual []misbehavior
+	for range expected {
+		select {
+		case misbehavior := <-misbehaviors:
+			actual = append(actual, misbehavior)
+		case <-time.After(time.Second):
+		}
+	}
+
+	select {
+	case misbehavior := <-misbehaviors:
+		actual = append(actual, misbehavior)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf(
+			"unexpected misbehaviors\nexpected: [%v]\nactual:   [%v]",
+			expected,
+			actual,
+		)
+	}
+}
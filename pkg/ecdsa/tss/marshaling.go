@@ -13,10 +13,20 @@ import (
 
 // Marshal converts ThresholdSigner to byte array.
 func (s *ThresholdSigner) Marshal() ([]byte, error) {
+	pbSigner := &pb.ThresholdSigner{}
+
 	// Threshold key
-	keygenData, err := s.thresholdKey.Marshal()
-	if err != nil {
-		return nil, err
+	if s.HasShare() {
+		keygenData, err := s.thresholdKey.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		pbSigner.ThresholdKey = keygenData
+	} else {
+		pbSigner.PublicKey = &pb.LocalPartySaveData_ECPoint{
+			X: s.thresholdKey.ECDSAPub.X().Bytes(),
+			Y: s.thresholdKey.ECDSAPub.Y().Bytes(),
+		}
 	}
 
 	// Group Info
@@ -25,17 +35,14 @@ func (s *ThresholdSigner) Marshal() ([]byte, error) {
 		groupMemberIDs[i] = memberID
 	}
 
-	group := &pb.ThresholdSigner_GroupInfo{
+	pbSigner.GroupInfo = &pb.ThresholdSigner_GroupInfo{
 		GroupID:            s.groupID,
 		MemberID:           s.memberID,
 		GroupMemberIDs:     groupMemberIDs,
 		DishonestThreshold: int32(s.dishonestThreshold),
 	}
 
-	return (&pb.ThresholdSigner{
-		GroupInfo:    group,
-		ThresholdKey: keygenData,
-	}).Marshal()
+	return pbSigner.Marshal()
 }
 
 // Unmarshal converts a byte array back to ThresholdSigner.
@@ -49,7 +56,17 @@ func (s *ThresholdSigner) Unmarshal(bytes []byte) error {
 
 	// Threshold key
 	s.thresholdKey = ThresholdKey{}
-	if err := s.thresholdKey.Unmarshal(pbSigner.GetThresholdKey()); err != nil {
+	if pbPublicKey := pbSigner.GetPublicKey(); pbPublicKey != nil {
+		publicKey, err := crypto.NewECPoint(
+			tss.EC(),
+			new(big.Int).SetBytes(pbPublicKey.GetX()),
+			new(big.Int).SetBytes(pbPublicKey.GetY()),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to decode public key: [%v]", err)
+		}
+		s.thresholdKey.ECDSAPub = publicKey
+	} else if err := s.thresholdKey.Unmarshal(pbSigner.GetThresholdKey()); err != nil {
 		return fmt.Errorf("failed to unmarshal signer: [%v]", err)
 	}
 
@@ -213,10 +230,13 @@ func (tk *ThresholdKey) Unmarshal(bytes []byte) error {
 // Marshal converts this message to a byte array suitable for network communication.
 func (m *TSSProtocolMessage) Marshal() ([]byte, error) {
 	return (&pb.TSSProtocolMessage{
-		SenderID:    m.SenderID,
-		Payload:     m.Payload,
-		IsBroadcast: m.IsBroadcast,
-		SessionID:   m.SessionID,
+		SenderID:        m.SenderID,
+		Payload:         m.Payload,
+		IsBroadcast:     m.IsBroadcast,
+		SessionID:       m.SessionID,
+		ReceiverID:      m.ReceiverID,
+		ProtocolVersion: m.ProtocolVersion,
+		IsCompressed:    m.IsCompressed,
 	}).Marshal()
 }
 
@@ -231,6 +251,9 @@ func (m *TSSProtocolMessage) Unmarshal(bytes []byte) error {
 	m.Payload = pbMsg.Payload
 	m.IsBroadcast = pbMsg.IsBroadcast
 	m.SessionID = pbMsg.SessionID
+	m.ReceiverID = MemberID(pbMsg.ReceiverID)
+	m.ProtocolVersion = pbMsg.ProtocolVersion
+	m.IsCompressed = pbMsg.IsCompressed
 
 	return nil
 }
@@ -238,7 +261,9 @@ func (m *TSSProtocolMessage) Unmarshal(bytes []byte) error {
 // Marshal converts this message to a byte array suitable for network communication.
 func (m *ReadyMessage) Marshal() ([]byte, error) {
 	return (&pb.ReadyMessage{
-		SenderID: m.SenderID,
+		SenderID:         m.SenderID,
+		ProtocolVersions: m.ProtocolVersions,
+		Features:         m.Features,
 	}).Marshal()
 }
 
@@ -250,6 +275,8 @@ func (m *ReadyMessage) Unmarshal(bytes []byte) error {
 	}
 
 	m.SenderID = pbMsg.SenderID
+	m.ProtocolVersions = pbMsg.ProtocolVersions
+	m.Features = pbMsg.Features
 
 	return nil
 }
@@ -257,7 +284,9 @@ func (m *ReadyMessage) Unmarshal(bytes []byte) error {
 // Marshal converts this message to a byte array suitable for network communication.
 func (m *AnnounceMessage) Marshal() ([]byte, error) {
 	return (&pb.AnnounceMessage{
-		SenderID: m.SenderID,
+		SenderID:         m.SenderID,
+		ProtocolVersions: m.ProtocolVersions,
+		Features:         m.Features,
 	}).Marshal()
 }
 
@@ -268,6 +297,27 @@ func (m *AnnounceMessage) Unmarshal(bytes []byte) error {
 		return err
 	}
 
+	m.SenderID = pbMsg.SenderID
+	m.ProtocolVersions = pbMsg.ProtocolVersions
+	m.Features = pbMsg.Features
+
+	return nil
+}
+
+// Marshal converts this message to a byte array suitable for network communication.
+func (m *HeartbeatMessage) Marshal() ([]byte, error) {
+	return (&pb.HeartbeatMessage{
+		SenderID: m.SenderID,
+	}).Marshal()
+}
+
+// Unmarshal converts a byte array produced by Marshal to a message.
+func (m *HeartbeatMessage) Unmarshal(bytes []byte) error {
+	pbMsg := &pb.HeartbeatMessage{}
+	if err := pbMsg.Unmarshal(bytes); err != nil {
+		return err
+	}
+
 	m.SenderID = pbMsg.SenderID
 
 	return nil
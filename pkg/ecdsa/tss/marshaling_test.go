@@ -50,6 +50,40 @@ func TestSignerMarshalling(t *testing.T) {
 	}
 }
 
+func TestSignerWithoutShareMarshalling(t *testing.T) {
+	testData, err := testdata.LoadKeygenTestFixtures(1)
+	if err != nil {
+		t.Fatalf("failed to load test data: [%v]", err)
+	}
+
+	signer := (&ThresholdSigner{
+		groupInfo: &groupInfo{
+			groupID:            "test-group-id-1",
+			memberID:           MemberID("member-0"),
+			groupMemberIDs:     []MemberID{MemberID("member-0")},
+			dishonestThreshold: 0,
+		},
+		thresholdKey: ThresholdKey(testData[0]),
+	}).WithoutShare()
+
+	if signer.HasShare() {
+		t.Fatal("signer should not hold a key share")
+	}
+
+	unmarshaled := &ThresholdSigner{}
+
+	if err := pbutils.RoundTrip(signer, unmarshaled); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(signer, unmarshaled) {
+		t.Fatalf(
+			"unexpected content of unmarshaled signer\nexpected: [%+v]\nactual:   [%+v]\n",
+			signer,
+			unmarshaled,
+		)
+	}
+}
+
 func TestThresholdKeyMarshalling(t *testing.T) {
 	testData, err := testdata.LoadKeygenTestFixtures(1)
 	if err != nil {
@@ -74,10 +108,13 @@ func TestThresholdKeyMarshalling(t *testing.T) {
 
 func TestTSSProtocolMessageMarshalling(t *testing.T) {
 	msg := &TSSProtocolMessage{
-		SenderID:    MemberID([]byte("member-1")),
-		Payload:     []byte("very important message"),
-		IsBroadcast: true,
-		SessionID:   "session-1",
+		SenderID:        MemberID([]byte("member-1")),
+		ReceiverID:      MemberID([]byte("member-2")),
+		Payload:         []byte("very important message"),
+		IsBroadcast:     false,
+		SessionID:       "session-1",
+		ProtocolVersion: ProtocolVersion1,
+		IsCompressed:    true,
 	}
 
 	unmarshaled := &TSSProtocolMessage{}
@@ -111,7 +148,9 @@ func TestFuzzTSSProtocolMessageUnmarshaler(t *testing.T) {
 
 func TestReadyMessageMarshalling(t *testing.T) {
 	msg := &ReadyMessage{
-		SenderID: MemberID([]byte("member-1")),
+		SenderID:         MemberID([]byte("member-1")),
+		ProtocolVersions: []uint32{1, 2},
+		Features:         []string{"feature-1"},
 	}
 
 	unmarshaled := &ReadyMessage{}
@@ -145,7 +184,9 @@ func TestFuzzReadyMessageUnmarshaler(t *testing.T) {
 
 func TestAnnounceMessageMarshalling(t *testing.T) {
 	msg := &AnnounceMessage{
-		SenderID: MemberID([]byte("member-1")),
+		SenderID:         MemberID([]byte("member-1")),
+		ProtocolVersions: []uint32{1, 2},
+		Features:         []string{"feature-1"},
 	}
 
 	unmarshaled := &AnnounceMessage{}
@@ -176,3 +217,26 @@ func TestFuzzAnnounceMessageRoundtrip(t *testing.T) {
 func TestFuzzAnnounceMessageUnmarshaler(t *testing.T) {
 	pbutils.FuzzUnmarshaler(&AnnounceMessage{})
 }
+
+func TestHeartbeatMessageMarshalling(t *testing.T) {
+	msg := &HeartbeatMessage{
+		SenderID: MemberID([]byte("member-1")),
+	}
+
+	unmarshaled := &HeartbeatMessage{}
+
+	if err := pbutils.RoundTrip(msg, unmarshaled); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(msg, unmarshaled) {
+		t.Fatalf(
+			"unexpected content of unmarshaled message\nexpected: [%+v]\nactual:   [%+v]\n",
+			msg,
+			unmarshaled,
+		)
+	}
+}
+
+func TestFuzzHeartbeatMessageUnmarshaler(t *testing.T) {
+	pbutils.FuzzUnmarshaler(&HeartbeatMessage{})
+}
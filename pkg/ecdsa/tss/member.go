@@ -2,11 +2,16 @@ package tss
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"github.com/keep-network/keep-core/pkg/operator"
 	"math/big"
 )
 
+// publicKeyLength is the length of the marshalled operator public key every
+// member ID starts with.
+const publicKeyLength = 65
+
 // MemberID is an unique identifier of a member across the network.
 type MemberID []byte
 
@@ -15,9 +20,34 @@ func MemberIDFromPublicKey(publicKey *operator.PublicKey) MemberID {
 	return operator.Marshal(publicKey)
 }
 
-// PublicKey returns the MemberID as a public key.
+// MemberIDFromPublicKeyAndIndex creates a MemberID from a public key and the
+// index of the member in the group. An operator selected to the same group
+// several times has a distinct member ID for each of its seats.
+func MemberIDFromPublicKeyAndIndex(
+	publicKey *operator.PublicKey,
+	memberIndex uint16,
+) MemberID {
+	memberID := make([]byte, publicKeyLength+2)
+	copy(memberID, operator.Marshal(publicKey))
+	binary.BigEndian.PutUint16(memberID[publicKeyLength:], memberIndex)
+
+	return memberID
+}
+
+// PublicKey returns the public key of the operator the member belongs to.
 func (id MemberID) PublicKey() (*operator.PublicKey, error) {
-	return operator.Unmarshal(id)
+	return operator.Unmarshal(id.publicKeyBytes())
+}
+
+// publicKeyBytes returns the marshalled public key of the operator the member
+// belongs to. Member IDs created without the member index consist of the
+// public key only.
+func (id MemberID) publicKeyBytes() []byte {
+	if len(id) > publicKeyLength {
+		return id[:publicKeyLength]
+	}
+
+	return id
 }
 
 // MemberIDFromPublicKey creates a MemberID from a string.
@@ -33,3 +33,30 @@ func TestMemberID(t *testing.T) {
 		t.Errorf("member from string doesn't match the original member")
 	}
 }
+
+func TestMemberIDFromPublicKeyAndIndex(t *testing.T) {
+	_, publicKey, err := operator.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("could not generate public key: [%v]", err)
+	}
+
+	firstSeat := MemberIDFromPublicKeyAndIndex(publicKey, 0)
+	secondSeat := MemberIDFromPublicKeyAndIndex(publicKey, 1)
+
+	if firstSeat.Equal(secondSeat) {
+		t.Errorf("member IDs of different seats should not be equal")
+	}
+
+	for _, memberID := range []MemberID{firstSeat, secondSeat} {
+		extractedPublicKey, err := memberID.PublicKey()
+		if err != nil {
+			t.Fatalf("could not extract public key: [%v]", err)
+		}
+
+		if !MemberIDFromPublicKey(extractedPublicKey).Equal(
+			MemberIDFromPublicKey(publicKey),
+		) {
+			t.Errorf("public key extracted from member doesn't match the original")
+		}
+	}
+}
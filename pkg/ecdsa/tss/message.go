@@ -5,11 +5,21 @@ import "github.com/keep-network/keep-core/pkg/net"
 // TSSProtocolMessage is a network message used to transport messages generated in
 // TSS protocol execution. It is a wrapper over a message generated by underlying
 // implementation of the protocol.
+//
+// Receiver ID is set only for unicast messages. It lets the receiving host
+// deliver the message to the right member if the host executes the protocol
+// for several members of the group.
 type TSSProtocolMessage struct {
 	SenderID    MemberID
+	ReceiverID  MemberID
 	Payload     []byte
 	IsBroadcast bool
 	SessionID   string
+	// ProtocolVersion is the protocol version negotiated for the session.
+	// It is zero in messages of clients not supporting negotiation.
+	ProtocolVersion uint32
+	// IsCompressed is true if the payload is compressed with DEFLATE.
+	IsCompressed bool
 }
 
 // Type returns a string type of the `TSSMessage` so that it conforms to
@@ -21,7 +31,9 @@ func (m *TSSProtocolMessage) Type() string {
 // ReadyMessage is a network message used to notify peer members about readiness
 // to start protocol execution.
 type ReadyMessage struct {
-	SenderID MemberID
+	SenderID         MemberID
+	ProtocolVersions []uint32
+	Features         []string
 }
 
 // Type returns a string type of the `ReadyMessage`.
@@ -31,7 +43,9 @@ func (m *ReadyMessage) Type() string {
 
 // AnnounceMessage is a network message used to announce peer's presence.
 type AnnounceMessage struct {
-	SenderID MemberID
+	SenderID         MemberID
+	ProtocolVersions []uint32
+	Features         []string
 }
 
 // Type returns a string type of the `AnnounceMessage`.
@@ -39,14 +53,30 @@ func (m *AnnounceMessage) Type() string {
 	return "ecdsa/announce_message"
 }
 
+// HeartbeatMessage is a network message periodically broadcast by members of
+// an active keep to let peer members know they are still reachable.
+type HeartbeatMessage struct {
+	SenderID MemberID
+}
+
+// Type returns a string type of the `HeartbeatMessage`.
+func (m *HeartbeatMessage) Type() string {
+	return "ecdsa/heartbeat_message"
+}
+
+// Unmarshalers returns unmarshalers of all messages broadcast by members
+// executing protocols in this package.
+func Unmarshalers() []func() net.TaggedUnmarshaler {
+	return []func() net.TaggedUnmarshaler{
+		func() net.TaggedUnmarshaler { return &AnnounceMessage{} },
+		func() net.TaggedUnmarshaler { return &ReadyMessage{} },
+		func() net.TaggedUnmarshaler { return &TSSProtocolMessage{} },
+		func() net.TaggedUnmarshaler { return &HeartbeatMessage{} },
+	}
+}
+
 func RegisterUnmarshalers(broadcastChannel net.BroadcastChannel) {
-	broadcastChannel.RegisterUnmarshaler(func() net.TaggedUnmarshaler {
-		return &AnnounceMessage{}
-	})
-	broadcastChannel.RegisterUnmarshaler(func() net.TaggedUnmarshaler {
-		return &ReadyMessage{}
-	})
-	broadcastChannel.RegisterUnmarshaler(func() net.TaggedUnmarshaler {
-		return &TSSProtocolMessage{}
-	})
+	for _, unmarshaler := range Unmarshalers() {
+		broadcastChannel.RegisterUnmarshaler(unmarshaler)
+	}
 }
@@ -9,42 +9,87 @@ import (
 
 	"github.com/binance-chain/tss-lib/tss"
 	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
-const (
-	unicastChannelRetryCount    = 2
-	unicastChannelRetryWaitTime = 30 * time.Second
-)
+// earlyMessagesPerMember bounds the number of protocol messages buffered for
+// each group member until all parties executing the protocol in this process
+// are connected to the bridge.
+const earlyMessagesPerMember = 16
 
 // networkBridge translates TSS library network interface to unicast and
-// broadcast channels provided by our net abstraction.
+// broadcast channels provided by our net abstraction. A single bridge serves
+// all members of the group executing the protocol in this process.
 type networkBridge struct {
 	networkProvider net.Provider
+	retryPolicy     *retry.Policy
 
 	groupInfo *groupInfo
+	// localMemberIDs are IDs of group members executing the protocol in this
+	// process. Messages between them are not sent over the network.
+	localMemberIDs []MemberID
 
 	channelsMutex    *sync.Mutex
 	broadcastChannel net.BroadcastChannel
 	unicastChannels  map[net.TransportIdentifier]net.UnicastChannel
 
+	connectMutex *sync.Mutex
+	isConnected  bool
+
+	// pipeline processes incoming and outgoing protocol messages of
+	// the session.
+	pipeline *messagePipeline
+	// limiter enforces limits of messages received from peer members in
+	// the session.
+	limiter *sessionLimiter
+
+	// protocol is the protocol version and features negotiated by members
+	// of the group. It is nil until members signalled readiness.
+	protocolMutex *sync.Mutex
+	protocol      *sessionProtocol
+
+	// traffic counts bytes of protocol messages exchanged over the network
+	// in the session.
+	traffic *sessionTraffic
+
 	tssMessageHandlersMutex *sync.Mutex
 	tssMessageHandlers      []tssMessageHandler
+	// earlyMessages are protocol messages received since channels have been
+	// opened but before all parties of this process have been connected.
+	// They are replayed to parties connected later.
+	earlyMessages []*TSSProtocolMessage
 }
 
 type tssMessageHandler func(netMsg *TSSProtocolMessage) error
 
-// newNetworkBridge initializes a new network bridge for the given network provider.
+// newNetworkBridge initializes a new network bridge for the given network
+// provider and members executing the protocol in this process. Opening of
+// channels with other peers is retried according to the provided retry policy.
+// Messages received from peers are limited with the provided message limiter.
 func newNetworkBridge(
 	groupInfo *groupInfo,
+	localMemberIDs []MemberID,
 	networkProvider net.Provider,
+	retryPolicy *retry.Policy,
+	messageLimiter *MessageLimiter,
 ) (*networkBridge, error) {
 	networkBridge := &networkBridge{
 		networkProvider: networkProvider,
+		retryPolicy:     retryPolicy,
 		groupInfo:       groupInfo,
+		localMemberIDs:  localMemberIDs,
 
 		channelsMutex:   &sync.Mutex{},
 		unicastChannels: make(map[net.TransportIdentifier]net.UnicastChannel),
 
+		connectMutex: &sync.Mutex{},
+
+		pipeline: newSessionPipeline(groupInfo),
+		limiter:  messageLimiter.session(len(groupInfo.groupMemberIDs)),
+
+		protocolMutex: &sync.Mutex{},
+		traffic:       &sessionTraffic{},
+
 		tssMessageHandlersMutex: &sync.Mutex{},
 		tssMessageHandlers:      []tssMessageHandler{},
 	}
@@ -52,25 +97,79 @@ func newNetworkBridge(
 	return networkBridge, nil
 }
 
+// useProtocol makes the bridge send protocol messages of the negotiated
+// protocol version and drop messages of other versions.
+func (b *networkBridge) useProtocol(protocol *sessionProtocol) {
+	b.protocolMutex.Lock()
+	defer b.protocolMutex.Unlock()
+
+	b.protocol = protocol
+}
+
+// protocolVersion returns the negotiated protocol version or the first
+// protocol version if the protocol has not been negotiated yet.
+func (b *networkBridge) protocolVersion() uint32 {
+	b.protocolMutex.Lock()
+	defer b.protocolMutex.Unlock()
+
+	if b.protocol == nil {
+		return ProtocolVersion1
+	}
+
+	return b.protocol.version
+}
+
+// hasFeature returns true if the feature has been negotiated by members of
+// the group.
+func (b *networkBridge) hasFeature(feature string) bool {
+	b.protocolMutex.Lock()
+	defer b.protocolMutex.Unlock()
+
+	return b.protocol != nil && b.protocol.hasFeature(feature)
+}
+
+// isAcceptedProtocolVersion returns true if the message of the protocol version
+// can be handled in the session. Before the protocol is negotiated, messages
+// of all versions supported by this client are accepted.
+func (b *networkBridge) isAcceptedProtocolVersion(version uint32) bool {
+	if !isSupportedProtocolVersion(version) {
+		return false
+	}
+
+	b.protocolMutex.Lock()
+	defer b.protocolMutex.Unlock()
+
+	if b.protocol == nil {
+		return true
+	}
+
+	if version == 0 {
+		version = ProtocolVersion1
+	}
+
+	return version == b.protocol.version
+}
+
+// connect connects the party with peer members. Channels with peer members are
+// initialized when the first party is connected.
 func (b *networkBridge) connect(
 	ctx context.Context,
 	tssOutChan <-chan tss.Message,
 	party tss.Party,
 	sortedPartyIDs tss.SortedPartyIDs,
 ) error {
-	netInChan := make(chan *TSSProtocolMessage, len(b.groupInfo.groupMemberIDs))
-
-	if err := b.initializeChannels(ctx, netInChan); err != nil {
+	if err := b.initializeChannels(ctx); err != nil {
 		return fmt.Errorf("failed to initialize channels: [%v]", err)
 	}
 
+	// Outgoing messages of the party are sent one by one in the order in
+	// which they were produced. If sending is slow, the party is blocked on
+	// producing further messages.
 	go func() {
 		for {
 			select {
 			case tssLibMsg := <-tssOutChan:
-				go b.sendTSSMessage(ctx, tssLibMsg)
-			case msg := <-netInChan:
-				go b.handleTSSProtocolMessage(msg)
+				b.sendTSSMessage(ctx, tssLibMsg)
 			case <-ctx.Done():
 				return
 			}
@@ -82,16 +181,58 @@ func (b *networkBridge) connect(
 	return nil
 }
 
-func (b *networkBridge) initializeChannels(
+// receivedMessage is a protocol message received from the network along with
+// its network sender and size accounted in the session limits.
+type receivedMessage struct {
+	message *TSSProtocolMessage
+	sender  MemberID
+	size    int
+}
+
+// receiveMessageHandler returns a handler queueing protocol messages received
+// from the network for processing. Messages exceeding limits of the session
+// are dropped before they are queued. Once the context is done, messages are
+// no longer queued and space reserved for them is freed.
+func (b *networkBridge) receiveMessageHandler(
 	ctx context.Context,
-	netInChan chan *TSSProtocolMessage,
-) error {
-	handleFn := func(msg net.Message) {
+	netInChan chan<- *receivedMessage,
+) func(net.Message) {
+	return func(msg net.Message) {
 		switch protocolMessage := msg.Payload().(type) {
 		case *TSSProtocolMessage:
-			netInChan <- protocolMessage
+			sender, err := messageSender(msg)
+			if err != nil {
+				logger.Warningf("dropping protocol message: [%v]", err)
+				return
+			}
+
+			size := len(protocolMessage.Payload)
+			if !b.limiter.admit(sender, size) ||
+				!b.limiter.reserve(sender, size) {
+				return
+			}
+			b.traffic.received(size)
+
+			select {
+			case netInChan <- &receivedMessage{protocolMessage, sender, size}:
+			case <-ctx.Done():
+				b.limiter.release(sender, size)
+			}
 		}
 	}
+}
+
+func (b *networkBridge) initializeChannels(ctx context.Context) error {
+	b.connectMutex.Lock()
+	defer b.connectMutex.Unlock()
+
+	if b.isConnected {
+		return nil
+	}
+
+	netInChan := make(chan *receivedMessage, len(b.groupInfo.groupMemberIDs))
+
+	handleFn := b.receiveMessageHandler(ctx, netInChan)
 
 	// Initialize broadcast channel.
 	broadcastChannel, err := b.getBroadcastChannel()
@@ -101,9 +242,37 @@ func (b *networkBridge) initializeChannels(
 
 	broadcastChannel.Recv(ctx, handleFn)
 
-	// Initialize unicast channels.
+	// Messages are handled from the moment the broadcast channel is opened,
+	// so that messages of faster peers are buffered until parties of this
+	// process are connected. If the pipeline is full, receiving of further
+	// messages is blocked until queued messages are processed.
+	b.pipeline.start(ctx)
+	go func() {
+		for {
+			select {
+			case msg := <-netInChan:
+				b.pipeline.submit(ctx, incomingLane(msg.message), func() {
+					defer b.limiter.release(msg.sender, msg.size)
+
+					protocolMessage, ok := b.decompress(msg)
+					if !ok {
+						return
+					}
+					b.handleTSSProtocolMessage(protocolMessage)
+				})
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Initialize unicast channels. Several peer members may share the same
+	// host so each host's channel is initialized only once. Channels are
+	// initialized in parallel so that an unreachable peer does not delay
+	// initialization of channels with other peers.
+	peerTransportIDs := make(map[string]net.TransportIdentifier)
 	for _, peerMemberID := range b.groupInfo.groupMemberIDs {
-		if peerMemberID.Equal(b.groupInfo.memberID) {
+		if b.isLocalMember(peerMemberID) {
 			continue
 		}
 
@@ -112,54 +281,93 @@ func (b *networkBridge) initializeChannels(
 			return fmt.Errorf("failed to get transport identifier: [%v]", err)
 		}
 
-		unicastChannel, err := b.getUnicastChannel(
-			peerTransportID,
-			unicastChannelRetryCount,
-			unicastChannelRetryWaitTime,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to get unicast channel: [%v]", err)
-		}
+		peerTransportIDs[peerTransportID.String()] = peerTransportID
+	}
 
-		unicastChannel.Recv(ctx, handleFn)
+	unicastErrors := make(chan error, len(peerTransportIDs))
+	for _, peerTransportID := range peerTransportIDs {
+		go func(peerTransportID net.TransportIdentifier) {
+			unicastChannel, err := b.getUnicastChannel(ctx, peerTransportID)
+			if err != nil {
+				unicastErrors <- fmt.Errorf(
+					"failed to get unicast channel with [%s]: [%v]",
+					peerTransportID.String(),
+					err,
+				)
+				return
+			}
+
+			unicastChannel.Recv(ctx, handleFn)
+			unicastErrors <- nil
+		}(peerTransportID)
 	}
 
+	var unicastErr error
+	for range peerTransportIDs {
+		if err := <-unicastErrors; err != nil && unicastErr == nil {
+			unicastErr = err
+		}
+	}
+	if unicastErr != nil {
+		return unicastErr
+	}
+
+	b.isConnected = true
+
 	return nil
 }
 
+// isGroupMember checks if the sender of a protocol message is a member of
+// the group. Sender IDs of protocol messages are derived from party keys
+// so they are compared as numbers.
+func (b *networkBridge) isGroupMember(senderID MemberID) bool {
+	for _, memberID := range b.groupInfo.groupMemberIDs {
+		if memberID.bigInt().Cmp(senderID.bigInt()) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *networkBridge) isLocalMember(memberID MemberID) bool {
+	for _, localMemberID := range b.localMemberIDs {
+		if localMemberID.Equal(memberID) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (b *networkBridge) getUnicastChannel(
+	ctx context.Context,
 	peerTransportID net.TransportIdentifier,
-	retryCount int,
-	retryWaitTime time.Duration,
 ) (net.UnicastChannel, error) {
-	var (
-		unicastChannel net.UnicastChannel
-		err            error
-	)
+	var unicastChannel net.UnicastChannel
 
 	// getUnicastChannelWith is retried several times in order to recover
 	// from temporary network problems.
-	for i := 0; i < retryCount+1; i++ {
-		unicastChannel, err = b.getUnicastChannelWith(peerTransportID)
-		if unicastChannel != nil && err == nil {
-			return unicastChannel, nil
+	err := retry.Do(ctx, b.retryPolicy, func() error {
+		channel, err := b.getUnicastChannelWith(peerTransportID)
+		if err != nil {
+			logger.Warningf(
+				"failed to get unicast channel with peer [%v] "+
+					"because of: [%v]; will retry after wait time",
+				peerTransportID.String(),
+				err,
+			)
+			return err
 		}
 
-		logger.Warningf(
-			"failed to get unicast channel with peer [%v] "+
-				"because of: [%v]; will retry after wait time",
-			peerTransportID.String(),
-			err,
-		)
-
-		time.Sleep(retryWaitTime)
-	}
-
-	if err == nil {
-		err = fmt.Errorf("unknown error")
+		unicastChannel = channel
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	return unicastChannel, nil
 }
 
 func (b *networkBridge) getTransportIdentifier(member MemberID) (net.TransportIdentifier, error) {
@@ -202,7 +410,7 @@ func createMemberIDFilter(
 ) net.BroadcastChannelFilter {
 	authorizations := make(map[string]bool, len(members))
 	for _, member := range members {
-		authorizations[member.String()] = true
+		authorizations[MemberID(member.publicKeyBytes()).String()] = true
 	}
 
 	return func(authorPublicKey *cecdsa.PublicKey) bool {
@@ -256,14 +464,32 @@ func (b *networkBridge) sendTSSMessage(
 	}
 
 	protocolMessage := &TSSProtocolMessage{
-		SenderID:    routing.From.GetKey(),
-		Payload:     bytes,
-		IsBroadcast: routing.IsBroadcast,
-		SessionID:   b.groupInfo.groupID,
+		SenderID:        routing.From.GetKey(),
+		Payload:         bytes,
+		IsBroadcast:     routing.IsBroadcast,
+		SessionID:       b.groupInfo.groupID,
+		ProtocolVersion: b.protocolVersion(),
+	}
+
+	// Messages sent over the network are compressed if all members support
+	// compression. Messages for local members are delivered as they are.
+	networkMessage := protocolMessage
+	if b.hasFeature(CompressionFeature) {
+		if payload, ok := compressPayload(bytes); ok {
+			compressedMessage := *protocolMessage
+			compressedMessage.Payload = payload
+			compressedMessage.IsCompressed = true
+			networkMessage = &compressedMessage
+		}
 	}
 
 	if routing.To == nil {
-		b.broadcast(ctx, protocolMessage)
+		b.traffic.sent(len(networkMessage.Payload), len(bytes))
+		b.broadcast(
+			ctx,
+			networkMessage,
+			retransmissionWindow(tssLibMsg.Type()),
+		)
 	} else {
 		for _, destination := range routing.To {
 			destinationMemberID, err := MemberIDFromString(destination.GetId())
@@ -271,19 +497,43 @@ func (b *networkBridge) sendTSSMessage(
 				logger.Errorf("failed to get destination member id: [%v]", err)
 				return
 			}
+
+			// Messages for members executing the protocol in this process
+			// are delivered directly.
+			if b.isLocalMember(destinationMemberID) {
+				localMessage := *protocolMessage
+				localMessage.ReceiverID = destinationMemberID
+
+				b.pipeline.submitUnbounded(
+					incomingLane(&localMessage),
+					func() { b.handleTSSProtocolMessage(&localMessage) },
+				)
+				continue
+			}
+
+			unicastMessage := *networkMessage
+			unicastMessage.ReceiverID = destinationMemberID
+
 			destinationTransportID, err := b.getTransportIdentifier(destinationMemberID)
 			if err != nil {
 				logger.Errorf("failed to get transport identifier: [%v]", err)
 				return
 			}
-			b.sendTo(destinationTransportID, protocolMessage)
+			b.traffic.sent(len(unicastMessage.Payload), len(bytes))
+			b.sendTo(destinationTransportID, &unicastMessage)
 		}
 	}
 }
 
+// broadcast sends the message with the broadcast channel. The message is
+// retransmitted by the channel for the given retransmission window and then
+// with exponentially growing intervals for the lifetime of the context. If
+// the window is zero, the message is retransmitted by the channel for the
+// lifetime of the context.
 func (b *networkBridge) broadcast(
 	ctx context.Context,
 	msg *TSSProtocolMessage,
+	retransmissionWindow time.Duration,
 ) error {
 	broadcastChannel, err := b.getBroadcastChannel()
 	if err != nil {
@@ -291,13 +541,65 @@ func (b *networkBridge) broadcast(
 
 	}
 
-	if broadcastChannel.Send(ctx, msg); err != nil {
+	if retransmissionWindow == 0 {
+		if err := broadcastChannel.Send(ctx, msg); err != nil {
+			return fmt.Errorf("failed to send broadcast message: [%v]", err)
+		}
+
+		return nil
+	}
+
+	retransmissionCtx, cancel := context.WithTimeout(ctx, retransmissionWindow)
+	if err := broadcastChannel.Send(retransmissionCtx, msg); err != nil {
+		cancel()
 		return fmt.Errorf("failed to send broadcast message: [%v]", err)
 	}
 
+	go func() {
+		<-retransmissionCtx.Done()
+		cancel()
+
+		b.retransmitWithBackoff(
+			ctx,
+			broadcastChannel,
+			msg,
+			retransmissionWindow,
+		)
+	}()
+
 	return nil
 }
 
+// retransmitWithBackoff sends the message again after the given interval and
+// then after exponentially growing intervals until the context is done. Each
+// retransmission is a new message for the channel, so receivers which already
+// got the message store the same protocol message once again.
+func (b *networkBridge) retransmitWithBackoff(
+	ctx context.Context,
+	broadcastChannel net.BroadcastChannel,
+	msg *TSSProtocolMessage,
+	interval time.Duration,
+) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+
+		// The message is published once, without retransmissions performed
+		// by the channel.
+		sendCtx, cancelSend := context.WithCancel(ctx)
+		cancelSend()
+
+		if err := broadcastChannel.Send(sendCtx, msg); err != nil {
+			logger.Warningf("failed to retransmit broadcast message: [%v]", err)
+		}
+
+		interval = nextRetransmissionInterval(interval)
+	}
+}
+
 func (b *networkBridge) sendTo(
 	receiverTransportID net.TransportIdentifier,
 	message *TSSProtocolMessage,
@@ -340,6 +642,15 @@ func (b *networkBridge) registerProtocolMessageHandler(
 			return nil
 		}
 
+		// Unicast messages are delivered to the receiver only. Messages
+		// without receiver ID come from peers unaware of several members
+		// sharing the host.
+		if !protocolMessage.IsBroadcast &&
+			len(protocolMessage.ReceiverID) > 0 &&
+			protocolMessage.ReceiverID.String() != party.PartyID().GetId() {
+			return nil
+		}
+
 		_, err := party.UpdateFromBytes(
 			protocolMessage.Payload,
 			senderPartyID,
@@ -352,19 +663,115 @@ func (b *networkBridge) registerProtocolMessageHandler(
 		return nil
 	}
 
+	b.addTSSMessageHandler(handler)
+}
+
+// addTSSMessageHandler registers the handler and replays to it messages
+// received before it has been registered. Once handlers of all parties of this
+// process are registered, the messages are no longer buffered.
+func (b *networkBridge) addTSSMessageHandler(handler tssMessageHandler) {
 	b.tssMessageHandlersMutex.Lock()
 	defer b.tssMessageHandlersMutex.Unlock()
 
 	b.tssMessageHandlers = append(b.tssMessageHandlers, handler)
+
+	for _, protocolMessage := range b.earlyMessages {
+		if err := handler(protocolMessage); err != nil {
+			logger.Errorf("failed to handle buffered protocol message: [%v]", err)
+		}
+	}
+
+	if len(b.tssMessageHandlers) >= len(b.localMemberIDs) {
+		b.earlyMessages = nil
+	}
+}
+
+// decompress returns the received message with decompressed payload. False
+// is returned if the payload could not be decompressed. If the payload exceeds
+// the maximum message size once decompressed, the sender is reported.
+func (b *networkBridge) decompress(
+	msg *receivedMessage,
+) (*TSSProtocolMessage, bool) {
+	if !msg.message.IsCompressed {
+		return msg.message, true
+	}
+
+	payload, err := decompressPayload(
+		msg.message.Payload,
+		b.limiter.maxMessageSize(),
+	)
+	if err != nil {
+		logger.Warningf(
+			"dropping protocol message from [%s]: [%v]",
+			msg.message.SenderID,
+			err,
+		)
+		if _, ok := err.(oversizedPayloadError); ok {
+			b.limiter.oversized(msg.sender)
+		}
+		return nil, false
+	}
+
+	decompressedMessage := *msg.message
+	decompressedMessage.Payload = payload
+	decompressedMessage.IsCompressed = false
+
+	return &decompressedMessage, true
+}
+
+// incomingLane returns the pipeline lane of the incoming message. Messages
+// of the same sender are processed in the order in which they were received.
+func incomingLane(protocolMessage *TSSProtocolMessage) string {
+	return "in/" + protocolMessage.SenderID.String()
 }
 
 func (b *networkBridge) handleTSSProtocolMessage(protocolMessage *TSSProtocolMessage) {
+	if protocolMessage.SessionID != b.groupInfo.groupID ||
+		!b.isGroupMember(protocolMessage.SenderID) {
+		return
+	}
+
+	if !b.isAcceptedProtocolVersion(protocolMessage.ProtocolVersion) {
+		logger.Warningf(
+			"dropping protocol message from [%s] of protocol version [%d]",
+			protocolMessage.SenderID,
+			protocolMessage.ProtocolVersion,
+		)
+		return
+	}
+
+	// Handlers are called without holding the lock so that parties update
+	// their state with messages of different senders in parallel. A handler
+	// registered after the message has been buffered receives it on replay.
 	b.tssMessageHandlersMutex.Lock()
-	defer b.tssMessageHandlersMutex.Unlock()
+	if len(b.tssMessageHandlers) < len(b.localMemberIDs) {
+		b.bufferEarlyMessage(protocolMessage)
+	}
+	handlers := make([]tssMessageHandler, len(b.tssMessageHandlers))
+	copy(handlers, b.tssMessageHandlers)
+	b.tssMessageHandlersMutex.Unlock()
 
-	for _, handler := range b.tssMessageHandlers {
+	for _, handler := range handlers {
 		if err := handler(protocolMessage); err != nil {
 			logger.Errorf("failed to handle protocol message: [%v]", err)
 		}
 	}
 }
+
+// bufferEarlyMessage stores the message until all parties of this process are
+// connected. Messages exceeding the limit are dropped to bound the memory used
+// by messages of misbehaving peers.
+func (b *networkBridge) bufferEarlyMessage(protocolMessage *TSSProtocolMessage) {
+	limit := earlyMessagesPerMember * len(b.groupInfo.groupMemberIDs)
+	if len(b.earlyMessages) >= limit {
+		logger.Warningf(
+			"dropping protocol message from [%s]; "+
+				"limit of [%d] buffered messages reached",
+			protocolMessage.SenderID,
+			limit,
+		)
+		return
+	}
+
+	b.earlyMessages = append(b.earlyMessages, protocolMessage)
+}
@@ -0,0 +1,222 @@
+package tss
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/operator"
+)
+
+func TestNetworkBridgeReplaysEarlyMessages(t *testing.T) {
+	groupMemberIDs, err := generateMemberKeys(3)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	groupID := "test-group"
+	localMemberIDs := groupMemberIDs[:2]
+
+	bridge, err := newNetworkBridge(
+		&groupInfo{
+			groupID:        groupID,
+			memberID:       localMemberIDs[0],
+			groupMemberIDs: groupMemberIDs,
+		},
+		localMemberIDs,
+		nil,
+		networkRetryPolicy,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newMessage := func(senderID MemberID, sessionID string) *TSSProtocolMessage {
+		return &TSSProtocolMessage{
+			SenderID:    senderID,
+			Payload:     []byte{1, 2, 3},
+			IsBroadcast: true,
+			SessionID:   sessionID,
+		}
+	}
+
+	earlyMessage := newMessage(groupMemberIDs[2], groupID)
+	bridge.handleTSSProtocolMessage(earlyMessage)
+	// Messages of other sessions and from outside of the group are not
+	// buffered.
+	bridge.handleTSSProtocolMessage(newMessage(groupMemberIDs[2], "another-group"))
+	bridge.handleTSSProtocolMessage(newMessage(MemberID([]byte{1, 2, 3}), groupID))
+
+	firstHandler := &testMessageHandler{}
+	bridge.addTSSMessageHandler(firstHandler.handle)
+
+	laterMessage := newMessage(groupMemberIDs[1], groupID)
+	bridge.handleTSSProtocolMessage(laterMessage)
+
+	secondHandler := &testMessageHandler{}
+	bridge.addTSSMessageHandler(secondHandler.handle)
+
+	lastMessage := newMessage(groupMemberIDs[2], groupID)
+	bridge.handleTSSProtocolMessage(lastMessage)
+
+	expectedMessages := []*TSSProtocolMessage{earlyMessage, laterMessage, lastMessage}
+	for i, handler := range []*testMessageHandler{firstHandler, secondHandler} {
+		if !reflect.DeepEqual(expectedMessages, handler.messages) {
+			t.Errorf(
+				"unexpected messages of handler [%d]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				expectedMessages,
+				handler.messages,
+			)
+		}
+	}
+
+	if len(bridge.earlyMessages) != 0 {
+		t.Errorf(
+			"messages are still buffered after all parties are connected: [%d]",
+			len(bridge.earlyMessages),
+		)
+	}
+}
+
+func TestNetworkBridgeBoundsEarlyMessages(t *testing.T) {
+	groupMemberIDs, err := generateMemberKeys(2)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	bridge, err := newNetworkBridge(
+		&groupInfo{
+			groupID:        "test-group",
+			memberID:       groupMemberIDs[0],
+			groupMemberIDs: groupMemberIDs,
+		},
+		groupMemberIDs[:1],
+		nil,
+		networkRetryPolicy,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit := earlyMessagesPerMember * len(groupMemberIDs)
+	for i := 0; i < limit+1; i++ {
+		bridge.handleTSSProtocolMessage(&TSSProtocolMessage{
+			SenderID:  groupMemberIDs[1],
+			SessionID: "test-group",
+		})
+	}
+
+	if len(bridge.earlyMessages) != limit {
+		t.Errorf(
+			"unexpected number of buffered messages\nexpected: [%d]\nactual:   [%d]",
+			limit,
+			len(bridge.earlyMessages),
+		)
+	}
+}
+
+func TestNetworkBridgeReleasesMessagesReceivedAfterSessionEnd(t *testing.T) {
+	groupMemberIDs, err := generateMemberKeys(2)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	bridge, err := newNetworkBridge(
+		&groupInfo{
+			groupID:        "test-group",
+			memberID:       groupMemberIDs[0],
+			groupMemberIDs: groupMemberIDs,
+		},
+		groupMemberIDs[:1],
+		nil,
+		networkRetryPolicy,
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	senderPublicKey, err := groupMemberIDs[1].PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Nothing reads queued messages, as if the session has ended.
+	netInChan := make(chan *receivedMessage)
+	handle := bridge.receiveMessageHandler(ctx, netInChan)
+
+	handled := make(chan struct{})
+	go func() {
+		handle(&testNetMessage{
+			senderPublicKey: operator.Marshal(senderPublicKey),
+			payload: &TSSProtocolMessage{
+				SenderID:  groupMemberIDs[1],
+				Payload:   []byte{1, 2, 3},
+				SessionID: "test-group",
+			},
+		})
+		close(handled)
+	}()
+
+	cancel()
+
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatalf("handler is blocked after the session has ended")
+	}
+
+	if bridge.limiter.bufferedBytes != 0 {
+		t.Errorf(
+			"unexpected buffered bytes\nexpected: [%d]\nactual:   [%d]",
+			0,
+			bridge.limiter.bufferedBytes,
+		)
+	}
+}
+
+type testNetMessage struct {
+	senderPublicKey []byte
+	payload         interface{}
+}
+
+func (tnm *testNetMessage) TransportSenderID() net.TransportIdentifier {
+	return nil
+}
+
+func (tnm *testNetMessage) SenderPublicKey() []byte {
+	return tnm.senderPublicKey
+}
+
+func (tnm *testNetMessage) Payload() interface{} {
+	return tnm.payload
+}
+
+func (tnm *testNetMessage) Type() string {
+	return "test/message"
+}
+
+func (tnm *testNetMessage) Seqno() uint64 {
+	return 0
+}
+
+type testMessageHandler struct {
+	mutex    sync.Mutex
+	messages []*TSSProtocolMessage
+}
+
+func (tmh *testMessageHandler) handle(message *TSSProtocolMessage) error {
+	tmh.mutex.Lock()
+	defer tmh.mutex.Unlock()
+
+	tmh.messages = append(tmh.messages, message)
+	return nil
+}
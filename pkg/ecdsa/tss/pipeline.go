@@ -0,0 +1,260 @@
+package tss
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/keep-network/keep-common/pkg/metrics"
+)
+
+// messageQueueSizePerMember bounds the number of incoming network messages
+// queued in a pipeline for each group member. Once the queue is full,
+// receiving of further messages is blocked until queued messages are
+// processed.
+const messageQueueSizePerMember = 32
+
+type pipelineTask struct {
+	run      func()
+	queuedAt time.Time
+	bounded  bool
+}
+
+// messagePipeline processes protocol messages of a single session with a
+// bounded pool of workers. Tasks are grouped in lanes, e.g. one lane per
+// message sender; tasks of the same lane are processed one at a time in
+// the order in which they were submitted, while tasks of different lanes are
+// processed in parallel.
+type messagePipeline struct {
+	workersCount int
+	// slots limits the number of queued and processed bounded tasks.
+	slots chan struct{}
+
+	mutex     *sync.Mutex
+	cond      *sync.Cond
+	lanes     map[string][]*pipelineTask
+	busyLanes map[string]bool
+	// readyLanes are lanes with pending tasks which are not being processed
+	// by any worker.
+	readyLanes []string
+	isStopped  bool
+}
+
+func newMessagePipeline(workersCount int, capacity int) *messagePipeline {
+	mutex := &sync.Mutex{}
+
+	return &messagePipeline{
+		workersCount: workersCount,
+		slots:        make(chan struct{}, capacity),
+		mutex:        mutex,
+		cond:         sync.NewCond(mutex),
+		lanes:        make(map[string][]*pipelineTask),
+		busyLanes:    make(map[string]bool),
+	}
+}
+
+// newSessionPipeline creates a pipeline for a session of the given group.
+func newSessionPipeline(groupInfo *groupInfo) *messagePipeline {
+	return newMessagePipeline(
+		runtime.NumCPU(),
+		messageQueueSizePerMember*len(groupInfo.groupMemberIDs),
+	)
+}
+
+// start starts workers of the pipeline. Workers stop when the context is done
+// and tasks which have not been processed by then are dropped.
+func (mp *messagePipeline) start(ctx context.Context) {
+	for i := 0; i < mp.workersCount; i++ {
+		go mp.work()
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		mp.mutex.Lock()
+		defer mp.mutex.Unlock()
+
+		mp.isStopped = true
+		for _, tasks := range mp.lanes {
+			pipelineMetrics.dequeued(len(tasks))
+		}
+		mp.lanes = make(map[string][]*pipelineTask)
+		mp.readyLanes = nil
+
+		mp.cond.Broadcast()
+	}()
+}
+
+// submit adds the task to the lane. If the pipeline is at its capacity,
+// the function blocks until there is space for the task or until the context
+// is done.
+func (mp *messagePipeline) submit(
+	ctx context.Context,
+	lane string,
+	run func(),
+) {
+	select {
+	case mp.slots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+
+	mp.enqueue(lane, &pipelineTask{run: run, queuedAt: time.Now(), bounded: true})
+}
+
+// submitUnbounded adds the task to the lane regardless of the pipeline
+// capacity. It is used for messages exchanged by members of this process,
+// which are produced while processing other messages and must not wait for
+// space in the pipeline.
+func (mp *messagePipeline) submitUnbounded(lane string, run func()) {
+	mp.enqueue(lane, &pipelineTask{run: run, queuedAt: time.Now()})
+}
+
+func (mp *messagePipeline) enqueue(lane string, task *pipelineTask) {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	if mp.isStopped {
+		mp.release(task)
+		return
+	}
+
+	mp.lanes[lane] = append(mp.lanes[lane], task)
+	pipelineMetrics.queued()
+
+	if !mp.busyLanes[lane] && len(mp.lanes[lane]) == 1 {
+		mp.readyLanes = append(mp.readyLanes, lane)
+		mp.cond.Signal()
+	}
+}
+
+func (mp *messagePipeline) work() {
+	for {
+		mp.mutex.Lock()
+		for len(mp.readyLanes) == 0 && !mp.isStopped {
+			mp.cond.Wait()
+		}
+		if mp.isStopped {
+			mp.mutex.Unlock()
+			return
+		}
+
+		lane := mp.readyLanes[0]
+		mp.readyLanes = mp.readyLanes[1:]
+
+		task := mp.lanes[lane][0]
+		mp.lanes[lane] = mp.lanes[lane][1:]
+		mp.busyLanes[lane] = true
+		pipelineMetrics.dequeued(1)
+		mp.mutex.Unlock()
+
+		task.run()
+		pipelineMetrics.processed(time.Since(task.queuedAt))
+
+		mp.mutex.Lock()
+		mp.release(task)
+		delete(mp.busyLanes, lane)
+		if len(mp.lanes[lane]) > 0 {
+			mp.readyLanes = append(mp.readyLanes, lane)
+			mp.cond.Signal()
+		} else {
+			delete(mp.lanes, lane)
+		}
+		mp.mutex.Unlock()
+	}
+}
+
+func (mp *messagePipeline) release(task *pipelineTask) {
+	if task.bounded {
+		<-mp.slots
+	}
+}
+
+// pipelineMetrics aggregates metrics of pipelines of all sessions executed in
+// this process.
+var pipelineMetrics = &messagePipelineMetrics{}
+
+type messagePipelineMetrics struct {
+	mutex             sync.Mutex
+	queueDepth        int
+	processedCount    int
+	processingLatency time.Duration
+}
+
+func (mpm *messagePipelineMetrics) queued() {
+	mpm.mutex.Lock()
+	defer mpm.mutex.Unlock()
+
+	mpm.queueDepth++
+}
+
+func (mpm *messagePipelineMetrics) dequeued(count int) {
+	mpm.mutex.Lock()
+	defer mpm.mutex.Unlock()
+
+	mpm.queueDepth -= count
+}
+
+func (mpm *messagePipelineMetrics) processed(latency time.Duration) {
+	mpm.mutex.Lock()
+	defer mpm.mutex.Unlock()
+
+	mpm.processedCount++
+	mpm.processingLatency += latency
+}
+
+// MessageQueueDepth returns the number of protocol messages waiting for
+// processing in all sessions executed in this process.
+func MessageQueueDepth() int {
+	pipelineMetrics.mutex.Lock()
+	defer pipelineMetrics.mutex.Unlock()
+
+	return pipelineMetrics.queueDepth
+}
+
+// averageProcessingLatency returns the average time between queueing and
+// completing processing of protocol messages processed since the last call.
+func (mpm *messagePipelineMetrics) averageProcessingLatency() time.Duration {
+	mpm.mutex.Lock()
+	defer mpm.mutex.Unlock()
+
+	if mpm.processedCount == 0 {
+		return 0
+	}
+
+	average := mpm.processingLatency / time.Duration(mpm.processedCount)
+
+	mpm.processedCount = 0
+	mpm.processingLatency = 0
+
+	return average
+}
+
+// ObserveMessagePipeline triggers observation processes of
+// tss_message_queue_depth and tss_message_processing_latency metrics.
+// The latency is the average time in milliseconds between queueing and
+// completing processing of protocol messages processed within the last tick.
+func ObserveMessagePipeline(
+	ctx context.Context,
+	registry *metrics.Registry,
+	tick time.Duration,
+) {
+	observe := func(name string, value func() float64) {
+		observer, err := registry.NewGaugeObserver(name, value)
+		if err != nil {
+			logger.Warningf("could not create gauge observer [%v]", name)
+			return
+		}
+
+		observer.Observe(ctx, tick)
+	}
+
+	observe("tss_message_queue_depth", func() float64 {
+		return float64(MessageQueueDepth())
+	})
+	observe("tss_message_processing_latency", func() float64 {
+		latency := pipelineMetrics.averageProcessingLatency()
+		return float64(latency) / float64(time.Millisecond)
+	})
+}
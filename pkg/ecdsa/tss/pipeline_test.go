@@ -0,0 +1,115 @@
+package tss
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMessagePipelineKeepsLaneOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipeline := newMessagePipeline(4, 100)
+	pipeline.start(ctx)
+
+	lanesCount := 5
+	tasksCount := 20
+
+	var mutex sync.Mutex
+	processed := make(map[string][]int)
+
+	var wg sync.WaitGroup
+	wg.Add(lanesCount * tasksCount)
+	for task := 0; task < tasksCount; task++ {
+		for lane := 0; lane < lanesCount; lane++ {
+			laneName := fmt.Sprintf("lane-%d", lane)
+			task := task
+			pipeline.submit(ctx, laneName, func() {
+				defer wg.Done()
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				processed[laneName] = append(processed[laneName], task)
+			})
+		}
+	}
+	wg.Wait()
+
+	expectedOrder := make([]int, tasksCount)
+	for i := range expectedOrder {
+		expectedOrder[i] = i
+	}
+
+	for lane, order := range processed {
+		if !reflect.DeepEqual(expectedOrder, order) {
+			t.Errorf(
+				"unexpected order of tasks in lane [%s]\nexpected: [%v]\nactual:   [%v]",
+				lane,
+				expectedOrder,
+				order,
+			)
+		}
+	}
+}
+
+func TestMessagePipelineProcessesLanesInParallel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipeline := newMessagePipeline(2, 10)
+	pipeline.start(ctx)
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+	pipeline.submit(ctx, "blocked", func() { <-blocked })
+
+	processed := make(chan struct{})
+	pipeline.submit(ctx, "other", func() { close(processed) })
+
+	select {
+	case <-processed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task of another lane has not been processed")
+	}
+}
+
+func TestMessagePipelineBackpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	capacity := 3
+	pipeline := newMessagePipeline(1, capacity)
+	pipeline.start(ctx)
+
+	blocked := make(chan struct{})
+	for i := 0; i < capacity; i++ {
+		pipeline.submit(ctx, "lane", func() { <-blocked })
+	}
+
+	submitted := make(chan struct{})
+	go func() {
+		pipeline.submit(ctx, "lane", func() {})
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+		t.Fatal("task has been submitted to the full pipeline")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Unbounded tasks are accepted even if the pipeline is full.
+	pipeline.submitUnbounded("lane", func() {})
+
+	close(blocked)
+
+	select {
+	case <-submitted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task has not been submitted after the pipeline was drained")
+	}
+}
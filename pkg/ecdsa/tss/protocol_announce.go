@@ -3,6 +3,7 @@ package tss
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/keep-network/keep-core/pkg/net"
@@ -11,11 +12,30 @@ import (
 
 const protocolAnnounceTimeout = 2 * time.Minute
 
+// MemberValidator determines if the network sender with the given public key
+// may announce presence of the member with the given ID. A sender may announce
+// seats it holds in the group or seats of operators it acts on behalf of.
+type MemberValidator func(senderPublicKey *operator.PublicKey, memberID MemberID) bool
+
+// AnnounceProtocol announces presence of the provided members and gathers
+// member IDs of all peer members. Members executing the protocol in the same
+// process, such as seats of one operator, are announced together. If the
+// timeout is reached before all members announced their presence, member IDs
+// received so far are returned along with the timeout error. Members announce
+// protocol versions and features they support; the protocol fails as soon as
+// a member does not support any protocol version supported by all members
+// announced before. Announcements of members the sender may not announce
+// according to the provided member validator are dropped; if it is nil,
+// all announced members are accepted. Messages received from other members
+// are limited with the provided message limiter; if it is nil, default limits
+// are used.
 func AnnounceProtocol(
 	parentCtx context.Context,
-	publicKey *operator.PublicKey,
+	memberIDs []MemberID,
 	membersCount int,
+	validateMember MemberValidator,
 	broadcastChannel net.BroadcastChannel,
+	messageLimiter *MessageLimiter,
 ) (
 	[]MemberID,
 	error,
@@ -25,42 +45,88 @@ func AnnounceProtocol(
 	ctx, cancel := context.WithTimeout(parentCtx, protocolAnnounceTimeout)
 	defer cancel()
 
+	limiter := messageLimiter.session(membersCount)
+
 	announceInChan := make(chan *AnnounceMessage, membersCount)
 	handleAnnounceMessage := func(netMsg net.Message) {
 		switch msg := netMsg.Payload().(type) {
 		case *AnnounceMessage:
+			if !limiter.admitMessage(netMsg, len(msg.SenderID)) {
+				return
+			}
+			if validateMember != nil {
+				senderPublicKey, err := operator.Unmarshal(netMsg.SenderPublicKey())
+				if err != nil {
+					logger.Warningf(
+						"failed to unmarshal announcement sender public key: [%v]",
+						err,
+					)
+					return
+				}
+
+				if !validateMember(senderPublicKey, msg.SenderID) {
+					logger.Warningf(
+						"rejecting announcement of member [%v]; sender [%v] "+
+							"does not hold the seat",
+						msg.SenderID,
+						MemberIDFromPublicKey(senderPublicKey),
+					)
+					return
+				}
+			}
 			announceInChan <- msg
 		}
 	}
 	broadcastChannel.Recv(ctx, handleAnnounceMessage)
 
+	receivedMemberIDsMutex := &sync.Mutex{}
 	receivedMemberIDs := make(map[string]MemberID)
 
+	negotiation := newProtocolNegotiation()
+	var negotiationErr error
+
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case msg := <-announceInChan:
-				// Since broadcast channel has an address filter, we can
-				// assume each message come from a valid group member.
+				receivedMemberIDsMutex.Lock()
+				if err := negotiation.add(
+					msg.SenderID,
+					msg.ProtocolVersions,
+					msg.Features,
+				); err != nil {
+					negotiationErr = err
+					receivedMemberIDsMutex.Unlock()
+					cancel()
+					return
+				}
+
+				// Announced members have been validated against seats of
+				// the group so each message comes from a valid group member.
 				receivedMemberIDs[msg.SenderID.String()] = msg.SenderID
 
 				if len(receivedMemberIDs) == membersCount {
 					cancel()
 				}
+				receivedMemberIDsMutex.Unlock()
 			}
 		}
 	}()
 
 	go func() {
 		sendMessage := func() {
-			if err := broadcastChannel.Send(ctx,
-				&AnnounceMessage{
-					SenderID: MemberIDFromPublicKey(publicKey),
-				},
-			); err != nil {
-				logger.Errorf("failed to send announcement: [%v]", err)
+			for _, memberID := range memberIDs {
+				if err := broadcastChannel.Send(ctx,
+					&AnnounceMessage{
+						SenderID:         memberID,
+						ProtocolVersions: supportedProtocolVersions,
+						Features:         supportedFeatures,
+					},
+				); err != nil {
+					logger.Errorf("failed to send announcement: [%v]", err)
+				}
 			}
 		}
 
@@ -79,21 +145,32 @@ func AnnounceProtocol(
 
 	<-ctx.Done()
 
+	receivedMemberIDsMutex.Lock()
+	defer receivedMemberIDsMutex.Unlock()
+
+	if negotiationErr != nil {
+		return nil, fmt.Errorf(
+			"incompatible protocol announced: [%v]",
+			negotiationErr,
+		)
+	}
+
+	groupMemberIDs := make([]MemberID, 0)
+	for _, memberID := range receivedMemberIDs {
+		groupMemberIDs = append(groupMemberIDs, memberID)
+	}
+
 	switch ctx.Err() {
 	case context.DeadlineExceeded:
-		return nil, fmt.Errorf(
-			"waiting for announcements timed out after: [%v]",
+		return groupMemberIDs, timeoutError{
 			protocolAnnounceTimeout,
-		)
+			AnnounceStage,
+			nil,
+		}
 	case context.Canceled:
 		logger.Infof("announce protocol completed successfully")
 
-		memberIDs := make([]MemberID, 0)
-		for _, memberID := range receivedMemberIDs {
-			memberIDs = append(memberIDs, memberID)
-		}
-
-		return memberIDs, nil
+		return groupMemberIDs, nil
 	default:
 		return nil, fmt.Errorf("unexpected context error: [%v]", ctx.Err())
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ipfs/go-log"
 	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-ecdsa/pkg/localnet"
 )
 
 func TestAnnounceProtocol(t *testing.T) {
@@ -59,9 +60,11 @@ func TestAnnounceProtocol(t *testing.T) {
 
 			memberIDs, err := AnnounceProtocol(
 				ctx,
-				memberPublicKey,
+				[]MemberID{memberID},
 				groupSize,
+				nil,
 				broadcastChannel,
+				nil,
 			)
 			if err != nil {
 				errChan <- err
@@ -116,3 +119,87 @@ func TestAnnounceProtocol(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestAnnounceProtocolWithPartitionedMember(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	groupSize := 3
+
+	groupMembers, err := generateMemberKeys(groupSize)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	network := localnet.NewNetwork(1)
+	providers := make([]net.Provider, groupSize)
+	for i, memberID := range groupMembers {
+		memberPublicKey, err := memberID.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		memberNetworkKey := key.NetworkPublic(*memberPublicKey)
+		providers[i] = network.Connect(&memberNetworkKey)
+	}
+
+	// The last member is partitioned from the others.
+	network.Partition([]net.TransportIdentifier{providers[groupSize-1].ID()})
+
+	results := make([][]MemberID, groupSize)
+	errs := make([]error, groupSize)
+
+	var wg sync.WaitGroup
+	wg.Add(groupSize)
+	for i, memberID := range groupMembers {
+		go func(i int, memberID MemberID) {
+			defer wg.Done()
+
+			broadcastChannel, err := providers[i].BroadcastChannelFor("test-group-1")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			broadcastChannel.RegisterUnmarshaler(func() net.TaggedUnmarshaler {
+				return &AnnounceMessage{}
+			})
+
+			results[i], errs[i] = AnnounceProtocol(
+				ctx,
+				[]MemberID{memberID},
+				groupSize,
+				nil,
+				broadcastChannel,
+				nil,
+			)
+		}(i, memberID)
+	}
+	wg.Wait()
+
+	for i := range groupMembers {
+		if stage, _, ok := UnresponsiveMembers(errs[i]); !ok || stage != AnnounceStage {
+			t.Fatalf("expected announce timeout; has: [%v]", errs[i])
+		}
+	}
+
+	// Members of the majority partition announced to each other only.
+	for i := 0; i < groupSize-1; i++ {
+		if len(results[i]) != groupSize-1 {
+			t.Errorf(
+				"unexpected number of announced members\nexpected: [%v]\nactual:   [%v]",
+				groupSize-1,
+				len(results[i]),
+			)
+		}
+		for _, memberID := range results[i] {
+			if memberID.Equal(groupMembers[groupSize-1]) {
+				t.Errorf("partitioned member has been announced")
+			}
+		}
+	}
+
+	if len(results[groupSize-1]) != 1 {
+		t.Errorf("partitioned member received announcements of other members")
+	}
+}
@@ -0,0 +1,70 @@
+package tss
+
+import (
+	"context"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+)
+
+// HeartbeatProtocol periodically broadcasts heartbeats of the provided members
+// and passes sender IDs of heartbeats received from peer members to the
+// handler. Members executing the protocol in the same process, such as seats
+// of one operator, send their heartbeats together and do not receive their own
+// heartbeats. The protocol runs until the context is done.
+func HeartbeatProtocol(
+	ctx context.Context,
+	memberIDs []MemberID,
+	period time.Duration,
+	broadcastChannel net.BroadcastChannel,
+	handleHeartbeat func(senderID MemberID),
+) {
+	isOwnMember := func(senderID MemberID) bool {
+		for _, memberID := range memberIDs {
+			if memberID.Equal(senderID) {
+				return true
+			}
+		}
+		return false
+	}
+
+	broadcastChannel.Recv(ctx, func(netMsg net.Message) {
+		switch msg := netMsg.Payload().(type) {
+		case *HeartbeatMessage:
+			// Since broadcast channel has an address filter, we can
+			// assume each message come from a valid group member.
+			if !isOwnMember(msg.SenderID) {
+				handleHeartbeat(msg.SenderID)
+			}
+		}
+	})
+
+	sendHeartbeats := func(ctx context.Context) {
+		for _, memberID := range memberIDs {
+			if err := broadcastChannel.Send(
+				ctx,
+				&HeartbeatMessage{SenderID: memberID},
+			); err != nil {
+				logger.Warningf("failed to send heartbeat: [%v]", err)
+			}
+		}
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		// Heartbeats are retransmitted by the broadcast channel only until
+		// the next heartbeats are sent.
+		sendCtx, cancelSend := context.WithCancel(ctx)
+		sendHeartbeats(sendCtx)
+
+		select {
+		case <-ticker.C:
+			cancelSend()
+		case <-ctx.Done():
+			cancelSend()
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tss
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net/key"
+)
+
+func TestHeartbeatProtocol(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	groupSize := 3
+
+	groupMembers, err := generateMemberKeys(groupSize)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	mutex := &sync.Mutex{}
+	received := make(map[string]map[string]bool)
+
+	waitGroup := &sync.WaitGroup{}
+	waitGroup.Add(groupSize)
+
+	for _, memberID := range groupMembers {
+		memberPublicKey, err := memberID.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		memberNetworkKey := key.NetworkPublic(*memberPublicKey)
+		networkProvider := newTestNetProvider(&memberNetworkKey)
+
+		broadcastChannel, err := networkProvider.BroadcastChannelFor("test-group-heartbeat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterUnmarshalers(broadcastChannel)
+
+		received[memberID.String()] = make(map[string]bool)
+
+		go func(memberID MemberID) {
+			defer waitGroup.Done()
+
+			HeartbeatProtocol(
+				ctx,
+				[]MemberID{memberID},
+				100*time.Millisecond,
+				broadcastChannel,
+				func(senderID MemberID) {
+					mutex.Lock()
+					received[memberID.String()][senderID.String()] = true
+					mutex.Unlock()
+				},
+			)
+		}(memberID)
+	}
+
+	waitGroup.Wait()
+
+	for _, memberID := range groupMembers {
+		senders := received[memberID.String()]
+
+		if senders[memberID.String()] {
+			t.Errorf("member [%s] received its own heartbeat", memberID)
+		}
+
+		if len(senders) != groupSize-1 {
+			t.Errorf(
+				"unexpected number of peers heard by member [%s]\n"+
+					"expected: [%d]\nactual:   [%d]",
+				memberID,
+				groupSize-1,
+				len(senders),
+			)
+		}
+	}
+}
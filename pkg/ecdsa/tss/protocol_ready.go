@@ -3,6 +3,7 @@ package tss
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/keep-network/keep-core/pkg/net"
@@ -14,16 +15,25 @@ import (
 const protocolReadyTimeout = 2 * time.Minute
 
 // readyProtocol exchanges messages with peer members about readiness to start
-// the protocol execution. The member keeps sending the message in intervals
-// until they receive messages from all peer members. Function exits without an
+// the protocol execution. Readiness is signalled for all provided members
+// executing the protocol in this process. The member keeps sending the message
+// in intervals until they receive messages from all peer members. Function exits without an
 // error if messages were received from all peer members. If the timeout is
 // reached before receiving messages from all peer members the function returns
-// an error.
+// an error listing members which have not signalled readiness. Messages
+// exceeding limits of the session are dropped.
+//
+// Along with readiness, members exchange protocol versions and features they
+// support. The function returns the highest protocol version and features
+// supported by all members of the group. It fails as soon as a member does not
+// support any protocol version supported by other members.
 func readyProtocol(
 	parentCtx context.Context,
 	group *groupInfo,
+	memberIDs []MemberID,
 	broadcastChannel net.BroadcastChannel,
-) error {
+	limiter *sessionLimiter,
+) (*sessionProtocol, error) {
 	logger.Infof("signalling readiness")
 
 	ctx, cancel := context.WithTimeout(parentCtx, protocolReadyTimeout)
@@ -33,28 +43,47 @@ func readyProtocol(
 	handleReadyMessage := func(netMsg net.Message) {
 		switch msg := netMsg.Payload().(type) {
 		case *ReadyMessage:
+			if !limiter.admitMessage(netMsg, len(msg.SenderID)) {
+				return
+			}
 			readyInChan <- msg
 		}
 	}
 	broadcastChannel.Recv(ctx, handleReadyMessage)
 
-	go func() {
-		readyMembers := make(map[string]bool)
+	readyMembersMutex := &sync.Mutex{}
+	readyMembers := make(map[string]bool)
 
+	negotiation := newProtocolNegotiation()
+	var negotiationErr error
+
+	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case msg := <-readyInChan:
+				readyMembersMutex.Lock()
 				for _, memberID := range group.groupMemberIDs {
 					if msg.SenderID.Equal(memberID) {
-						readyMembers[msg.SenderID.String()] = true
+						negotiationErr = negotiation.add(
+							msg.SenderID,
+							msg.ProtocolVersions,
+							msg.Features,
+						)
+						if negotiationErr == nil {
+							readyMembers[msg.SenderID.String()] = true
+						}
 						break
 					}
 				}
+				isCompleted := negotiationErr != nil ||
+					len(readyMembers) == len(group.groupMemberIDs)
+				readyMembersMutex.Unlock()
 
-				if len(readyMembers) == len(group.groupMemberIDs) {
+				if isCompleted {
 					cancel()
+					return
 				}
 			}
 		}
@@ -62,10 +91,16 @@ func readyProtocol(
 
 	go func() {
 		sendMessage := func() {
-			if err := broadcastChannel.Send(ctx,
-				&ReadyMessage{SenderID: group.memberID},
-			); err != nil {
-				logger.Errorf("failed to send readiness notification: [%v]", err)
+			for _, memberID := range memberIDs {
+				if err := broadcastChannel.Send(ctx,
+					&ReadyMessage{
+						SenderID:         memberID,
+						ProtocolVersions: supportedProtocolVersions,
+						Features:         supportedFeatures,
+					},
+				); err != nil {
+					logger.Errorf("failed to send readiness notification: [%v]", err)
+				}
 			}
 		}
 
@@ -84,16 +119,40 @@ func readyProtocol(
 
 	<-ctx.Done()
 
+	readyMembersMutex.Lock()
+	defer readyMembersMutex.Unlock()
+
+	if negotiationErr != nil {
+		return nil, fmt.Errorf(
+			"incompatible protocol signalled: [%v]",
+			negotiationErr,
+		)
+	}
+
 	switch ctx.Err() {
 	case context.DeadlineExceeded:
-		return fmt.Errorf(
-			"waiting for readiness timed out after: [%v]", protocolReadyTimeout,
-		)
+
+		notReadyMembers := []MemberID{}
+		for _, memberID := range group.groupMemberIDs {
+			if !readyMembers[memberID.String()] {
+				notReadyMembers = append(notReadyMembers, memberID)
+			}
+		}
+
+		return nil, timeoutError{
+			protocolReadyTimeout,
+			ReadinessStage,
+			notReadyMembers,
+		}
 	case context.Canceled:
-		logger.Infof("successfully signalled readiness")
+		protocol := negotiation.result()
+		logger.Infof(
+			"successfully signalled readiness; using protocol version [%d]",
+			protocol.version,
+		)
 
-		return nil
+		return protocol, nil
 	default:
-		return fmt.Errorf("unexpected context error: [%v]", ctx.Err())
+		return nil, fmt.Errorf("unexpected context error: [%v]", ctx.Err())
 	}
 }
@@ -63,7 +63,13 @@ func TestReadyProtocol(t *testing.T) {
 
 			defer waitGroup.Done()
 
-			if err := readyProtocol(ctx, groupInfo, broadcastChannel); err != nil {
+			if _, err := readyProtocol(
+				ctx,
+				groupInfo,
+				[]MemberID{memberID},
+				broadcastChannel,
+				(*MessageLimiter)(nil).session(groupSize),
+			); err != nil {
 				errChan <- err
 				return
 			}
@@ -93,3 +99,62 @@ func TestReadyProtocol(t *testing.T) {
 	}
 
 }
+
+func TestReadyProtocolIncompatibleVersion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	groupMembers, err := generateMemberKeys(2)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	broadcastChannels := make([]net.BroadcastChannel, len(groupMembers))
+	for i, memberID := range groupMembers {
+		memberPublicKey, err := memberID.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		memberNetworkKey := key.NetworkPublic(*memberPublicKey)
+		networkProvider := newTestNetProvider(&memberNetworkKey)
+
+		broadcastChannels[i], err = networkProvider.BroadcastChannelFor(
+			"test-group-2",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		broadcastChannels[i].RegisterUnmarshaler(func() net.TaggedUnmarshaler {
+			return &ReadyMessage{}
+		})
+	}
+
+	// The second member supports only a protocol version unknown to
+	// the first member.
+	if err := broadcastChannels[1].Send(ctx, &ReadyMessage{
+		SenderID:         groupMembers[1],
+		ProtocolVersions: []uint32{ProtocolVersion1 + 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = readyProtocol(
+		ctx,
+		&groupInfo{
+			groupID:        "test-group-2",
+			memberID:       groupMembers[0],
+			groupMemberIDs: groupMembers,
+		},
+		[]MemberID{groupMembers[0]},
+		broadcastChannels[0],
+		(*MessageLimiter)(nil).session(len(groupMembers)),
+	)
+	if err == nil {
+		t.Fatal("expected incompatible protocol error")
+	}
+	if _, _, ok := UnresponsiveMembers(err); ok {
+		t.Fatalf("expected the protocol to fail before timeout: [%v]", err)
+	}
+}
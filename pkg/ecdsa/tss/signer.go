@@ -15,6 +15,23 @@ type ThresholdSigner struct {
 	thresholdKey ThresholdKey
 }
 
+// WithoutShare returns a copy of the signer holding only public data of
+// the threshold key. Such a signer can be persisted outside of the process
+// holding the key share but it can not take part in signing.
+func (s *ThresholdSigner) WithoutShare() *ThresholdSigner {
+	return &ThresholdSigner{
+		groupInfo: s.groupInfo,
+		thresholdKey: ThresholdKey{
+			ECDSAPub: s.thresholdKey.ECDSAPub,
+		},
+	}
+}
+
+// HasShare returns true if the signer holds the share of the threshold key.
+func (s *ThresholdSigner) HasShare() bool {
+	return s.thresholdKey.LocalSecrets.Xi != nil
+}
+
 // ThresholdKey contains data of signer's threshold key.
 type ThresholdKey keygen.LocalPartySaveData
 
@@ -28,6 +45,12 @@ func (s *ThresholdSigner) GroupID() string {
 	return s.groupID
 }
 
+// GroupMemberIDs returns member IDs of all members of the signing group,
+// including the signer.
+func (s *ThresholdSigner) GroupMemberIDs() []MemberID {
+	return s.groupMemberIDs
+}
+
 // PublicKey returns signer's ECDSA public key which is also the signing group's
 // public key.
 func (s *ThresholdSigner) PublicKey() *ecdsa.PublicKey {
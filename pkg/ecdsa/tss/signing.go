@@ -93,7 +93,7 @@ func (s *signingSigner) sign(ctx context.Context) (*ecdsa.Signature, error) {
 				}
 			}
 
-			return nil, timeoutError{SigningProtocolTimeout, "signing", memberIDs}
+			return nil, timeoutError{SigningProtocolTimeout, SigningStage, memberIDs}
 		}
 	}
 }
@@ -0,0 +1,154 @@
+package tss
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keep-network/keep-common/pkg/metrics"
+)
+
+// largeMessageRetransmissionWindow is the period for which large broadcast
+// messages are retransmitted by the broadcast channel on every tick of its
+// retransmission ticker. Members are subscribed to the broadcast channel once
+// they signalled readiness, so retransmitting multi-kilobyte messages that
+// often for the entire protocol session mostly costs egress.
+const largeMessageRetransmissionWindow = 30 * time.Second
+
+// maxRetransmissionInterval is the longest interval between retransmissions
+// of large broadcast messages once their retransmission window elapsed.
+const maxRetransmissionInterval = 4 * time.Minute
+
+// retransmissionWindows bounds the period of frequent retransmissions of
+// broadcast messages of the given tss-lib message types. Once the window
+// elapses, the message is retransmitted with exponentially growing intervals
+// until the end of the protocol session. Messages of other types are small
+// and are frequently retransmitted for the entire protocol session.
+var retransmissionWindows = map[string]time.Duration{
+	// Paillier public key, safe prime parameters and their proofs.
+	"KGRound1Message": largeMessageRetransmissionWindow,
+	// Paillier key proof.
+	"KGRound3Message": largeMessageRetransmissionWindow,
+	// Ciphertext and range proof.
+	"SignRound1Message1": largeMessageRetransmissionWindow,
+}
+
+// retransmissionWindow returns the period for which the broadcast message of
+// the given tss-lib message type should be frequently retransmitted. Zero
+// means that the message is frequently retransmitted for the entire protocol
+// session.
+func retransmissionWindow(messageType string) time.Duration {
+	// Message type can be qualified with the protobuf package name.
+	messageType = messageType[strings.LastIndex(messageType, ".")+1:]
+
+	return retransmissionWindows[messageType]
+}
+
+// nextRetransmissionInterval returns the interval to wait before the next
+// retransmission of a large broadcast message, given the previous interval.
+func nextRetransmissionInterval(interval time.Duration) time.Duration {
+	interval *= 2
+	if interval > maxRetransmissionInterval {
+		return maxRetransmissionInterval
+	}
+
+	return interval
+}
+
+// sessionTraffic counts bytes of protocol messages exchanged over the network
+// in a single protocol session. Retransmissions performed by the network layer
+// are not counted.
+type sessionTraffic struct {
+	mutex             sync.Mutex
+	sentBytes         int
+	receivedBytes     int
+	uncompressedBytes int
+}
+
+// sent records a message sent with the given payload size on the wire and
+// before compression.
+func (st *sessionTraffic) sent(size int, uncompressedSize int) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.sentBytes += size
+	st.uncompressedBytes += uncompressedSize
+}
+
+func (st *sessionTraffic) received(size int) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	st.receivedBytes += size
+}
+
+// trafficMetrics holds traffic of all key generations executed in this
+// process. Traffic of sessions executed concurrently is summed up.
+var trafficMetrics = &protocolTrafficMetrics{}
+
+type protocolTrafficMetrics struct {
+	mutex                          sync.Mutex
+	keyGenerationsCount            int
+	keyGenerationSentBytes         int
+	keyGenerationReceivedBytes     int
+	keyGenerationUncompressedBytes int
+}
+
+// keyGenerationCompleted adds traffic of a completed key generation session,
+// regardless of its result, to the totals.
+func (ptm *protocolTrafficMetrics) keyGenerationCompleted(traffic *sessionTraffic) {
+	traffic.mutex.Lock()
+	defer traffic.mutex.Unlock()
+
+	ptm.mutex.Lock()
+	defer ptm.mutex.Unlock()
+
+	ptm.keyGenerationsCount++
+	ptm.keyGenerationSentBytes += traffic.sentBytes
+	ptm.keyGenerationReceivedBytes += traffic.receivedBytes
+	ptm.keyGenerationUncompressedBytes += traffic.uncompressedBytes
+}
+
+func (ptm *protocolTrafficMetrics) value(field *int) float64 {
+	ptm.mutex.Lock()
+	defer ptm.mutex.Unlock()
+
+	return float64(*field)
+}
+
+// ObserveKeyGenerationTraffic triggers observation processes of
+// tss_keygen_total, tss_keygen_sent_bytes_total,
+// tss_keygen_received_bytes_total and tss_keygen_uncompressed_bytes_total
+// metrics. The metrics are the number of key generations completed since
+// the process started and the total number of bytes of protocol message
+// payloads they sent, received and sent before compression. Retransmissions
+// performed by the network layer are not included.
+func ObserveKeyGenerationTraffic(
+	ctx context.Context,
+	registry *metrics.Registry,
+	tick time.Duration,
+) {
+	observe := func(name string, value func() float64) {
+		observer, err := registry.NewGaugeObserver(name, value)
+		if err != nil {
+			logger.Warningf("could not create gauge observer [%v]", name)
+			return
+		}
+
+		observer.Observe(ctx, tick)
+	}
+
+	observe("tss_keygen_total", func() float64 {
+		return trafficMetrics.value(&trafficMetrics.keyGenerationsCount)
+	})
+	observe("tss_keygen_sent_bytes_total", func() float64 {
+		return trafficMetrics.value(&trafficMetrics.keyGenerationSentBytes)
+	})
+	observe("tss_keygen_received_bytes_total", func() float64 {
+		return trafficMetrics.value(&trafficMetrics.keyGenerationReceivedBytes)
+	})
+	observe("tss_keygen_uncompressed_bytes_total", func() float64 {
+		return trafficMetrics.value(&trafficMetrics.keyGenerationUncompressedBytes)
+	})
+}
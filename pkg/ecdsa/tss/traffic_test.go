@@ -0,0 +1,182 @@
+package tss
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/operator"
+)
+
+func TestRetransmissionWindow(t *testing.T) {
+	var tests = map[string]struct {
+		messageType    string
+		expectedWindow time.Duration
+	}{
+		"large message": {
+			messageType:    "KGRound1Message",
+			expectedWindow: largeMessageRetransmissionWindow,
+		},
+		"large message qualified with package name": {
+			messageType:    "binance.tsslib.ecdsa.keygen.KGRound3Message",
+			expectedWindow: largeMessageRetransmissionWindow,
+		},
+		"small message": {
+			messageType:    "KGRound2Message2",
+			expectedWindow: 0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			window := retransmissionWindow(test.messageType)
+			if test.expectedWindow != window {
+				t.Errorf(
+					"unexpected retransmission window\nexpected: [%v]\nactual:   [%v]",
+					test.expectedWindow,
+					window,
+				)
+			}
+		})
+	}
+}
+
+func TestNextRetransmissionInterval(t *testing.T) {
+	interval := largeMessageRetransmissionWindow
+
+	expectedIntervals := []time.Duration{
+		1 * time.Minute,
+		2 * time.Minute,
+		maxRetransmissionInterval,
+		maxRetransmissionInterval,
+	}
+
+	for i, expectedInterval := range expectedIntervals {
+		interval = nextRetransmissionInterval(interval)
+		if expectedInterval != interval {
+			t.Errorf(
+				"unexpected interval [%d]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				expectedInterval,
+				interval,
+			)
+		}
+	}
+}
+
+func TestRetransmitWithBackoff(t *testing.T) {
+	channelName := fmt.Sprintf("test-channel-%d", rand.Int())
+
+	newBroadcastChannel := func() net.BroadcastChannel {
+		_, publicKey, err := operator.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		networkKey := key.NetworkPublic(*publicKey)
+		broadcastChannel, err := newTestNetProvider(&networkKey).
+			BroadcastChannelFor(channelName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		RegisterUnmarshalers(broadcastChannel)
+
+		return broadcastChannel
+	}
+
+	senderChannel := newBroadcastChannel()
+	receiverChannel := newBroadcastChannel()
+
+	mutex := &sync.Mutex{}
+	receivedCount := 0
+
+	recvCtx, cancelRecv := context.WithCancel(context.Background())
+	defer cancelRecv()
+
+	receiverChannel.Recv(recvCtx, func(message net.Message) {
+		if _, ok := message.Payload().(*TSSProtocolMessage); ok {
+			mutex.Lock()
+			receivedCount++
+			mutex.Unlock()
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	bridge := &networkBridge{}
+	bridge.retransmitWithBackoff(
+		ctx,
+		senderChannel,
+		&TSSProtocolMessage{SessionID: "session-1"},
+		10*time.Millisecond,
+	)
+
+	time.Sleep(100 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// Retransmissions after 10, 30, 70 and 150 milliseconds.
+	if receivedCount < 2 || receivedCount > 4 {
+		t.Errorf("unexpected number of retransmissions: [%d]", receivedCount)
+	}
+}
+
+func TestKeyGenerationTrafficIsCumulative(t *testing.T) {
+	metrics := &protocolTrafficMetrics{}
+
+	waitGroup := &sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+
+			traffic := &sessionTraffic{}
+			traffic.sent(100, 300)
+			traffic.received(200)
+
+			metrics.keyGenerationCompleted(traffic)
+		}()
+	}
+	waitGroup.Wait()
+
+	var tests = map[string]struct {
+		field         *int
+		expectedValue float64
+	}{
+		"key generations count": {
+			field:         &metrics.keyGenerationsCount,
+			expectedValue: 3,
+		},
+		"sent bytes": {
+			field:         &metrics.keyGenerationSentBytes,
+			expectedValue: 300,
+		},
+		"received bytes": {
+			field:         &metrics.keyGenerationReceivedBytes,
+			expectedValue: 600,
+		},
+		"uncompressed bytes": {
+			field:         &metrics.keyGenerationUncompressedBytes,
+			expectedValue: 900,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if value := metrics.value(test.field); test.expectedValue != value {
+				t.Errorf(
+					"unexpected value\nexpected: [%v]\nactual:   [%v]",
+					test.expectedValue,
+					value,
+				)
+			}
+		})
+	}
+}
@@ -9,12 +9,14 @@ package tss
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-log"
 	"github.com/keep-network/keep-core/pkg/net"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/params"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
 const (
@@ -38,6 +40,10 @@ var logger = log.Logger("keep-tss")
 // execution. The parameters should be generated prior to running this function.
 // If not provided they will be generated.
 //
+// Opening of network channels with other members is retried according to the
+// provided network retry policy. Messages received from other members are
+// subject to default message limits.
+//
 // As a result a signer will be returned or an error, if key generation failed.
 func GenerateThresholdSigner(
 	parentCtx context.Context,
@@ -46,8 +52,48 @@ func GenerateThresholdSigner(
 	groupMemberIDs []MemberID,
 	dishonestThreshold uint,
 	networkProvider net.Provider,
+	networkRetryPolicy *retry.Policy,
 	paramsBox *params.Box,
 ) (*ThresholdSigner, error) {
+	signers, err := GenerateThresholdSigners(
+		parentCtx,
+		groupID,
+		[]MemberID{memberID},
+		groupMemberIDs,
+		dishonestThreshold,
+		networkProvider,
+		networkRetryPolicy,
+		nil,
+		[]*params.Box{paramsBox},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return signers[0], nil
+}
+
+// GenerateThresholdSigners executes a threshold multi-party key generation
+// protocol for several members of the group at once, e.g. for all seats an
+// operator holds in the group. All the members take part in the same protocol
+// execution and messages between them are not sent over the network.
+//
+// Each member needs its own pre-parameters; boxes are expected in the same
+// order as member IDs. As a result one signer for each member will be
+// returned, in the order of member IDs, or an error, if key generation failed.
+// Messages received from other members are limited with the provided message
+// limiter; if it is nil, default limits are used.
+func GenerateThresholdSigners(
+	parentCtx context.Context,
+	groupID string,
+	memberIDs []MemberID,
+	groupMemberIDs []MemberID,
+	dishonestThreshold uint,
+	networkProvider net.Provider,
+	networkRetryPolicy *retry.Policy,
+	messageLimiter *MessageLimiter,
+	paramsBoxes []*params.Box,
+) ([]*ThresholdSigner, error) {
 	if len(groupMemberIDs) < 2 {
 		return nil, fmt.Errorf(
 			"group should have at least 2 members but got: [%d]",
@@ -63,14 +109,35 @@ func GenerateThresholdSigner(
 		)
 	}
 
-	group := &groupInfo{
-		groupID:            groupID,
-		memberID:           memberID,
-		groupMemberIDs:     groupMemberIDs,
-		dishonestThreshold: int(dishonestThreshold),
+	if len(memberIDs) == 0 {
+		return nil, fmt.Errorf("at least one member is required")
+	}
+
+	if len(memberIDs) != len(paramsBoxes) {
+		return nil, fmt.Errorf(
+			"[%d] pre-parameters provided for [%d] members",
+			len(paramsBoxes),
+			len(memberIDs),
+		)
+	}
+
+	groups := make([]*groupInfo, len(memberIDs))
+	for i, memberID := range memberIDs {
+		groups[i] = &groupInfo{
+			groupID:            groupID,
+			memberID:           memberID,
+			groupMemberIDs:     groupMemberIDs,
+			dishonestThreshold: int(dishonestThreshold),
+		}
 	}
 
-	netBridge, err := newNetworkBridge(group, networkProvider)
+	netBridge, err := newNetworkBridge(
+		groups[0],
+		memberIDs,
+		networkProvider,
+		networkRetryPolicy,
+		messageLimiter,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize network bridge: [%v]", err)
 	}
@@ -78,56 +145,153 @@ func GenerateThresholdSigner(
 	ctx, cancel := context.WithTimeout(parentCtx, KeyGenerationProtocolTimeout)
 	defer cancel()
 
-	preParams, err := paramsBox.Content()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pre-parameters: [%v]", err)
-	}
+	defer trafficMetrics.keyGenerationCompleted(netBridge.traffic)
 
-	keyGenSigner, err := initializeKeyGeneration(
-		ctx,
-		group,
-		preParams,
-		netBridge,
-	)
-	if err != nil {
-		return nil, err
+	keyGenSigners := make([]*member, len(memberIDs))
+	for i, group := range groups {
+		preParams, err := paramsBoxes[i].Content()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pre-parameters: [%v]", err)
+		}
+
+		keyGenSigner, err := initializeKeyGeneration(
+			ctx,
+			group,
+			preParams,
+			netBridge,
+		)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("[party:%s]: initialized key generation", keyGenSigner.keygenParty.PartyID())
+
+		keyGenSigners[i] = keyGenSigner
 	}
-	logger.Infof("[party:%s]: initialized key generation", keyGenSigner.keygenParty.PartyID())
 
 	broadcastChannel, err := netBridge.getBroadcastChannel()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := readyProtocol(ctx, group, broadcastChannel); err != nil {
-		return nil, fmt.Errorf("readiness signaling protocol failed: [%v]", err)
+	protocol, err := readyProtocol(
+		ctx,
+		groups[0],
+		memberIDs,
+		broadcastChannel,
+		netBridge.limiter,
+	)
+	if err != nil {
+		return nil, wrapError("readiness signaling protocol failed", err)
 	}
+	netBridge.useProtocol(protocol)
 
 	// We are begining the communication with other members using pre-parameters
 	// provided inside of this box. It's time to destroy box content so that the
 	// pre-parameters cannot be later reused.
-	paramsBox.DestroyContent()
+	for _, paramsBox := range paramsBoxes {
+		paramsBox.DestroyContent()
+	}
 
-	logger.Infof("[party:%s]: starting key generation", keyGenSigner.keygenParty.PartyID())
+	signers := make([]*ThresholdSigner, len(keyGenSigners))
+	errs := make([]error, len(keyGenSigners))
 
-	signer, err := keyGenSigner.generateKey(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate key: [%v]", err)
+	var wg sync.WaitGroup
+	wg.Add(len(keyGenSigners))
+	for i, keyGenSigner := range keyGenSigners {
+		go func(i int, keyGenSigner *member) {
+			defer wg.Done()
+
+			logger.Infof("[party:%s]: starting key generation", keyGenSigner.keygenParty.PartyID())
+
+			signers[i], errs[i] = keyGenSigner.generateKey(ctx)
+			if errs[i] != nil {
+				// Other members of this process can not complete without
+				// this one.
+				cancel()
+				return
+			}
+
+			logger.Infof("[party:%s]: completed key generation", keyGenSigner.keygenParty.PartyID())
+		}(i, keyGenSigner)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, wrapError("failed to generate key", err)
+		}
 	}
-	logger.Infof("[party:%s]: completed key generation", keyGenSigner.keygenParty.PartyID())
 
-	return signer, nil
+	return signers, nil
 }
 
 // CalculateSignature executes a threshold multi-party signature calculation
 // protocol for the given digest. As a result the calculated ECDSA signature will
-// be returned or an error, if the signature generation failed.
+// be returned or an error, if the signature generation failed. Opening of
+// network channels with other members is retried according to the provided
+// network retry policy. Messages received from other members are subject to
+// default message limits.
 func (s *ThresholdSigner) CalculateSignature(
 	parentCtx context.Context,
 	digest []byte,
 	networkProvider net.Provider,
+	networkRetryPolicy *retry.Policy,
+) (*ecdsa.Signature, error) {
+	return CalculateSignature(
+		parentCtx,
+		digest,
+		[]*ThresholdSigner{s},
+		networkProvider,
+		networkRetryPolicy,
+		nil,
+	)
+}
+
+// CalculateSignature executes a threshold multi-party signature calculation
+// protocol for the given digest with several signers of the same group at
+// once, e.g. with signers of all seats an operator holds in the group. All
+// the signers take part in the same protocol execution. Messages received from
+// other members are limited with the provided message limiter; if it is nil,
+// default limits are used.
+func CalculateSignature(
+	parentCtx context.Context,
+	digest []byte,
+	signers []*ThresholdSigner,
+	networkProvider net.Provider,
+	networkRetryPolicy *retry.Policy,
+	messageLimiter *MessageLimiter,
 ) (*ecdsa.Signature, error) {
-	netBridge, err := newNetworkBridge(s.groupInfo, networkProvider)
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer is required")
+	}
+
+	memberIDs := make([]MemberID, len(signers))
+	for i, signer := range signers {
+		if signer.groupID != signers[0].groupID {
+			return nil, fmt.Errorf(
+				"signers belong to different groups [%s] and [%s]",
+				signers[0].groupID,
+				signer.groupID,
+			)
+		}
+
+		if !signer.HasShare() {
+			return nil, fmt.Errorf(
+				"signer [%s] does not hold a key share",
+				signer.memberID,
+			)
+		}
+
+		memberIDs[i] = signer.memberID
+	}
+
+	netBridge, err := newNetworkBridge(
+		signers[0].groupInfo,
+		memberIDs,
+		networkProvider,
+		networkRetryPolicy,
+		messageLimiter,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize network bridge: [%v]", err)
 	}
@@ -135,9 +299,12 @@ func (s *ThresholdSigner) CalculateSignature(
 	ctx, cancel := context.WithTimeout(parentCtx, SigningProtocolTimeout)
 	defer cancel()
 
-	signingSigner, err := s.initializeSigning(ctx, digest[:], netBridge)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize signing: [%v]", err)
+	signingSigners := make([]*signingSigner, len(signers))
+	for i, signer := range signers {
+		signingSigners[i], err = signer.initializeSigning(ctx, digest[:], netBridge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize signing: [%v]", err)
+		}
 	}
 
 	broadcastChannel, err := netBridge.getBroadcastChannel()
@@ -145,14 +312,43 @@ func (s *ThresholdSigner) CalculateSignature(
 		return nil, err
 	}
 
-	if err := readyProtocol(ctx, s.groupInfo, broadcastChannel); err != nil {
-		return nil, fmt.Errorf("readiness signaling protocol failed: [%v]", err)
+	protocol, err := readyProtocol(
+		ctx,
+		signers[0].groupInfo,
+		memberIDs,
+		broadcastChannel,
+		netBridge.limiter,
+	)
+	if err != nil {
+		return nil, wrapError("readiness signaling protocol failed", err)
 	}
+	netBridge.useProtocol(protocol)
 
-	signature, err := signingSigner.sign(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign: [%v]", err)
+	signatures := make([]*ecdsa.Signature, len(signingSigners))
+	errs := make([]error, len(signingSigners))
+
+	var wg sync.WaitGroup
+	wg.Add(len(signingSigners))
+	for i, localSigner := range signingSigners {
+		go func(i int, localSigner *signingSigner) {
+			defer wg.Done()
+
+			signatures[i], errs[i] = localSigner.sign(ctx)
+			if errs[i] != nil {
+				// Other signers of this process can not complete without
+				// this one.
+				cancel()
+			}
+		}(i, localSigner)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, wrapError("failed to sign", err)
+		}
 	}
 
-	return signature, err
+	// All signers calculate the same signature.
+	return signatures[0], nil
 }
@@ -21,9 +21,13 @@ import (
 	"github.com/keep-network/keep-ecdsa/internal/testdata"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/params"
+	"github.com/keep-network/keep-ecdsa/pkg/localnet"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 	"github.com/keep-network/keep-ecdsa/pkg/utils/testutils"
 )
 
+var networkRetryPolicy = (&retry.Config{}).Policy(retry.Network)
+
 func TestGenerateKeyAndSign(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
@@ -93,6 +97,7 @@ func TestGenerateKeyAndSign(t *testing.T) {
 					groupMemberIDs,
 					dishonestThreshold,
 					network,
+					networkRetryPolicy,
 					params.NewBox(&preParams),
 				)
 				if err != nil {
@@ -166,6 +171,7 @@ func TestGenerateKeyAndSign(t *testing.T) {
 					ctx,
 					digest[:],
 					networkProvider,
+					networkRetryPolicy,
 				)
 				if err != nil {
 					errChan <- fmt.Errorf("failed to sign: [%v]", err)
@@ -237,3 +243,246 @@ func generateMemberKeys(groupSize int) ([]MemberID, error) {
 func newTestNetProvider(memberNetworkKey *key.NetworkPublic) net.Provider {
 	return local.ConnectWithKey(memberNetworkKey)
 }
+
+func TestGenerateKeyAndSignWithSeats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	groupID := fmt.Sprintf("tss-test-%d", rand.Int())
+
+	// The first operator holds two seats in the group.
+	operatorsSeats := [][]uint16{{0, 2}, {1}, {3}}
+	groupSize := 4
+	dishonestThreshold := uint(groupSize - 1)
+
+	testData, err := testdata.LoadKeygenTestFixtures(groupSize)
+	if err != nil {
+		t.Fatalf("failed to load test data: [%v]", err)
+	}
+
+	groupMemberIDs := make([]MemberID, groupSize)
+	operatorsMemberIDs := make([][]MemberID, len(operatorsSeats))
+	networkProviders := make([]net.Provider, len(operatorsSeats))
+
+	for i, seats := range operatorsSeats {
+		_, publicKey, err := operator.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, seat := range seats {
+			memberID := MemberIDFromPublicKeyAndIndex(publicKey, seat)
+			groupMemberIDs[seat] = memberID
+			operatorsMemberIDs[i] = append(operatorsMemberIDs[i], memberID)
+		}
+
+		networkPublicKey := key.NetworkPublic(*publicKey)
+		networkProviders[i] = newTestNetProvider(&networkPublicKey)
+	}
+
+	type result struct {
+		signers   []*ThresholdSigner
+		signature *ecdsa.Signature
+		err       error
+	}
+
+	operatorsSigners := make([][]*ThresholdSigner, len(operatorsSeats))
+	keyGenResults := make(chan result, len(operatorsSeats))
+
+	for i, seats := range operatorsSeats {
+		go func(i int, seats []uint16) {
+			paramsBoxes := make([]*params.Box, len(seats))
+			for j, seat := range seats {
+				preParams := testData[seat].LocalPreParams
+				paramsBoxes[j] = params.NewBox(&preParams)
+			}
+
+			signers, err := GenerateThresholdSigners(
+				ctx,
+				groupID,
+				operatorsMemberIDs[i],
+				groupMemberIDs,
+				dishonestThreshold,
+				networkProviders[i],
+				networkRetryPolicy,
+				nil,
+				paramsBoxes,
+			)
+			operatorsSigners[i] = signers
+			keyGenResults <- result{signers: signers, err: err}
+		}(i, seats)
+	}
+
+	for range operatorsSeats {
+		result := <-keyGenResults
+		if result.err != nil {
+			t.Fatalf("unexpected error on key generation: [%v]", result.err)
+		}
+	}
+
+	if len(operatorsSigners[0]) != 2 {
+		t.Fatalf(
+			"unexpected number of signers of operator with two seats: [%v]",
+			len(operatorsSigners[0]),
+		)
+	}
+
+	publicKey := operatorsSigners[0][0].PublicKey()
+	for _, signers := range operatorsSigners {
+		for _, signer := range signers {
+			if !reflect.DeepEqual(publicKey, signer.PublicKey()) {
+				t.Errorf(
+					"public key doesn't match expected\nexpected: [%v]\nactual: [%v]",
+					publicKey,
+					signer.PublicKey(),
+				)
+			}
+		}
+	}
+
+	digest := sha256.Sum256([]byte("message to sign"))
+	signingResults := make(chan result, len(operatorsSeats))
+
+	for i := range operatorsSeats {
+		go func(i int) {
+			signature, err := CalculateSignature(
+				ctx,
+				digest[:],
+				operatorsSigners[i],
+				networkProviders[i],
+				networkRetryPolicy,
+				nil,
+			)
+			signingResults <- result{signature: signature, err: err}
+		}(i)
+	}
+
+	for range operatorsSeats {
+		result := <-signingResults
+		if result.err != nil {
+			t.Fatalf("unexpected error on signing: [%v]", result.err)
+		}
+
+		if !cecdsa.Verify(
+			(*cecdsa.PublicKey)(publicKey),
+			digest[:],
+			result.signature.R,
+			result.signature.S,
+		) {
+			t.Errorf("invalid signature: [%+v]", result.signature)
+		}
+	}
+}
+
+func TestGenerateKeyAndSignWithNetworkFaults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	groupSize := 3
+	dishonestThreshold := uint(groupSize - 1)
+	groupID := fmt.Sprintf("tss-test-%d", rand.Int())
+
+	groupMemberIDs, err := generateMemberKeys(groupSize)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	testData, err := testdata.LoadKeygenTestFixtures(groupSize)
+	if err != nil {
+		t.Fatalf("failed to load test data: [%v]", err)
+	}
+
+	// Messages of all members are delayed, reordered and duplicated.
+	network := localnet.NewNetwork(1)
+	networkProviders := make([]net.Provider, groupSize)
+	for i, memberID := range groupMemberIDs {
+		memberPublicKey, err := memberID.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		networkPublicKey := key.NetworkPublic(*memberPublicKey)
+		networkProviders[i] = network.Connect(&networkPublicKey)
+		network.SetFaults(networkProviders[i].ID(), &localnet.Faults{
+			Delay:         10 * time.Millisecond,
+			Jitter:        50 * time.Millisecond,
+			DuplicateRate: 0.2,
+		})
+	}
+
+	type result struct {
+		signer    *ThresholdSigner
+		signature *ecdsa.Signature
+		err       error
+	}
+
+	results := make([]result, groupSize)
+	var wg sync.WaitGroup
+	wg.Add(groupSize)
+	for i, memberID := range groupMemberIDs {
+		go func(i int, memberID MemberID) {
+			defer wg.Done()
+
+			preParams := testData[i].LocalPreParams
+
+			signer, err := GenerateThresholdSigner(
+				ctx,
+				groupID,
+				memberID,
+				groupMemberIDs,
+				dishonestThreshold,
+				networkProviders[i],
+				networkRetryPolicy,
+				params.NewBox(&preParams),
+			)
+			if err != nil {
+				results[i].err = fmt.Errorf("failed to generate signer: [%v]", err)
+				return
+			}
+			results[i].signer = signer
+		}(i, memberID)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.err != nil {
+			t.Fatal(result.err)
+		}
+	}
+
+	digest := sha256.Sum256([]byte("message to sign"))
+
+	wg.Add(groupSize)
+	for i := range groupMemberIDs {
+		go func(i int) {
+			defer wg.Done()
+
+			results[i].signature, results[i].err = results[i].signer.CalculateSignature(
+				ctx,
+				digest[:],
+				networkProviders[i],
+				networkRetryPolicy,
+			)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.err != nil {
+			t.Fatalf("failed to sign: [%v]", result.err)
+		}
+	}
+
+	publicKey := results[0].signer.PublicKey()
+	for _, result := range results {
+		if !reflect.DeepEqual(results[0].signature, result.signature) {
+			t.Errorf(
+				"signature doesn't match expected\nexpected: [%v]\nactual: [%v]",
+				results[0].signature,
+				result.signature,
+			)
+		}
+	}
+
+	testutils.VerifyEthereumSignature(t, digest[:], results[0].signature, publicKey)
+}
@@ -0,0 +1,172 @@
+package tss
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ProtocolVersion1 is the version of the protocol executed by clients which
+// do not announce supported protocol versions. Members not announcing any
+// version are assumed to support only this version.
+const ProtocolVersion1 uint32 = 1
+
+// supportedProtocolVersions are protocol versions this client is able to
+// execute.
+var supportedProtocolVersions = []uint32{ProtocolVersion1}
+
+// CompressionFeature is the feature of exchanging compressed payloads of
+// protocol messages.
+const CompressionFeature = "compression"
+
+// supportedFeatures are optional protocol features this client is able
+// to use.
+var supportedFeatures = []string{CompressionFeature}
+
+// sessionProtocol is the protocol version and features agreed by all members
+// of the group for the protocol session.
+type sessionProtocol struct {
+	version  uint32
+	features map[string]bool
+}
+
+// hasFeature returns true if the feature is supported by all members of
+// the group.
+func (sp *sessionProtocol) hasFeature(feature string) bool {
+	return sp.features[feature]
+}
+
+// incompatibleProtocolError is returned when a member does not support any
+// protocol version supported by all members processed so far.
+type incompatibleProtocolError struct {
+	memberID       MemberID
+	versions       []uint32
+	commonVersions []uint32
+}
+
+func (e incompatibleProtocolError) Error() string {
+	return fmt.Sprintf(
+		"member [%s] supports protocol versions %v; "+
+			"none of them is supported by all members so far %v",
+		e.memberID,
+		e.versions,
+		e.commonVersions,
+	)
+}
+
+// protocolNegotiation intersects protocol versions and features supported
+// by members of the group, starting with versions and features supported by
+// this client. As each member computes the intersection over all members of
+// the group, all members agree on the same protocol.
+type protocolNegotiation struct {
+	mutex          sync.Mutex
+	commonVersions []uint32
+	commonFeatures map[string]bool
+	members        map[string]bool
+}
+
+func newProtocolNegotiation() *protocolNegotiation {
+	commonVersions := make([]uint32, len(supportedProtocolVersions))
+	copy(commonVersions, supportedProtocolVersions)
+
+	commonFeatures := make(map[string]bool)
+	for _, feature := range supportedFeatures {
+		commonFeatures[feature] = true
+	}
+
+	return &protocolNegotiation{
+		commonVersions: commonVersions,
+		commonFeatures: commonFeatures,
+		members:        make(map[string]bool),
+	}
+}
+
+// add takes into account protocol versions and features supported by
+// the member. An error is returned if the member does not support any of
+// versions supported by all members added before.
+func (pn *protocolNegotiation) add(
+	memberID MemberID,
+	versions []uint32,
+	features []string,
+) error {
+	pn.mutex.Lock()
+	defer pn.mutex.Unlock()
+
+	if pn.members[memberID.String()] {
+		return nil
+	}
+
+	if len(versions) == 0 {
+		versions = []uint32{ProtocolVersion1}
+	}
+
+	memberVersions := make(map[uint32]bool)
+	for _, version := range versions {
+		memberVersions[version] = true
+	}
+
+	commonVersions := []uint32{}
+	for _, version := range pn.commonVersions {
+		if memberVersions[version] {
+			commonVersions = append(commonVersions, version)
+		}
+	}
+
+	if len(commonVersions) == 0 {
+		return incompatibleProtocolError{memberID, versions, pn.commonVersions}
+	}
+
+	memberFeatures := make(map[string]bool)
+	for _, feature := range features {
+		memberFeatures[feature] = true
+	}
+
+	for feature := range pn.commonFeatures {
+		if !memberFeatures[feature] {
+			delete(pn.commonFeatures, feature)
+		}
+	}
+
+	pn.commonVersions = commonVersions
+	pn.members[memberID.String()] = true
+
+	return nil
+}
+
+// result returns the highest protocol version and features supported by all
+// members added so far.
+func (pn *protocolNegotiation) result() *sessionProtocol {
+	pn.mutex.Lock()
+	defer pn.mutex.Unlock()
+
+	versions := make([]uint32, len(pn.commonVersions))
+	copy(versions, pn.commonVersions)
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	features := make(map[string]bool)
+	for feature := range pn.commonFeatures {
+		features[feature] = true
+	}
+
+	return &sessionProtocol{
+		version:  versions[0],
+		features: features,
+	}
+}
+
+// isSupportedProtocolVersion returns true if this client is able to execute
+// the protocol version. Zero is the version of messages sent by clients which
+// do not negotiate the protocol.
+func isSupportedProtocolVersion(version uint32) bool {
+	if version == 0 {
+		version = ProtocolVersion1
+	}
+
+	for _, supportedVersion := range supportedProtocolVersions {
+		if version == supportedVersion {
+			return true
+		}
+	}
+
+	return false
+}
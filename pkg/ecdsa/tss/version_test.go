@@ -0,0 +1,101 @@
+package tss
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProtocolNegotiation(t *testing.T) {
+	memberIDs, err := generateMemberKeys(3)
+	if err != nil {
+		t.Fatalf("failed to generate members keys: [%v]", err)
+	}
+
+	defer func(versions []uint32, features []string) {
+		supportedProtocolVersions = versions
+		supportedFeatures = features
+	}(supportedProtocolVersions, supportedFeatures)
+
+	supportedProtocolVersions = []uint32{1, 2, 3}
+	supportedFeatures = []string{"feature-1", "feature-2"}
+
+	var tests = map[string]struct {
+		versions         [][]uint32
+		features         [][]string
+		expectedVersion  uint32
+		expectedFeatures map[string]bool
+		expectedError    error
+	}{
+		"all members support all versions and features": {
+			versions:         [][]uint32{{1, 2, 3}, {3, 2, 1}, {1, 2, 3}},
+			features:         [][]string{{"feature-1", "feature-2"}, {"feature-2", "feature-1"}, {"feature-1", "feature-2"}},
+			expectedVersion:  3,
+			expectedFeatures: map[string]bool{"feature-1": true, "feature-2": true},
+		},
+		"highest common version and common features": {
+			versions:         [][]uint32{{1, 2, 3}, {1, 2}, {2, 4}},
+			features:         [][]string{{"feature-1", "feature-2"}, {"feature-2"}, {"feature-2", "feature-3"}},
+			expectedVersion:  2,
+			expectedFeatures: map[string]bool{"feature-2": true},
+		},
+		"member not negotiating protocol": {
+			versions:         [][]uint32{{1, 2, 3}, {}, {1, 2}},
+			features:         [][]string{{"feature-1"}, {}, {"feature-1"}},
+			expectedVersion:  1,
+			expectedFeatures: map[string]bool{},
+		},
+		"no common version": {
+			versions: [][]uint32{{2, 3}, {2}, {3}},
+			features: [][]string{{}, {}, {}},
+			expectedError: incompatibleProtocolError{
+				memberID:       memberIDs[2],
+				versions:       []uint32{3},
+				commonVersions: []uint32{2},
+			},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			negotiation := newProtocolNegotiation()
+
+			var err error
+			for i, memberID := range memberIDs {
+				if err = negotiation.add(
+					memberID,
+					test.versions[i],
+					test.features[i],
+				); err != nil {
+					break
+				}
+			}
+
+			if !reflect.DeepEqual(test.expectedError, err) {
+				t.Fatalf(
+					"unexpected error\nexpected: [%v]\nactual:   [%v]",
+					test.expectedError,
+					err,
+				)
+			}
+			if err != nil {
+				return
+			}
+
+			protocol := negotiation.result()
+			if test.expectedVersion != protocol.version {
+				t.Errorf(
+					"unexpected protocol version\nexpected: [%v]\nactual:   [%v]",
+					test.expectedVersion,
+					protocol.version,
+				)
+			}
+			if !reflect.DeepEqual(test.expectedFeatures, protocol.features) {
+				t.Errorf(
+					"unexpected protocol features\nexpected: [%v]\nactual:   [%v]",
+					test.expectedFeatures,
+					protocol.features,
+				)
+			}
+		})
+	}
+}
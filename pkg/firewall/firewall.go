@@ -3,28 +3,21 @@ package firewall
 import (
 	"crypto/ecdsa"
 	"fmt"
-	"math/big"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ipfs/go-log"
 
-	"github.com/keep-network/keep-common/pkg/cache"
-
 	coreChain "github.com/keep-network/keep-core/pkg/chain"
 	coreFirewall "github.com/keep-network/keep-core/pkg/firewall"
 	coreNet "github.com/keep-network/keep-core/pkg/net"
 	coreKey "github.com/keep-network/keep-core/pkg/net/key"
 
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
 )
 
 var logger = log.Logger("keep-firewall")
 
-// KeepCachePeriod is the time the cache maintains the list of active keep
-// members. We use the cache to minimize calls to Ethereum client.
-const KeepCachePeriod = 168 * time.Hour // one week
-
 var errNoAuthorization = fmt.Errorf("remote peer has no authorization on the factory")
 
 var errNoMinStakeNoActiveKeep = fmt.Errorf("remote peer has no minimum " +
@@ -32,22 +25,24 @@ var errNoMinStakeNoActiveKeep = fmt.Errorf("remote peer has no minimum " +
 
 // NewStakeOrActiveKeepPolicy is a firewall policy checking if the remote peer
 // has a minimum stake and in case it has no minimum stake if it is a member of
-// at least one active keep.
+// at least one active keep. Active keeps membership is checked against
+// the provided keeps index.
 func NewStakeOrActiveKeepPolicy(
 	chain eth.Handle,
 	stakeMonitor coreChain.StakeMonitor,
+	keepsIndex *index.Keeps,
 ) coreNet.Firewall {
 	return &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall.MinimumStakePolicy(stakeMonitor),
-		activeKeepMembersCache: cache.NewTimeCache(KeepCachePeriod),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall.MinimumStakePolicy(stakeMonitor),
+		keepsIndex:         keepsIndex,
 	}
 }
 
 type stakeOrActiveKeepPolicy struct {
-	chain                  eth.Handle
-	minimumStakePolicy     coreNet.Firewall
-	activeKeepMembersCache *cache.TimeCache
+	chain              eth.Handle
+	minimumStakePolicy coreNet.Firewall
+	keepsIndex         *index.Keeps
 }
 
 func (soakp *stakeOrActiveKeepPolicy) Validate(
@@ -91,86 +86,15 @@ func (soakp *stakeOrActiveKeepPolicy) Validate(
 func (soakp *stakeOrActiveKeepPolicy) validateActiveKeepMembership(
 	remotePeerAddress string,
 ) error {
-
-	// First, check in the in-memory time cache to minimize hits to ETH client.
-	// If the Keep client with the given chain address is in the cache it means
-	// it's been a member in at least one active keep the last time
-	// validateActiveKeepMembership was executed and caching period has not
-	// elapsed yet.
-	//
-	// If the caching period elapsed, this check will return false and we
-	// have to ask the chain about the current status.
-	//
-	// Similarly, if the client was not a member of an active keep the last time
-	// validateActiveKeepMembership was executed, we have to ask the chain
-	// about the current status.
-	soakp.activeKeepMembersCache.Sweep()
-	if soakp.activeKeepMembersCache.Has(remotePeerAddress) {
+	// The keeps index is kept up to date with keep creation, closing and
+	// termination events in the background so we do not need to ask
+	// the chain about each keep separately.
+	if soakp.keepsIndex.IsActiveKeepMember(
+		common.HexToAddress(remotePeerAddress),
+	) {
 		return nil
 	}
 
-	zero := big.NewInt(0)
-	one := big.NewInt(1)
-
-	// Start iterating through all keeps known to the factory starting from the
-	// ones most recently created as there is a higher chance they are active.
-	keepCount, err := soakp.chain.GetKeepCount()
-	if err != nil {
-		return fmt.Errorf("could not get keep count: [%v]", err)
-	}
-
-	lastIndex := new(big.Int).Sub(keepCount, one)
-
-	for keepIndex := new(big.Int).Set(lastIndex); keepIndex.Cmp(zero) != -1; keepIndex.Sub(keepIndex, one) {
-		keepAddress, err := soakp.chain.GetKeepAtIndex(keepIndex)
-		if err != nil {
-			logger.Errorf(
-				"could not get keep at index [%v]: [%v]",
-				keepIndex,
-				err,
-			)
-			continue
-		}
-
-		// We are interested only in active keeps. If the current keep is not
-		// active, we skip it. We still need to process the rest of the keeps
-		// because it's possible that although this keep is not active some
-		// peers created before this one are still active.
-		isActive, err := soakp.chain.IsActive(keepAddress)
-		if err != nil {
-			logger.Errorf(
-				"could not check if keep [%x] is active: [%v]",
-				keepAddress,
-				err,
-			)
-			continue
-		}
-		if !isActive {
-			continue
-		}
-
-		// Get all the members of the active keep and store them in the active
-		// keep members cache.
-		members, err := soakp.chain.GetMembers(keepAddress)
-		if err != nil {
-			logger.Errorf(
-				"could not get members of keep [%x]: [%v]",
-				keepAddress,
-				err,
-			)
-			continue
-		}
-		for _, member := range members {
-			soakp.activeKeepMembersCache.Add(member.String())
-		}
-
-		// If the remote peer address has been added to the cache we can
-		// connect with this client as it's a member of an active keep.
-		if soakp.activeKeepMembersCache.Has(remotePeerAddress) {
-			return nil
-		}
-	}
-
 	// If we are here, it means that the client is not a member in any of
 	// active keeps and it's minimum stake check failed as well. We are not
 	// allowing to connect with that peer.
@@ -4,25 +4,24 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"testing"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/keep-network/keep-common/pkg/cache"
+	"github.com/keep-network/keep-common/pkg/persistence"
 	"github.com/keep-network/keep-core/pkg/net/key"
 	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+	"github.com/keep-network/keep-ecdsa/pkg/index"
 )
 
-var cacheLifeTime = time.Second
-
 // Has minimum stake.
 // Should allow to connect.
 func TestHasMinimumStake(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -45,10 +44,11 @@ func TestHasMinimumStake(t *testing.T) {
 func TestNoAuthorization(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -74,10 +74,11 @@ func TestNoAuthorization(t *testing.T) {
 func TestNoMinimumStakeNoKeepsExist(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -89,6 +90,8 @@ func TestNoMinimumStakeNoKeepsExist(t *testing.T) {
 		key.NetworkPubKeyToEthAddress(remotePeerPublicKey),
 	))
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != errNoMinStakeNoActiveKeep {
@@ -107,10 +110,11 @@ func TestNoMinimumStakeNoKeepsExist(t *testing.T) {
 func TestNoMinimumStakeIsNotKeepMember(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -130,6 +134,8 @@ func TestNoMinimumStakeIsNotKeepMember(t *testing.T) {
 		},
 	)
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != errNoMinStakeNoActiveKeep {
@@ -148,10 +154,11 @@ func TestNoMinimumStakeIsNotKeepMember(t *testing.T) {
 func TestNoMinimumStakeIsActiveKeepMember(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -171,6 +178,8 @@ func TestNoMinimumStakeIsActiveKeepMember(t *testing.T) {
 		},
 	)
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != nil {
@@ -185,10 +194,11 @@ func TestNoMinimumStakeIsActiveKeepMember(t *testing.T) {
 func TestNoMinimumStakeIsClosedKeepMember(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -212,6 +222,8 @@ func TestNoMinimumStakeIsClosedKeepMember(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != errNoMinStakeNoActiveKeep {
@@ -231,10 +243,11 @@ func TestNoMinimumStakeIsClosedKeepMember(t *testing.T) {
 func TestNoMinimumStakeMultipleKeepsMember(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -267,6 +280,8 @@ func TestNoMinimumStakeMultipleKeepsMember(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != nil {
@@ -278,15 +293,15 @@ func TestNoMinimumStakeMultipleKeepsMember(t *testing.T) {
 // Has authorization.
 // There are multiple keeps.
 // Is not a member of an active keep.
-// Should NOT allow to connect but should cache all active keep members in-memory.
-func TestCachesAllActiveKeepMembers(t *testing.T) {
+// Should NOT allow to connect but should index all active keep members.
+func TestIndexesAllActiveKeepMembers(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
-	cache := cache.NewTimeCache(cacheLifeTime)
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache,
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -318,36 +333,39 @@ func TestCachesAllActiveKeepMembers(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	policy.Validate(key.NetworkKeyToECDSAKey(remotePeerPublicKey))
 
-	if !cache.Has(activeKeepMembers[0].String()) {
-		t.Errorf("should cache active keep members")
+	if !keepsIndex.IsActiveKeepMember(activeKeepMembers[0]) {
+		t.Errorf("should index active keep members")
 	}
-	if !cache.Has(activeKeepMembers[1].String()) {
-		t.Errorf("should cache active keep members")
+	if !keepsIndex.IsActiveKeepMember(activeKeepMembers[1]) {
+		t.Errorf("should index active keep members")
 	}
-	if cache.Has(closedKeepMembers[0].String()) {
-		t.Errorf("should not cache non-active keep members")
+	if keepsIndex.IsActiveKeepMember(closedKeepMembers[0]) {
+		t.Errorf("should not index non-active keep members")
 	}
-	if cache.Has(closedKeepMembers[1].String()) {
-		t.Errorf("should not cache non-active keep members")
+	if keepsIndex.IsActiveKeepMember(closedKeepMembers[1]) {
+		t.Errorf("should not index non-active keep members")
 	}
 }
 
 // Has no minimum stake.
 // Has authorization.
-// There are multiple keeps.
 // Is a member of an active keep
 // Should allow to connect.
-// After some time, the keep gets closed.
-// It should no longer allow to connect.
-func TestSweepsCache(t *testing.T) {
+// The keep gets closed.
+// It should still allow to connect until the index is synchronized.
+// It should no longer allow to connect after the index is synchronized.
+func TestClosedKeepRemovedFromIndex(t *testing.T) {
 	chain := local.Connect()
 	coreFirewall := newMockCoreFirewall()
+	keepsIndex := newTestKeepsIndex(chain)
 	policy := &stakeOrActiveKeepPolicy{
-		chain:                  chain,
-		minimumStakePolicy:     coreFirewall,
-		activeKeepMembersCache: cache.NewTimeCache(cacheLifeTime),
+		chain:              chain,
+		minimumStakePolicy: coreFirewall,
+		keepsIndex:         keepsIndex,
 	}
 
 	_, remotePeerPublicKey, err := key.GenerateStaticNetworkKey()
@@ -367,6 +385,8 @@ func TestSweepsCache(t *testing.T) {
 		},
 	)
 
+	syncKeepsIndex(t, chain, keepsIndex)
+
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != nil {
@@ -377,16 +397,15 @@ func TestSweepsCache(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// still caching the old result
+	// index not yet synchronized with the closing event
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != nil {
 		t.Fatalf("validation should pass: [%v]", err)
 	}
 
-	time.Sleep(cacheLifeTime)
+	syncKeepsIndex(t, chain, keepsIndex)
 
-	// no longer caches the previous result
 	if err := policy.Validate(
 		key.NetworkKeyToECDSAKey(remotePeerPublicKey),
 	); err != errNoMinStakeNoActiveKeep {
@@ -396,7 +415,50 @@ func TestSweepsCache(t *testing.T) {
 			errNoMinStakeNoActiveKeep,
 		)
 	}
+}
+
+func newTestKeepsIndex(chain local.Chain) *index.Keeps {
+	return index.NewKeepsIndex(
+		chain,
+		&persistenceHandleMock{},
+		&index.Config{StartBlock: 0},
+		0,
+	)
+}
 
+// syncKeepsIndex waits until the block in which local chain reports events
+// emitted so far is mined and synchronizes the index with the chain.
+func syncKeepsIndex(t *testing.T, chain local.Chain, keepsIndex *index.Keeps) {
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.BlockCounter().WaitForBlockHeight(currentBlock + 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keepsIndex.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type persistenceHandleMock struct{}
+
+func (phm *persistenceHandleMock) Save(data []byte, directory string, name string) error {
+	return nil
+}
+
+func (phm *persistenceHandleMock) ReadAll() (<-chan persistence.DataDescriptor, <-chan error) {
+	dataChannel := make(chan persistence.DataDescriptor)
+	errorChannel := make(chan error)
+	close(dataChannel)
+	close(errorChannel)
+	return dataChannel, errorChannel
+}
+
+func (phm *persistenceHandleMock) Archive(directory string) error {
+	return nil
 }
 
 func newMockCoreFirewall() *mockCoreFirewall {
@@ -0,0 +1,228 @@
+package identity
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-core/pkg/operator"
+)
+
+// hostedOperatorPrefix is prepended to the host public key and the proof
+// timestamp before they are signed by the operator so that the signature can
+// not be mistaken for a signature over any other data.
+const hostedOperatorPrefix = "keep-ecdsa hosted operator:"
+
+// Directory holds operators known to be hosted by network hosts with keys
+// other than their own. An operator is hosted by exactly one host at a time;
+// a newer proof replaces the previous host of the operator. Proofs older than
+// the one the current host has been recorded with are rejected so that
+// a replaced host can not take the operator back by replaying its old proof.
+type Directory struct {
+	mutex sync.RWMutex
+	// Host public key by operator address.
+	hosts map[common.Address]*operator.PublicKey
+	// Hosted operators public keys by host address.
+	operators map[common.Address]map[common.Address]*operator.PublicKey
+	// Timestamp of the proof of the current host by operator address.
+	timestamps map[common.Address]uint64
+}
+
+// NewDirectory creates an empty directory.
+func NewDirectory() *Directory {
+	return &Directory{
+		hosts:      make(map[common.Address]*operator.PublicKey),
+		operators:  make(map[common.Address]map[common.Address]*operator.PublicKey),
+		timestamps: make(map[common.Address]uint64),
+	}
+}
+
+// Add records that the operator is hosted by the host. It does not verify
+// the operator approved the host; use AddProven for data received from the
+// network.
+func (d *Directory) Add(
+	hostPublicKey *operator.PublicKey,
+	operatorPublicKey *operator.PublicKey,
+) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.add(hostPublicKey, operatorPublicKey)
+}
+
+// add records that the operator is hosted by the host. Must be called with
+// the mutex locked.
+func (d *Directory) add(
+	hostPublicKey *operator.PublicKey,
+	operatorPublicKey *operator.PublicKey,
+) {
+	hostAddress := crypto.PubkeyToAddress(*hostPublicKey)
+	operatorAddress := crypto.PubkeyToAddress(*operatorPublicKey)
+
+	if hostAddress == operatorAddress {
+		return
+	}
+
+	if previousHost, ok := d.hosts[operatorAddress]; ok {
+		previousHostAddress := crypto.PubkeyToAddress(*previousHost)
+		if previousHostAddress == hostAddress {
+			return
+		}
+
+		delete(d.operators[previousHostAddress], operatorAddress)
+		if len(d.operators[previousHostAddress]) == 0 {
+			delete(d.operators, previousHostAddress)
+		}
+	}
+
+	d.hosts[operatorAddress] = hostPublicKey
+
+	if _, ok := d.operators[hostAddress]; !ok {
+		d.operators[hostAddress] = make(map[common.Address]*operator.PublicKey)
+	}
+	d.operators[hostAddress][operatorAddress] = operatorPublicKey
+}
+
+// AddProven verifies that the message has been signed by the operator for
+// the given host and records that the operator is hosted by the host.
+// The message is rejected if it is older than the proof of the current host
+// of the operator. A proof with the same timestamp is accepted only from
+// the current host, which publishes it periodically.
+func (d *Directory) AddProven(
+	hostPublicKey *operator.PublicKey,
+	message *HostedOperatorMessage,
+) error {
+	if err := verifyHostedOperator(hostPublicKey, message); err != nil {
+		return err
+	}
+
+	operatorAddress := crypto.PubkeyToAddress(*message.OperatorPublicKey)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if currentTimestamp, ok := d.timestamps[operatorAddress]; ok {
+		isCurrentHost := false
+		if currentHost, ok := d.hosts[operatorAddress]; ok {
+			isCurrentHost = crypto.PubkeyToAddress(*currentHost) ==
+				crypto.PubkeyToAddress(*hostPublicKey)
+		}
+
+		if message.Timestamp < currentTimestamp ||
+			(message.Timestamp == currentTimestamp && !isCurrentHost) {
+			return fmt.Errorf(
+				"proof of operator [%s] for host [%s] with timestamp [%d] "+
+					"is not newer than the current proof with timestamp [%d]",
+				operatorAddress.String(),
+				crypto.PubkeyToAddress(*hostPublicKey).String(),
+				message.Timestamp,
+				currentTimestamp,
+			)
+		}
+	}
+
+	d.add(hostPublicKey, message.OperatorPublicKey)
+	d.timestamps[operatorAddress] = message.Timestamp
+
+	return nil
+}
+
+// HostOf returns the public key of the host acting on behalf of the operator.
+// The second returned value is false if the operator is not hosted by any
+// other host.
+func (d *Directory) HostOf(
+	operatorPublicKey *operator.PublicKey,
+) (*operator.PublicKey, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	hostPublicKey, ok := d.hosts[crypto.PubkeyToAddress(*operatorPublicKey)]
+	return hostPublicKey, ok
+}
+
+// OperatorsOf returns public keys of operators hosted by the host.
+func (d *Directory) OperatorsOf(
+	hostPublicKey *operator.PublicKey,
+) []*operator.PublicKey {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	hostedOperators := d.operators[crypto.PubkeyToAddress(*hostPublicKey)]
+
+	operators := make([]*operator.PublicKey, 0, len(hostedOperators))
+	for _, operatorPublicKey := range hostedOperators {
+		operators = append(operators, operatorPublicKey)
+	}
+
+	return operators
+}
+
+// signHostedOperator creates a message proving the operator approved the host
+// to act on its behalf at the given time. Hosts should use the current time
+// in nanoseconds so that proofs for a new host supersede proofs for
+// the previous one.
+func signHostedOperator(
+	hostPublicKey *operator.PublicKey,
+	operatorPrivateKey *operator.PrivateKey,
+	timestamp uint64,
+) (*HostedOperatorMessage, error) {
+	signature, err := crypto.Sign(
+		hostedOperatorDigest(hostPublicKey, timestamp),
+		operatorPrivateKey,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign host public key: [%v]", err)
+	}
+
+	return &HostedOperatorMessage{
+		OperatorPublicKey: &operatorPrivateKey.PublicKey,
+		Signature:         signature,
+		Timestamp:         timestamp,
+	}, nil
+}
+
+func verifyHostedOperator(
+	hostPublicKey *operator.PublicKey,
+	message *HostedOperatorMessage,
+) error {
+	if message.OperatorPublicKey == nil {
+		return fmt.Errorf("missing operator public key")
+	}
+
+	if len(message.Signature) != crypto.SignatureLength {
+		return fmt.Errorf(
+			"invalid signature length [%d]",
+			len(message.Signature),
+		)
+	}
+
+	if !crypto.VerifySignature(
+		operator.Marshal(message.OperatorPublicKey),
+		hostedOperatorDigest(hostPublicKey, message.Timestamp),
+		message.Signature[:crypto.RecoveryIDOffset],
+	) {
+		return fmt.Errorf(
+			"operator [%s] did not approve host [%s]",
+			crypto.PubkeyToAddress(*message.OperatorPublicKey).String(),
+			crypto.PubkeyToAddress(*hostPublicKey).String(),
+		)
+	}
+
+	return nil
+}
+
+func hostedOperatorDigest(
+	hostPublicKey *operator.PublicKey,
+	timestamp uint64,
+) []byte {
+	timestampBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(timestampBytes, timestamp)
+
+	return crypto.Keccak256(
+		[]byte(hostedOperatorPrefix),
+		operator.Marshal(hostPublicKey),
+		timestampBytes,
+	)
+}
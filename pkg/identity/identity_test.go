@@ -0,0 +1,495 @@
+package identity
+
+import (
+	"context"
+	cecdsa "crypto/ecdsa"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/net/local"
+	"github.com/keep-network/keep-core/pkg/operator"
+
+	"github.com/keep-network/keep-ecdsa/internal/testdata"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/params"
+	"github.com/keep-network/keep-ecdsa/pkg/localnet"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+func TestHostedOperatorProof(t *testing.T) {
+	_, hostPublicKey := generateKeyPair(t)
+	_, otherHostPublicKey := generateKeyPair(t)
+	operatorPrivateKey, _ := generateKeyPair(t)
+
+	message, err := signHostedOperator(hostPublicKey, operatorPrivateKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyHostedOperator(hostPublicKey, message); err != nil {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+
+	if err := verifyHostedOperator(otherHostPublicKey, message); err == nil {
+		t.Errorf("expected error for proof published by other host")
+	}
+}
+
+func TestHostedOperatorMessageMarshalling(t *testing.T) {
+	_, hostPublicKey := generateKeyPair(t)
+	operatorPrivateKey, _ := generateKeyPair(t)
+
+	message, err := signHostedOperator(hostPublicKey, operatorPrivateKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bytes, err := message.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unmarshalled := &HostedOperatorMessage{}
+	if err := unmarshalled.Unmarshal(bytes); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(message, unmarshalled) {
+		t.Errorf(
+			"unexpected unmarshalled message\nexpected: [%+v]\nactual:   [%+v]",
+			message,
+			unmarshalled,
+		)
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	_, host1PublicKey := generateKeyPair(t)
+	_, host2PublicKey := generateKeyPair(t)
+	_, operatorPublicKey := generateKeyPair(t)
+
+	directory := NewDirectory()
+
+	if _, ok := directory.HostOf(operatorPublicKey); ok {
+		t.Errorf("operator should not be hosted")
+	}
+
+	directory.Add(host1PublicKey, operatorPublicKey)
+
+	assertHost(t, directory, operatorPublicKey, host1PublicKey)
+	assertOperatorsCount(t, directory, host1PublicKey, 1)
+
+	directory.Add(host2PublicKey, operatorPublicKey)
+
+	assertHost(t, directory, operatorPublicKey, host2PublicKey)
+	assertOperatorsCount(t, directory, host1PublicKey, 0)
+	assertOperatorsCount(t, directory, host2PublicKey, 1)
+
+	directory.Add(operatorPublicKey, operatorPublicKey)
+
+	assertHost(t, directory, operatorPublicKey, host2PublicKey)
+}
+
+func TestDirectoryAddProven(t *testing.T) {
+	_, hostPublicKey := generateKeyPair(t)
+	_, otherHostPublicKey := generateKeyPair(t)
+	operatorPrivateKey, operatorPublicKey := generateKeyPair(t)
+
+	message, err := signHostedOperator(hostPublicKey, operatorPrivateKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directory := NewDirectory()
+
+	if err := directory.AddProven(otherHostPublicKey, message); err == nil {
+		t.Errorf("expected error for proof published by other host")
+	}
+	if _, ok := directory.HostOf(operatorPublicKey); ok {
+		t.Errorf("operator should not be hosted")
+	}
+
+	if err := directory.AddProven(hostPublicKey, message); err != nil {
+		t.Fatal(err)
+	}
+	assertHost(t, directory, operatorPublicKey, hostPublicKey)
+}
+
+func TestDirectoryRejectsReplayedProof(t *testing.T) {
+	_, oldHostPublicKey := generateKeyPair(t)
+	_, newHostPublicKey := generateKeyPair(t)
+	operatorPrivateKey, operatorPublicKey := generateKeyPair(t)
+
+	oldMessage, err := signHostedOperator(oldHostPublicKey, operatorPrivateKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMessage, err := signHostedOperator(newHostPublicKey, operatorPrivateKey, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiedMessage, err := signHostedOperator(oldHostPublicKey, operatorPrivateKey, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directory := NewDirectory()
+
+	if err := directory.AddProven(oldHostPublicKey, oldMessage); err != nil {
+		t.Fatal(err)
+	}
+	if err := directory.AddProven(newHostPublicKey, newMessage); err != nil {
+		t.Fatal(err)
+	}
+	assertHost(t, directory, operatorPublicKey, newHostPublicKey)
+
+	if err := directory.AddProven(oldHostPublicKey, oldMessage); err == nil {
+		t.Errorf("expected error for replayed proof of the previous host")
+	}
+	if err := directory.AddProven(oldHostPublicKey, tiedMessage); err == nil {
+		t.Errorf("expected error for proof of other host with the same timestamp")
+	}
+	assertHost(t, directory, operatorPublicKey, newHostPublicKey)
+
+	// The current host publishes its proof periodically.
+	if err := directory.AddProven(newHostPublicKey, newMessage); err != nil {
+		t.Errorf("unexpected error for republished proof: [%v]", err)
+	}
+	assertOperatorsCount(t, directory, oldHostPublicKey, 0)
+	assertOperatorsCount(t, directory, newHostPublicKey, 1)
+}
+
+func TestHostedOperatorsFilter(t *testing.T) {
+	_, hostPublicKey := generateKeyPair(t)
+	_, operatorPublicKey := generateKeyPair(t)
+	_, outsiderPublicKey := generateKeyPair(t)
+
+	directory := NewDirectory()
+	directory.Add(hostPublicKey, operatorPublicKey)
+
+	memberFilter := func(authorPublicKey *cecdsa.PublicKey) bool {
+		return reflect.DeepEqual(authorPublicKey, operatorPublicKey)
+	}
+
+	filter := createHostedOperatorsFilter(directory, memberFilter)
+
+	if !filter(operatorPublicKey) {
+		t.Errorf("message from operator should be accepted")
+	}
+	if !filter(hostPublicKey) {
+		t.Errorf("message from host of operator should be accepted")
+	}
+	if filter(outsiderPublicKey) {
+		t.Errorf("message from outsider should be rejected")
+	}
+}
+
+func TestProviderResolvesHostedOperator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hostPrivateKey, hostPublicKey := generateKeyPair(t)
+	operatorPrivateKey, operatorPublicKey := generateKeyPair(t)
+	peerPrivateKey, peerPublicKey := generateKeyPair(t)
+
+	hostProvider, err := NewProvider(
+		ctx,
+		connectLocal(hostPrivateKey, hostPublicKey),
+		hostPublicKey,
+		[]*operator.PrivateKey{hostPrivateKey, operatorPrivateKey},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerLocalProvider := connectLocal(peerPrivateKey, peerPublicKey)
+	peerProvider, err := NewProvider(ctx, peerLocalProvider, peerPublicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Proofs published before the peer subscribed are published again
+	// by the host; republish now instead of waiting for the next period.
+	channel, err := hostProvider.Provider.BroadcastChannelFor(channelName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registerUnmarshalers(channel); err != nil {
+		t.Fatal(err)
+	}
+	message, err := signHostedOperator(hostPublicKey, operatorPrivateKey, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := channel.Send(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTransportID, err := peerLocalProvider.CreateTransportIdentifier(
+		*hostPublicKey,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		transportID, err := peerProvider.CreateTransportIdentifier(
+			*operatorPublicKey,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if transportID.String() == expectedTransportID.String() {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf(
+				"unexpected transport identifier\nexpected: [%v]\nactual:   [%v]",
+				expectedTransportID,
+				transportID,
+			)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(peerProvider.Directory().OperatorsOf(hostPublicKey)) != 1 {
+		t.Errorf("host should act on behalf of exactly one other operator")
+	}
+}
+
+func generateKeyPair(t *testing.T) (*operator.PrivateKey, *operator.PublicKey) {
+	privateKey, publicKey, err := operator.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return privateKey, publicKey
+}
+
+func connectLocal(
+	privateKey *operator.PrivateKey,
+	publicKey *operator.PublicKey,
+) local.Provider {
+	_, networkPublicKey := key.OperatorKeyToNetworkKey(privateKey, publicKey)
+	return local.ConnectWithKey(networkPublicKey)
+}
+
+func assertHost(
+	t *testing.T,
+	directory *Directory,
+	operatorPublicKey *operator.PublicKey,
+	expectedHostPublicKey *operator.PublicKey,
+) {
+	hostPublicKey, ok := directory.HostOf(operatorPublicKey)
+	if !ok {
+		t.Fatalf("operator should be hosted")
+	}
+
+	if !reflect.DeepEqual(expectedHostPublicKey, hostPublicKey) {
+		t.Errorf(
+			"unexpected host\nexpected: [%v]\nactual:   [%v]",
+			expectedHostPublicKey,
+			hostPublicKey,
+		)
+	}
+}
+
+func assertOperatorsCount(
+	t *testing.T,
+	directory *Directory,
+	hostPublicKey *operator.PublicKey,
+	expectedCount int,
+) {
+	if count := len(directory.OperatorsOf(hostPublicKey)); count != expectedCount {
+		t.Errorf(
+			"unexpected number of hosted operators\nexpected: [%v]\nactual:   [%v]",
+			expectedCount,
+			count,
+		)
+	}
+}
+
+func TestProviderForwardsClosingChannels(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hostPrivateKey, hostPublicKey := generateKeyPair(t)
+	_, peerPublicKey := generateKeyPair(t)
+
+	closingProvider := &closingProvider{
+		Provider: connectLocal(hostPrivateKey, hostPublicKey),
+	}
+
+	provider, err := NewProvider(ctx, closingProvider, hostPublicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerID, err := closingProvider.CreateTransportIdentifier(*peerPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.CloseBroadcastChannel("keep"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.CloseUnicastChannel(peerID); err != nil {
+		t.Fatal(err)
+	}
+	// The in-process channel of the host with itself is not closed with
+	// the wrapped provider.
+	if err := provider.CloseUnicastChannel(closingProvider.ID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual([]string{"keep"}, closingProvider.closedBroadcastChannels) {
+		t.Errorf(
+			"unexpected closed broadcast channels: [%v]",
+			closingProvider.closedBroadcastChannels,
+		)
+	}
+	if len(closingProvider.closedUnicastChannels) != 1 {
+		t.Errorf(
+			"unexpected closed unicast channels: [%v]",
+			closingProvider.closedUnicastChannels,
+		)
+	}
+}
+
+// closingProvider records channels closed with the provider.
+type closingProvider struct {
+	net.Provider
+
+	closedBroadcastChannels []string
+	closedUnicastChannels   []net.TransportIdentifier
+}
+
+func (cp *closingProvider) CloseBroadcastChannel(name string) error {
+	cp.closedBroadcastChannels = append(cp.closedBroadcastChannels, name)
+	return nil
+}
+
+func (cp *closingProvider) CloseUnicastChannel(peerID net.TransportIdentifier) error {
+	cp.closedUnicastChannels = append(cp.closedUnicastChannels, peerID)
+	return nil
+}
+
+func TestKeyGenerationWithOperatorsOfTheSameHost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	hostPrivateKey, hostPublicKey := generateKeyPair(t)
+	hostedPrivateKey, hostedPublicKey := generateKeyPair(t)
+	_, peerPublicKey := generateKeyPair(t)
+
+	network := localnet.NewNetwork(1)
+	connect := func(publicKey *operator.PublicKey) net.Provider {
+		networkPublicKey := key.NetworkPublic(*publicKey)
+		return &noSelfDialProvider{network.Connect(&networkPublicKey)}
+	}
+
+	peerProvider, err := NewProvider(ctx, connect(peerPublicKey), peerPublicKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostProvider, err := NewProvider(
+		ctx,
+		connect(hostPublicKey),
+		hostPublicKey,
+		[]*operator.PrivateKey{hostPrivateKey, hostedPrivateKey},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(peerProvider.HostedOperators(hostPublicKey)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("peer should learn about the hosted operator")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Two of three keep members act through the same host.
+	operatorsPublicKeys := []*operator.PublicKey{
+		hostPublicKey,
+		hostedPublicKey,
+		peerPublicKey,
+	}
+	operatorsProviders := []net.Provider{hostProvider, hostProvider, peerProvider}
+
+	groupMemberIDs := make([]tss.MemberID, len(operatorsPublicKeys))
+	for i, publicKey := range operatorsPublicKeys {
+		groupMemberIDs[i] = tss.MemberIDFromPublicKey(publicKey)
+	}
+
+	testData, err := testdata.LoadKeygenTestFixtures(len(groupMemberIDs))
+	if err != nil {
+		t.Fatalf("failed to load test data: [%v]", err)
+	}
+
+	groupID := fmt.Sprintf("identity-test-%d", time.Now().UnixNano())
+	networkRetryPolicy := (&retry.Config{}).Policy(retry.Network)
+
+	signers := make([]*tss.ThresholdSigner, len(groupMemberIDs))
+	keyGenErrors := make([]error, len(groupMemberIDs))
+
+	var wg sync.WaitGroup
+	wg.Add(len(groupMemberIDs))
+	for i, memberID := range groupMemberIDs {
+		go func(i int, memberID tss.MemberID) {
+			defer wg.Done()
+
+			preParams := testData[i].LocalPreParams
+			signers[i], keyGenErrors[i] = tss.GenerateThresholdSigner(
+				ctx,
+				groupID,
+				memberID,
+				groupMemberIDs,
+				uint(len(groupMemberIDs)-1),
+				operatorsProviders[i],
+				networkRetryPolicy,
+				params.NewBox(&preParams),
+			)
+		}(i, memberID)
+	}
+	wg.Wait()
+
+	for i, err := range keyGenErrors {
+		if err != nil {
+			t.Fatalf("key generation failed for member [%d]: [%v]", i, err)
+		}
+	}
+
+	for i, signer := range signers[1:] {
+		if !reflect.DeepEqual(signers[0].PublicKey(), signer.PublicKey()) {
+			t.Errorf("member [%d] generated a different public key", i+1)
+		}
+	}
+}
+
+// noSelfDialProvider rejects unicast channels with itself, as the libp2p
+// provider does since it can not open a stream with its own host.
+type noSelfDialProvider struct {
+	net.Provider
+}
+
+func (nsdp *noSelfDialProvider) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	if peerID.String() == nsdp.ID().String() {
+		return nil, fmt.Errorf("can not dial to self")
+	}
+
+	return nsdp.Provider.UnicastChannelWith(peerID)
+}
@@ -0,0 +1,170 @@
+package identity
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/keep-network/keep-core/pkg/net"
+)
+
+// loopbackHandlerThrottle is the number of messages buffered for each handler
+// of the loopback channel. Messages are dropped if the handler is too slow to
+// process them.
+const loopbackHandlerThrottle = 256
+
+// loopbackChannel is a unicast channel of the host with itself. Operators
+// acting through the same host are members of the same keep just like any
+// other operators and exchange unicast messages with each other, but the host
+// can not open a network stream with itself. Messages sent on the channel are
+// delivered in-process to all handlers registered on it.
+type loopbackChannel struct {
+	// counter is the first field so that it is 64-bit aligned for atomic
+	// operations.
+	counter         uint64
+	transportID     net.TransportIdentifier
+	senderPublicKey []byte
+
+	mutex              sync.Mutex
+	handlers           []*loopbackHandler
+	unmarshalersByType map[string]func() net.TaggedUnmarshaler
+}
+
+type loopbackHandler struct {
+	channel chan net.Message
+}
+
+func newLoopbackChannel(
+	transportID net.TransportIdentifier,
+	senderPublicKey []byte,
+) *loopbackChannel {
+	return &loopbackChannel{
+		transportID:        transportID,
+		senderPublicKey:    senderPublicKey,
+		unmarshalersByType: make(map[string]func() net.TaggedUnmarshaler),
+	}
+}
+
+// Send delivers the message to all handlers registered on the channel.
+// The message is marshaled and unmarshaled as if it was sent over the network
+// so that handlers do not share the sent object.
+func (lc *loopbackChannel) Send(m net.TaggedMarshaler) error {
+	bytes, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	lc.mutex.Lock()
+	unmarshaler, ok := lc.unmarshalersByType[m.Type()]
+	handlers := make([]*loopbackHandler, len(lc.handlers))
+	copy(handlers, lc.handlers)
+	lc.mutex.Unlock()
+
+	if !ok {
+		logger.Debugf("no unmarshaler for message of type [%s]", m.Type())
+		return nil
+	}
+
+	seqno := atomic.AddUint64(&lc.counter, 1)
+
+	for _, handler := range handlers {
+		payload := unmarshaler()
+		if err := payload.Unmarshal(bytes); err != nil {
+			return err
+		}
+
+		message := &loopbackMessage{
+			transportSenderID: lc.transportID,
+			senderPublicKey:   lc.senderPublicKey,
+			payload:           payload,
+			messageType:       m.Type(),
+			seqno:             seqno,
+		}
+
+		select {
+		case handler.channel <- message:
+		default:
+			logger.Warningf("loopback handler too slow; dropping message")
+		}
+	}
+
+	return nil
+}
+
+// Recv installs the handler for the lifetime of the context.
+func (lc *loopbackChannel) Recv(ctx context.Context, handle func(m net.Message)) {
+	handler := &loopbackHandler{
+		channel: make(chan net.Message, loopbackHandlerThrottle),
+	}
+
+	lc.mutex.Lock()
+	lc.handlers = append(lc.handlers, handler)
+	lc.mutex.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				lc.removeHandler(handler)
+				return
+			case message := <-handler.channel:
+				// The handler must not be called once its context is done,
+				// even if a message has been received at the same time.
+				if ctx.Err() != nil {
+					continue
+				}
+
+				handle(message)
+			}
+		}
+	}()
+}
+
+func (lc *loopbackChannel) removeHandler(handler *loopbackHandler) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	for i, existing := range lc.handlers {
+		if existing == handler {
+			lc.handlers = append(lc.handlers[:i], lc.handlers[i+1:]...)
+			break
+		}
+	}
+}
+
+func (lc *loopbackChannel) SetUnmarshaler(
+	unmarshaler func() net.TaggedUnmarshaler,
+) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	lc.unmarshalersByType[unmarshaler().Type()] = unmarshaler
+}
+
+type loopbackMessage struct {
+	transportSenderID net.TransportIdentifier
+	senderPublicKey   []byte
+	payload           interface{}
+	messageType       string
+	seqno             uint64
+}
+
+func (lm *loopbackMessage) TransportSenderID() net.TransportIdentifier {
+	return lm.transportSenderID
+}
+
+func (lm *loopbackMessage) SenderPublicKey() []byte {
+	return lm.senderPublicKey
+}
+
+func (lm *loopbackMessage) Payload() interface{} {
+	return lm.payload
+}
+
+func (lm *loopbackMessage) Type() string {
+	return lm.messageType
+}
+
+func (lm *loopbackMessage) Seqno() uint64 {
+	return lm.seqno
+}
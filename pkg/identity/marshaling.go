@@ -0,0 +1,36 @@
+package identity
+
+import (
+	"fmt"
+
+	"github.com/keep-network/keep-core/pkg/operator"
+	"github.com/keep-network/keep-ecdsa/pkg/identity/gen/pb"
+)
+
+// Marshal converts HostedOperatorMessage to byte array.
+func (m *HostedOperatorMessage) Marshal() ([]byte, error) {
+	return (&pb.HostedOperatorMessage{
+		OperatorPublicKey: operator.Marshal(m.OperatorPublicKey),
+		Signature:         m.Signature,
+		Timestamp:         m.Timestamp,
+	}).Marshal()
+}
+
+// Unmarshal converts a byte array back to HostedOperatorMessage.
+func (m *HostedOperatorMessage) Unmarshal(bytes []byte) error {
+	pbMsg := pb.HostedOperatorMessage{}
+	if err := pbMsg.Unmarshal(bytes); err != nil {
+		return fmt.Errorf("failed to unmarshal hosted operator message: [%v]", err)
+	}
+
+	operatorPublicKey, err := operator.Unmarshal(pbMsg.GetOperatorPublicKey())
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal operator public key: [%v]", err)
+	}
+
+	m.OperatorPublicKey = operatorPublicKey
+	m.Signature = pbMsg.GetSignature()
+	m.Timestamp = pbMsg.GetTimestamp()
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package identity
+
+import (
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/operator"
+)
+
+// HostedOperatorMessage is a network message used by a host to prove that it
+// acts on behalf of the operator. The signature is calculated by the operator
+// over the public key of the host publishing the message and the timestamp
+// of the proof.
+type HostedOperatorMessage struct {
+	OperatorPublicKey *operator.PublicKey
+	Signature         []byte
+	Timestamp         uint64
+}
+
+// Type returns a string type of the `HostedOperatorMessage` so that it
+// conforms to `net.Message` interface.
+func (m *HostedOperatorMessage) Type() string {
+	return "ecdsa/hosted_operator_message"
+}
+
+func registerUnmarshalers(broadcastChannel net.BroadcastChannel) error {
+	return broadcastChannel.RegisterUnmarshaler(func() net.TaggedUnmarshaler {
+		return &HostedOperatorMessage{}
+	})
+}
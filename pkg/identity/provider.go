@@ -0,0 +1,283 @@
+// Package identity lets a single network host act on behalf of several
+// operators. Operators other than the one whose key is used by the host sign
+// the host public key with a timestamp and the host publishes the signatures
+// to other peers. Peers keep received proofs in a directory and use it to find
+// the host of an operator and to accept messages the host publishes for
+// operators it acts for. A proof is accepted only if it is newer than the
+// proof of the operator's current host.
+package identity
+
+import (
+	"context"
+	cecdsa "crypto/ecdsa"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/operator"
+
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+)
+
+var logger = log.Logger("keep-identity")
+
+const (
+	// channelName is the name of the broadcast channel on which hosts
+	// publish proofs for operators they act for.
+	channelName = "keep-ecdsa-hosted-operators"
+
+	// publicationPeriod determines how often the proofs are published.
+	// Proofs are published periodically so that peers which connected
+	// after the previous publication learn about hosted operators.
+	publicationPeriod = 30 * time.Second
+)
+
+// Provider is a network provider which lets the host act on behalf of
+// several operators. It routes unicast channels with hosted operators to
+// their hosts and makes broadcast channel filters accept messages published
+// by hosts for the operators they act for.
+//
+// Operators acting through the same host can be members of the same keep.
+// Broadcast messages the host publishes are delivered to its own subscribers
+// by the network and unicast messages between them are delivered in-process.
+type Provider struct {
+	net.Provider
+
+	directory *Directory
+	loopback  *loopbackChannel
+}
+
+// NewProvider wraps the network provider of the host with the given public
+// key. The host publishes proofs for all provided operators until the context
+// is done. Operators with the host public key do not need proofs and are
+// skipped.
+func NewProvider(
+	ctx context.Context,
+	provider net.Provider,
+	hostPublicKey *operator.PublicKey,
+	operators []*operator.PrivateKey,
+) (*Provider, error) {
+	directory := NewDirectory()
+
+	channel, err := provider.BroadcastChannelFor(channelName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize broadcast channel: [%v]", err)
+	}
+
+	if err := registerUnmarshalers(channel); err != nil {
+		return nil, fmt.Errorf("failed to register unmarshaler: [%v]", err)
+	}
+
+	channel.Recv(ctx, func(message net.Message) {
+		hostedOperatorMessage, ok := message.Payload().(*HostedOperatorMessage)
+		if !ok {
+			return
+		}
+
+		hostPublicKey, err := operator.Unmarshal(message.SenderPublicKey())
+		if err != nil {
+			logger.Warningf("failed to unmarshal host public key: [%v]", err)
+			return
+		}
+
+		if err := directory.AddProven(hostPublicKey, hostedOperatorMessage); err != nil {
+			logger.Warningf("rejecting hosted operator proof: [%v]", err)
+		}
+	})
+
+	hostAddress := crypto.PubkeyToAddress(*hostPublicKey)
+	timestamp := uint64(time.Now().UnixNano())
+
+	var messages []*HostedOperatorMessage
+	for _, operatorPrivateKey := range operators {
+		operatorPublicKey := &operatorPrivateKey.PublicKey
+		operatorAddress := crypto.PubkeyToAddress(*operatorPublicKey)
+		if operatorAddress == hostAddress {
+			continue
+		}
+
+		message, err := signHostedOperator(
+			hostPublicKey,
+			operatorPrivateKey,
+			timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to create proof for operator [%s]: [%v]",
+				operatorAddress.String(),
+				err,
+			)
+		}
+
+		if err := directory.AddProven(hostPublicKey, message); err != nil {
+			return nil, fmt.Errorf(
+				"failed to record proof for operator [%s]: [%v]",
+				operatorAddress.String(),
+				err,
+			)
+		}
+		messages = append(messages, message)
+
+		logger.Infof(
+			"host [%s] acts on behalf of operator [%s]",
+			hostAddress.String(),
+			operatorAddress.String(),
+		)
+	}
+
+	if len(messages) > 0 {
+		go publish(ctx, channel, messages)
+	}
+
+	return &Provider{
+		Provider:  provider,
+		directory: directory,
+		loopback: newLoopbackChannel(
+			provider.ID(),
+			operator.Marshal(hostPublicKey),
+		),
+	}, nil
+}
+
+// Directory returns the directory of hosted operators known to the provider.
+func (p *Provider) Directory() *Directory {
+	return p.directory
+}
+
+// HostedOperators returns public keys of operators on behalf of which
+// the host with the given public key acts.
+func (p *Provider) HostedOperators(
+	hostPublicKey *operator.PublicKey,
+) []*operator.PublicKey {
+	return p.directory.OperatorsOf(hostPublicKey)
+}
+
+// CreateTransportIdentifier creates a transport identifier of the host
+// acting on behalf of the operator with the provided public key.
+func (p *Provider) CreateTransportIdentifier(
+	publicKey cecdsa.PublicKey,
+) (net.TransportIdentifier, error) {
+	if hostPublicKey, ok := p.directory.HostOf(&publicKey); ok {
+		return p.Provider.CreateTransportIdentifier(*hostPublicKey)
+	}
+
+	return p.Provider.CreateTransportIdentifier(publicKey)
+}
+
+// UnicastChannelWith provides a unicast channel with the peer. The host does
+// not open a network stream with itself so the channel with its own transport
+// identifier, used by operators acting through the host to reach each other,
+// delivers messages in-process.
+func (p *Provider) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	if p.isHost(peerID) {
+		return p.loopback, nil
+	}
+
+	return p.Provider.UnicastChannelWith(peerID)
+}
+
+func (p *Provider) isHost(peerID net.TransportIdentifier) bool {
+	return peerID.String() == p.Provider.ID().String()
+}
+
+// CloseBroadcastChannel closes the broadcast channel with the wrapped provider
+// if it supports closing channels.
+func (p *Provider) CloseBroadcastChannel(name string) error {
+	if closer, ok := p.Provider.(channels.Closer); ok {
+		return closer.CloseBroadcastChannel(name)
+	}
+
+	return nil
+}
+
+// CloseUnicastChannel closes the unicast channel with the peer with
+// the wrapped provider if it supports closing channels. The in-process channel
+// of the host with itself is not closed; its handlers are removed once their
+// contexts are done.
+func (p *Provider) CloseUnicastChannel(peerID net.TransportIdentifier) error {
+	if p.isHost(peerID) {
+		return nil
+	}
+
+	if closer, ok := p.Provider.(channels.Closer); ok {
+		return closer.CloseUnicastChannel(peerID)
+	}
+
+	return nil
+}
+
+// BroadcastChannelFor provides a broadcast channel which accepts messages
+// published by hosts for the operators they act for.
+func (p *Provider) BroadcastChannelFor(name string) (net.BroadcastChannel, error) {
+	channel, err := p.Provider.BroadcastChannelFor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &broadcastChannel{
+		BroadcastChannel: channel,
+		directory:        p.directory,
+	}, nil
+}
+
+type broadcastChannel struct {
+	net.BroadcastChannel
+
+	directory *Directory
+}
+
+func (bc *broadcastChannel) SetFilter(filter net.BroadcastChannelFilter) error {
+	return bc.BroadcastChannel.SetFilter(
+		createHostedOperatorsFilter(bc.directory, filter),
+	)
+}
+
+// createHostedOperatorsFilter creates a filter accepting messages accepted by
+// the provided filter for the author or for any operator the author acts for.
+// Operators are checked first so that messages published by hosts for their
+// operators are not reported as rejected by the provided filter.
+func createHostedOperatorsFilter(
+	directory *Directory,
+	filter net.BroadcastChannelFilter,
+) net.BroadcastChannelFilter {
+	return func(authorPublicKey *cecdsa.PublicKey) bool {
+		for _, operatorPublicKey := range directory.OperatorsOf(authorPublicKey) {
+			if filter(operatorPublicKey) {
+				return true
+			}
+		}
+
+		return filter(authorPublicKey)
+	}
+}
+
+func publish(
+	ctx context.Context,
+	channel net.BroadcastChannel,
+	messages []*HostedOperatorMessage,
+) {
+	ticker := time.NewTicker(publicationPeriod)
+	defer ticker.Stop()
+
+	for {
+		// Retransmissions of the messages stop before the next publication.
+		publicationCtx, cancel := context.WithTimeout(ctx, publicationPeriod)
+		for _, message := range messages {
+			if err := channel.Send(publicationCtx, message); err != nil {
+				logger.Warningf("failed to publish hosted operator proof: [%v]", err)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+			cancel()
+		case <-ctx.Done():
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// Package index maintains a local index of keeps created by the factory,
+// built from on-chain events instead of per-keep contract calls.
+package index
+
+import "github.com/ipfs/go-log"
+
+var logger = log.Logger("keep-index")
+
+// Config contains configuration of the keeps index.
+type Config struct {
+	// Block from which the index starts scanning for keep events when there
+	// is no persisted index yet. Should be set to the block in which the keep
+	// factory has been deployed.
+	StartBlock uint64
+}
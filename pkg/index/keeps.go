@@ -0,0 +1,378 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-common/pkg/persistence"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+)
+
+// syncBatchSize is the maximum number of blocks queried for past events in
+// a single call. Nodes usually limit the range of blocks or the number of
+// logs returned for a single query so the catch-up is performed in batches.
+const syncBatchSize = 5000
+
+// Keep holds information about an active keep as seen in the keep creation
+// event.
+type Keep struct {
+	Address         common.Address
+	Members         []common.Address
+	Application     common.Address
+	HonestThreshold uint64
+	CreationBlock   uint64
+}
+
+// IsMember checks if the given address is one of the keep members.
+func (k *Keep) IsMember(address common.Address) bool {
+	for _, member := range k.Members {
+		if member == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Keeps is a local index of all active keeps created by the factory. The index
+// is built from keep created, closed and terminated events and is incrementally
+// updated with events from new blocks. The index is persisted along with the
+// first block not yet processed so that after a restart only new blocks
+// have to be scanned.
+//
+// Only blocks with the given number of confirmations are processed and
+// persisted. Events from more recent blocks are scanned again on each
+// synchronization and applied on top of the confirmed keeps, so a keep created
+// in a block reorganized out of the chain disappears from the index with
+// the next synchronization.
+type Keeps struct {
+	chain             eth.Handle
+	confirmationDepth uint64
+
+	keepsMutex *sync.RWMutex
+	// keeps are active keeps as seen in confirmed blocks.
+	keeps     map[common.Address]*Keep
+	nextBlock uint64
+	// latestKeeps are active keeps as seen in all blocks up to the current
+	// one.
+	latestKeeps map[common.Address]*Keep
+
+	syncMutex *sync.Mutex
+
+	storage storage
+}
+
+// NewKeepsIndex returns an empty keeps index. If there is no persisted index,
+// events will be scanned starting from the block provided in the config.
+// Blocks are persisted in the index once they get the given number of
+// confirmations.
+func NewKeepsIndex(
+	chain eth.Handle,
+	persistence persistence.Handle,
+	config *Config,
+	confirmationDepth uint64,
+) *Keeps {
+	return &Keeps{
+		chain:             chain,
+		confirmationDepth: confirmationDepth,
+		keepsMutex:        &sync.RWMutex{},
+		keeps:             make(map[common.Address]*Keep),
+		nextBlock:         config.StartBlock,
+		latestKeeps:       make(map[common.Address]*Keep),
+		syncMutex:         &sync.Mutex{},
+		storage:           newStorage(persistence),
+	}
+}
+
+// Load reads the index persisted in the storage. If nothing has been
+// persisted yet, the index stays empty.
+func (k *Keeps) Load() error {
+	snapshot, err := k.storage.read()
+	if err != nil {
+		return err
+	}
+
+	if snapshot == nil {
+		logger.Infof(
+			"no persisted keeps index found; events will be scanned "+
+				"starting from block [%v]",
+			k.nextBlock,
+		)
+		return nil
+	}
+
+	k.keepsMutex.Lock()
+	defer k.keepsMutex.Unlock()
+
+	for _, keep := range snapshot.Keeps {
+		k.keeps[keep.Address] = keep
+		k.latestKeeps[keep.Address] = keep
+	}
+	k.nextBlock = snapshot.NextBlock
+
+	logger.Infof(
+		"loaded [%d] active keeps from the index; "+
+			"scanning will resume from block [%v]",
+		len(k.keeps),
+		k.nextBlock,
+	)
+
+	return nil
+}
+
+// Sync catches up the index with the current block. Events from confirmed
+// blocks are fetched in batches and the index is persisted after each
+// processed batch. Events from blocks not confirmed yet are fetched at once
+// and are not persisted.
+func (k *Keeps) Sync() error {
+	k.syncMutex.Lock()
+	defer k.syncMutex.Unlock()
+
+	currentBlock, err := k.chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		return fmt.Errorf("failed to get current block: [%v]", err)
+	}
+
+	if currentBlock >= k.confirmationDepth {
+		confirmedBlock := currentBlock - k.confirmationDepth
+
+		for k.nextBlock <= confirmedBlock {
+			startBlock := k.nextBlock
+			endBlock := startBlock + syncBatchSize - 1
+			if endBlock > confirmedBlock {
+				endBlock = confirmedBlock
+			}
+
+			if err := k.processBlocks(startBlock, endBlock); err != nil {
+				return fmt.Errorf(
+					"failed to process blocks [%v-%v]: [%v]",
+					startBlock,
+					endBlock,
+					err,
+				)
+			}
+		}
+	}
+
+	if err := k.processUnconfirmedBlocks(currentBlock); err != nil {
+		return fmt.Errorf(
+			"failed to process unconfirmed blocks [%v-%v]: [%v]",
+			k.nextBlock,
+			currentBlock,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// Run keeps the index up to date by synchronizing it on each new block until
+// the context is done.
+func (k *Keeps) Run(ctx context.Context) {
+	newBlockChan := k.chain.BlockCounter().WatchBlocks(ctx)
+
+	for {
+		select {
+		case _, ok := <-newBlockChan:
+			if !ok {
+				return
+			}
+
+			if err := k.Sync(); err != nil {
+				logger.Errorf("failed to synchronize keeps index: [%v]", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// keepEvents are keep events emitted in a range of blocks.
+type keepEvents struct {
+	created    []*eth.BondedECDSAKeepCreatedEvent
+	closed     []*eth.KeepClosedEvent
+	terminated []*eth.KeepTerminatedEvent
+}
+
+func (k *Keeps) fetchEvents(startBlock, endBlock uint64) (*keepEvents, error) {
+	createdEvents, err := k.chain.PastBondedECDSAKeepCreatedEvents(
+		startBlock,
+		endBlock,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	closedEvents, err := k.chain.PastKeepClosedEvents(startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	terminatedEvents, err := k.chain.PastKeepTerminatedEvents(
+		startBlock,
+		endBlock,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keepEvents{
+		created:    createdEvents,
+		closed:     closedEvents,
+		terminated: terminatedEvents,
+	}, nil
+}
+
+// apply updates the given keeps with the events.
+func (ke *keepEvents) apply(keeps map[common.Address]*Keep) {
+	// A keep has to be created before it can be closed or terminated so we
+	// apply all creations from the range first.
+	for _, event := range ke.created {
+		keeps[event.KeepAddress] = &Keep{
+			Address:         event.KeepAddress,
+			Members:         event.Members,
+			Application:     event.Application,
+			HonestThreshold: event.HonestThreshold,
+			CreationBlock:   event.BlockNumber,
+		}
+	}
+
+	// Only addresses of known keeps are removed. Closed and terminated
+	// events are fetched for all addresses so they may contain logs emitted
+	// by contracts unrelated to the factory.
+	for _, event := range ke.closed {
+		delete(keeps, event.KeepAddress)
+	}
+	for _, event := range ke.terminated {
+		delete(keeps, event.KeepAddress)
+	}
+}
+
+func (k *Keeps) processBlocks(startBlock, endBlock uint64) error {
+	events, err := k.fetchEvents(startBlock, endBlock)
+	if err != nil {
+		return err
+	}
+
+	k.keepsMutex.Lock()
+
+	events.apply(k.keeps)
+	k.nextBlock = endBlock + 1
+
+	snapshot := k.snapshot()
+
+	k.keepsMutex.Unlock()
+
+	logger.Debugf(
+		"processed blocks [%v-%v] with [%d] created, [%d] closed and "+
+			"[%d] terminated keep events",
+		startBlock,
+		endBlock,
+		len(events.created),
+		len(events.closed),
+		len(events.terminated),
+	)
+
+	if err := k.storage.save(snapshot); err != nil {
+		logger.Errorf("could not persist keeps index: [%v]", err)
+	}
+
+	return nil
+}
+
+// processUnconfirmedBlocks replaces the latest keeps with the confirmed keeps
+// updated with events from blocks not confirmed yet, up to the given block.
+func (k *Keeps) processUnconfirmedBlocks(currentBlock uint64) error {
+	k.keepsMutex.RLock()
+	startBlock := k.nextBlock
+	latestKeeps := make(map[common.Address]*Keep, len(k.keeps))
+	for address, keep := range k.keeps {
+		latestKeeps[address] = keep
+	}
+	k.keepsMutex.RUnlock()
+
+	if startBlock <= currentBlock {
+		events, err := k.fetchEvents(startBlock, currentBlock)
+		if err != nil {
+			return err
+		}
+
+		events.apply(latestKeeps)
+	}
+
+	k.keepsMutex.Lock()
+	k.latestKeeps = latestKeeps
+	k.keepsMutex.Unlock()
+
+	return nil
+}
+
+func (k *Keeps) snapshot() *snapshot {
+	keeps := make([]*Keep, 0, len(k.keeps))
+	for _, keep := range k.keeps {
+		keeps = append(keeps, keep)
+	}
+
+	return &snapshot{
+		NextBlock: k.nextBlock,
+		Keeps:     keeps,
+	}
+}
+
+// ActiveKeeps returns all active keeps known to the index ordered from the
+// most recently created ones.
+func (k *Keeps) ActiveKeeps() []*Keep {
+	k.keepsMutex.RLock()
+	defer k.keepsMutex.RUnlock()
+
+	keeps := make([]*Keep, 0, len(k.latestKeeps))
+	for _, keep := range k.latestKeeps {
+		keeps = append(keeps, keep)
+	}
+
+	sort.SliceStable(keeps, func(i, j int) bool {
+		return keeps[i].CreationBlock > keeps[j].CreationBlock
+	})
+
+	return keeps
+}
+
+// Keep returns the active keep with the given address. The second returned
+// value is false if there is no such active keep in the index.
+func (k *Keeps) Keep(address common.Address) (*Keep, bool) {
+	k.keepsMutex.RLock()
+	defer k.keepsMutex.RUnlock()
+
+	keep, ok := k.latestKeeps[address]
+	return keep, ok
+}
+
+// IsActiveKeepMember checks if the given address is a member of at least one
+// active keep known to the index.
+func (k *Keeps) IsActiveKeepMember(address common.Address) bool {
+	k.keepsMutex.RLock()
+	defer k.keepsMutex.RUnlock()
+
+	for _, keep := range k.latestKeeps {
+		if keep.IsMember(address) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LastProcessedBlock returns the number of the last block processed by
+// the index.
+func (k *Keeps) LastProcessedBlock() uint64 {
+	k.keepsMutex.RLock()
+	defer k.keepsMutex.RUnlock()
+
+	if k.nextBlock == 0 {
+		return 0
+	}
+
+	return k.nextBlock - 1
+}
@@ -0,0 +1,402 @@
+package index
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-common/pkg/persistence"
+	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+)
+
+var (
+	keepAddress1 = common.HexToAddress("0x770a9E2F2Aa1eC2d3Ca916Fc3e6A55058A898632")
+	keepAddress2 = common.HexToAddress("0x8B3BccB3A3994681A1C1584DE4b4E8b23ed1Ed6d")
+	keepAddress3 = common.HexToAddress("0x0472ec0185ebb8202f3d4ddb0226998889663cf2")
+
+	member1 = common.HexToAddress("0x65ea55c1f10491038425725dc00dffeab2a1e28a")
+	member2 = common.HexToAddress("0x524f2e0176350d950fa630d9a5a59a0a190daf48")
+	member3 = common.HexToAddress("0x3365d0ed0f0d8d5a6c3c4cdc4a3b8b0e0e8b1a8d")
+)
+
+func TestSyncIndexesCreatedKeeps(t *testing.T) {
+	chain := local.Connect()
+	keepsIndex := NewKeepsIndex(chain, newPersistenceHandleMock(), &Config{}, 0)
+
+	chain.OpenKeep(keepAddress1, []common.Address{member1, member2})
+	waitForNextBlock(t, chain)
+	chain.OpenKeep(keepAddress2, []common.Address{member2, member3})
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	activeKeeps := keepsIndex.ActiveKeeps()
+	if len(activeKeeps) != 2 {
+		t.Fatalf(
+			"unexpected number of active keeps\nexpected: [%v]\nactual:   [%v]",
+			2,
+			len(activeKeeps),
+		)
+	}
+
+	// The most recently created keep is expected to be the first one.
+	expectedAddresses := []common.Address{keepAddress2, keepAddress1}
+	actualAddresses := []common.Address{
+		activeKeeps[0].Address,
+		activeKeeps[1].Address,
+	}
+	if !reflect.DeepEqual(expectedAddresses, actualAddresses) {
+		t.Errorf(
+			"unexpected active keeps\nexpected: [%v]\nactual:   [%v]",
+			expectedAddresses,
+			actualAddresses,
+		)
+	}
+
+	expectedMembers := []common.Address{member2, member3}
+	if !reflect.DeepEqual(expectedMembers, activeKeeps[0].Members) {
+		t.Errorf(
+			"unexpected keep members\nexpected: [%v]\nactual:   [%v]",
+			expectedMembers,
+			activeKeeps[0].Members,
+		)
+	}
+
+	if activeKeeps[0].HonestThreshold != 2 {
+		t.Errorf(
+			"unexpected honest threshold\nexpected: [%v]\nactual:   [%v]",
+			2,
+			activeKeeps[0].HonestThreshold,
+		)
+	}
+
+	for _, member := range []common.Address{member1, member2, member3} {
+		if !keepsIndex.IsActiveKeepMember(member) {
+			t.Errorf("expected [%v] to be an active keep member", member.String())
+		}
+	}
+}
+
+func TestSyncRemovesClosedAndTerminatedKeeps(t *testing.T) {
+	chain := local.Connect()
+	keepsIndex := NewKeepsIndex(chain, newPersistenceHandleMock(), &Config{}, 0)
+
+	chain.OpenKeep(keepAddress1, []common.Address{member1})
+	chain.OpenKeep(keepAddress2, []common.Address{member2})
+	chain.OpenKeep(keepAddress3, []common.Address{member3})
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	if err := chain.CloseKeep(keepAddress1); err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.TerminateKeep(keepAddress2); err != nil {
+		t.Fatal(err)
+	}
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	activeKeeps := keepsIndex.ActiveKeeps()
+	if len(activeKeeps) != 1 || activeKeeps[0].Address != keepAddress3 {
+		t.Fatalf(
+			"unexpected active keeps\nexpected: [%v]\nactual:   [%v]",
+			[]common.Address{keepAddress3},
+			activeKeeps,
+		)
+	}
+
+	if keepsIndex.IsActiveKeepMember(member1) {
+		t.Errorf("member of closed keep should not be an active keep member")
+	}
+	if keepsIndex.IsActiveKeepMember(member2) {
+		t.Errorf("member of terminated keep should not be an active keep member")
+	}
+}
+
+func TestLoadPersistedIndex(t *testing.T) {
+	chain := local.Connect()
+	persistenceMock := newPersistenceHandleMock()
+
+	keepsIndex := NewKeepsIndex(chain, persistenceMock, &Config{}, 0)
+
+	chain.OpenKeep(keepAddress1, []common.Address{member1, member2})
+	chain.OpenKeep(keepAddress2, []common.Address{member3})
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	loadedIndex := NewKeepsIndex(chain, persistenceMock, &Config{}, 0)
+	if err := loadedIndex.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if loadedIndex.LastProcessedBlock() != keepsIndex.LastProcessedBlock() {
+		t.Errorf(
+			"unexpected last processed block\nexpected: [%v]\nactual:   [%v]",
+			keepsIndex.LastProcessedBlock(),
+			loadedIndex.LastProcessedBlock(),
+		)
+	}
+
+	if !reflect.DeepEqual(keepsIndex.ActiveKeeps(), loadedIndex.ActiveKeeps()) {
+		t.Errorf(
+			"unexpected active keeps\nexpected: [%v]\nactual:   [%v]",
+			keepsIndex.ActiveKeeps(),
+			loadedIndex.ActiveKeeps(),
+		)
+	}
+}
+
+func TestLoadWithNoPersistedIndex(t *testing.T) {
+	chain := local.Connect()
+
+	keepsIndex := NewKeepsIndex(
+		chain,
+		newPersistenceHandleMock(),
+		&Config{StartBlock: 10},
+		0,
+	)
+	if err := keepsIndex.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keepsIndex.ActiveKeeps()) != 0 {
+		t.Errorf("expected no active keeps")
+	}
+
+	if keepsIndex.LastProcessedBlock() != 9 {
+		t.Errorf(
+			"unexpected last processed block\nexpected: [%v]\nactual:   [%v]",
+			9,
+			keepsIndex.LastProcessedBlock(),
+		)
+	}
+}
+
+func TestSyncStartsFromConfiguredBlock(t *testing.T) {
+	chain := local.Connect()
+
+	chain.OpenKeep(keepAddress1, []common.Address{member1})
+	waitForNextBlock(t, chain)
+
+	startBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain.OpenKeep(keepAddress2, []common.Address{member2})
+
+	keepsIndex := NewKeepsIndex(
+		chain,
+		newPersistenceHandleMock(),
+		&Config{StartBlock: startBlock + 1},
+		0,
+	)
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	activeKeeps := keepsIndex.ActiveKeeps()
+	if len(activeKeeps) != 1 || activeKeeps[0].Address != keepAddress2 {
+		t.Fatalf(
+			"unexpected active keeps\nexpected: [%v]\nactual:   [%v]",
+			[]common.Address{keepAddress2},
+			activeKeeps,
+		)
+	}
+}
+
+func TestSyncPersistsOnlyConfirmedBlocks(t *testing.T) {
+	chain := local.Connect()
+	persistenceMock := newPersistenceHandleMock()
+
+	keepsIndex := NewKeepsIndex(chain, persistenceMock, &Config{}, 2)
+
+	chain.OpenKeep(keepAddress1, []common.Address{member1})
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	if _, ok := keepsIndex.Keep(keepAddress1); !ok {
+		t.Fatalf("keep from unconfirmed block should be active")
+	}
+
+	loadedIndex := NewKeepsIndex(chain, persistenceMock, &Config{}, 2)
+	if err := loadedIndex.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadedIndex.Keep(keepAddress1); ok {
+		t.Errorf("keep from unconfirmed block should not be persisted")
+	}
+
+	waitForNextBlock(t, chain)
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	loadedIndex = NewKeepsIndex(chain, persistenceMock, &Config{}, 2)
+	if err := loadedIndex.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadedIndex.Keep(keepAddress1); !ok {
+		t.Errorf("keep from confirmed block should be persisted")
+	}
+}
+
+func TestSyncDropsKeepsOfReorganizedBlocks(t *testing.T) {
+	chain := &reorganizingChain{
+		Chain:        local.Connect(),
+		mutex:        &sync.Mutex{},
+		droppedKeeps: make(map[common.Address]bool),
+	}
+
+	keepsIndex := NewKeepsIndex(
+		chain,
+		newPersistenceHandleMock(),
+		&Config{},
+		2,
+	)
+
+	chain.OpenKeep(keepAddress1, []common.Address{member1})
+	chain.OpenKeep(keepAddress2, []common.Address{member2})
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	if len(keepsIndex.ActiveKeeps()) != 2 {
+		t.Fatalf("keeps from unconfirmed blocks should be active")
+	}
+
+	chain.dropKeep(keepAddress1)
+
+	syncKeepsIndex(t, chain, keepsIndex)
+
+	activeKeeps := keepsIndex.ActiveKeeps()
+	if len(activeKeeps) != 1 || activeKeeps[0].Address != keepAddress2 {
+		t.Fatalf(
+			"unexpected active keeps\nexpected: [%v]\nactual:   [%v]",
+			[]common.Address{keepAddress2},
+			activeKeeps,
+		)
+	}
+
+	if keepsIndex.IsActiveKeepMember(member1) {
+		t.Errorf("member of reorganized keep should not be an active keep member")
+	}
+}
+
+// reorganizingChain drops creation events of keeps as if the blocks in which
+// they were created have been reorganized out of the chain.
+type reorganizingChain struct {
+	local.Chain
+
+	mutex        *sync.Mutex
+	droppedKeeps map[common.Address]bool
+}
+
+func (rc *reorganizingChain) dropKeep(keepAddress common.Address) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	rc.droppedKeeps[keepAddress] = true
+}
+
+func (rc *reorganizingChain) PastBondedECDSAKeepCreatedEvents(
+	startBlock uint64,
+	endBlock uint64,
+) ([]*eth.BondedECDSAKeepCreatedEvent, error) {
+	events, err := rc.Chain.PastBondedECDSAKeepCreatedEvents(startBlock, endBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	var result []*eth.BondedECDSAKeepCreatedEvent
+	for _, event := range events {
+		if !rc.droppedKeeps[event.KeepAddress] {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+// waitForNextBlock waits until the block in which local chain reports events
+// emitted so far is mined.
+func waitForNextBlock(t *testing.T, chain eth.Handle) {
+	currentBlock, err := chain.BlockCounter().CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chain.BlockCounter().WaitForBlockHeight(currentBlock + 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func syncKeepsIndex(t *testing.T, chain eth.Handle, keepsIndex *Keeps) {
+	waitForNextBlock(t, chain)
+
+	if err := keepsIndex.Sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type persistenceHandleMock struct {
+	mutex *sync.Mutex
+	files map[string]*testDataDescriptor
+}
+
+func newPersistenceHandleMock() *persistenceHandleMock {
+	return &persistenceHandleMock{
+		mutex: &sync.Mutex{},
+		files: make(map[string]*testDataDescriptor),
+	}
+}
+
+func (phm *persistenceHandleMock) Save(data []byte, directory string, name string) error {
+	phm.mutex.Lock()
+	defer phm.mutex.Unlock()
+
+	name = strings.TrimPrefix(name, "/")
+	phm.files[directory+"/"+name] = &testDataDescriptor{name, directory, data}
+
+	return nil
+}
+
+func (phm *persistenceHandleMock) ReadAll() (<-chan persistence.DataDescriptor, <-chan error) {
+	phm.mutex.Lock()
+	defer phm.mutex.Unlock()
+
+	outputData := make(chan persistence.DataDescriptor, len(phm.files))
+	outputErrors := make(chan error)
+
+	for _, file := range phm.files {
+		outputData <- file
+	}
+
+	close(outputData)
+	close(outputErrors)
+
+	return outputData, outputErrors
+}
+
+func (phm *persistenceHandleMock) Archive(directory string) error {
+	return nil
+}
+
+type testDataDescriptor struct {
+	name      string
+	directory string
+	content   []byte
+}
+
+func (tdd *testDataDescriptor) Name() string {
+	return tdd.name
+}
+
+func (tdd *testDataDescriptor) Directory() string {
+	return tdd.directory
+}
+
+func (tdd *testDataDescriptor) Content() ([]byte, error) {
+	return tdd.content, nil
+}
@@ -0,0 +1,104 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/keep-network/keep-common/pkg/persistence"
+)
+
+const (
+	snapshotDirectory = "index"
+	snapshotName      = "keeps"
+)
+
+// snapshot is the persisted form of the index. It holds all the keeps known
+// to the index and the first block not yet processed by the index.
+type snapshot struct {
+	NextBlock uint64
+	Keeps     []*Keep
+}
+
+type storage interface {
+	save(snapshot *snapshot) error
+	read() (*snapshot, error)
+}
+
+type persistentStorage struct {
+	handle persistence.Handle
+}
+
+func newStorage(persistence persistence.Handle) storage {
+	return &persistentStorage{
+		handle: persistence,
+	}
+}
+
+func (ps *persistentStorage) save(snapshot *snapshot) error {
+	snapshotBytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index snapshot: [%v]", err)
+	}
+
+	return ps.handle.Save(
+		snapshotBytes,
+		snapshotDirectory,
+		"/"+snapshotName,
+	)
+}
+
+// read returns the persisted snapshot or nil if nothing has been persisted
+// so far.
+func (ps *persistentStorage) read() (*snapshot, error) {
+	inputData, inputErrors := ps.handle.ReadAll()
+
+	var (
+		result    *snapshot
+		resultErr error
+	)
+
+	// Both channels have to be drained until they are closed, no matter if
+	// the snapshot has been already found, so that the persistence layer does
+	// not block on a write.
+	for inputData != nil || inputErrors != nil {
+		select {
+		case descriptor, ok := <-inputData:
+			if !ok {
+				inputData = nil
+				continue
+			}
+
+			if descriptor.Directory() != snapshotDirectory ||
+				descriptor.Name() != snapshotName {
+				continue
+			}
+
+			content, err := descriptor.Content()
+			if err != nil {
+				resultErr = fmt.Errorf(
+					"failed to decode index snapshot content: [%v]",
+					err,
+				)
+				continue
+			}
+
+			result = &snapshot{}
+			if err := json.Unmarshal(content, result); err != nil {
+				result = nil
+				resultErr = fmt.Errorf(
+					"failed to unmarshal index snapshot: [%v]",
+					err,
+				)
+			}
+		case err, ok := <-inputErrors:
+			if !ok {
+				inputErrors = nil
+				continue
+			}
+
+			logger.Warningf("could not read from the index storage: [%v]", err)
+		}
+	}
+
+	return result, resultErr
+}
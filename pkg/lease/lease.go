@@ -0,0 +1,253 @@
+// Package lease implements an active/passive high availability of an operator
+// run on several machines. Nodes compete for a lease kept in storage shared by
+// all of them. Only the node holding the lease, the leader, may subscribe to
+// chain events and execute protocols. Other nodes stand by and take the lease
+// over once the leader stops renewing it.
+//
+// Expiration of the lease is never judged by comparing clocks of different
+// machines. A standby node considers the lease expired only if the record has
+// not changed for the whole lease duration measured by its own clock since it
+// observed the record. The leader considers itself the leader only until one
+// renewal period before the lease duration elapses, counting from the moment
+// it started the last successful renewal. Because the standby could not have
+// observed the renewed record before the renewal started, the leader always
+// steps down at least one renewal period before any standby may take the lease
+// over, which gives the leader time to abort protocols it is executing.
+package lease
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ipfs/go-log"
+
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+var logger = log.Logger("keep-lease")
+
+const (
+	// DefaultDuration is the lease duration used if no duration has been
+	// configured.
+	DefaultDuration = 30 * time.Second
+	// defaultRenewalPeriodDivisor determines the renewal period used if no
+	// period has been configured as a fraction of the lease duration.
+	defaultRenewalPeriodDivisor = 3
+)
+
+// errLeaseLost is returned when the record shows the lease has been taken
+// over by another node.
+var errLeaseLost = fmt.Errorf("lease has been taken over by another node")
+
+// Config contains the lease configuration.
+type Config struct {
+	// File is the path to the lease file on storage shared by all nodes of
+	// the operator. If not set, the lease is disabled and the node acts as
+	// the leader right away.
+	File string
+	// NodeID identifies the node. If not set, the host name and the process
+	// ID are used.
+	NodeID string
+	// Duration is the time for which the lease has to stay unchanged before
+	// a standby node takes it over. If not set, DefaultDuration is used.
+	Duration retry.Duration
+	// RenewalPeriod determines how often the leader renews the lease and how
+	// often standby nodes check it. It has to be shorter than half of
+	// the lease duration. If not set, a third of the lease duration is used.
+	RenewalPeriod retry.Duration
+}
+
+// IsEnabled returns true if the lease file has been configured.
+func (c *Config) IsEnabled() bool {
+	return c != nil && c.File != ""
+}
+
+// Lease lets the node compete for the leadership with other nodes of the same
+// operator.
+type Lease struct {
+	storage       Storage
+	nodeID        string
+	duration      time.Duration
+	renewalPeriod time.Duration
+}
+
+// New creates a lease kept in the provided storage.
+func New(storage Storage, config *Config) (*Lease, error) {
+	nodeID := config.NodeID
+	if nodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine host name: [%v]", err)
+		}
+		nodeID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	duration := config.Duration.Duration
+	if duration == 0 {
+		duration = DefaultDuration
+	}
+
+	renewalPeriod := config.RenewalPeriod.Duration
+	if renewalPeriod == 0 {
+		renewalPeriod = duration / defaultRenewalPeriodDivisor
+	}
+
+	if renewalPeriod <= 0 || 2*renewalPeriod >= duration {
+		return nil, fmt.Errorf(
+			"renewal period [%v] has to be shorter than half of the lease duration [%v]",
+			renewalPeriod,
+			duration,
+		)
+	}
+
+	return &Lease{
+		storage:       storage,
+		nodeID:        nodeID,
+		duration:      duration,
+		renewalPeriod: renewalPeriod,
+	}, nil
+}
+
+// NodeID returns the identifier of the node.
+func (l *Lease) NodeID() string {
+	return l.nodeID
+}
+
+// Acquire blocks until the node becomes the leader or until the provided
+// context is done. Once the node becomes the leader, the lease is renewed in
+// the background and the returned context is done when the node stops being
+// the leader: the lease has been taken over, the lease could not be renewed
+// in time or the provided context is done. All protocols executed by the
+// leader should be aborted when the returned context is done.
+func (l *Lease) Acquire(ctx context.Context) (context.Context, error) {
+	var (
+		observed   *Record
+		observedAt time.Time
+	)
+
+	ticker := time.NewTicker(l.renewalPeriod)
+	defer ticker.Stop()
+
+	for {
+		attemptStart := time.Now()
+
+		var term uint64
+		err := l.storage.Update(func(current *Record) (*Record, error) {
+			if current != nil && current.Holder != "" {
+				if observed == nil || *observed != *current {
+					observed = current
+					observedAt = time.Now()
+					return nil, nil
+				}
+
+				if time.Since(observedAt) < l.duration {
+					return nil, nil
+				}
+
+				logger.Warningf(
+					"lease held by [%s] has not been renewed for [%v]; taking over",
+					current.Holder,
+					l.duration,
+				)
+			}
+
+			updated := &Record{Holder: l.nodeID, Term: 1, Version: 1}
+			if current != nil {
+				updated.Term = current.Term + 1
+				updated.Version = current.Version + 1
+			}
+
+			term = updated.Term
+			return updated, nil
+		})
+		if err != nil {
+			logger.Errorf("failed to check lease: [%v]", err)
+		} else if term != 0 {
+			logger.Infof("node [%s] acquired lease in term [%d]", l.nodeID, term)
+
+			leaderCtx, cancel := context.WithCancel(ctx)
+			go l.renew(leaderCtx, cancel, term, attemptStart)
+
+			return leaderCtx, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// renew renews the lease of the given term until the context is done or
+// until the lease is lost. The cancel function is called as soon as the node
+// can no longer be sure it is the leader.
+func (l *Lease) renew(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	term uint64,
+	lastRenewalStart time.Time,
+) {
+	defer cancel()
+
+	ticker := time.NewTicker(l.renewalPeriod)
+	defer ticker.Stop()
+
+	validityTimer := time.NewTimer(l.validUntil(lastRenewalStart))
+	defer validityTimer.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-validityTimer.C:
+			logger.Errorf(
+				"node [%s] could not renew lease in term [%d] in time; stepping down",
+				l.nodeID,
+				term,
+			)
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		renewalStart := time.Now()
+
+		err := l.storage.Update(func(current *Record) (*Record, error) {
+			if current == nil || current.Holder != l.nodeID || current.Term != term {
+				return nil, errLeaseLost
+			}
+
+			return &Record{
+				Holder:  l.nodeID,
+				Term:    term,
+				Version: current.Version + 1,
+			}, nil
+		})
+		if err == errLeaseLost {
+			logger.Errorf(
+				"node [%s] lost lease in term [%d]; stepping down",
+				l.nodeID,
+				term,
+			)
+			return
+		}
+		if err != nil {
+			logger.Warningf("failed to renew lease: [%v]", err)
+			continue
+		}
+
+		if !validityTimer.Stop() {
+			// The timer fired while the lease was being renewed.
+			<-validityTimer.C
+		}
+		validityTimer.Reset(l.validUntil(renewalStart))
+	}
+}
+
+// validUntil returns the time left until the leadership confirmed by
+// the renewal started at the given time has to be given up.
+func (l *Lease) validUntil(renewalStart time.Time) time.Duration {
+	return time.Until(renewalStart.Add(l.duration - l.renewalPeriod))
+}
@@ -0,0 +1,286 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+const (
+	testDuration      = 300 * time.Millisecond
+	testRenewalPeriod = 50 * time.Millisecond
+)
+
+func TestAcquireFreeLease(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	lease := newTestLease(t, storage, "node-1")
+
+	start := time.Now()
+	leaderCtx, err := lease.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > testRenewalPeriod {
+		t.Errorf("free lease should be acquired right away; took [%v]", elapsed)
+	}
+
+	select {
+	case <-leaderCtx.Done():
+		t.Fatal("leader context should not be done")
+	default:
+	}
+}
+
+func TestStandbyWaitsForLeader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	leaderCtx, err := newTestLease(t, storage, "node-1").Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	standbyCtx, cancelStandby := context.WithTimeout(ctx, 4*testDuration)
+	defer cancelStandby()
+
+	if _, err := newTestLease(t, storage, "node-2").Acquire(standbyCtx); err == nil {
+		t.Fatal("standby should not acquire lease renewed by the leader")
+	}
+
+	select {
+	case <-leaderCtx.Done():
+		t.Fatal("leader context should not be done")
+	default:
+	}
+}
+
+func TestStandbyTakesOverWhenLeaderStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	leaderParentCtx, stopLeader := context.WithCancel(ctx)
+	leaderCtx, err := newTestLease(t, storage, "node-1").Acquire(leaderParentCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	standbyResult := make(chan error, 1)
+	go func() {
+		_, err := newTestLease(t, storage, "node-2").Acquire(ctx)
+		standbyResult <- err
+	}()
+
+	time.Sleep(testDuration)
+	stopLeader()
+	stoppedAt := time.Now()
+
+	<-leaderCtx.Done()
+
+	select {
+	case err := <-standbyResult:
+		if err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(stoppedAt); elapsed < testDuration-testRenewalPeriod {
+			t.Errorf("lease taken over too early; after [%v]", elapsed)
+		}
+	case <-time.After(4 * testDuration):
+		t.Fatal("standby should take the lease over")
+	}
+}
+
+func TestLeaderStepsDownBeforeTakeOver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	// The leader loses access to the shared storage, so it is still running
+	// but can not renew the lease.
+	leaderStorage := &failingStorage{Storage: storage}
+	leaderCtx, err := newTestLease(t, leaderStorage, "node-1").Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	var leaderSteppedDownAt time.Time
+	go func() {
+		<-leaderCtx.Done()
+
+		mutex.Lock()
+		leaderSteppedDownAt = time.Now()
+		mutex.Unlock()
+	}()
+
+	standbyResult := make(chan error, 1)
+	go func() {
+		_, err := newTestLease(t, storage, "node-2").Acquire(ctx)
+		standbyResult <- err
+	}()
+
+	time.Sleep(testDuration)
+	leaderStorage.fail()
+
+	select {
+	case err := <-standbyResult:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(4 * testDuration):
+		t.Fatal("standby should take the lease over")
+	}
+	takenOverAt := time.Now()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if leaderSteppedDownAt.IsZero() || !leaderSteppedDownAt.Before(takenOverAt) {
+		t.Errorf("leader should step down before the lease is taken over")
+	}
+}
+
+func TestLeaderStepsDownWhenLeaseLost(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	leaderCtx, err := newTestLease(t, storage, "node-1").Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Update(func(current *Record) (*Record, error) {
+		return &Record{
+			Holder:  "node-2",
+			Term:    current.Term + 1,
+			Version: current.Version + 1,
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-leaderCtx.Done():
+	case <-time.After(2 * testRenewalPeriod):
+		t.Fatal("leader should step down once the lease is lost")
+	}
+}
+
+func TestNewValidatesRenewalPeriod(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	_, err := New(storage, &Config{
+		NodeID:        "node-1",
+		Duration:      retry.Duration{Duration: time.Second},
+		RenewalPeriod: retry.Duration{Duration: 500 * time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("expected error for too long renewal period")
+	}
+}
+
+func TestFileStorage(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	err := storage.Update(func(current *Record) (*Record, error) {
+		if current != nil {
+			return nil, fmt.Errorf("unexpected record: [%+v]", current)
+		}
+		return &Record{Holder: "node-1", Term: 1, Version: 1}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Update(func(current *Record) (*Record, error) {
+		expected := Record{Holder: "node-1", Term: 1, Version: 1}
+		if current == nil || *current != expected {
+			return nil, fmt.Errorf(
+				"unexpected record\nexpected: [%+v]\nactual:   [%+v]",
+				expected,
+				current,
+			)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestStorage(t *testing.T) (*FileStorage, func()) {
+	dir, err := ioutil.TempDir("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewFileStorage(filepath.Join(dir, "lease")), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestLease(t *testing.T, storage Storage, nodeID string) *Lease {
+	lease, err := New(storage, &Config{
+		NodeID:        nodeID,
+		Duration:      retry.Duration{Duration: testDuration},
+		RenewalPeriod: retry.Duration{Duration: testRenewalPeriod},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lease
+}
+
+type failingStorage struct {
+	Storage
+
+	mutex   sync.Mutex
+	failing bool
+}
+
+func (fs *failingStorage) fail() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.failing = true
+}
+
+func (fs *failingStorage) Update(
+	update func(current *Record) (*Record, error),
+) error {
+	fs.mutex.Lock()
+	failing := fs.failing
+	fs.mutex.Unlock()
+
+	if failing {
+		return fmt.Errorf("storage is not available")
+	}
+
+	return fs.Storage.Update(update)
+}
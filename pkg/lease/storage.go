@@ -0,0 +1,98 @@
+package lease
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"syscall"
+)
+
+// Record is the lease record kept in the shared storage.
+type Record struct {
+	// Holder identifies the node holding the lease. Empty if the lease is
+	// not held by any node.
+	Holder string
+	// Term is incremented every time the lease is taken over by a node.
+	Term uint64
+	// Version is incremented on every change of the record, including
+	// renewals of the lease by its holder.
+	Version uint64
+}
+
+// Storage is the storage shared by all nodes of the operator in which the
+// lease record is kept.
+type Storage interface {
+	// Update atomically reads the current record and replaces it with
+	// the record returned by the update function. The current record is nil
+	// if no record has been stored yet. If the update function returns a nil
+	// record or an error, the stored record is not changed. An error returned
+	// by the update function is returned from Update.
+	Update(update func(current *Record) (*Record, error)) error
+}
+
+// FileStorage keeps the lease record in a file. Updates are serialized with
+// an exclusive advisory lock on the file, so the file has to be placed on
+// storage supporting locks shared by all nodes.
+type FileStorage struct {
+	path string
+}
+
+// NewFileStorage creates a storage keeping the lease record in the file at
+// the given path. The file is created when the record is stored for the
+// first time.
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{path: path}
+}
+
+// Update atomically updates the lease record stored in the file.
+func (fs *FileStorage) Update(update func(current *Record) (*Record, error)) error {
+	file, err := os.OpenFile(fs.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open lease file: [%v]", err)
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock lease file: [%v]", err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read lease file: [%v]", err)
+	}
+
+	var current *Record
+	if len(content) > 0 {
+		current = &Record{}
+		if err := json.Unmarshal(content, current); err != nil {
+			return fmt.Errorf("failed to unmarshal lease record: [%v]", err)
+		}
+	}
+
+	updated, err := update(current)
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		return nil
+	}
+
+	content, err = json.Marshal(updated)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lease record: [%v]", err)
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate lease file: [%v]", err)
+	}
+	if _, err := file.WriteAt(content, 0); err != nil {
+		return fmt.Errorf("failed to write lease file: [%v]", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync lease file: [%v]", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package localnet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/net/retransmission"
+)
+
+// messageHandlerThrottle is the number of messages buffered for each handler.
+// Messages are dropped if the handler is too slow to process them.
+const messageHandlerThrottle = 256
+
+type message struct {
+	transportSenderID net.TransportIdentifier
+	senderPublicKey   []byte
+	payload           interface{}
+	messageType       string
+	seqno             uint64
+}
+
+func (m *message) TransportSenderID() net.TransportIdentifier {
+	return m.transportSenderID
+}
+
+func (m *message) SenderPublicKey() []byte {
+	return m.senderPublicKey
+}
+
+func (m *message) Payload() interface{} {
+	return m.payload
+}
+
+func (m *message) Type() string {
+	return m.messageType
+}
+
+func (m *message) Seqno() uint64 {
+	return m.seqno
+}
+
+// wireMessage is a message as transmitted by the network, before it is
+// unmarshaled by the receiver.
+type wireMessage struct {
+	sender      *provider
+	messageType string
+	bytes       []byte
+	seqno       uint64
+}
+
+// handlers dispatches received messages to handlers registered for
+// the lifetime of their contexts, as channels of a real network do.
+type handlers struct {
+	mutex              sync.Mutex
+	registered         []*messageHandler
+	unmarshalersByType map[string]func() net.TaggedUnmarshaler
+}
+
+type messageHandler struct {
+	ctx     context.Context
+	channel chan net.Message
+}
+
+func newHandlers() *handlers {
+	return &handlers{
+		unmarshalersByType: make(map[string]func() net.TaggedUnmarshaler),
+	}
+}
+
+func (h *handlers) setUnmarshaler(unmarshaler func() net.TaggedUnmarshaler) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.unmarshalersByType[unmarshaler().Type()] = unmarshaler
+}
+
+func (h *handlers) add(ctx context.Context, handle func(m net.Message)) {
+	handler := &messageHandler{
+		ctx:     ctx,
+		channel: make(chan net.Message, messageHandlerThrottle),
+	}
+
+	h.mutex.Lock()
+	h.registered = append(h.registered, handler)
+	h.mutex.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				h.remove(handler)
+				return
+			case msg := <-handler.channel:
+				// The handler must not be called once its context is done,
+				// even if a message has been received at the same time.
+				if ctx.Err() != nil {
+					continue
+				}
+
+				handle(msg)
+			}
+		}
+	}()
+}
+
+func (h *handlers) remove(handler *messageHandler) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, existing := range h.registered {
+		if existing == handler {
+			h.registered = append(h.registered[:i], h.registered[i+1:]...)
+			break
+		}
+	}
+}
+
+// dispatch unmarshals the message and passes it to all registered handlers.
+func (h *handlers) dispatch(wireMsg *wireMessage) {
+	h.mutex.Lock()
+	unmarshaler, ok := h.unmarshalersByType[wireMsg.messageType]
+	snapshot := make([]*messageHandler, len(h.registered))
+	copy(snapshot, h.registered)
+	h.mutex.Unlock()
+
+	if !ok {
+		logger.Debugf(
+			"no unmarshaler for message of type [%s]",
+			wireMsg.messageType,
+		)
+		return
+	}
+
+	payload := unmarshaler()
+	if err := payload.Unmarshal(wireMsg.bytes); err != nil {
+		logger.Warningf(
+			"could not unmarshal message of type [%s]: [%v]",
+			wireMsg.messageType,
+			err,
+		)
+		return
+	}
+
+	msg := &message{
+		transportSenderID: wireMsg.sender.id,
+		senderPublicKey:   key.Marshal(wireMsg.sender.staticKey),
+		payload:           payload,
+		messageType:       wireMsg.messageType,
+		seqno:             wireMsg.seqno,
+	}
+
+	for _, handler := range snapshot {
+		select {
+		case handler.channel <- msg:
+		default:
+			logger.Warningf("handler too slow; dropping message")
+		}
+	}
+}
+
+type broadcastChannel struct {
+	// counter is the first field so that it is 64-bit aligned for atomic
+	// operations.
+	counter  uint64
+	name     string
+	provider *provider
+
+	handlers *handlers
+
+	filterMutex sync.Mutex
+	filter      net.BroadcastChannelFilter
+}
+
+func newBroadcastChannel(name string, provider *provider) *broadcastChannel {
+	return &broadcastChannel{
+		name:     name,
+		provider: provider,
+		handlers: newHandlers(),
+	}
+}
+
+func (bc *broadcastChannel) Name() string {
+	return bc.name
+}
+
+// Send delivers the message to all members subscribed to the channel,
+// including the sender. The message is retransmitted for the lifetime of
+// the context.
+func (bc *broadcastChannel) Send(ctx context.Context, m net.TaggedMarshaler) error {
+	bytes, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	wireMsg := &wireMessage{
+		sender:      bc.provider,
+		messageType: m.Type(),
+		bytes:       bytes,
+		seqno:       atomic.AddUint64(&bc.counter, 1),
+	}
+
+	send := func() error {
+		network := bc.provider.network
+		for _, subscriber := range network.broadcastSubscribers(bc.name) {
+			subscriber := subscriber
+			network.transmit(
+				bc.provider.id.String(),
+				subscriber.provider.id.String(),
+				func() { subscriber.deliver(wireMsg) },
+			)
+		}
+		return nil
+	}
+
+	retransmission.ScheduleRetransmissions(
+		ctx,
+		bc.provider.network.retransmissionTicker,
+		send,
+	)
+
+	return send()
+}
+
+func (bc *broadcastChannel) deliver(wireMsg *wireMessage) {
+	bc.filterMutex.Lock()
+	filter := bc.filter
+	bc.filterMutex.Unlock()
+
+	if filter != nil &&
+		!filter(key.NetworkKeyToECDSAKey(wireMsg.sender.staticKey)) {
+		return
+	}
+
+	bc.handlers.dispatch(wireMsg)
+}
+
+// Recv installs the handler for the lifetime of the context. Retransmissions
+// of messages already passed to the handler are filtered out.
+func (bc *broadcastChannel) Recv(ctx context.Context, handler func(m net.Message)) {
+	bc.handlers.add(ctx, retransmission.WithRetransmissionSupport(handler))
+}
+
+func (bc *broadcastChannel) RegisterUnmarshaler(
+	unmarshaler func() net.TaggedUnmarshaler,
+) error {
+	bc.handlers.setUnmarshaler(unmarshaler)
+	return nil
+}
+
+func (bc *broadcastChannel) SetFilter(filter net.BroadcastChannelFilter) error {
+	bc.filterMutex.Lock()
+	defer bc.filterMutex.Unlock()
+
+	bc.filter = filter
+	return nil
+}
+
+type unicastChannel struct {
+	counter  uint64
+	provider *provider
+	peer     *provider
+
+	handlers *handlers
+}
+
+func newUnicastChannel(provider *provider, peer *provider) *unicastChannel {
+	return &unicastChannel{
+		provider: provider,
+		peer:     peer,
+		handlers: newHandlers(),
+	}
+}
+
+// Send delivers the message to the peer's channel with the sender. An error
+// is returned if the peer is unreachable, as a real network would fail to
+// open a stream to the peer.
+func (uc *unicastChannel) Send(m net.TaggedMarshaler) error {
+	if !uc.provider.isReachable(uc.peer) {
+		return fmt.Errorf("peer [%s] is unreachable", uc.peer.id)
+	}
+
+	bytes, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	wireMsg := &wireMessage{
+		sender:      uc.provider,
+		messageType: m.Type(),
+		bytes:       bytes,
+		seqno:       atomic.AddUint64(&uc.counter, 1),
+	}
+
+	peerChannel, isNew := uc.peer.unicastChannelWith(uc.provider)
+	if isNew {
+		uc.peer.notifyUnicastChannelOpened(peerChannel)
+	}
+
+	uc.provider.network.transmit(
+		uc.provider.id.String(),
+		uc.peer.id.String(),
+		func() { peerChannel.handlers.dispatch(wireMsg) },
+	)
+
+	return nil
+}
+
+func (uc *unicastChannel) Recv(ctx context.Context, handler func(m net.Message)) {
+	uc.handlers.add(ctx, handler)
+}
+
+func (uc *unicastChannel) SetUnmarshaler(unmarshaler func() net.TaggedUnmarshaler) {
+	uc.handlers.setUnmarshaler(unmarshaler)
+}
@@ -0,0 +1,420 @@
+// Package localnet provides an in-memory network connecting providers
+// executing in a single process. Unlike a real network, the in-memory network
+// can be controlled by the test: messages sent by a member can be delayed,
+// dropped, reordered and duplicated, and members can be partitioned from each
+// other. Random decisions of the network are taken with a seeded source, so
+// the same faults are injected in consecutive runs with the same seed.
+package localnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-log"
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/net/retransmission"
+)
+
+var logger = log.Logger("keep-localnet")
+
+// retransmissionInterval is the interval in which broadcast messages are
+// retransmitted for the lifetime of their context.
+const retransmissionInterval = 50 * time.Millisecond
+
+// Faults describes faults injected into messages sent by a member. Each
+// transmission of a message, including retransmissions of broadcast
+// messages, is subject to the faults separately.
+type Faults struct {
+	// Delay is the time after which the message is delivered.
+	Delay time.Duration
+	// Jitter is the maximum random time added to the delay. Messages delayed
+	// by different times are delivered out of order.
+	Jitter time.Duration
+	// DropRate is the probability of a message being dropped.
+	DropRate float64
+	// DuplicateRate is the probability of a message being delivered twice.
+	DuplicateRate float64
+}
+
+// Network is an in-memory network connecting providers of its members.
+// Members are identified by their transport identifiers.
+type Network struct {
+	retransmissionTicker *retransmission.Ticker
+
+	mutex     sync.Mutex
+	random    *rand.Rand
+	providers map[string]*provider
+	// subscribers are broadcast channels of providers by the channel name.
+	subscribers map[string]map[string]*broadcastChannel
+	faults      map[string]*Faults
+	// partitions assign members to partitions. Members not assigned to any
+	// partition are in the default partition.
+	partitions map[string]int
+}
+
+// NewNetwork creates an in-memory network taking random decisions with
+// a source initialized with the provided seed.
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		retransmissionTicker: retransmission.NewTimeTicker(
+			context.Background(),
+			retransmissionInterval,
+		),
+		random:      rand.New(rand.NewSource(seed)),
+		providers:   make(map[string]*provider),
+		subscribers: make(map[string]map[string]*broadcastChannel),
+		faults:      make(map[string]*Faults),
+		partitions:  make(map[string]int),
+	}
+}
+
+// Connect returns a provider of the member identified by the provided network
+// key. Subsequent calls with the same key return the same provider.
+func (n *Network) Connect(staticKey *key.NetworkPublic) net.Provider {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	id := createTransportIdentifier(staticKey)
+
+	if existing, ok := n.providers[id.String()]; ok {
+		return existing
+	}
+
+	provider := &provider{
+		network:           n,
+		id:                id,
+		staticKey:         staticKey,
+		broadcastChannels: make(map[string]*broadcastChannel),
+		unicastChannels:   make(map[string]*unicastChannel),
+	}
+	n.providers[id.String()] = provider
+
+	return provider
+}
+
+// SetFaults injects faults into messages sent by the member. Nil faults
+// stop injecting faults into messages of the member.
+func (n *Network) SetFaults(memberID net.TransportIdentifier, faults *Faults) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if faults == nil {
+		delete(n.faults, memberID.String())
+		return
+	}
+
+	n.faults[memberID.String()] = faults
+}
+
+// Partition splits members into the provided groups. Members of different
+// groups can not communicate with each other. Members not listed in any group
+// form a group on their own. Partitioning replaces previous partitions.
+func (n *Network) Partition(groups ...[]net.TransportIdentifier) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.partitions = make(map[string]int)
+	for i, group := range groups {
+		for _, memberID := range group {
+			n.partitions[memberID.String()] = i + 1
+		}
+	}
+}
+
+// Heal removes all partitions of the network.
+func (n *Network) Heal() {
+	n.Partition()
+}
+
+func (n *Network) isReachable(senderID string, receiverID string) bool {
+	return n.partitions[senderID] == n.partitions[receiverID]
+}
+
+// transmit delivers the message from the sender to the receiver subject to
+// partitions and faults injected into messages of the sender.
+func (n *Network) transmit(senderID string, receiverID string, deliver func()) {
+	n.mutex.Lock()
+
+	if !n.isReachable(senderID, receiverID) {
+		n.mutex.Unlock()
+		return
+	}
+
+	faults, ok := n.faults[senderID]
+	if !ok {
+		n.mutex.Unlock()
+		deliver()
+		return
+	}
+
+	if n.random.Float64() < faults.DropRate {
+		n.mutex.Unlock()
+		return
+	}
+
+	copies := 1
+	if n.random.Float64() < faults.DuplicateRate {
+		copies = 2
+	}
+
+	delays := make([]time.Duration, copies)
+	for i := range delays {
+		delays[i] = faults.Delay
+		if faults.Jitter > 0 {
+			delays[i] += time.Duration(n.random.Int63n(int64(faults.Jitter)))
+		}
+	}
+
+	n.mutex.Unlock()
+
+	for _, delay := range delays {
+		if delay == 0 {
+			deliver()
+			continue
+		}
+
+		time.AfterFunc(delay, deliver)
+	}
+}
+
+func (n *Network) subscribe(channel *broadcastChannel) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	subscribers, ok := n.subscribers[channel.name]
+	if !ok {
+		subscribers = make(map[string]*broadcastChannel)
+		n.subscribers[channel.name] = subscribers
+	}
+	subscribers[channel.provider.id.String()] = channel
+}
+
+func (n *Network) unsubscribe(channel *broadcastChannel) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	subscribers := n.subscribers[channel.name]
+	delete(subscribers, channel.provider.id.String())
+	if len(subscribers) == 0 {
+		delete(n.subscribers, channel.name)
+	}
+}
+
+func (n *Network) broadcastSubscribers(name string) []*broadcastChannel {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	subscribers := make([]*broadcastChannel, 0, len(n.subscribers[name]))
+	for _, channel := range n.subscribers[name] {
+		subscribers = append(subscribers, channel)
+	}
+
+	return subscribers
+}
+
+func (n *Network) provider(id string) (*provider, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	provider, ok := n.providers[id]
+	return provider, ok
+}
+
+type transportIdentifier string
+
+func (ti transportIdentifier) String() string {
+	return string(ti)
+}
+
+func createTransportIdentifier(staticKey *key.NetworkPublic) transportIdentifier {
+	return transportIdentifier(hex.EncodeToString(key.Marshal(staticKey)))
+}
+
+// provider is a network provider of a single member of the in-memory network.
+type provider struct {
+	network   *Network
+	id        transportIdentifier
+	staticKey *key.NetworkPublic
+
+	mutex                  sync.Mutex
+	broadcastChannels      map[string]*broadcastChannel
+	unicastChannels        map[string]*unicastChannel
+	unicastChannelHandlers []func(channel net.UnicastChannel)
+}
+
+func (p *provider) ID() net.TransportIdentifier {
+	return p.id
+}
+
+func (p *provider) Type() string {
+	return "localnet"
+}
+
+func (p *provider) BroadcastChannelFor(name string) (net.BroadcastChannel, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if channel, ok := p.broadcastChannels[name]; ok {
+		return channel, nil
+	}
+
+	channel := newBroadcastChannel(name, p)
+	p.broadcastChannels[name] = channel
+	p.network.subscribe(channel)
+
+	return channel, nil
+}
+
+// CloseBroadcastChannel unsubscribes the provider from the broadcast channel.
+func (p *provider) CloseBroadcastChannel(name string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	channel, ok := p.broadcastChannels[name]
+	if !ok {
+		return nil
+	}
+
+	delete(p.broadcastChannels, name)
+	p.network.unsubscribe(channel)
+
+	return nil
+}
+
+func (p *provider) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	peer, ok := p.network.provider(peerID.String())
+	if !ok {
+		return nil, fmt.Errorf("peer [%s] is not known", peerID)
+	}
+
+	if !p.isReachable(peer) {
+		return nil, fmt.Errorf("peer [%s] is unreachable", peerID)
+	}
+
+	channel, _ := p.unicastChannelWith(peer)
+	if peerChannel, isNew := peer.unicastChannelWith(p); isNew {
+		peer.notifyUnicastChannelOpened(peerChannel)
+	}
+
+	return channel, nil
+}
+
+// CloseUnicastChannel closes the unicast channel with the peer.
+func (p *provider) CloseUnicastChannel(peerID net.TransportIdentifier) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.unicastChannels, peerID.String())
+
+	return nil
+}
+
+func (p *provider) unicastChannelWith(peer *provider) (*unicastChannel, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if channel, ok := p.unicastChannels[peer.id.String()]; ok {
+		return channel, false
+	}
+
+	channel := newUnicastChannel(p, peer)
+	p.unicastChannels[peer.id.String()] = channel
+
+	return channel, true
+}
+
+func (p *provider) OnUnicastChannelOpened(handler func(channel net.UnicastChannel)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.unicastChannelHandlers = append(p.unicastChannelHandlers, handler)
+}
+
+func (p *provider) notifyUnicastChannelOpened(channel net.UnicastChannel) {
+	p.mutex.Lock()
+	handlers := make([]func(channel net.UnicastChannel), len(p.unicastChannelHandlers))
+	copy(handlers, p.unicastChannelHandlers)
+	p.mutex.Unlock()
+
+	for _, handler := range handlers {
+		go handler(channel)
+	}
+}
+
+func (p *provider) isReachable(peer *provider) bool {
+	p.network.mutex.Lock()
+	defer p.network.mutex.Unlock()
+
+	return p.network.isReachable(p.id.String(), peer.id.String())
+}
+
+func (p *provider) ConnectionManager() net.ConnectionManager {
+	return &connectionManager{provider: p}
+}
+
+func (p *provider) CreateTransportIdentifier(
+	publicKey ecdsa.PublicKey,
+) (net.TransportIdentifier, error) {
+	networkPublicKey := key.NetworkPublic(publicKey)
+	return createTransportIdentifier(&networkPublicKey), nil
+}
+
+func (p *provider) BroadcastChannelForwarderFor(name string) {
+	// no-op
+}
+
+// connectionManager reports members of the network reachable from
+// the provider as connected peers.
+type connectionManager struct {
+	provider *provider
+}
+
+func (cm *connectionManager) ConnectedPeers() []string {
+	network := cm.provider.network
+
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+
+	var peers []string
+	for id := range network.providers {
+		if id != cm.provider.id.String() &&
+			network.isReachable(cm.provider.id.String(), id) {
+			peers = append(peers, id)
+		}
+	}
+
+	return peers
+}
+
+func (cm *connectionManager) GetPeerPublicKey(
+	connectedPeer string,
+) (*key.NetworkPublic, error) {
+	peer, ok := cm.provider.network.provider(connectedPeer)
+	if !ok {
+		return nil, fmt.Errorf("peer [%s] is not known", connectedPeer)
+	}
+
+	return peer.staticKey, nil
+}
+
+// DisconnectPeer does nothing; members of the in-memory network are
+// disconnected with partitions.
+func (cm *connectionManager) DisconnectPeer(connectedPeer string) {
+	logger.Debugf("not disconnecting peer [%s]", connectedPeer)
+}
+
+func (cm *connectionManager) AddrStrings() []string {
+	return []string{}
+}
+
+func (cm *connectionManager) IsConnected(address string) bool {
+	return false
+}
@@ -0,0 +1,322 @@
+package localnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+)
+
+func TestBroadcastChannel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	network := NewNetwork(1)
+	providers := connectProviders(t, network, 3)
+
+	channels := make([]net.BroadcastChannel, len(providers))
+	received := make([]chan string, len(providers))
+	for i, provider := range providers {
+		channels[i] = openBroadcastChannel(t, provider, "test")
+		received[i] = receive(ctx, channels[i])
+	}
+
+	// The third member accepts messages of the first member only.
+	firstMemberKey := publicKeyOf(t, providers[0])
+	if err := channels[2].SetFilter(func(publicKey *ecdsa.PublicKey) bool {
+		return publicKey.X.Cmp(firstMemberKey.X) == 0
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := channels[0].Send(ctx, testMessage("message-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := channels[1].Send(ctx, testMessage("message-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, []string{"message-1", "message-2"}, received[0])
+	assertReceived(t, []string{"message-1", "message-2"}, received[1])
+	assertReceived(t, []string{"message-1"}, received[2])
+}
+
+func TestUnicastChannel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	network := NewNetwork(1)
+	providers := connectProviders(t, network, 2)
+
+	openedChannels := make(chan net.UnicastChannel, 1)
+	providers[1].OnUnicastChannelOpened(func(channel net.UnicastChannel) {
+		openedChannels <- channel
+	})
+
+	channel, err := providers[0].UnicastChannelWith(providers[1].ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var peerChannel net.UnicastChannel
+	select {
+	case peerChannel = <-openedChannels:
+	case <-ctx.Done():
+		t.Fatal("peer has not been notified about the opened channel")
+	}
+
+	peerChannel.SetUnmarshaler(func() net.TaggedUnmarshaler {
+		return new(testMessage)
+	})
+	received := make(chan string, 10)
+	peerChannel.Recv(ctx, func(m net.Message) {
+		if m.TransportSenderID().String() != providers[0].ID().String() {
+			t.Errorf("unexpected sender [%s]", m.TransportSenderID())
+		}
+		received <- string(*m.Payload().(*testMessage))
+	})
+
+	if err := channel.Send(testMessage("message-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, []string{"message-1"}, received)
+}
+
+func TestFaults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	network := NewNetwork(1)
+	providers := connectProviders(t, network, 3)
+
+	channels := make([]net.BroadcastChannel, len(providers))
+	received := make([]chan string, len(providers))
+	for i, provider := range providers {
+		channels[i] = openBroadcastChannel(t, provider, "test")
+		received[i] = receive(ctx, channels[i])
+	}
+
+	network.SetFaults(providers[0].ID(), &Faults{DropRate: 1})
+	network.SetFaults(providers[1].ID(), &Faults{
+		Delay:         200 * time.Millisecond,
+		Jitter:        100 * time.Millisecond,
+		DuplicateRate: 1,
+	})
+
+	// Messages of the first member are dropped until faults are removed.
+	// Messages of the second member are delayed and duplicated; duplicates
+	// are filtered out by receivers.
+	dropCtx, cancelDrop := context.WithCancel(ctx)
+	if err := channels[0].Send(dropCtx, testMessage("dropped")); err != nil {
+		t.Fatal(err)
+	}
+	if err := channels[1].Send(ctx, testMessage("delayed")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case message := <-received[2]:
+		t.Fatalf("unexpected message received before delay: [%s]", message)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	assertReceived(t, []string{"delayed"}, received[2])
+
+	// Retransmissions of the dropped message are stopped before faults are
+	// removed.
+	cancelDrop()
+	time.Sleep(2 * retransmissionInterval)
+
+	network.SetFaults(providers[0].ID(), nil)
+	if err := channels[0].Send(ctx, testMessage("delivered")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, []string{"delivered"}, received[2])
+}
+
+func TestUnicastDuplicates(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	network := NewNetwork(1)
+	providers := connectProviders(t, network, 2)
+
+	network.SetFaults(providers[0].ID(), &Faults{DuplicateRate: 1})
+
+	channel, err := providers[0].UnicastChannelWith(providers[1].ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerChannel, err := providers[1].UnicastChannelWith(providers[0].ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerChannel.SetUnmarshaler(func() net.TaggedUnmarshaler {
+		return new(testMessage)
+	})
+	received := make(chan string, 10)
+	peerChannel.Recv(ctx, func(m net.Message) {
+		received <- string(*m.Payload().(*testMessage))
+	})
+
+	if err := channel.Send(testMessage("message-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unicast channels do not filter out duplicates.
+	assertReceived(t, []string{"message-1", "message-1"}, received)
+}
+
+func TestPartition(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	network := NewNetwork(1)
+	providers := connectProviders(t, network, 3)
+
+	channels := make([]net.BroadcastChannel, len(providers))
+	received := make([]chan string, len(providers))
+	for i, provider := range providers {
+		channels[i] = openBroadcastChannel(t, provider, "test")
+		received[i] = receive(ctx, channels[i])
+	}
+
+	// The third member is partitioned from the others.
+	network.Partition([]net.TransportIdentifier{providers[2].ID()})
+
+	if _, err := providers[0].UnicastChannelWith(providers[2].ID()); err == nil {
+		t.Errorf("channel with partitioned peer has been opened")
+	}
+
+	if peers := providers[2].ConnectionManager().ConnectedPeers(); len(peers) != 0 {
+		t.Errorf("partitioned member has connected peers: [%v]", peers)
+	}
+
+	sendCtx, cancelSend := context.WithCancel(ctx)
+	defer cancelSend()
+
+	if err := channels[0].Send(sendCtx, testMessage("message-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertReceived(t, []string{"message-1"}, received[1])
+	select {
+	case message := <-received[2]:
+		t.Fatalf("partitioned member received message: [%s]", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Once the partition is healed, the message is delivered with
+	// a retransmission.
+	network.Heal()
+
+	assertReceived(t, []string{"message-1"}, received[2])
+}
+
+type testMessage string
+
+func (tm testMessage) Type() string {
+	return "localnet/test_message"
+}
+
+func (tm testMessage) Marshal() ([]byte, error) {
+	return []byte(tm), nil
+}
+
+func (tm *testMessage) Unmarshal(bytes []byte) error {
+	*tm = testMessage(bytes)
+	return nil
+}
+
+func connectProviders(
+	t *testing.T,
+	network *Network,
+	count int,
+) []net.Provider {
+	providers := make([]net.Provider, count)
+	for i := range providers {
+		_, publicKey, err := key.GenerateStaticNetworkKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		providers[i] = network.Connect(publicKey)
+	}
+
+	return providers
+}
+
+func publicKeyOf(t *testing.T, provider net.Provider) *ecdsa.PublicKey {
+	publicKey, err := provider.ConnectionManager().GetPeerPublicKey(
+		provider.ID().String(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key.NetworkKeyToECDSAKey(publicKey)
+}
+
+func openBroadcastChannel(
+	t *testing.T,
+	provider net.Provider,
+	name string,
+) net.BroadcastChannel {
+	channel, err := provider.BroadcastChannelFor(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := channel.RegisterUnmarshaler(func() net.TaggedUnmarshaler {
+		return new(testMessage)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return channel
+}
+
+func receive(ctx context.Context, channel net.BroadcastChannel) chan string {
+	received := make(chan string, 10)
+	channel.Recv(ctx, func(m net.Message) {
+		received <- string(*m.Payload().(*testMessage))
+	})
+
+	return received
+}
+
+func assertReceived(t *testing.T, expected []string, received chan string) {
+	var actual []string
+	for range expected {
+		select {
+		case message := <-received:
+			actual = append(actual, message)
+		case <-time.After(time.Second):
+		}
+	}
+
+	select {
+	case message := <-received:
+		actual = append(actual, message)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	sort.Strings(expected)
+	sort.Strings(actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf(
+			"unexpected received messages\nexpected: [%v]\nactual:   [%v]",
+			expected,
+			actual,
+		)
+	}
+}
@@ -0,0 +1,169 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/operator"
+)
+
+const (
+	// connectivityCheckTimeout bounds the pre-flight connectivity check.
+	// Peer members which can not be reached in this time are reported as
+	// unreachable.
+	connectivityCheckTimeout = 2 * time.Minute
+	// connectivityRetryPeriod is the time between attempts to reach a peer
+	// member which has not been reached yet.
+	connectivityRetryPeriod = 5 * time.Second
+)
+
+// hostedOperatorsProvider is implemented by network providers letting hosts
+// act on behalf of operators other than the one whose key is used by the host.
+type hostedOperatorsProvider interface {
+	HostedOperators(hostPublicKey *operator.PublicKey) []*operator.PublicKey
+}
+
+// CheckConnectivity opens unicast channels with all peer members of the keep
+// in parallel, so that connection problems are discovered and reported before
+// key generation starts. Channels opened by the check are held for the keep
+// until channels of the keep are released, so that they are reused by key
+// generation. Members which could not be reached are returned.
+func (n *Node) CheckConnectivity(
+	ctx context.Context,
+	operatorPublicKey *operator.PublicKey,
+	keepAddress common.Address,
+	members []common.Address,
+) []common.Address {
+	ctx, cancel := context.WithTimeout(ctx, connectivityCheckTimeout)
+	defer cancel()
+
+	operatorAddress := crypto.PubkeyToAddress(*operatorPublicKey)
+
+	// An operator holding several seats in the keep is reached once.
+	peers := make(map[common.Address]bool)
+	for _, member := range members {
+		if member != operatorAddress {
+			peers[member] = true
+		}
+	}
+
+	keepChannels := n.channelManager.NewOwner(keepAddress.Hex())
+
+	var mutex sync.Mutex
+	var unreachable []common.Address
+
+	var wg sync.WaitGroup
+	wg.Add(len(peers))
+
+	for peer := range peers {
+		go func(peer common.Address) {
+			defer wg.Done()
+
+			if err := n.reachMember(ctx, keepChannels, peer); err != nil {
+				logger.Warningf(
+					"member [%s] of keep [%s] is not reachable: [%v]",
+					peer.String(),
+					keepAddress.String(),
+					err,
+				)
+
+				mutex.Lock()
+				unreachable = append(unreachable, peer)
+				mutex.Unlock()
+			}
+		}(peer)
+	}
+
+	wg.Wait()
+
+	if len(unreachable) == 0 {
+		logger.Infof(
+			"all [%d] peer members of keep [%s] are reachable",
+			len(peers),
+			keepAddress.String(),
+		)
+	}
+
+	return unreachable
+}
+
+// reachMember attempts to open a unicast channel with the member until
+// the channel is opened or the context is done.
+func (n *Node) reachMember(
+	ctx context.Context,
+	keepChannels net.Provider,
+	member common.Address,
+) error {
+	for {
+		err := n.openChannelWith(keepChannels, member)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-time.After(connectivityRetryPeriod):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (n *Node) openChannelWith(
+	keepChannels net.Provider,
+	member common.Address,
+) error {
+	publicKey, err := n.lookupMember(member)
+	if err != nil {
+		return err
+	}
+
+	transportID, err := n.networkProvider.CreateTransportIdentifier(*publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to create transport identifier: [%v]", err)
+	}
+
+	if _, err := keepChannels.UnicastChannelWith(transportID); err != nil {
+		return fmt.Errorf("failed to open unicast channel: [%v]", err)
+	}
+
+	return nil
+}
+
+// lookupMember finds the public key of the member among connected peers and
+// operators they act on behalf of.
+func (n *Node) lookupMember(member common.Address) (*operator.PublicKey, error) {
+	connectionManager := n.networkProvider.ConnectionManager()
+	if connectionManager == nil {
+		return nil, fmt.Errorf("connected peers are not known")
+	}
+
+	hostedOperators, hasHostedOperators := n.networkProvider.(hostedOperatorsProvider)
+
+	for _, peer := range connectionManager.ConnectedPeers() {
+		networkPublicKey, err := connectionManager.GetPeerPublicKey(peer)
+		if err != nil || networkPublicKey == nil {
+			continue
+		}
+
+		peerPublicKey := key.NetworkKeyToECDSAKey(networkPublicKey)
+		if crypto.PubkeyToAddress(*peerPublicKey) == member {
+			return peerPublicKey, nil
+		}
+
+		if hasHostedOperators {
+			for _, hostedOperator := range hostedOperators.HostedOperators(peerPublicKey) {
+				if crypto.PubkeyToAddress(*hostedOperator) == member {
+					return hostedOperator, nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("member is not connected to the network")
+}
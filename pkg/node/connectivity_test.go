@@ -0,0 +1,154 @@
+package node
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-core/pkg/net"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	"github.com/keep-network/keep-core/pkg/operator"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+)
+
+func TestCheckConnectivity(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	generateKey := func() *operator.PublicKey {
+		_, publicKey, err := operator.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return publicKey
+	}
+
+	operatorPublicKey := generateKey()
+	connectedPeer := generateKey()
+	hostedOperator := generateKey()
+	disconnectedPeer := generateKey()
+
+	provider := &testConnectivityProvider{
+		connectedPeers: []*operator.PublicKey{connectedPeer},
+		hostedOperators: map[common.Address][]*operator.PublicKey{
+			crypto.PubkeyToAddress(*connectedPeer): {hostedOperator},
+		},
+		openedChannels: make(map[string]bool),
+	}
+
+	node := NewNode(nil, provider, nil, nil, nil)
+	node.UseChannelManager(channels.NewManager(provider))
+
+	unreachable := node.CheckConnectivity(
+		ctx,
+		operatorPublicKey,
+		common.HexToAddress("0x4f76C7CF6a8Fa8dE4C4e5E4B1c2bD6e6D0B9E1c7"),
+		[]common.Address{
+			crypto.PubkeyToAddress(*operatorPublicKey),
+			crypto.PubkeyToAddress(*connectedPeer),
+			crypto.PubkeyToAddress(*hostedOperator),
+			crypto.PubkeyToAddress(*disconnectedPeer),
+			crypto.PubkeyToAddress(*operatorPublicKey),
+		},
+	)
+
+	expectedUnreachable := []common.Address{crypto.PubkeyToAddress(*disconnectedPeer)}
+	if !reflect.DeepEqual(expectedUnreachable, unreachable) {
+		t.Errorf(
+			"unexpected unreachable members\nexpected: [%v]\nactual:   [%v]",
+			expectedUnreachable,
+			unreachable,
+		)
+	}
+
+	expectedChannels := map[string]bool{
+		crypto.PubkeyToAddress(*connectedPeer).String():  true,
+		crypto.PubkeyToAddress(*hostedOperator).String(): true,
+	}
+	if !reflect.DeepEqual(expectedChannels, provider.openedChannels) {
+		t.Errorf(
+			"unexpected opened channels\nexpected: [%v]\nactual:   [%v]",
+			expectedChannels,
+			provider.openedChannels,
+		)
+	}
+}
+
+type testTransportID string
+
+func (id testTransportID) String() string {
+	return string(id)
+}
+
+// testConnectivityProvider identifies peers by addresses of their operators
+// and records unicast channels opened with them.
+type testConnectivityProvider struct {
+	net.Provider
+
+	connectedPeers  []*operator.PublicKey
+	hostedOperators map[common.Address][]*operator.PublicKey
+
+	mutex          sync.Mutex
+	openedChannels map[string]bool
+}
+
+func (tcp *testConnectivityProvider) ConnectionManager() net.ConnectionManager {
+	return tcp
+}
+
+func (tcp *testConnectivityProvider) ConnectedPeers() []string {
+	peers := make([]string, len(tcp.connectedPeers))
+	for i, peer := range tcp.connectedPeers {
+		peers[i] = crypto.PubkeyToAddress(*peer).String()
+	}
+	return peers
+}
+
+func (tcp *testConnectivityProvider) GetPeerPublicKey(
+	connectedPeer string,
+) (*key.NetworkPublic, error) {
+	for _, peer := range tcp.connectedPeers {
+		if crypto.PubkeyToAddress(*peer).String() == connectedPeer {
+			networkPublicKey := key.NetworkPublic(*peer)
+			return &networkPublicKey, nil
+		}
+	}
+	return nil, nil
+}
+
+func (tcp *testConnectivityProvider) DisconnectPeer(connectedPeer string) {}
+
+func (tcp *testConnectivityProvider) AddrStrings() []string {
+	return nil
+}
+
+func (tcp *testConnectivityProvider) IsConnected(address string) bool {
+	return false
+}
+
+func (tcp *testConnectivityProvider) HostedOperators(
+	hostPublicKey *operator.PublicKey,
+) []*operator.PublicKey {
+	return tcp.hostedOperators[crypto.PubkeyToAddress(*hostPublicKey)]
+}
+
+func (tcp *testConnectivityProvider) CreateTransportIdentifier(
+	publicKey operator.PublicKey,
+) (net.TransportIdentifier, error) {
+	return testTransportID(crypto.PubkeyToAddress(publicKey).String()), nil
+}
+
+func (tcp *testConnectivityProvider) UnicastChannelWith(
+	peerID net.TransportIdentifier,
+) (net.UnicastChannel, error) {
+	tcp.mutex.Lock()
+	defer tcp.mutex.Unlock()
+
+	tcp.openedChannels[peerID.String()] = true
+
+	return nil, nil
+}
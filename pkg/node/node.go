@@ -16,51 +16,142 @@ import (
 
 	"github.com/keep-network/keep-core/pkg/net"
 	eth "github.com/keep-network/keep-ecdsa/pkg/chain"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/params"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
 )
 
 var logger = log.Logger("keep-ecdsa")
 
 const monitorKeepPublicKeySubmissionTimeout = 30 * time.Minute
-const retryDelay = 1 * time.Second
+
+// maxKeyGenerationQueueWait is the maximum time a key generation attempt waits
+// for its turn in the scheduler after signer presence has been announced.
+// Members which announced their presence wait for each other to get ready
+// for the protocol for two minutes, so an attempt which can not start within
+// this time is retried with a new announcement instead.
+const maxKeyGenerationQueueWait = 1 * time.Minute
 
 // Node holds interfaces to interact with the blockchain and network messages
 // transport layer.
 type Node struct {
-	ethereumChain   eth.Handle
-	networkProvider net.Provider
-	tssParamsPool   *tssPreParamsPool
-	tssConfig       *tss.Config
+	ethereumChain     eth.Handle
+	networkProvider   net.Provider
+	channelManager    *channels.Manager
+	tssParamsPool     *PreParamsPool
+	custodian         Custodian
+	peerMonitor       *PeerMonitor
+	reliabilityLedger *ReliabilityLedger
+	tssConfig         *tss.Config
+	messageLimiter    *tss.MessageLimiter
+	retryConfig       *retry.Config
+	scheduler         *Scheduler
+
+	keyGenerationQueueWait time.Duration
 }
 
 // NewNode initializes node struct with provided ethereum chain interface and
 // network provider. It also initializes TSS Pre-Parameters pool. But does not
-// start parameters generation. This should be called separately.
+// start parameters generation. This should be called separately. Failed
+// operations are retried according to policies from the provided retry config.
+// Execution of key generation and signing protocols is bounded by the provided
+// scheduler. Messages received from other members are limited according to
+// the provided TSS config. Channels opened by the node are held by keeps and
+// protocol sessions with a channel manager of the network provider.
 func NewNode(
 	ethereumChain eth.Handle,
 	networkProvider net.Provider,
 	tssConfig *tss.Config,
+	retryConfig *retry.Config,
+	scheduler *Scheduler,
 ) *Node {
-	return &Node{
+	node := &Node{
 		ethereumChain:   ethereumChain,
 		networkProvider: networkProvider,
 		tssConfig:       tssConfig,
+		retryConfig:     retryConfig,
+		scheduler:       scheduler,
+
+		keyGenerationQueueWait: maxKeyGenerationQueueWait,
+	}
+
+	var messageLimits *tss.MessageLimits
+	if tssConfig != nil {
+		messageLimits = &tssConfig.MessageLimits
 	}
+	node.messageLimiter = tss.NewMessageLimiter(
+		messageLimits,
+		node.recordMessageLimitViolation,
+	)
+
+	return node
+}
+
+// Custodian holds key shares of the operator outside of the node and executes
+// key generation and signing protocols with them. Signers returned by
+// the custodian hold only public data of the threshold key.
+type Custodian interface {
+	// GenerateThresholdSigners executes the key generation protocol for
+	// the given members of the group.
+	GenerateThresholdSigners(
+		ctx context.Context,
+		groupID string,
+		memberIDs []tss.MemberID,
+		groupMemberIDs []tss.MemberID,
+		dishonestThreshold uint,
+	) ([]*tss.ThresholdSigner, error)
+	// CalculateSignature executes the signing protocol with key shares of
+	// the given signers.
+	CalculateSignature(
+		ctx context.Context,
+		digest []byte,
+		signers []*tss.ThresholdSigner,
+	) (*ecdsa.Signature, error)
+}
+
+// UseChannelManager makes the node open channels with the provided manager.
+// Nodes sharing the network provider should share the manager. The manager
+// has to be set before the node executes any protocol.
+func (n *Node) UseChannelManager(manager *channels.Manager) {
+	n.channelManager = manager
+}
+
+// UseCustodian makes the node delegate key generation and signing protocols
+// to the provided custodian. Key shares are then never present in the node
+// and the node does not need TSS pre-parameters.
+func (n *Node) UseCustodian(custodian Custodian) {
+	n.custodian = custodian
 }
 
 // AnnounceSignerPresence triggers the announce protocol in order to signal
-// signer presence and gather information about other signers.
+// signer presence and gather information about other signers. Presence is
+// announced for all seats the operator holds in the keep. Member IDs of all
+// keep members are returned along with member IDs of the operator's seats.
+// If the announce protocol times out, member IDs of members which announced
+// their presence so far are returned along with the error.
 func (n *Node) AnnounceSignerPresence(
 	ctx context.Context,
 	operatorPublicKey *operator.PublicKey,
 	keepAddress common.Address,
 	keepMembersAddresses []common.Address,
-) ([]tss.MemberID, error) {
-	broadcastChannel, err := n.networkProvider.BroadcastChannelFor(keepAddress.Hex())
+) ([]tss.MemberID, []tss.MemberID, error) {
+	memberIDs := seatMemberIDs(operatorPublicKey, keepMembersAddresses)
+	if len(memberIDs) == 0 {
+		return nil, nil, fmt.Errorf(
+			"operator [%s] is not a member of keep [%s]",
+			crypto.PubkeyToAddress(*operatorPublicKey).String(),
+			keepAddress.String(),
+		)
+	}
+
+	keepChannels := n.channelManager.NewOwner(keepAddress.Hex())
+	defer keepChannels.Release()
+
+	broadcastChannel, err := keepChannels.BroadcastChannelFor(keepAddress.Hex())
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize broadcast channel: [%v]", err)
+		return nil, nil, fmt.Errorf("failed to initialize broadcast channel: [%v]", err)
 	}
 
 	tss.RegisterUnmarshalers(broadcastChannel)
@@ -68,15 +159,106 @@ func (n *Node) AnnounceSignerPresence(
 	if err := broadcastChannel.SetFilter(
 		createAddressFilter(keepMembersAddresses),
 	); err != nil {
-		return nil, fmt.Errorf("failed to set broadcast channel filter: [%v]", err)
+		return nil, nil, fmt.Errorf("failed to set broadcast channel filter: [%v]", err)
 	}
 
-	return tss.AnnounceProtocol(
+	groupMemberIDs, err := tss.AnnounceProtocol(
 		ctx,
-		operatorPublicKey,
+		memberIDs,
 		len(keepMembersAddresses),
+		n.createSeatValidator(keepMembersAddresses),
 		broadcastChannel,
+		n.messageLimiter,
 	)
+	if err != nil {
+		return groupMemberIDs, nil, err
+	}
+
+	return groupMemberIDs, memberIDs, nil
+}
+
+// seatMemberIDs returns member IDs of all seats the operator holds in the
+// keep. An operator can be selected to the same keep several times and it
+// holds a separate seat for each selection. The first seat of the operator
+// has the member ID consisting of the operator public key only, the same as
+// clients not supporting several seats use, so that keeps with operators
+// holding single seats are compatible with them. Further seats have member
+// IDs including the index of the seat.
+func seatMemberIDs(
+	operatorPublicKey *operator.PublicKey,
+	keepMembersAddresses []common.Address,
+) []tss.MemberID {
+	operatorAddress := crypto.PubkeyToAddress(*operatorPublicKey)
+
+	var memberIDs []tss.MemberID
+	for memberIndex, memberAddress := range keepMembersAddresses {
+		if memberAddress != operatorAddress {
+			continue
+		}
+
+		if len(memberIDs) == 0 {
+			memberIDs = append(
+				memberIDs,
+				tss.MemberIDFromPublicKey(operatorPublicKey),
+			)
+			continue
+		}
+
+		memberIDs = append(
+			memberIDs,
+			tss.MemberIDFromPublicKeyAndIndex(
+				operatorPublicKey,
+				uint16(memberIndex),
+			),
+		)
+	}
+
+	return memberIDs
+}
+
+// createSeatValidator creates a validator accepting announcements of members
+// holding seats in the keep with the given members. A member may be announced
+// by its operator or by the host acting on behalf of the operator.
+func (n *Node) createSeatValidator(
+	keepMembersAddresses []common.Address,
+) tss.MemberValidator {
+	hostedOperators, hasHostedOperators := n.networkProvider.(hostedOperatorsProvider)
+
+	return func(
+		senderPublicKey *operator.PublicKey,
+		memberID tss.MemberID,
+	) bool {
+		memberPublicKey, err := memberID.PublicKey()
+		if err != nil {
+			return false
+		}
+
+		memberAddress := crypto.PubkeyToAddress(*memberPublicKey)
+
+		isSenderAllowed := crypto.PubkeyToAddress(*senderPublicKey) == memberAddress
+		if !isSenderAllowed && hasHostedOperators {
+			for _, hostedOperator := range hostedOperators.HostedOperators(senderPublicKey) {
+				if crypto.PubkeyToAddress(*hostedOperator) == memberAddress {
+					isSenderAllowed = true
+					break
+				}
+			}
+		}
+		if !isSenderAllowed {
+			return false
+		}
+
+		for _, seatMemberID := range seatMemberIDs(
+			memberPublicKey,
+			keepMembersAddresses,
+		) {
+			if seatMemberID.Equal(memberID) {
+				return true
+			}
+		}
+
+		return false
+	}
 }
 
 func createAddressFilter(
@@ -104,19 +286,41 @@ func createAddressFilter(
 	}
 }
 
-// GenerateSignerForKeep generates a new threshold signer with ECDSA key pair
-// and submits the public key to the on-chain keep.
+// GenerateSignersForKeep generates new threshold signers with ECDSA key pair
+// and submits the public key to the on-chain keep. One signer is generated for
+// each seat the operator holds in the keep.
 //
 // The attempt for generating signer is retried on failure until the provided
-// context is done.
-func (n *Node) GenerateSignerForKeep(
+// context is done. Once signer presence is announced, each attempt waits for
+// its turn in the scheduler where it is ordered by the provided on-chain key
+// generation deadline. The announcement does not wait for the scheduler, so
+// that members whose queues differ can still meet within the announcement
+// window. Pre-parameters are taken before the announcement and the wait for
+// the scheduler is bounded, so that other members do not give up waiting for
+// this one to get ready for the protocol.
+func (n *Node) GenerateSignersForKeep(
 	ctx context.Context,
+	deadline time.Time,
 	operatorPublicKey *operator.PublicKey,
 	keepAddress common.Address,
 	members []common.Address,
-) (*tss.ThresholdSigner, error) {
-	memberID := tss.MemberIDFromPublicKey(operatorPublicKey)
-	preParamsBox := params.NewBox(n.tssParamsPool.get())
+) ([]*tss.ThresholdSigner, error) {
+	seatsCount := len(seatMemberIDs(operatorPublicKey, members))
+	if seatsCount == 0 {
+		return nil, fmt.Errorf(
+			"operator [%s] is not a member of keep [%s]",
+			crypto.PubkeyToAddress(*operatorPublicKey).String(),
+			keepAddress.String(),
+		)
+	}
+
+	// Each seat needs its own pre-parameters.
+	preParamsBoxes := make([]*params.Box, seatsCount)
+
+	operators := coSigners(crypto.PubkeyToAddress(*operatorPublicKey), members)
+
+	chainCallBackoff := retry.NewBackoff(n.retryConfig.Policy(retry.ChainCall))
+	protocolBackoff := retry.NewBackoff(n.retryConfig.Policy(retry.Protocol))
 
 	attemptCounter := 0
 	for {
@@ -135,7 +339,9 @@ func (n *Node) GenerateSignerForKeep(
 				keepAddress.String(),
 				err,
 			)
-			time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+			if err := chainCallBackoff.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("key generation retries stopped: [%v]", err)
+			}
 			continue
 		}
 
@@ -145,17 +351,20 @@ func (n *Node) GenerateSignerForKeep(
 			return nil, fmt.Errorf("keep is no longer active")
 		}
 
+		n.recordAttempt(operators)
+
 		// If we are re-attempting the key generation, pre-parameters in the box
 		// could be destroyed because they were shared with other members.
-		// In this case, we need to re-generate them.
-		if preParamsBox.IsEmpty() {
-			preParamsBox = params.NewBox(n.tssParamsPool.get())
-		}
-
-		// Global timeout for generating a signer exceeded.
-		// We are giving up and leaving this function.
-		if ctx.Err() != nil {
-			return nil, fmt.Errorf("key generation timeout exceeded")
+		// In this case, we need to re-generate them. Generating them may take
+		// minutes if the pool is empty, so it is done before this member
+		// announces its presence.
+		// A custodian uses its own pre-parameters.
+		if n.custodian == nil {
+			for i, preParamsBox := range preParamsBoxes {
+				if preParamsBox == nil || preParamsBox.IsEmpty() {
+					preParamsBoxes[i] = params.NewBox(n.tssParamsPool.Get())
+				}
+			}
 		}
 
 		// Announce signer presence. Other members of the keep need to receive
@@ -164,7 +373,7 @@ func (n *Node) GenerateSignerForKeep(
 		// signer selection protocol are known.
 		//
 		// If signer announcement fails, we retry from the beginning.
-		memberIDs, err := n.AnnounceSignerPresence(
+		groupMemberIDs, memberIDs, err := n.AnnounceSignerPresence(
 			ctx,
 			operatorPublicKey,
 			keepAddress,
@@ -172,7 +381,31 @@ func (n *Node) GenerateSignerForKeep(
 		)
 		if err != nil {
 			logger.Warningf("failed to announce signer presence: [%v]", err)
-			time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+			n.recordMissedAnnouncements(operators, groupMemberIDs, err)
+			if err := protocolBackoff.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("key generation retries stopped: [%v]", err)
+			}
+			continue
+		}
+
+		release, err := n.acquireKeyGenerationSlot(ctx, deadline)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Global timeout for generating a signer exceeded.
+				// We are giving up and leaving this function.
+				return nil, fmt.Errorf("key generation timeout exceeded")
+			}
+
+			// Other members stop waiting for this one before it could start
+			// the protocol, so we retry from the beginning.
+			logger.Warningf(
+				"could not start key generation for keep [%s] in time: [%v]",
+				keepAddress.String(),
+				err,
+			)
+			if err := protocolBackoff.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("key generation retries stopped: [%v]", err)
+			}
 			continue
 		}
 
@@ -180,27 +413,34 @@ func (n *Node) GenerateSignerForKeep(
 		// keep members.
 		//
 		// If threshold key generation fails, we retry from the beginning.
-		signer, err := tss.GenerateThresholdSigner(
+		signers, err := n.generateThresholdSigners(
 			ctx,
 			keepAddress.Hex(),
-			memberID,
 			memberIDs,
-			uint(len(memberIDs)-1),
-			n.networkProvider,
-			preParamsBox,
+			groupMemberIDs,
+			uint(len(groupMemberIDs)-1),
+			preParamsBoxes,
 		)
+		release()
 		if err != nil {
 			logger.Errorf("failed to generate threshold signer: [%v]", err)
-			time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+			n.recordUnresponsiveMembers(operators, err)
+			if err := protocolBackoff.Wait(ctx); err != nil {
+				return nil, fmt.Errorf("key generation retries stopped: [%v]", err)
+			}
 			continue
 		}
+		if len(signers) == 0 {
+			return nil, fmt.Errorf("key generation returned no signers")
+		}
 
-		// Serialize and publish public key to the keep.
+		// Serialize and publish public key to the keep. All seats share
+		// the same public key and it is submitted once for the operator.
 		//
 		// We don't retry in case of an error although the specific chain
 		// implementation may implement its own retry policy. This action
 		// should never fail and if it failed, something terrible happened.
-		publicKey, err := eth.SerializePublicKey(signer.PublicKey())
+		publicKey, err := eth.SerializePublicKey(signers[0].PublicKey())
 		if err != nil {
 			return nil, fmt.Errorf("failed to serialize public key: [%v]", err)
 		}
@@ -209,10 +449,57 @@ func (n *Node) GenerateSignerForKeep(
 			return nil, err
 		}
 
-		return signer, nil // key generation succeeded.
+		return signers, nil // key generation succeeded.
 	}
 }
 
+// acquireKeyGenerationSlot waits for the turn of a key generation attempt in
+// the scheduler for a limited time.
+func (n *Node) acquireKeyGenerationSlot(
+	ctx context.Context,
+	deadline time.Time,
+) (func(), error) {
+	acquireCtx, cancel := context.WithTimeout(ctx, n.keyGenerationQueueWait)
+	defer cancel()
+
+	return n.scheduler.Acquire(acquireCtx, KeyGeneration, deadline)
+}
+
+func (n *Node) generateThresholdSigners(
+	ctx context.Context,
+	groupID string,
+	memberIDs []tss.MemberID,
+	groupMemberIDs []tss.MemberID,
+	dishonestThreshold uint,
+	preParamsBoxes []*params.Box,
+) ([]*tss.ThresholdSigner, error) {
+	if n.custodian != nil {
+		return n.custodian.GenerateThresholdSigners(
+			ctx,
+			groupID,
+			memberIDs,
+			groupMemberIDs,
+			dishonestThreshold,
+		)
+	}
+
+	// Channels of the session are released once the protocol completes.
+	sessionChannels := n.channelManager.NewOwner(groupID)
+	defer sessionChannels.Release()
+
+	return tss.GenerateThresholdSigners(
+		ctx,
+		groupID,
+		memberIDs,
+		groupMemberIDs,
+		dishonestThreshold,
+		sessionChannels,
+		n.retryConfig.Policy(retry.Network),
+		n.messageLimiter,
+		preParamsBoxes,
+	)
+}
+
 func (n *Node) publishSignerPublicKey(
 	ctx context.Context,
 	keepAddress common.Address,
@@ -237,16 +524,34 @@ func (n *Node) publishSignerPublicKey(
 }
 
 // CalculateSignature calculates a signature over a digest with threshold
-// signer and publishes the result to the keep associated with the signer.
+// signers of all seats the operator holds in the keep and publishes the result
+// to the keep associated with the signers.
 //
 // The attempt for generating and publishing signature is retried on failure
-// until the provided context is done.
+// until the provided context is done. Each attempt waits for its turn in the
+// scheduler where it is ordered by the provided on-chain signing deadline.
 func (n *Node) CalculateSignature(
 	ctx context.Context,
-	signer *tss.ThresholdSigner,
+	deadline time.Time,
+	signers []*tss.ThresholdSigner,
 	digest [32]byte,
 ) error {
-	keepAddress := common.HexToAddress(signer.GroupID())
+	if len(signers) == 0 {
+		return fmt.Errorf("no signers provided")
+	}
+
+	keepAddress := common.HexToAddress(signers[0].GroupID())
+
+	operatorPublicKey, err := signers[0].MemberID().PublicKey()
+	if err != nil {
+		return fmt.Errorf("invalid signer member ID: [%v]", err)
+	}
+	operators := coSigners(
+		crypto.PubkeyToAddress(*operatorPublicKey),
+		memberAddresses(signers[0].GroupMemberIDs()),
+	)
+
+	protocolBackoff := retry.NewBackoff(n.retryConfig.Policy(retry.Protocol))
 
 	attemptCounter := 0
 	for {
@@ -258,24 +563,31 @@ func (n *Node) CalculateSignature(
 			attemptCounter,
 		)
 
-		// Global timeout for generating a signature exceeded.
-		// We are giving up and leaving this function.
-		if ctx.Err() != nil {
+		release, err := n.scheduler.Acquire(ctx, Signing, deadline)
+		if err != nil {
+			// Global timeout for generating a signature exceeded.
+			// We are giving up and leaving this function.
 			return fmt.Errorf("signing timeout exceeded")
 		}
 
+		n.recordAttempt(operators)
+
 		// Calculate the signature executing threshold signing protocol with
 		// other keep members.
 		//
 		// If threshold signing fails, we retry from the beginning.
-		signature, err := signer.CalculateSignature(ctx, digest[:], n.networkProvider)
+		signature, err := n.calculateSignature(ctx, digest[:], signers)
+		release()
 		if err != nil {
 			logger.Errorf(
 				"failed to calculate signature for keep [%s]: [%v]",
 				keepAddress.String(),
 				err,
 			)
-			time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+			n.recordUnresponsiveMembers(operators, err)
+			if err := protocolBackoff.Wait(ctx); err != nil {
+				return fmt.Errorf("signing retries stopped: [%v]", err)
+			}
 			continue
 		}
 
@@ -293,6 +605,33 @@ func (n *Node) CalculateSignature(
 	}
 }
 
+func (n *Node) calculateSignature(
+	ctx context.Context,
+	digest []byte,
+	signers []*tss.ThresholdSigner,
+) (*ecdsa.Signature, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no signers provided")
+	}
+
+	if n.custodian != nil {
+		return n.custodian.CalculateSignature(ctx, digest, signers)
+	}
+
+	// Channels of the session are released once the protocol completes.
+	sessionChannels := n.channelManager.NewOwner(signers[0].GroupID())
+	defer sessionChannels.Release()
+
+	return tss.CalculateSignature(
+		ctx,
+		digest,
+		signers,
+		sessionChannels,
+		n.retryConfig.Policy(retry.Network),
+		n.messageLimiter,
+	)
+}
+
 // publishSignature takes the provided signature and attempts to publish it to
 // the chain. It implements retry mechanism allowing to attempt to publish again
 // in case of a failure.
@@ -309,6 +648,9 @@ func (n *Node) publishSignature(
 	digest [32]byte,
 	signature *ecdsa.Signature,
 ) error {
+	chainCallBackoff := retry.NewBackoff(n.retryConfig.Policy(retry.ChainCall))
+	submissionBackoff := retry.NewBackoff(n.retryConfig.Policy(retry.ChainSubmission))
+
 	attemptCounter := 0
 	for {
 		attemptCounter++
@@ -330,7 +672,9 @@ func (n *Node) publishSignature(
 				keepAddress.String(),
 				err,
 			)
-			time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+			if err := chainCallBackoff.Wait(ctx); err != nil {
+				return fmt.Errorf("signature publication retries stopped: [%v]", err)
+			}
 			continue
 		}
 		if !isActive {
@@ -351,7 +695,9 @@ func (n *Node) publishSignature(
 				keepAddress.String(),
 				err,
 			)
-			time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+			if err := chainCallBackoff.Wait(ctx); err != nil {
+				return fmt.Errorf("signature publication retries stopped: [%v]", err)
+			}
 			continue
 		}
 
@@ -380,7 +726,9 @@ func (n *Node) publishSignature(
 					keepAddress.String(),
 					err,
 				)
-				time.Sleep(retryDelay) // TODO: #413 Replace with backoff.
+				if err := chainCallBackoff.Wait(ctx); err != nil {
+					return fmt.Errorf("signature publication retries stopped: [%v]", err)
+				}
 				continue
 			}
 
@@ -399,11 +747,13 @@ func (n *Node) publishSignature(
 			// wait for some time and then retry from the beginning.
 			logger.Errorf(
 				"failed to submit signature for keep [%s]: [%v]; "+
-					"will retry after 1 minute",
+					"will retry after backoff",
 				keepAddress.String(),
 				submissionErr,
 			)
-			time.Sleep(1 * time.Minute)
+			if err := submissionBackoff.Wait(ctx); err != nil {
+				return fmt.Errorf("signature publication retries stopped: [%v]", err)
+			}
 			continue
 		}
 
@@ -473,6 +823,7 @@ func (n *Node) monitorKeepPublicKeySubmission(
 			keepAddress.String(),
 			event.ConflictingPublicKey,
 		)
+		n.recordConflictingKey(event.SubmittingMember)
 	case <-monitoringCtx.Done():
 		logger.Warningf(
 			"monitoring of public key submission for keep [%s] "+
@@ -0,0 +1,287 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-core/pkg/net/key"
+	netlocal "github.com/keep-network/keep-core/pkg/net/local"
+	"github.com/keep-network/keep-core/pkg/operator"
+	"github.com/keep-network/keep-ecdsa/pkg/chain/local"
+	"github.com/keep-network/keep-ecdsa/pkg/channels"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa"
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+func TestSeatMemberIDs(t *testing.T) {
+	_, operatorPublicKey, err := operator.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	operatorAddress := crypto.PubkeyToAddress(*operatorPublicKey)
+
+	otherAddress := common.HexToAddress("0x8B3BccB3A3994681A1C1584DE4b4E8b23ed1Ed6d")
+
+	var tests = map[string]struct {
+		members           []common.Address
+		expectedMemberIDs []tss.MemberID
+	}{
+		"not a member": {
+			members: []common.Address{otherAddress, otherAddress},
+		},
+		"single seat": {
+			members: []common.Address{otherAddress, operatorAddress},
+			expectedMemberIDs: []tss.MemberID{
+				tss.MemberIDFromPublicKey(operatorPublicKey),
+			},
+		},
+		"several seats": {
+			members: []common.Address{operatorAddress, otherAddress, operatorAddress},
+			expectedMemberIDs: []tss.MemberID{
+				tss.MemberIDFromPublicKey(operatorPublicKey),
+				tss.MemberIDFromPublicKeyAndIndex(operatorPublicKey, 2),
+			},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			memberIDs := seatMemberIDs(operatorPublicKey, test.members)
+
+			if len(memberIDs) != len(test.expectedMemberIDs) {
+				t.Fatalf(
+					"unexpected number of member IDs\nexpected: [%v]\nactual:   [%v]",
+					len(test.expectedMemberIDs),
+					len(memberIDs),
+				)
+			}
+
+			for i, memberID := range memberIDs {
+				if !memberID.Equal(test.expectedMemberIDs[i]) {
+					t.Errorf(
+						"unexpected member ID [%v]\nexpected: [%v]\nactual:   [%v]",
+						i,
+						test.expectedMemberIDs[i],
+						memberID,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestSeatValidator(t *testing.T) {
+	generateKey := func() *operator.PublicKey {
+		_, publicKey, err := operator.GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return publicKey
+	}
+
+	operatorPublicKey := generateKey()
+	hostPublicKey := generateKey()
+	hostedOperatorPublicKey := generateKey()
+	outsiderPublicKey := generateKey()
+
+	provider := &testConnectivityProvider{
+		hostedOperators: map[common.Address][]*operator.PublicKey{
+			crypto.PubkeyToAddress(*hostPublicKey): {hostedOperatorPublicKey},
+		},
+	}
+
+	node := NewNode(nil, provider, nil, nil, nil)
+
+	validateMember := node.createSeatValidator([]common.Address{
+		crypto.PubkeyToAddress(*operatorPublicKey),
+		crypto.PubkeyToAddress(*hostedOperatorPublicKey),
+		crypto.PubkeyToAddress(*operatorPublicKey),
+	})
+
+	var tests = map[string]struct {
+		senderPublicKey *operator.PublicKey
+		memberID        tss.MemberID
+		expectedValid   bool
+	}{
+		"first seat of the sender": {
+			senderPublicKey: operatorPublicKey,
+			memberID:        tss.MemberIDFromPublicKey(operatorPublicKey),
+			expectedValid:   true,
+		},
+		"further seat of the sender": {
+			senderPublicKey: operatorPublicKey,
+			memberID:        tss.MemberIDFromPublicKeyAndIndex(operatorPublicKey, 2),
+			expectedValid:   true,
+		},
+		"first seat with member index": {
+			senderPublicKey: operatorPublicKey,
+			memberID:        tss.MemberIDFromPublicKeyAndIndex(operatorPublicKey, 0),
+			expectedValid:   false,
+		},
+		"seat of other operator": {
+			senderPublicKey: operatorPublicKey,
+			memberID:        tss.MemberIDFromPublicKeyAndIndex(operatorPublicKey, 1),
+			expectedValid:   false,
+		},
+		"seat of hosted operator": {
+			senderPublicKey: hostPublicKey,
+			memberID:        tss.MemberIDFromPublicKey(hostedOperatorPublicKey),
+			expectedValid:   true,
+		},
+		"seat of operator not hosted by the sender": {
+			senderPublicKey: outsiderPublicKey,
+			memberID:        tss.MemberIDFromPublicKey(operatorPublicKey),
+			expectedValid:   false,
+		},
+		"operator not in the keep": {
+			senderPublicKey: outsiderPublicKey,
+			memberID:        tss.MemberIDFromPublicKey(outsiderPublicKey),
+			expectedValid:   false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			isValid := validateMember(test.senderPublicKey, test.memberID)
+			if isValid != test.expectedValid {
+				t.Errorf(
+					"unexpected validation result\nexpected: [%v]\nactual:   [%v]",
+					test.expectedValid,
+					isValid,
+				)
+			}
+		})
+	}
+}
+
+func TestCalculateSignatureWithoutSigners(t *testing.T) {
+	node := NewNode(nil, nil, nil, nil, nil)
+
+	err := node.CalculateSignature(
+		context.Background(),
+		time.Now().Add(time.Minute),
+		nil,
+		[32]byte{},
+	)
+	if err == nil {
+		t.Errorf("expected error for missing signers")
+	}
+
+	if _, err := node.calculateSignature(context.Background(), nil, nil); err == nil {
+		t.Errorf("expected error for missing signers")
+	}
+}
+
+func TestGenerateSignersForKeepWithBusyKeyGenerationSlot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	operatorPrivateKey, operatorPublicKey, err := operator.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, networkPublicKey := key.OperatorKeyToNetworkKey(
+		operatorPrivateKey,
+		operatorPublicKey,
+	)
+	provider := netlocal.ConnectWithKey(networkPublicKey)
+
+	// The operator holds both seats of the keep so the announcement
+	// completes without other members.
+	operatorAddress := crypto.PubkeyToAddress(*operatorPublicKey)
+	members := []common.Address{operatorAddress, operatorAddress}
+	keepAddress := common.HexToAddress("0x4f76C7CF6a8Fa8dE4C4e5E4B1c2bD6e6D0B9E1c7")
+
+	chain := local.Connect()
+	chain.OpenKeep(keepAddress, members)
+
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrentKeyGenerations: 1})
+	releaseBusySlot, err := scheduler.Acquire(ctx, KeyGeneration, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retryInterval := retry.Duration{Duration: 300 * time.Millisecond}
+	retryConfig := &retry.Config{
+		Protocol: retry.PolicyConfig{
+			InitialInterval: &retryInterval,
+			MaxInterval:     &retryInterval,
+		},
+	}
+
+	custodian := &testCustodian{started: make(chan struct{}, 1)}
+
+	node := NewNode(chain, provider, nil, retryConfig, scheduler)
+	node.UseChannelManager(channels.NewManager(provider))
+	node.UseCustodian(custodian)
+	node.keyGenerationQueueWait = 100 * time.Millisecond
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := node.GenerateSignersForKeep(
+			ctx,
+			time.Now().Add(time.Hour),
+			operatorPublicKey,
+			keepAddress,
+			members,
+		)
+		errChan <- err
+	}()
+
+	// The attempt gets queued after the announcement and gives up waiting
+	// for the busy slot. The next attempt is queued after the retry interval.
+	waitForQueueDepth(t, scheduler, KeyGeneration, 1)
+	waitForQueueDepth(t, scheduler, KeyGeneration, 0)
+
+	select {
+	case <-custodian.started:
+		t.Fatalf("key generation should not start while the slot is busy")
+	default:
+	}
+
+	releaseBusySlot()
+
+	select {
+	case <-custodian.started:
+	case <-ctx.Done():
+		t.Fatalf("key generation should start once the slot is free")
+	}
+
+	cancel()
+	if err := <-errChan; err == nil {
+		t.Errorf("expected key generation error")
+	}
+}
+
+// testCustodian signals started key generations and fails them.
+type testCustodian struct {
+	started chan struct{}
+}
+
+func (tc *testCustodian) GenerateThresholdSigners(
+	ctx context.Context,
+	groupID string,
+	memberIDs []tss.MemberID,
+	groupMemberIDs []tss.MemberID,
+	dishonestThreshold uint,
+) ([]*tss.ThresholdSigner, error) {
+	select {
+	case tc.started <- struct{}{}:
+	default:
+	}
+
+	return nil, fmt.Errorf("key generation failed")
+}
+
+func (tc *testCustodian) CalculateSignature(
+	ctx context.Context,
+	digest []byte,
+	signers []*tss.ThresholdSigner,
+) (*ecdsa.Signature, error) {
+	return nil, fmt.Errorf("signing failed")
+}
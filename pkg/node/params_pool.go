@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"time"
 
 	"github.com/binance-chain/tss-lib/ecdsa/keygen"
@@ -11,37 +12,90 @@ const (
 	defaultPreParamsGenerationTimeout = 2 * time.Minute
 )
 
-// tssPreParamsPool is a pool holding TSS pre parameters. It autogenerates entries
+// PreParamsPool is a pool holding TSS pre parameters. It autogenerates entries
 // up to the pool size. When an entry is pulled from the pool it will generate
-// new entry.
-type tssPreParamsPool struct {
+// new entry. The pool can be shared by nodes of all operators running in the
+// same process.
+type PreParamsPool struct {
 	pool chan *keygen.LocalPreParams
 	new  func() (*keygen.LocalPreParams, error)
+	done <-chan struct{}
 }
 
-// InitializeTSSPreParamsPool generates TSS pre-parameters and stores them in a pool.
-func (n *Node) InitializeTSSPreParamsPool() {
+// PreParamsPoolStatus describes the current state of the TSS pre-parameters
+// pool.
+type PreParamsPoolStatus struct {
+	// Size is the number of pre-parameters ready to be used.
+	Size int
+	// Capacity is the maximum number of pre-parameters kept in the pool.
+	Capacity int
+}
+
+// NewPreParamsPool creates a pool and starts generating TSS pre-parameters.
+// The pool stops generating new pre-parameters when the context is done.
+func NewPreParamsPool(ctx context.Context, tssConfig *tss.Config) *PreParamsPool {
 	poolSize := 20
 
 	var timeout time.Duration
-	if n.tssConfig.PreParamsGenerationTimeout.Duration > 0 {
-		timeout = time.Duration(n.tssConfig.PreParamsGenerationTimeout.Duration)
+	if tssConfig.PreParamsGenerationTimeout.Duration > 0 {
+		timeout = time.Duration(tssConfig.PreParamsGenerationTimeout.Duration)
 	} else {
 		timeout = defaultPreParamsGenerationTimeout
 	}
 
-	n.tssParamsPool = &tssPreParamsPool{
+	pool := &PreParamsPool{
 		pool: make(chan *keygen.LocalPreParams, poolSize),
 		new: func() (*keygen.LocalPreParams, error) {
 			return tss.GenerateTSSPreParams(timeout)
 		},
+		done: ctx.Done(),
 	}
 
-	go n.tssParamsPool.pumpPool()
+	go pool.pumpPool()
+
+	return pool
 }
 
-func (t *tssPreParamsPool) pumpPool() {
+// Status returns the current state of the pool.
+func (t *PreParamsPool) Status() PreParamsPoolStatus {
+	return PreParamsPoolStatus{
+		Size:     len(t.pool),
+		Capacity: cap(t.pool),
+	}
+}
+
+// InitializeTSSPreParamsPool generates TSS pre-parameters and stores them in a
+// pool used only by this node. The pool stops generating new pre-parameters
+// when the context is done.
+func (n *Node) InitializeTSSPreParamsPool(ctx context.Context) {
+	n.tssParamsPool = NewPreParamsPool(ctx, n.tssConfig)
+}
+
+// UseTSSPreParamsPool makes the node take TSS pre-parameters from the provided
+// pool instead of initializing its own one.
+func (n *Node) UseTSSPreParamsPool(pool *PreParamsPool) {
+	n.tssParamsPool = pool
+}
+
+// PreParamsPoolStatus returns the current state of the TSS pre-parameters
+// pool. Zero value is returned if the pool has not been initialized.
+func (n *Node) PreParamsPoolStatus() PreParamsPoolStatus {
+	if n.tssParamsPool == nil {
+		return PreParamsPoolStatus{}
+	}
+
+	return n.tssParamsPool.Status()
+}
+
+func (t *PreParamsPool) pumpPool() {
 	for {
+		select {
+		case <-t.done:
+			logger.Info("stopping tss pre parameters generation")
+			return
+		default:
+		}
+
 		logger.Info("generating new tss pre parameters")
 
 		start := time.Now()
@@ -62,12 +116,17 @@ func (t *tssPreParamsPool) pumpPool() {
 			len(t.pool)+1,
 		)
 
-		t.pool <- params
+		select {
+		case t.pool <- params:
+		case <-t.done:
+			logger.Info("stopping tss pre parameters generation")
+			return
+		}
 	}
 }
 
-// get returns TSS pre parameters from the pool. It pumps the pool after getting
+// Get returns TSS pre parameters from the pool. It pumps the pool after getting
 // and entry. If the pool is empty it will wait for a new entry to be generated.
-func (t *tssPreParamsPool) get() *keygen.LocalPreParams {
+func (t *PreParamsPool) Get() *keygen.LocalPreParams {
 	return <-t.pool
 }
@@ -41,12 +41,12 @@ func TestTSSPreParamsPool(t *testing.T) {
 	}
 
 	// Get entry from pool.
-	result := tssPool.get()
+	result := tssPool.Get()
 	if result == nil {
 		t.Errorf("result is nil")
 	}
 
-	result = tssPool.get()
+	result = tssPool.Get()
 	if result == nil {
 		t.Errorf("result is nil")
 	}
@@ -94,7 +94,7 @@ func TestTSSPreParamsPoolEmpty(t *testing.T) {
 	}()
 
 	// Get entry from pool.
-	result := tssPool.get()
+	result := tssPool.Get()
 	if result == nil {
 		t.Errorf("result is nil")
 	}
@@ -129,13 +129,13 @@ func TestTSSPreParamsPoolConcurrent(t *testing.T) {
 	waitGroup.Add(2)
 
 	go func() {
-		if result := tssPool.get(); result == nil {
+		if result := tssPool.Get(); result == nil {
 			t.Errorf("result is nil")
 		}
 		waitGroup.Done()
 	}()
 	go func() {
-		if result := tssPool.get(); result == nil {
+		if result := tssPool.Get(); result == nil {
 			t.Errorf("result is nil")
 		}
 		waitGroup.Done()
@@ -167,8 +167,8 @@ func TestTSSPreParamsPoolConcurrent(t *testing.T) {
 	}
 }
 
-func newTestPool(poolSize int) *tssPreParamsPool {
-	return &tssPreParamsPool{
+func newTestPool(poolSize int) *PreParamsPool {
+	return &PreParamsPool{
 		pool: make(chan *keygen.LocalPreParams, poolSize),
 		new: func() (*keygen.LocalPreParams, error) {
 			time.Sleep(10 * time.Millisecond)
@@ -0,0 +1,364 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-common/pkg/metrics"
+
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+const (
+	// DefaultHeartbeatPeriod is the default period of heartbeats sent to
+	// members of active keeps.
+	DefaultHeartbeatPeriod = 1 * time.Minute
+	// DefaultSilenceThreshold is the default time after which a peer member
+	// which has not sent a heartbeat is considered silent.
+	DefaultSilenceThreshold = 10 * time.Minute
+)
+
+// HeartbeatConfig contains settings of heartbeats exchanged by members of
+// active keeps. Zero value means that the default should be used.
+type HeartbeatConfig struct {
+	// Period is the time between heartbeats sent by the node.
+	Period retry.Duration
+	// SilenceThreshold is the time after which a peer member which has not
+	// sent a heartbeat is considered silent and an alert is logged.
+	SilenceThreshold retry.Duration
+}
+
+// PeerStatus describes when a peer member of a keep has been last seen.
+type PeerStatus struct {
+	MemberID tss.MemberID
+	// LastSeen is the time the last heartbeat of the member has been
+	// received. It is zero if no heartbeat has been received since
+	// the keep is monitored.
+	LastSeen time.Time
+	// IsSilent is true if the member has not sent a heartbeat for longer
+	// than the silence threshold.
+	IsSilent bool
+}
+
+type peerState struct {
+	memberID tss.MemberID
+	since    time.Time
+	lastSeen time.Time
+	isSilent bool
+}
+
+type monitoredKeep struct {
+	monitors int
+	ownSeats map[string]bool
+	peers    map[string]*peerState
+}
+
+// PeerMonitor records when peer members of active keeps have been last seen
+// and alerts when a peer member has been silent for too long, so that
+// problems with co-signers can be investigated before a signature is
+// requested. The monitor can be shared by nodes of all operators running in
+// the same process.
+type PeerMonitor struct {
+	period           time.Duration
+	silenceThreshold time.Duration
+	now              func() time.Time
+
+	mutex sync.Mutex
+	keeps map[common.Address]*monitoredKeep
+}
+
+// NewPeerMonitor creates a monitor with the provided heartbeat settings.
+// If config is nil, default settings are used.
+func NewPeerMonitor(config *HeartbeatConfig) *PeerMonitor {
+	monitor := &PeerMonitor{
+		period:           DefaultHeartbeatPeriod,
+		silenceThreshold: DefaultSilenceThreshold,
+		now:              time.Now,
+		keeps:            make(map[common.Address]*monitoredKeep),
+	}
+
+	if config != nil {
+		if config.Period.Duration > 0 {
+			monitor.period = config.Period.Duration
+		}
+		if config.SilenceThreshold.Duration > 0 {
+			monitor.silenceThreshold = config.SilenceThreshold.Duration
+		}
+	}
+
+	return monitor
+}
+
+// Peers returns statuses of peer members of the keep ordered by member ID.
+// Nil is returned if the keep is not monitored.
+func (pm *PeerMonitor) Peers(keepAddress common.Address) []PeerStatus {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	keep, ok := pm.keeps[keepAddress]
+	if !ok {
+		return nil
+	}
+
+	peers := make([]PeerStatus, 0, len(keep.peers))
+	for memberID, peer := range keep.peers {
+		if keep.ownSeats[memberID] {
+			continue
+		}
+
+		peers = append(peers, PeerStatus{
+			MemberID: peer.memberID,
+			LastSeen: peer.lastSeen,
+			IsSilent: peer.isSilent,
+		})
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].MemberID.String() < peers[j].MemberID.String()
+	})
+
+	return peers
+}
+
+// SilentPeersCount returns the number of silent peer members in all monitored
+// keeps.
+func (pm *PeerMonitor) SilentPeersCount() int {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	count := 0
+	for _, keep := range pm.keeps {
+		for memberID, peer := range keep.peers {
+			if peer.isSilent && !keep.ownSeats[memberID] {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+// ObserveSilentPeers triggers observation process of silent_peers_count
+// metric.
+func (pm *PeerMonitor) ObserveSilentPeers(
+	ctx context.Context,
+	registry *metrics.Registry,
+	tick time.Duration,
+) {
+	observer, err := registry.NewGaugeObserver(
+		"silent_peers_count",
+		func() float64 {
+			return float64(pm.SilentPeersCount())
+		},
+	)
+	if err != nil {
+		logger.Warningf("could not create gauge observer [silent_peers_count]")
+		return
+	}
+
+	observer.Observe(ctx, tick)
+}
+
+// UsePeerMonitor makes the node record heartbeats of peer members in
+// the provided monitor.
+func (n *Node) UsePeerMonitor(monitor *PeerMonitor) {
+	n.peerMonitor = monitor
+}
+
+// MonitorPeers exchanges heartbeats with peer members of the keep until
+// the context is done. Heartbeats are sent for all the given signers, one for
+// each seat the operator holds in the keep.
+func (n *Node) MonitorPeers(
+	ctx context.Context,
+	keepAddress common.Address,
+	signers []*tss.ThresholdSigner,
+) error {
+	if n.peerMonitor == nil {
+		return fmt.Errorf("peer monitor has not been set")
+	}
+	if len(signers) == 0 {
+		return fmt.Errorf("at least one signer is required")
+	}
+
+	memberIDs := make([]tss.MemberID, len(signers))
+	for i, signer := range signers {
+		memberIDs[i] = signer.MemberID()
+	}
+	groupMemberIDs := signers[0].GroupMemberIDs()
+
+	membersAddresses := make([]common.Address, len(groupMemberIDs))
+	for i, memberID := range groupMemberIDs {
+		publicKey, err := memberID.PublicKey()
+		if err != nil {
+			return fmt.Errorf("invalid member [%s]: [%v]", memberID, err)
+		}
+		membersAddresses[i] = crypto.PubkeyToAddress(*publicKey)
+	}
+
+	keepChannels := n.channelManager.NewOwner(keepAddress.Hex())
+	defer keepChannels.Release()
+
+	broadcastChannel, err := keepChannels.BroadcastChannelFor(keepAddress.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to initialize broadcast channel: [%v]", err)
+	}
+
+	tss.RegisterUnmarshalers(broadcastChannel)
+
+	if err := broadcastChannel.SetFilter(
+		createAddressFilter(membersAddresses),
+	); err != nil {
+		return fmt.Errorf("failed to set broadcast channel filter: [%v]", err)
+	}
+
+	n.peerMonitor.track(keepAddress, memberIDs, groupMemberIDs)
+	defer n.peerMonitor.untrack(keepAddress)
+
+	go func() {
+		ticker := time.NewTicker(n.peerMonitor.period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				n.peerMonitor.checkSilence(keepAddress)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	tss.HeartbeatProtocol(
+		ctx,
+		memberIDs,
+		n.peerMonitor.period,
+		broadcastChannel,
+		func(senderID tss.MemberID) {
+			n.peerMonitor.heartbeat(keepAddress, senderID)
+		},
+	)
+
+	return nil
+}
+
+// track starts monitoring peer members of the keep. Seats of the operator are
+// not monitored. Keeps can be tracked by several nodes sharing the monitor;
+// the keep is monitored until all of them untrack it.
+func (pm *PeerMonitor) track(
+	keepAddress common.Address,
+	memberIDs []tss.MemberID,
+	groupMemberIDs []tss.MemberID,
+) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	keep, ok := pm.keeps[keepAddress]
+	if !ok {
+		keep = &monitoredKeep{
+			ownSeats: make(map[string]bool),
+			peers:    make(map[string]*peerState),
+		}
+		pm.keeps[keepAddress] = keep
+	}
+	keep.monitors++
+
+	for _, memberID := range memberIDs {
+		keep.ownSeats[memberID.String()] = true
+	}
+
+	now := pm.now()
+	for _, memberID := range groupMemberIDs {
+		if _, ok := keep.peers[memberID.String()]; !ok {
+			keep.peers[memberID.String()] = &peerState{
+				memberID: memberID,
+				since:    now,
+			}
+		}
+	}
+}
+
+func (pm *PeerMonitor) untrack(keepAddress common.Address) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	keep, ok := pm.keeps[keepAddress]
+	if !ok {
+		return
+	}
+
+	keep.monitors--
+	if keep.monitors == 0 {
+		delete(pm.keeps, keepAddress)
+	}
+}
+
+// heartbeat records a heartbeat received from the peer member of the keep.
+func (pm *PeerMonitor) heartbeat(
+	keepAddress common.Address,
+	memberID tss.MemberID,
+) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	keep, ok := pm.keeps[keepAddress]
+	if !ok {
+		return
+	}
+
+	peer, ok := keep.peers[memberID.String()]
+	if !ok {
+		return
+	}
+
+	peer.lastSeen = pm.now()
+
+	if peer.isSilent {
+		peer.isSilent = false
+		logger.Infof(
+			"member [%s] of keep [%s] is reachable again",
+			memberID,
+			keepAddress.String(),
+		)
+	}
+}
+
+// checkSilence marks peer members of the keep which have not sent a heartbeat
+// for longer than the silence threshold as silent and alerts about them.
+func (pm *PeerMonitor) checkSilence(keepAddress common.Address) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	keep, ok := pm.keeps[keepAddress]
+	if !ok {
+		return
+	}
+
+	now := pm.now()
+	for memberID, peer := range keep.peers {
+		if peer.isSilent || keep.ownSeats[memberID] {
+			continue
+		}
+
+		lastSeen := peer.lastSeen
+		if lastSeen.IsZero() {
+			lastSeen = peer.since
+		}
+
+		if silence := now.Sub(lastSeen); silence > pm.silenceThreshold {
+			peer.isSilent = true
+			logger.Warningf(
+				"member [%s] of keep [%s] has been silent for [%v]; "+
+					"the member may not be able to take part in signing",
+				memberID,
+				keepAddress.String(),
+				silence.Round(time.Second),
+			)
+		}
+	}
+}
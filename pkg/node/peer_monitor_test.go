@@ -0,0 +1,146 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+func TestPeerMonitor(t *testing.T) {
+	keepAddress := common.HexToAddress("0x4f76C7CF6a8Fa8dE4C4e5E4B1c2bD6e6D0B9E1c7")
+
+	ownSeat := tss.MemberID("member-1")
+	peer1 := tss.MemberID("member-2")
+	peer2 := tss.MemberID("member-3")
+
+	now := time.Unix(1600000000, 0)
+	monitor := NewPeerMonitor(&HeartbeatConfig{
+		SilenceThreshold: retry.Duration{Duration: 10 * time.Minute},
+	})
+	monitor.now = func() time.Time { return now }
+
+	monitor.track(
+		keepAddress,
+		[]tss.MemberID{ownSeat},
+		[]tss.MemberID{ownSeat, peer1, peer2},
+	)
+
+	now = now.Add(5 * time.Minute)
+	monitor.heartbeat(keepAddress, peer1)
+	monitor.heartbeat(keepAddress, peer2)
+	lastSeen := now
+
+	now = now.Add(8 * time.Minute)
+	monitor.heartbeat(keepAddress, peer1)
+
+	now = now.Add(5 * time.Minute)
+	monitor.checkSilence(keepAddress)
+
+	peers := monitor.Peers(keepAddress)
+	expectedPeers := []PeerStatus{
+		{MemberID: peer1, LastSeen: lastSeen.Add(8 * time.Minute), IsSilent: false},
+		{MemberID: peer2, LastSeen: lastSeen, IsSilent: true},
+	}
+	assertPeers(t, expectedPeers, peers)
+
+	if count := monitor.SilentPeersCount(); count != 1 {
+		t.Errorf(
+			"unexpected number of silent peers\nexpected: [%d]\nactual:   [%d]",
+			1,
+			count,
+		)
+	}
+
+	monitor.heartbeat(keepAddress, peer2)
+	if count := monitor.SilentPeersCount(); count != 0 {
+		t.Errorf(
+			"unexpected number of silent peers after heartbeat\n"+
+				"expected: [%d]\nactual:   [%d]",
+			0,
+			count,
+		)
+	}
+}
+
+func TestPeerMonitorSilentSinceTracking(t *testing.T) {
+	keepAddress := common.HexToAddress("0x4f76C7CF6a8Fa8dE4C4e5E4B1c2bD6e6D0B9E1c7")
+
+	ownSeat := tss.MemberID("member-1")
+	peer := tss.MemberID("member-2")
+
+	now := time.Unix(1600000000, 0)
+	monitor := NewPeerMonitor(nil)
+	monitor.now = func() time.Time { return now }
+
+	monitor.track(keepAddress, []tss.MemberID{ownSeat}, []tss.MemberID{ownSeat, peer})
+
+	now = now.Add(DefaultSilenceThreshold)
+	monitor.checkSilence(keepAddress)
+	assertPeers(t, []PeerStatus{{MemberID: peer}}, monitor.Peers(keepAddress))
+
+	now = now.Add(time.Second)
+	monitor.checkSilence(keepAddress)
+	assertPeers(
+		t,
+		[]PeerStatus{{MemberID: peer, IsSilent: true}},
+		monitor.Peers(keepAddress),
+	)
+}
+
+func TestPeerMonitorUntrack(t *testing.T) {
+	keepAddress := common.HexToAddress("0x4f76C7CF6a8Fa8dE4C4e5E4B1c2bD6e6D0B9E1c7")
+
+	seat1 := tss.MemberID("member-1")
+	seat2 := tss.MemberID("member-2")
+	groupMemberIDs := []tss.MemberID{seat1, seat2, tss.MemberID("member-3")}
+
+	monitor := NewPeerMonitor(nil)
+
+	// Two operators running in the same process are members of the keep.
+	monitor.track(keepAddress, []tss.MemberID{seat1}, groupMemberIDs)
+	monitor.track(keepAddress, []tss.MemberID{seat2}, groupMemberIDs)
+
+	if peers := monitor.Peers(keepAddress); len(peers) != 1 {
+		t.Errorf(
+			"unexpected number of peers\nexpected: [%d]\nactual:   [%d]",
+			1,
+			len(peers),
+		)
+	}
+
+	monitor.untrack(keepAddress)
+	if peers := monitor.Peers(keepAddress); peers == nil {
+		t.Errorf("keep should be monitored until all nodes untrack it")
+	}
+
+	monitor.untrack(keepAddress)
+	if peers := monitor.Peers(keepAddress); peers != nil {
+		t.Errorf("keep should not be monitored")
+	}
+}
+
+func assertPeers(t *testing.T, expected []PeerStatus, actual []PeerStatus) {
+	if len(expected) != len(actual) {
+		t.Fatalf(
+			"unexpected number of peers\nexpected: [%d]\nactual:   [%d]",
+			len(expected),
+			len(actual),
+		)
+	}
+
+	for i := range expected {
+		if !expected[i].MemberID.Equal(actual[i].MemberID) ||
+			!expected[i].LastSeen.Equal(actual[i].LastSeen) ||
+			expected[i].IsSilent != actual[i].IsSilent {
+			t.Errorf(
+				"unexpected peer status\nexpected: [%+v]\nactual:   [%+v]",
+				expected[i],
+				actual[i],
+			)
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/keep-network/keep-common/pkg/metrics"
+	"github.com/keep-network/keep-common/pkg/persistence"
+
+	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
+)
+
+const (
+	ledgerDirectory = "reliability"
+	ledgerName      = "ledger"
+)
+
+const (
+	// minAttemptsForScore is the number of attempts after which the score
+	// of an operator is taken into account when judging if the operator is
+	// unreliable. It prevents marking an operator as unreliable after a single
+	// failed attempt.
+	minAttemptsForScore = 5
+	// unreliableScoreThreshold is the score below which an operator is
+	// considered unreliable.
+	unreliableScoreThreshold = 0.8
+)
+
+// OperatorReliability records how a co-signer operator behaved in key
+// generation and signing attempts executed together with this node.
+type OperatorReliability struct {
+	Operator common.Address
+	// Attempts is the number of protocol attempts the operator was expected
+	// to take part in.
+	Attempts uint64
+	// MissedAnnouncements is the number of attempts in which the operator
+	// has not announced its presence in time.
+	MissedAnnouncements uint64
+	// LateReadiness is the number of attempts in which the operator has not
+	// signalled readiness in time.
+	LateReadiness uint64
+	// Timeouts is the number of attempts in which the protocol timed out
+	// while still waiting for the operator.
+	Timeouts uint64
+	// ConflictingKeys is the number of keeps to which the operator submitted
+	// a public key conflicting with the key submitted by this node.
+	ConflictingKeys uint64
+	// MessageLimitViolations is the number of protocol sessions in which
+	// the operator's node exceeded message limits.
+	MessageLimitViolations uint64
+	// LastAttempt is the time of the last attempt the operator was expected
+	// to take part in.
+	LastAttempt time.Time
+	// LastIncident is the time of the last missed announcement, late
+	// readiness, timeout, conflicting key or message limit violation of
+	// the operator.
+	LastIncident time.Time
+}
+
+// Score returns the fraction of attempts in which the operator has not
+// missed the announcement, readiness or a protocol step. Operators without
+// recorded attempts have the score of 1.
+func (or *OperatorReliability) Score() float64 {
+	if or.Attempts == 0 {
+		return 1
+	}
+
+	failures := or.MissedAnnouncements + or.LateReadiness + or.Timeouts
+	if failures >= or.Attempts {
+		return 0
+	}
+
+	return 1 - float64(failures)/float64(or.Attempts)
+}
+
+// IsUnreliable returns true if the operator submitted a conflicting public key,
+// violated message limits or if its score is below the threshold after enough
+// attempts.
+func (or *OperatorReliability) IsUnreliable() bool {
+	if or.ConflictingKeys > 0 || or.MessageLimitViolations > 0 {
+		return true
+	}
+
+	return or.Attempts >= minAttemptsForScore &&
+		or.Score() < unreliableScoreThreshold
+}
+
+// ReliabilityLedger records reliability of co-signer operators across all
+// keeps, so that operators can be presented with data about their nodes
+// failing to take part in protocols. The ledger is persisted after each
+// change and can be shared by nodes of all operators running in the same
+// process.
+type ReliabilityLedger struct {
+	handle persistence.Handle
+	now    func() time.Time
+
+	mutex     sync.Mutex
+	operators map[common.Address]*OperatorReliability
+}
+
+// NewReliabilityLedger returns an empty ledger persisted with the provided
+// handle. If the handle is nil, the ledger is held in memory only.
+func NewReliabilityLedger(handle persistence.Handle) *ReliabilityLedger {
+	return &ReliabilityLedger{
+		handle:    handle,
+		now:       time.Now,
+		operators: make(map[common.Address]*OperatorReliability),
+	}
+}
+
+// Load reads the ledger persisted with the handle. If nothing has been
+// persisted yet, the ledger stays empty.
+func (rl *ReliabilityLedger) Load() error {
+	if rl.handle == nil {
+		return nil
+	}
+
+	inputData, inputErrors := rl.handle.ReadAll()
+
+	var (
+		records    []*OperatorReliability
+		recordsErr error
+	)
+
+	// Both channels have to be drained until they are closed, so that
+	// the persistence layer does not block on a write.
+	for inputData != nil || inputErrors != nil {
+		select {
+		case descriptor, ok := <-inputData:
+			if !ok {
+				inputData = nil
+				continue
+			}
+
+			if descriptor.Directory() != ledgerDirectory ||
+				descriptor.Name() != ledgerName {
+				continue
+			}
+
+			content, err := descriptor.Content()
+			if err != nil {
+				recordsErr = fmt.Errorf(
+					"failed to decode reliability ledger content: [%v]",
+					err,
+				)
+				continue
+			}
+
+			if err := json.Unmarshal(content, &records); err != nil {
+				recordsErr = fmt.Errorf(
+					"failed to unmarshal reliability ledger: [%v]",
+					err,
+				)
+			}
+		case err, ok := <-inputErrors:
+			if !ok {
+				inputErrors = nil
+				continue
+			}
+
+			logger.Warningf(
+				"could not read from the reliability ledger storage: [%v]",
+				err,
+			)
+		}
+	}
+
+	if recordsErr != nil {
+		return recordsErr
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for _, record := range records {
+		rl.operators[record.Operator] = record
+	}
+
+	return nil
+}
+
+// Operators returns records of all operators in the ledger, the least
+// reliable first.
+func (rl *ReliabilityLedger) Operators() []OperatorReliability {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	return rl.sortedRecords()
+}
+
+// UnreliableOperatorsCount returns the number of operators considered
+// unreliable.
+func (rl *ReliabilityLedger) UnreliableOperatorsCount() int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	count := 0
+	for _, record := range rl.operators {
+		if record.IsUnreliable() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ObserveUnreliableOperators triggers observation process of
+// unreliable_operators_count metric.
+func (rl *ReliabilityLedger) ObserveUnreliableOperators(
+	ctx context.Context,
+	registry *metrics.Registry,
+	tick time.Duration,
+) {
+	observer, err := registry.NewGaugeObserver(
+		"unreliable_operators_count",
+		func() float64 {
+			return float64(rl.UnreliableOperatorsCount())
+		},
+	)
+	if err != nil {
+		logger.Warningf("could not create gauge observer [unreliable_operators_count]")
+		return
+	}
+
+	observer.Observe(ctx, tick)
+}
+
+// UseReliabilityLedger makes the node record behaviour of co-signers in
+// the provided ledger.
+func (n *Node) UseReliabilityLedger(ledger *ReliabilityLedger) {
+	n.reliabilityLedger = ledger
+}
+
+// recordAttempt records an attempt of a protocol execution with the given
+// co-signers.
+func (n *Node) recordAttempt(operators []common.Address) {
+	if n.reliabilityLedger == nil {
+		return
+	}
+
+	n.reliabilityLedger.update(operators, false, func(record *OperatorReliability) {
+		record.Attempts++
+		record.LastAttempt = n.reliabilityLedger.now()
+	})
+}
+
+// recordMissedAnnouncements records co-signers which have not announced
+// their presence before the announce protocol timed out.
+func (n *Node) recordMissedAnnouncements(
+	operators []common.Address,
+	announcedMemberIDs []tss.MemberID,
+	err error,
+) {
+	if n.reliabilityLedger == nil {
+		return
+	}
+
+	if stage, _, ok := tss.UnresponsiveMembers(err); !ok || stage != tss.AnnounceStage {
+		return
+	}
+
+	announced := make(map[common.Address]bool)
+	for _, address := range memberAddresses(announcedMemberIDs) {
+		announced[address] = true
+	}
+
+	var missing []common.Address
+	for _, operator := range operators {
+		if !announced[operator] {
+			missing = append(missing, operator)
+		}
+	}
+
+	n.reliabilityLedger.update(missing, true, func(record *OperatorReliability) {
+		record.MissedAnnouncements++
+	})
+}
+
+// recordUnresponsiveMembers records co-signers the protocol was still waiting
+// for when it failed with the given error. Nothing is recorded if the error is
+// not a protocol timeout.
+func (n *Node) recordUnresponsiveMembers(
+	operators []common.Address,
+	err error,
+) {
+	if n.reliabilityLedger == nil {
+		return
+	}
+
+	stage, memberIDs, ok := tss.UnresponsiveMembers(err)
+	if !ok {
+		return
+	}
+
+	coSigners := make(map[common.Address]bool)
+	for _, operator := range operators {
+		coSigners[operator] = true
+	}
+
+	var unresponsive []common.Address
+	for _, address := range memberAddresses(memberIDs) {
+		if coSigners[address] {
+			unresponsive = append(unresponsive, address)
+		}
+	}
+
+	n.reliabilityLedger.update(unresponsive, true, func(record *OperatorReliability) {
+		if stage == tss.ReadinessStage {
+			record.LateReadiness++
+		} else {
+			record.Timeouts++
+		}
+	})
+}
+
+// recordConflictingKey records a co-signer which submitted a public key
+// conflicting with the key submitted by this node.
+func (n *Node) recordConflictingKey(operator common.Address) {
+	if n.reliabilityLedger == nil || operator == n.ethereumChain.Address() {
+		return
+	}
+
+	n.reliabilityLedger.update(
+		[]common.Address{operator},
+		true,
+		func(record *OperatorReliability) {
+			record.ConflictingKeys++
+		},
+	)
+}
+
+// recordMessageLimitViolation records an operator whose node exceeded message
+// limits in a protocol session. Several operators may share the node; the
+// violation is recorded for the operator of the node's network key.
+func (n *Node) recordMessageLimitViolation(
+	senderID tss.MemberID,
+	violation string,
+) {
+	if n.reliabilityLedger == nil {
+		return
+	}
+
+	n.reliabilityLedger.update(
+		memberAddresses([]tss.MemberID{senderID}),
+		true,
+		func(record *OperatorReliability) {
+			record.MessageLimitViolations++
+		},
+	)
+}
+
+// update applies the change to records of the given operators and persists
+// the ledger.
+func (rl *ReliabilityLedger) update(
+	operators []common.Address,
+	isIncident bool,
+	change func(record *OperatorReliability),
+) {
+	if len(operators) == 0 {
+		return
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	now := rl.now()
+	for _, operator := range operators {
+		record, ok := rl.operators[operator]
+		if !ok {
+			record = &OperatorReliability{Operator: operator}
+			rl.operators[operator] = record
+		}
+
+		change(record)
+		if isIncident {
+			record.LastIncident = now
+		}
+	}
+
+	if err := rl.save(); err != nil {
+		logger.Warningf("could not persist reliability ledger: [%v]", err)
+	}
+}
+
+func (rl *ReliabilityLedger) save() error {
+	if rl.handle == nil {
+		return nil
+	}
+
+	records := make([]*OperatorReliability, 0, len(rl.operators))
+	for _, record := range rl.operators {
+		records = append(records, record)
+	}
+
+	recordsBytes, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reliability ledger: [%v]", err)
+	}
+
+	return rl.handle.Save(recordsBytes, ledgerDirectory, "/"+ledgerName)
+}
+
+func (rl *ReliabilityLedger) sortedRecords() []OperatorReliability {
+	records := make([]OperatorReliability, 0, len(rl.operators))
+	for _, record := range rl.operators {
+		records = append(records, *record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].IsUnreliable() != records[j].IsUnreliable() {
+			return records[i].IsUnreliable()
+		}
+		if records[i].Score() != records[j].Score() {
+			return records[i].Score() < records[j].Score()
+		}
+		return records[i].Operator.Hex() < records[j].Operator.Hex()
+	})
+
+	return records
+}
+
+// coSigners returns unique addresses of keep members other than the operator.
+func coSigners(
+	operatorAddress common.Address,
+	members []common.Address,
+) []common.Address {
+	seen := make(map[common.Address]bool)
+
+	var operators []common.Address
+	for _, member := range members {
+		if member == operatorAddress || seen[member] {
+			continue
+		}
+		seen[member] = true
+		operators = append(operators, member)
+	}
+
+	return operators
+}
+
+// memberAddresses returns unique operator addresses of the given members.
+func memberAddresses(memberIDs []tss.MemberID) []common.Address {
+	seen := make(map[common.Address]bool)
+
+	var addresses []common.Address
+	for _, memberID := range memberIDs {
+		publicKey, err := memberID.PublicKey()
+		if err != nil {
+			logger.Warningf("invalid member [%s]: [%v]", memberID, err)
+			continue
+		}
+
+		address := crypto.PubkeyToAddress(*publicKey)
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
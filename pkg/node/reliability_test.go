@@ -0,0 +1,174 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/keep-network/keep-common/pkg/persistence"
+)
+
+func TestReliabilityLedger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reliability")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	handle, err := persistence.NewDiskHandle(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reliable := common.HexToAddress("0x65ea55c1f10491038425725dc00dffeab2a1e28a")
+	unresponsive := common.HexToAddress("0x4f76c7cf6a8fa8de4c4e5e4b1c2bd6e6d0b9e1c7")
+	conflicting := common.HexToAddress("0x524f2e0176350d950fa630d65a5a6ff60ef2b1c8")
+
+	now := time.Unix(1600000000, 0).UTC()
+	ledger := NewReliabilityLedger(handle)
+	ledger.now = func() time.Time { return now }
+
+	for i := 0; i < minAttemptsForScore; i++ {
+		ledger.update(
+			[]common.Address{reliable, unresponsive, conflicting},
+			false,
+			func(record *OperatorReliability) { record.Attempts++ },
+		)
+	}
+	ledger.update(
+		[]common.Address{unresponsive},
+		true,
+		func(record *OperatorReliability) { record.MissedAnnouncements++ },
+	)
+	ledger.update(
+		[]common.Address{unresponsive},
+		true,
+		func(record *OperatorReliability) { record.Timeouts++ },
+	)
+	ledger.update(
+		[]common.Address{conflicting},
+		true,
+		func(record *OperatorReliability) { record.ConflictingKeys++ },
+	)
+
+	expectedRecords := []OperatorReliability{
+		{
+			Operator:            unresponsive,
+			Attempts:            5,
+			MissedAnnouncements: 1,
+			Timeouts:            1,
+			LastIncident:        now,
+		},
+		{
+			Operator:        conflicting,
+			Attempts:        5,
+			ConflictingKeys: 1,
+			LastIncident:    now,
+		},
+		{
+			Operator: reliable,
+			Attempts: 5,
+		},
+	}
+
+	if count := ledger.UnreliableOperatorsCount(); count != 2 {
+		t.Errorf(
+			"unexpected number of unreliable operators\nexpected: [%d]\nactual:   [%d]",
+			2,
+			count,
+		)
+	}
+
+	loadedLedger := NewReliabilityLedger(handle)
+	if err := loadedLedger.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ledger := range []*ReliabilityLedger{ledger, loadedLedger} {
+		if records := ledger.Operators(); !reflect.DeepEqual(expectedRecords, records) {
+			t.Errorf(
+				"unexpected records\nexpected: [%+v]\nactual:   [%+v]",
+				expectedRecords,
+				records,
+			)
+		}
+	}
+}
+
+func TestOperatorReliabilityScore(t *testing.T) {
+	var tests = map[string]struct {
+		record             OperatorReliability
+		expectedScore      float64
+		expectedUnreliable bool
+	}{
+		"no attempts": {
+			record:             OperatorReliability{},
+			expectedScore:      1,
+			expectedUnreliable: false,
+		},
+		"few attempts with failures": {
+			record: OperatorReliability{
+				Attempts:      2,
+				LateReadiness: 2,
+			},
+			expectedScore:      0,
+			expectedUnreliable: false,
+		},
+		"many attempts with few failures": {
+			record: OperatorReliability{
+				Attempts: 10,
+				Timeouts: 1,
+			},
+			expectedScore:      0.9,
+			expectedUnreliable: false,
+		},
+		"many attempts with many failures": {
+			record: OperatorReliability{
+				Attempts:            10,
+				MissedAnnouncements: 2,
+				Timeouts:            1,
+			},
+			expectedScore:      0.7,
+			expectedUnreliable: true,
+		},
+		"conflicting key": {
+			record: OperatorReliability{
+				Attempts:        1,
+				ConflictingKeys: 1,
+			},
+			expectedScore:      1,
+			expectedUnreliable: true,
+		},
+		"message limit violation": {
+			record: OperatorReliability{
+				Attempts:               1,
+				MessageLimitViolations: 1,
+			},
+			expectedScore:      1,
+			expectedUnreliable: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if score := test.record.Score(); score != test.expectedScore {
+				t.Errorf(
+					"unexpected score\nexpected: [%v]\nactual:   [%v]",
+					test.expectedScore,
+					score,
+				)
+			}
+
+			if isUnreliable := test.record.IsUnreliable(); isUnreliable != test.expectedUnreliable {
+				t.Errorf(
+					"unexpected unreliability\nexpected: [%v]\nactual:   [%v]",
+					test.expectedUnreliable,
+					isUnreliable,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package node
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/keep-network/keep-common/pkg/metrics"
+)
+
+const (
+	// DefaultMaxConcurrentKeyGenerations is the default number of key
+	// generation protocols executed at the same time.
+	DefaultMaxConcurrentKeyGenerations = 2
+	// DefaultMaxConcurrentSignings is the default number of signing protocols
+	// executed at the same time.
+	DefaultMaxConcurrentSignings = 4
+
+	// maxKeyGenerationHoldback is the maximum time a queued key generation
+	// job is held back by waiting signing jobs. Once it passes, the job is
+	// started as soon as there is a free key generation slot, so that a steady
+	// flow of signing requests does not starve key generation. It is shorter
+	// than the time a key generation attempt waits for its turn after keep
+	// members announced their presence.
+	maxKeyGenerationHoldback = 30 * time.Second
+)
+
+// JobType identifies a type of protocol executed by the node.
+type JobType int
+
+// Types of protocols executed by the node.
+const (
+	KeyGeneration JobType = iota
+	Signing
+)
+
+func (jt JobType) String() string {
+	switch jt {
+	case KeyGeneration:
+		return "key generation"
+	case Signing:
+		return "signing"
+	default:
+		return "unknown"
+	}
+}
+
+// SchedulerConfig contains limits of protocols executed at the same time.
+// Zero value means that the default limit should be used.
+type SchedulerConfig struct {
+	MaxConcurrentKeyGenerations int
+	MaxConcurrentSignings       int
+}
+
+type job struct {
+	jobType  JobType
+	deadline time.Time
+	sequence uint64
+	queuedAt time.Time
+
+	started chan struct{}
+}
+
+// before determines if the job should be started before the other one.
+// Signing jobs go before key generation jobs since a delayed signature may
+// cost signers their bonds. Jobs of the same type are ordered by their
+// deadlines and then by the order in which they have been queued.
+func (j *job) before(other *job) bool {
+	if j.jobType != other.jobType {
+		return j.jobType == Signing
+	}
+
+	if !j.deadline.Equal(other.deadline) {
+		return j.deadline.Before(other.deadline)
+	}
+
+	return j.sequence < other.sequence
+}
+
+// Scheduler bounds the number of key generation and signing protocols
+// executed at the same time. Jobs which can not be started immediately are
+// queued and started in the order of their priority.
+//
+// As long as there is a signing job waiting in the queue, no new key
+// generation job is started, so that signing does not compete for resources
+// with key generation of keeps opened in the meantime. Key generation jobs
+// are held back this way for a limited time only.
+type Scheduler struct {
+	limits                map[JobType]int
+	keyGenerationHoldback time.Duration
+
+	mutex    sync.Mutex
+	running  map[JobType]int
+	queue    []*job
+	sequence uint64
+}
+
+// NewScheduler creates a new scheduler with limits defined in the provided
+// config.
+func NewScheduler(config *SchedulerConfig) *Scheduler {
+	if config == nil {
+		config = &SchedulerConfig{}
+	}
+
+	limits := map[JobType]int{
+		KeyGeneration: DefaultMaxConcurrentKeyGenerations,
+		Signing:       DefaultMaxConcurrentSignings,
+	}
+	if config.MaxConcurrentKeyGenerations > 0 {
+		limits[KeyGeneration] = config.MaxConcurrentKeyGenerations
+	}
+	if config.MaxConcurrentSignings > 0 {
+		limits[Signing] = config.MaxConcurrentSignings
+	}
+
+	logger.Infof(
+		"executing up to [%v] key generations and [%v] signings at the same time",
+		limits[KeyGeneration],
+		limits[Signing],
+	)
+
+	return &Scheduler{
+		limits:                limits,
+		keyGenerationHoldback: maxKeyGenerationHoldback,
+		running:               make(map[JobType]int),
+	}
+}
+
+// Acquire blocks until a job of the given type with the given deadline can be
+// started. It returns a function which must be called once the job is done to
+// free its slot. If the context is done before the job is started, the
+// context error is returned.
+func (s *Scheduler) Acquire(
+	ctx context.Context,
+	jobType JobType,
+	deadline time.Time,
+) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.sequence++
+	job := &job{
+		jobType:  jobType,
+		deadline: deadline,
+		sequence: s.sequence,
+		queuedAt: time.Now(),
+		started:  make(chan struct{}),
+	}
+	s.enqueue(job)
+	s.dispatch()
+	s.mutex.Unlock()
+
+	// A key generation job held back by signing jobs must be dispatched once
+	// the holdback passes, even if no other job is queued or released.
+	if jobType == KeyGeneration {
+		holdbackTimer := time.AfterFunc(s.keyGenerationHoldback, func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			s.dispatch()
+		})
+		defer holdbackTimer.Stop()
+	}
+
+	releaseOnce := &sync.Once{}
+	release := func() {
+		releaseOnce.Do(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			s.running[jobType]--
+			s.dispatch()
+		})
+	}
+
+	select {
+	case <-job.started:
+		return release, nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		select {
+		case <-job.started:
+			// The job has been started in the meantime; free its slot.
+			s.running[jobType]--
+		default:
+			s.remove(job)
+		}
+
+		s.dispatch()
+
+		return nil, ctx.Err()
+	}
+}
+
+// QueueDepth returns the number of jobs of the given type waiting to be
+// started.
+func (s *Scheduler) QueueDepth(jobType JobType) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	depth := 0
+	for _, job := range s.queue {
+		if job.jobType == jobType {
+			depth++
+		}
+	}
+
+	return depth
+}
+
+func (s *Scheduler) enqueue(job *job) {
+	index := sort.Search(len(s.queue), func(i int) bool {
+		return job.before(s.queue[i])
+	})
+
+	s.queue = append(s.queue, nil)
+	copy(s.queue[index+1:], s.queue[index:])
+	s.queue[index] = job
+}
+
+func (s *Scheduler) remove(job *job) {
+	for i, queued := range s.queue {
+		if queued == job {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch starts queued jobs for which there are free slots. Must be called
+// with the mutex locked.
+func (s *Scheduler) dispatch() {
+	isSigningWaiting := false
+	now := time.Now()
+
+	remaining := s.queue[:0]
+	for _, job := range s.queue {
+		canStart := s.running[job.jobType] < s.limits[job.jobType]
+		if job.jobType == KeyGeneration && isSigningWaiting &&
+			now.Sub(job.queuedAt) < s.keyGenerationHoldback {
+			canStart = false
+		}
+
+		if !canStart {
+			if job.jobType == Signing {
+				isSigningWaiting = true
+			}
+
+			remaining = append(remaining, job)
+			continue
+		}
+
+		s.running[job.jobType]++
+		close(job.started)
+	}
+
+	for i := len(remaining); i < len(s.queue); i++ {
+		s.queue[i] = nil
+	}
+	s.queue = remaining
+}
+
+// ObserveQueueDepth triggers observation processes of keygen_queue_depth and
+// signing_queue_depth metrics.
+func (s *Scheduler) ObserveQueueDepth(
+	ctx context.Context,
+	registry *metrics.Registry,
+	tick time.Duration,
+) {
+	observe := func(name string, jobType JobType) {
+		observer, err := registry.NewGaugeObserver(
+			name,
+			func() float64 {
+				return float64(s.QueueDepth(jobType))
+			},
+		)
+		if err != nil {
+			logger.Warningf("could not create gauge observer [%v]", name)
+			return
+		}
+
+		observer.Observe(ctx, tick)
+	}
+
+	observe("keygen_queue_depth", KeyGeneration)
+	observe("signing_queue_depth", Signing)
+}
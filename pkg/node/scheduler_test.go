@@ -0,0 +1,236 @@
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerLimitsConcurrentJobs(t *testing.T) {
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrentKeyGenerations: 1})
+
+	release, err := scheduler.Acquire(context.Background(), KeyGeneration, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	go func() {
+		secondRelease, err := scheduler.Acquire(
+			context.Background(),
+			KeyGeneration,
+			time.Now(),
+		)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer secondRelease()
+
+		close(started)
+	}()
+
+	waitForQueueDepth(t, scheduler, KeyGeneration, 1)
+
+	select {
+	case <-started:
+		t.Fatal("job should not be started before a slot is free")
+	default:
+	}
+
+	release()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("job should be started once a slot is free")
+	}
+
+	if depth := scheduler.QueueDepth(KeyGeneration); depth != 0 {
+		t.Errorf("unexpected queue depth: [%v]", depth)
+	}
+}
+
+func TestSchedulerOrdersQueuedJobs(t *testing.T) {
+	scheduler := NewScheduler(&SchedulerConfig{
+		MaxConcurrentKeyGenerations: 1,
+		MaxConcurrentSignings:       1,
+	})
+
+	// Occupy all slots so that the following jobs are queued.
+	releaseKeyGeneration, _ := scheduler.Acquire(
+		context.Background(),
+		KeyGeneration,
+		time.Now(),
+	)
+	releaseSigning, _ := scheduler.Acquire(
+		context.Background(),
+		Signing,
+		time.Now(),
+	)
+
+	now := time.Now()
+	jobs := []struct {
+		name     string
+		jobType  JobType
+		deadline time.Time
+	}{
+		{"keygen-late", KeyGeneration, now.Add(2 * time.Hour)},
+		{"keygen-early", KeyGeneration, now.Add(1 * time.Hour)},
+		{"signing-late", Signing, now.Add(2 * time.Hour)},
+		{"signing-early", Signing, now.Add(1 * time.Hour)},
+	}
+
+	order := make(chan string, len(jobs))
+	for _, job := range jobs {
+		go func(name string, jobType JobType, deadline time.Time) {
+			release, err := scheduler.Acquire(
+				context.Background(),
+				jobType,
+				deadline,
+			)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			order <- name
+			release()
+		}(job.name, job.jobType, job.deadline)
+
+		waitForQueueDepth(
+			t,
+			scheduler,
+			job.jobType,
+			scheduler.QueueDepth(job.jobType)+1,
+		)
+	}
+
+	// Once the key generation slot is free, key generation should still wait
+	// because there are signing jobs in the queue.
+	releaseKeyGeneration()
+	releaseSigning()
+
+	var actualOrder []string
+	for range jobs {
+		select {
+		case name := <-order:
+			actualOrder = append(actualOrder, name)
+		case <-time.After(time.Second):
+			t.Fatalf("not all jobs started; started: [%v]", actualOrder)
+		}
+	}
+
+	position := make(map[string]int)
+	for i, name := range actualOrder {
+		position[name] = i
+	}
+
+	// Signing and key generation slots are separate so the late signing and
+	// the early key generation may start at the same time. Key generation
+	// may not start while the first signing job waits for its slot though.
+	var expectedBefore = [][2]string{
+		{"signing-early", "signing-late"},
+		{"keygen-early", "keygen-late"},
+		{"signing-early", "keygen-early"},
+	}
+	for _, expected := range expectedBefore {
+		if position[expected[0]] > position[expected[1]] {
+			t.Errorf(
+				"[%v] should start before [%v]; actual order: [%v]",
+				expected[0],
+				expected[1],
+				actualOrder,
+			)
+		}
+	}
+}
+
+func TestSchedulerKeyGenerationHoldbackIsBounded(t *testing.T) {
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrentSignings: 1})
+	scheduler.keyGenerationHoldback = 100 * time.Millisecond
+
+	releaseSigning, _ := scheduler.Acquire(context.Background(), Signing, time.Now())
+	defer releaseSigning()
+
+	// Signing job waiting for its slot for the whole test.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Acquire(ctx, Signing, time.Now())
+	waitForQueueDepth(t, scheduler, Signing, 1)
+
+	started := make(chan time.Time, 1)
+	queuedAt := time.Now()
+	go func() {
+		release, err := scheduler.Acquire(ctx, KeyGeneration, time.Now())
+		if err != nil {
+			return
+		}
+		defer release()
+
+		started <- time.Now()
+	}()
+
+	select {
+	case startedAt := <-started:
+		if startedAt.Sub(queuedAt) < scheduler.keyGenerationHoldback {
+			t.Errorf("key generation should be held back by the waiting signing")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("key generation should be started once the holdback passes")
+	}
+}
+
+func TestSchedulerCancelledJob(t *testing.T) {
+	scheduler := NewScheduler(&SchedulerConfig{MaxConcurrentSignings: 1})
+
+	release, _ := scheduler.Acquire(context.Background(), Signing, time.Now())
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := scheduler.Acquire(ctx, Signing, time.Now())
+	if err != context.DeadlineExceeded {
+		t.Errorf(
+			"unexpected error\nexpected: [%v]\nactual:   [%v]",
+			context.DeadlineExceeded,
+			err,
+		)
+	}
+
+	if depth := scheduler.QueueDepth(Signing); depth != 0 {
+		t.Errorf("cancelled job should be removed from the queue")
+	}
+
+	// Cancelled signing job must not block key generation.
+	keyGenerationCtx, cancelKeyGeneration := context.WithTimeout(
+		context.Background(),
+		time.Second,
+	)
+	defer cancelKeyGeneration()
+
+	if _, err := scheduler.Acquire(keyGenerationCtx, KeyGeneration, time.Now()); err != nil {
+		t.Errorf("key generation should be started: [%v]", err)
+	}
+}
+
+func waitForQueueDepth(
+	t *testing.T,
+	scheduler *Scheduler,
+	jobType JobType,
+	expectedDepth int,
+) {
+	for i := 0; i < 100; i++ {
+		if scheduler.QueueDepth(jobType) == expectedDepth {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf(
+		"queue depth of [%v] jobs did not reach [%v]",
+		jobType,
+		expectedDepth,
+	)
+}
@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// DefaultCommandTimeout is the default time after which a hook command is
+// killed.
+const DefaultCommandTimeout = 10 * time.Second
+
+// Stages passed to hook commands.
+const (
+	stageBeforeSigning = "before_signing"
+	stageAfterSigning  = "after_signing"
+)
+
+// CommandHook executes a local command before and after signing. Signing
+// request details are passed to the command in environment variables:
+//   - KEEP_HOOK_STAGE: before_signing or after_signing,
+//   - KEEP_ADDRESS: address of the keep,
+//   - KEEP_DIGEST: hex-encoded digest to sign,
+//   - KEEP_REQUEST_BLOCK: block in which the signature has been requested,
+//   - KEEP_APPLICATION: address of the application which opened the keep,
+//   - KEEP_SIGNING_ERROR: signing error, set only after failed signing.
+//
+// A command exiting with a non-zero code before signing does not approve
+// signing. Exit code of the command executed after signing is only logged.
+type CommandHook struct {
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+// NewCommandHook creates a hook executing the given command with the given
+// arguments. Zero timeout means that DefaultCommandTimeout is used.
+func NewCommandHook(path string, args []string, timeout time.Duration) *CommandHook {
+	if timeout == 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	return &CommandHook{
+		path:    path,
+		args:    args,
+		timeout: timeout,
+	}
+}
+
+// BeforeSigning executes the command and returns an error if the command
+// fails.
+func (ch *CommandHook) BeforeSigning(
+	ctx context.Context,
+	request *SigningRequest,
+) error {
+	return ch.run(ctx, stageBeforeSigning, request, nil)
+}
+
+// AfterSigning executes the command and logs an error if the command fails.
+func (ch *CommandHook) AfterSigning(
+	ctx context.Context,
+	request *SigningRequest,
+	signingErr error,
+) {
+	if err := ch.run(ctx, stageAfterSigning, request, signingErr); err != nil {
+		logger.Warningf("after signing hook failed: [%v]", err)
+	}
+}
+
+func (ch *CommandHook) run(
+	ctx context.Context,
+	stage string,
+	request *SigningRequest,
+	signingErr error,
+) error {
+	commandCtx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	command := exec.CommandContext(commandCtx, ch.path, ch.args...)
+	command.Env = append(
+		os.Environ(),
+		"KEEP_HOOK_STAGE="+stage,
+		"KEEP_ADDRESS="+request.KeepAddress.String(),
+		"KEEP_DIGEST="+hex.EncodeToString(request.Digest[:]),
+		"KEEP_REQUEST_BLOCK="+strconv.FormatUint(request.RequestBlock, 10),
+		"KEEP_APPLICATION="+request.Application.String(),
+	)
+	if signingErr != nil {
+		command.Env = append(command.Env, "KEEP_SIGNING_ERROR="+signingErr.Error())
+	}
+
+	output, err := command.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf(
+			"command [%s] failed: [%v]; output: [%s]",
+			ch.path,
+			err,
+			output,
+		)
+	}
+
+	if len(output) > 0 {
+		logger.Debugf("command [%s] output: [%s]", ch.path, output)
+	}
+
+	return nil
+}
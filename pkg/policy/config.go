@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/keep-network/keep-ecdsa/pkg/retry"
+)
+
+// Config contains configuration of signing hooks.
+type Config struct {
+	// Mode is either advisory or blocking. If not set, advisory mode is used.
+	Mode Mode
+	// Commands are local commands executed before and after signing.
+	Commands []CommandConfig
+	// Plugins are paths of Go plugins exporting signing hooks.
+	Plugins []string
+}
+
+// CommandConfig contains configuration of a hook command.
+type CommandConfig struct {
+	Path string
+	Args []string
+	// Timeout after which the command is killed. If not set,
+	// DefaultCommandTimeout is used.
+	Timeout retry.Duration
+}
+
+// NewFromConfig creates a policy executing hooks defined in the config.
+// Command hooks are executed before plugin hooks.
+func NewFromConfig(config *Config) (*Policy, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	var hooks []Hook
+
+	for _, command := range config.Commands {
+		if command.Path == "" {
+			return nil, fmt.Errorf("signing hook command path is required")
+		}
+
+		hooks = append(
+			hooks,
+			NewCommandHook(command.Path, command.Args, command.Timeout.Duration),
+		)
+	}
+
+	for _, path := range config.Plugins {
+		hook, err := LoadPlugin(path)
+		if err != nil {
+			return nil, err
+		}
+
+		hooks = append(hooks, hook)
+	}
+
+	return New(config.Mode, hooks...)
+}
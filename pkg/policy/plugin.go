@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// PluginSymbol is the name of the symbol a Go plugin has to export in order to
+// be used as a signing hook. The symbol should be a variable implementing
+// the Hook interface.
+const PluginSymbol = "SigningHook"
+
+// LoadPlugin opens the Go plugin from the given path and returns the hook it
+// exports.
+func LoadPlugin(path string) (Hook, error) {
+	loadedPlugin, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open plugin [%s]: [%v]", path, err)
+	}
+
+	symbol, err := loadedPlugin.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not find [%s] in plugin [%s]: [%v]",
+			PluginSymbol,
+			path,
+			err,
+		)
+	}
+
+	switch hook := symbol.(type) {
+	case *Hook:
+		return *hook, nil
+	case Hook:
+		return hook, nil
+	default:
+		return nil, fmt.Errorf(
+			"[%s] in plugin [%s] does not implement signing hook",
+			PluginSymbol,
+			path,
+		)
+	}
+}
@@ -0,0 +1,116 @@
+// Package policy provides hooks executed by the client before and after it
+// calculates a signature. Hooks let operators log, notify about or verify
+// digests against their own policy before anything is signed.
+package policy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ipfs/go-log"
+)
+
+var logger = log.Logger("keep-policy")
+
+// SigningRequest describes a signature the client has been asked to calculate.
+type SigningRequest struct {
+	KeepAddress  common.Address
+	Digest       [32]byte
+	RequestBlock uint64
+	// Application is the address of the application which opened the keep.
+	// It is a zero address if the application is not known.
+	Application common.Address
+}
+
+// Hook is called by the client before and after signing.
+type Hook interface {
+	// BeforeSigning is called before the client starts signing. A non-nil
+	// error means the hook does not approve signing.
+	BeforeSigning(ctx context.Context, request *SigningRequest) error
+
+	// AfterSigning is called once signing completes. The signing error is nil
+	// if the signature has been calculated and published successfully.
+	AfterSigning(ctx context.Context, request *SigningRequest, signingErr error)
+}
+
+// Mode determines what happens when a hook does not approve signing.
+type Mode string
+
+const (
+	// Advisory mode logs hook errors and lets the client sign anyway.
+	Advisory Mode = "advisory"
+	// Blocking mode makes the client refuse to sign if any hook does not
+	// approve signing.
+	Blocking Mode = "blocking"
+)
+
+// Policy executes signing hooks in the order they have been provided.
+// A nil policy executes no hooks.
+type Policy struct {
+	mode  Mode
+	hooks []Hook
+}
+
+// New creates a policy executing the given hooks in the given mode.
+func New(mode Mode, hooks ...Hook) (*Policy, error) {
+	switch mode {
+	case "":
+		mode = Advisory
+	case Advisory, Blocking:
+	default:
+		return nil, fmt.Errorf("unknown signing policy mode [%v]", mode)
+	}
+
+	return &Policy{mode: mode, hooks: hooks}, nil
+}
+
+// BeforeSigning executes BeforeSigning of all hooks. In blocking mode it
+// returns an error if any hook does not approve signing; remaining hooks are
+// not executed then. In advisory mode hook errors are only logged.
+func (p *Policy) BeforeSigning(ctx context.Context, request *SigningRequest) error {
+	if p == nil {
+		return nil
+	}
+
+	for _, hook := range p.hooks {
+		err := hook.BeforeSigning(ctx, request)
+		if err == nil {
+			continue
+		}
+
+		if p.mode == Blocking {
+			return fmt.Errorf(
+				"signing of digest [%x] for keep [%s] rejected: [%v]",
+				request.Digest,
+				request.KeepAddress.String(),
+				err,
+			)
+		}
+
+		logger.Warningf(
+			"signing hook does not approve signing of digest [%x] "+
+				"for keep [%s]; signing anyway in advisory mode: [%v]",
+			request.Digest,
+			request.KeepAddress.String(),
+			err,
+		)
+	}
+
+	return nil
+}
+
+// AfterSigning executes AfterSigning of all hooks.
+func (p *Policy) AfterSigning(
+	ctx context.Context,
+	request *SigningRequest,
+	signingErr error,
+) {
+	if p == nil {
+		return
+	}
+
+	for _, hook := range p.hooks {
+		hook.AfterSigning(ctx, request, signingErr)
+	}
+}
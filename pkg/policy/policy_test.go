@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var testRequest = &SigningRequest{
+	KeepAddress:  common.HexToAddress("0x770a9E2F2Aa1eC2d3Ca916Fc3e6A55058A898632"),
+	Digest:       [32]byte{1, 2, 3},
+	RequestBlock: 100,
+	Application:  common.HexToAddress("0x8B3BccB3A3994681A1C1584DE4b4E8b23ed1Ed6d"),
+}
+
+func TestPolicyModes(t *testing.T) {
+	var tests = map[string]struct {
+		mode          Mode
+		hookError     error
+		expectedError bool
+	}{
+		"advisory mode with approving hook": {
+			mode: Advisory,
+		},
+		"advisory mode with rejecting hook": {
+			mode:      Advisory,
+			hookError: fmt.Errorf("rejected"),
+		},
+		"blocking mode with approving hook": {
+			mode: Blocking,
+		},
+		"blocking mode with rejecting hook": {
+			mode:          Blocking,
+			hookError:     fmt.Errorf("rejected"),
+			expectedError: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			hook := &testHook{beforeSigningError: test.hookError}
+
+			policy, err := New(test.mode, hook)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = policy.BeforeSigning(context.Background(), testRequest)
+			if test.expectedError != (err != nil) {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+
+			policy.AfterSigning(context.Background(), testRequest, nil)
+
+			if hook.beforeSigningCalls != 1 || hook.afterSigningCalls != 1 {
+				t.Errorf(
+					"unexpected number of calls\nbefore: [%v]\nafter:  [%v]",
+					hook.beforeSigningCalls,
+					hook.afterSigningCalls,
+				)
+			}
+		})
+	}
+}
+
+func TestPolicyUnknownMode(t *testing.T) {
+	if _, err := New("strict"); err == nil {
+		t.Errorf("expected error for unknown mode")
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	var policy *Policy
+
+	if err := policy.BeforeSigning(context.Background(), testRequest); err != nil {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+
+	policy.AfterSigning(context.Background(), testRequest, nil)
+}
+
+func TestCommandHook(t *testing.T) {
+	approvingHook := NewCommandHook(
+		"sh",
+		[]string{
+			"-c",
+			"test \"$KEEP_HOOK_STAGE\" = before_signing && " +
+				"test \"$KEEP_DIGEST\" = " +
+				"\"0102030000000000000000000000000000000000000000000000000000000000\" && " +
+				"test \"$KEEP_REQUEST_BLOCK\" = 100",
+		},
+		0,
+	)
+	if err := approvingHook.BeforeSigning(context.Background(), testRequest); err != nil {
+		t.Errorf("unexpected error: [%v]", err)
+	}
+
+	rejectingHook := NewCommandHook("sh", []string{"-c", "exit 1"}, 0)
+	if err := rejectingHook.BeforeSigning(context.Background(), testRequest); err == nil {
+		t.Errorf("expected error for failing command")
+	}
+}
+
+type testHook struct {
+	beforeSigningError error
+
+	beforeSigningCalls int
+	afterSigningCalls  int
+}
+
+func (th *testHook) BeforeSigning(ctx context.Context, request *SigningRequest) error {
+	th.beforeSigningCalls++
+	return th.beforeSigningError
+}
+
+func (th *testHook) AfterSigning(
+	ctx context.Context,
+	request *SigningRequest,
+	signingErr error,
+) {
+	th.afterSigningCalls++
+}
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sync"
 
@@ -14,6 +15,12 @@ type Keeps struct {
 	myKeepsMutex *sync.RWMutex
 	myKeeps      map[common.Address][]*tss.ThresholdSigner
 
+	lastProcessedBlocksMutex *sync.RWMutex
+	lastProcessedBlocks      map[common.Address]uint64
+
+	keepStatesMutex *sync.RWMutex
+	keepStates      map[common.Address][]byte
+
 	storage storage
 }
 
@@ -22,7 +29,14 @@ func NewKeepsRegistry(persistence persistence.Handle) *Keeps {
 	return &Keeps{
 		myKeepsMutex: &sync.RWMutex{},
 		myKeeps:      make(map[common.Address][]*tss.ThresholdSigner),
-		storage:      newStorage(persistence),
+
+		lastProcessedBlocksMutex: &sync.RWMutex{},
+		lastProcessedBlocks:      make(map[common.Address]uint64),
+
+		keepStatesMutex: &sync.RWMutex{},
+		keepStates:      make(map[common.Address][]byte),
+
+		storage: newStorage(persistence),
 	}
 }
 
@@ -53,6 +67,84 @@ func (k *Keeps) UnregisterKeep(keepAddress common.Address) {
 	}
 
 	delete(k.myKeeps, keepAddress)
+
+	k.lastProcessedBlocksMutex.Lock()
+	delete(k.lastProcessedBlocks, keepAddress)
+	k.lastProcessedBlocksMutex.Unlock()
+
+	k.keepStatesMutex.Lock()
+	delete(k.keepStates, keepAddress)
+	k.keepStatesMutex.Unlock()
+}
+
+// SaveKeepState persists the lifecycle state of the given keep. The state is
+// opaque to the registry and is kept in the keep directory, so it is archived
+// together with the keep.
+func (k *Keeps) SaveKeepState(keepAddress common.Address, state []byte) error {
+	k.keepStatesMutex.Lock()
+	defer k.keepStatesMutex.Unlock()
+
+	err := k.storage.saveKeepState(keepAddress, state)
+	if err != nil {
+		return fmt.Errorf("could not persist keep state to the storage: [%v]", err)
+	}
+
+	k.keepStates[keepAddress] = state
+
+	return nil
+}
+
+// GetKeepStates returns lifecycle states of all keeps for which a state has
+// been saved and which have not been archived.
+func (k *Keeps) GetKeepStates() map[common.Address][]byte {
+	k.keepStatesMutex.RLock()
+	defer k.keepStatesMutex.RUnlock()
+
+	keepStates := make(map[common.Address][]byte, len(k.keepStates))
+	for keepAddress, state := range k.keepStates {
+		keepStates[keepAddress] = state
+	}
+
+	return keepStates
+}
+
+// UpdateLastProcessedBlock records that all events of the given keep have
+// been processed up to and including the given block. The block is persisted
+// so that events missed while the client was not running can be processed
+// after a restart. Blocks lower than the one already recorded are ignored.
+func (k *Keeps) UpdateLastProcessedBlock(
+	keepAddress common.Address,
+	block uint64,
+) error {
+	k.lastProcessedBlocksMutex.Lock()
+	defer k.lastProcessedBlocksMutex.Unlock()
+
+	if lastBlock, ok := k.lastProcessedBlocks[keepAddress]; ok && lastBlock >= block {
+		return nil
+	}
+
+	err := k.storage.saveLastProcessedBlock(keepAddress, block)
+	if err != nil {
+		return fmt.Errorf(
+			"could not persist last processed block to the storage: [%v]",
+			err,
+		)
+	}
+
+	k.lastProcessedBlocks[keepAddress] = block
+
+	return nil
+}
+
+// LastProcessedBlock returns the last block for which events of the given
+// keep have been processed. The second returned value is false if no block
+// has been recorded for the keep yet.
+func (k *Keeps) LastProcessedBlock(keepAddress common.Address) (uint64, bool) {
+	k.lastProcessedBlocksMutex.RLock()
+	defer k.lastProcessedBlocksMutex.RUnlock()
+
+	block, ok := k.lastProcessedBlocks[keepAddress]
+	return block, ok
 }
 
 // GetSigners gets signers by a keep address.
@@ -92,22 +184,53 @@ func (k *Keeps) GetKeepsAddresses() []common.Address {
 }
 
 // LoadExistingKeeps iterates over all signers stored on disk and loads them
-// into memory
+// into memory. Keeps loaded before are replaced, so the registry can be
+// refreshed with keeps persisted in the meantime by another node sharing
+// the storage.
 func (k *Keeps) LoadExistingKeeps() {
-	keepSignersChannel, errorsChannel := k.storage.readAll()
-
-	// Two goroutines read from signers and errors channels and either adds
-	// signers to the keeps registry or outputs an error to stderr.
-	// The reason for using two goroutines at the same time - one for signers
-	// and one for errors is because channels do not have to be
-	// buffered and we do not know in what order information is written to
-	// channels.
+	keepSignersChannel, keepFilesChannel, errorsChannel := k.storage.readAll()
+
+	myKeeps := make(map[common.Address][]*tss.ThresholdSigner)
+	lastProcessedBlocks := make(map[common.Address]uint64)
+	keepStates := make(map[common.Address][]byte)
+
+	// Three goroutines read from signers, files and errors channels and
+	// either collect signers, last processed blocks and keep states or output
+	// an error to stderr.
+	// The reason for using three goroutines at the same time - one for each
+	// channel is because channels do not have to be buffered and we do not
+	// know in what order information is written to channels.
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 
 	go func() {
 		for keepSigner := range keepSignersChannel {
-			k.storeSigner(keepSigner.keepAddress, keepSigner.signer)
+			myKeeps[keepSigner.keepAddress] = append(
+				myKeeps[keepSigner.keepAddress],
+				keepSigner.signer,
+			)
+		}
+
+		wg.Done()
+	}()
+
+	go func() {
+		for keepFile := range keepFilesChannel {
+			switch keepFile.name {
+			case lastProcessedBlockFileName:
+				if len(keepFile.content) != 8 {
+					logger.Errorf(
+						"invalid last processed block for keep [%s]",
+						keepFile.keepAddress.String(),
+					)
+					continue
+				}
+
+				lastProcessedBlocks[keepFile.keepAddress] =
+					binary.BigEndian.Uint64(keepFile.content)
+			case keepStateFileName:
+				keepStates[keepFile.keepAddress] = keepFile.content
+			}
 		}
 
 		wg.Done()
@@ -123,6 +246,18 @@ func (k *Keeps) LoadExistingKeeps() {
 
 	wg.Wait()
 
+	k.myKeepsMutex.Lock()
+	k.myKeeps = myKeeps
+	k.myKeepsMutex.Unlock()
+
+	k.lastProcessedBlocksMutex.Lock()
+	k.lastProcessedBlocks = lastProcessedBlocks
+	k.lastProcessedBlocksMutex.Unlock()
+
+	k.keepStatesMutex.Lock()
+	k.keepStates = keepStates
+	k.keepStatesMutex.Unlock()
+
 	k.printSigners()
 }
 
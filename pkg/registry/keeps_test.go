@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"encoding/binary"
 	"fmt"
 	"reflect"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 
 	"github.com/keep-network/keep-common/pkg/persistence"
+	"github.com/keep-network/keep-core/pkg/operator"
 	"github.com/keep-network/keep-ecdsa/internal/testdata"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss/gen/pb"
@@ -19,6 +21,9 @@ var (
 	keepAddress2 = common.HexToAddress("0x8B3BccB3A3994681A1C1584DE4b4E8b23ed1Ed6d")
 	keepAddress3 = common.HexToAddress("0x0472ec0185ebb8202f3d4ddb0226998889663cf2")
 
+	testLastProcessedBlock = uint64(1234)
+	testKeepState          = []byte("key_published")
+
 	groupMemberIDs = [][]byte{
 		[]byte("member-1"),
 		[]byte("member-2"),
@@ -205,6 +210,173 @@ func TestLoadExistingGroups(t *testing.T) {
 	if !reflect.DeepEqual(expectedSigners2, actualSigners2) {
 		t.Errorf("\nexpected: [%v]\nactual:   [%v]", expectedSigners2, actualSigners2)
 	}
+
+	if _, ok := kr.LastProcessedBlock(keepAddress1); ok {
+		t.Errorf("unexpected last processed block for keep [%v]", keepAddress1.String())
+	}
+
+	lastProcessedBlock, ok := kr.LastProcessedBlock(keepAddress2)
+	if !ok {
+		t.Fatalf("no last processed block for keep [%v]", keepAddress2.String())
+	}
+	if lastProcessedBlock != testLastProcessedBlock {
+		t.Errorf(
+			"unexpected last processed block\nexpected: [%v]\nactual:   [%v]",
+			testLastProcessedBlock,
+			lastProcessedBlock,
+		)
+	}
+
+	expectedKeepStates := map[common.Address][]byte{keepAddress2: testKeepState}
+	if !reflect.DeepEqual(expectedKeepStates, kr.GetKeepStates()) {
+		t.Errorf(
+			"unexpected keep states\nexpected: [%s]\nactual:   [%s]",
+			expectedKeepStates,
+			kr.GetKeepStates(),
+		)
+	}
+}
+
+func TestReloadExistingGroups(t *testing.T) {
+	persistenceMock := &persistenceHandleMock{}
+
+	signers, err := testSigners()
+	if err != nil {
+		t.Fatalf("failed to get signer: [%v]", err)
+	}
+
+	kr := NewKeepsRegistry(persistenceMock)
+	kr.LoadExistingKeeps()
+
+	// Keep registered only in memory is not present in the storage read by
+	// the mock, as if it has been archived by another node in the meantime.
+	if err := kr.RegisterSigner(keepAddress3, signers[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	kr.LoadExistingKeeps()
+
+	if kr.HasSigner(keepAddress3) {
+		t.Errorf("keep not present in the storage should be removed")
+	}
+
+	actualSigners2, err := kr.GetSigners(keepAddress2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actualSigners2) != 2 {
+		t.Errorf(
+			"unexpected number of signers after reload\nexpected: [%v]\nactual:   [%v]",
+			2,
+			len(actualSigners2),
+		)
+	}
+}
+
+func TestSaveKeepState(t *testing.T) {
+	persistenceMock := &persistenceHandleMock{}
+	kr := NewKeepsRegistry(persistenceMock)
+
+	if err := kr.SaveKeepState(keepAddress1, testKeepState); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedFiles := []*testFileInfo{
+		{
+			data:      testKeepState,
+			directory: keepAddress1.String(),
+			name:      "/state",
+		},
+	}
+	if !reflect.DeepEqual(expectedFiles, persistenceMock.persistedGroups) {
+		t.Errorf(
+			"unexpected persisted files\nexpected: [%+v]\nactual:   [%+v]",
+			expectedFiles,
+			persistenceMock.persistedGroups,
+		)
+	}
+
+	kr.UnregisterKeep(keepAddress1)
+
+	if len(kr.GetKeepStates()) != 0 {
+		t.Errorf("keep state should be removed for unregistered keep")
+	}
+}
+
+func TestUpdateLastProcessedBlock(t *testing.T) {
+	persistenceMock := &persistenceHandleMock{}
+	kr := NewKeepsRegistry(persistenceMock)
+
+	if _, ok := kr.LastProcessedBlock(keepAddress1); ok {
+		t.Fatal("unexpected last processed block at start")
+	}
+
+	if err := kr.UpdateLastProcessedBlock(keepAddress1, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lower block should be ignored and not persisted.
+	if err := kr.UpdateLastProcessedBlock(keepAddress1, 90); err != nil {
+		t.Fatal(err)
+	}
+
+	lastProcessedBlock, ok := kr.LastProcessedBlock(keepAddress1)
+	if !ok || lastProcessedBlock != 100 {
+		t.Errorf(
+			"unexpected last processed block\nexpected: [%v]\nactual:   [%v]",
+			100,
+			lastProcessedBlock,
+		)
+	}
+
+	expectedBlockBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(expectedBlockBytes, 100)
+
+	expectedFiles := []*testFileInfo{
+		{
+			data:      expectedBlockBytes,
+			directory: keepAddress1.String(),
+			name:      "/last_processed_block",
+		},
+	}
+	if !reflect.DeepEqual(expectedFiles, persistenceMock.persistedGroups) {
+		t.Errorf(
+			"unexpected persisted files\nexpected: [%+v]\nactual:   [%+v]",
+			expectedFiles,
+			persistenceMock.persistedGroups,
+		)
+	}
+}
+
+func TestMembershipFileNameOfSeats(t *testing.T) {
+	_, operatorPublicKey, err := operator.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seat1 := membershipFileName(
+		tss.MemberIDFromPublicKeyAndIndex(operatorPublicKey, 1),
+	)
+	seat2 := membershipFileName(
+		tss.MemberIDFromPublicKeyAndIndex(operatorPublicKey, 2),
+	)
+
+	if seat1 == seat2 {
+		t.Errorf("seats of the same operator should have different file names")
+	}
+
+	legacy := membershipFileName(tss.MemberIDFromPublicKey(operatorPublicKey))
+	expectedLegacy := fmt.Sprintf(
+		"/membership_%.40s",
+		tss.MemberIDFromPublicKey(operatorPublicKey).String(),
+	)
+	if legacy != expectedLegacy {
+		t.Errorf(
+			"unexpected file name\nexpected: [%v]\nactual:   [%v]",
+			expectedLegacy,
+			legacy,
+		)
+	}
 }
 
 type persistenceHandleMock struct {
@@ -236,12 +408,17 @@ func (phm *persistenceHandleMock) ReadAll() (<-chan persistence.DataDescriptor,
 	signerBytes2, _ := signer2.Marshal()
 	signerBytes3, _ := signer3.Marshal()
 
-	outputData := make(chan persistence.DataDescriptor, 3)
+	lastProcessedBlockBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(lastProcessedBlockBytes, testLastProcessedBlock)
+
+	outputData := make(chan persistence.DataDescriptor, 5)
 	outputErrors := make(chan error)
 
 	outputData <- &testDataDescriptor{"/membership_0", keepAddress1.String(), signerBytes1}
 	outputData <- &testDataDescriptor{"/membership_0", keepAddress2.String(), signerBytes2}
 	outputData <- &testDataDescriptor{"/membership_1", keepAddress2.String(), signerBytes3}
+	outputData <- &testDataDescriptor{"last_processed_block", keepAddress2.String(), lastProcessedBlockBytes}
+	outputData <- &testDataDescriptor{"/state", keepAddress2.String(), testKeepState}
 
 	close(outputData)
 	close(outputErrors)
@@ -1,7 +1,9 @@
 package registry
 
 import (
+	"encoding/binary"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -9,9 +11,20 @@ import (
 	"github.com/keep-network/keep-ecdsa/pkg/ecdsa/tss"
 )
 
+// lastProcessedBlockFileName is the name of the file in the keep directory
+// holding the number of the last block for which the keep events have been
+// processed.
+const lastProcessedBlockFileName = "last_processed_block"
+
+// keepStateFileName is the name of the file in the keep directory holding
+// the lifecycle state of the keep.
+const keepStateFileName = "state"
+
 type storage interface {
 	save(keepAddress common.Address, signer *tss.ThresholdSigner) error
-	readAll() (<-chan *keepSigner, <-chan error)
+	saveLastProcessedBlock(keepAddress common.Address, block uint64) error
+	saveKeepState(keepAddress common.Address, state []byte) error
+	readAll() (<-chan *keepSigner, <-chan *keepFile, <-chan error)
 	archive(keepAddress string) error
 }
 
@@ -34,9 +47,48 @@ func (ps *persistentStorage) save(keepAddress common.Address, signer *tss.Thresh
 	return ps.handle.Save(
 		signerBytes,
 		keepAddress.String(),
-		// Take just the first 20 bytes of member ID so that we don't produce
-		// too long file names.
-		fmt.Sprintf("/membership_%.40s", signer.MemberID().String()),
+		membershipFileName(signer.MemberID()),
+	)
+}
+
+// membershipFileName returns the name of the file holding the signer of
+// the member with the given ID. Take just the first 20 bytes of member ID so
+// that we don't produce too long file names. Member IDs of seats the operator
+// holds in the keep share the public key of the operator, so the seat index
+// following the uncompressed public key is appended to the name.
+func membershipFileName(memberID tss.MemberID) string {
+	const publicKeyLength = 65
+
+	name := fmt.Sprintf("/membership_%.40s", memberID.String())
+	if len(memberID) > publicKeyLength {
+		name += "_" + tss.MemberID(memberID[publicKeyLength:]).String()
+	}
+
+	return name
+}
+
+func (ps *persistentStorage) saveLastProcessedBlock(
+	keepAddress common.Address,
+	block uint64,
+) error {
+	blockBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(blockBytes, block)
+
+	return ps.handle.Save(
+		blockBytes,
+		keepAddress.String(),
+		"/"+lastProcessedBlockFileName,
+	)
+}
+
+func (ps *persistentStorage) saveKeepState(
+	keepAddress common.Address,
+	state []byte,
+) error {
+	return ps.handle.Save(
+		state,
+		keepAddress.String(),
+		"/"+keepStateFileName,
 	)
 }
 
@@ -45,8 +97,21 @@ type keepSigner struct {
 	signer      *tss.ThresholdSigner
 }
 
-func (ps *persistentStorage) readAll() (<-chan *keepSigner, <-chan error) {
+// keepFile is a file from the keep directory which does not hold a signer,
+// e.g. the last processed block or the keep state.
+type keepFile struct {
+	keepAddress common.Address
+	name        string
+	content     []byte
+}
+
+func (ps *persistentStorage) readAll() (
+	<-chan *keepSigner,
+	<-chan *keepFile,
+	<-chan error,
+) {
 	outputKeepSigner := make(chan *keepSigner)
+	outputKeepFile := make(chan *keepFile)
 	outputErrors := make(chan error)
 
 	inputData, inputErrors := ps.handle.ReadAll()
@@ -66,6 +131,7 @@ func (ps *persistentStorage) readAll() (<-chan *keepSigner, <-chan error) {
 	go func() {
 		wg.Wait()
 		close(outputKeepSigner)
+		close(outputKeepFile)
 		close(outputErrors)
 	}()
 
@@ -80,8 +146,9 @@ func (ps *persistentStorage) readAll() (<-chan *keepSigner, <-chan error) {
 
 	// Signers goroutine reads data from input channel, tries to unmarshal
 	// the data to Signer and write the unmarshalled Signer to the output signers
-	// channel. In case of an error, goroutine writes that error to an output
-	// errors channel.
+	// channel. The last processed block and the state of the keep are written
+	// to the output files channel. In case of an error, goroutine writes that
+	// error to an output errors channel.
 	go func() {
 		for descriptor := range inputData {
 			content, err := descriptor.Content()
@@ -104,6 +171,16 @@ func (ps *persistentStorage) readAll() (<-chan *keepSigner, <-chan error) {
 			}
 			keepAddress := common.HexToAddress(descriptor.Directory())
 
+			name := strings.TrimPrefix(descriptor.Name(), "/")
+			if name == lastProcessedBlockFileName || name == keepStateFileName {
+				outputKeepFile <- &keepFile{
+					keepAddress: keepAddress,
+					name:        name,
+					content:     content,
+				}
+				continue
+			}
+
 			signer := &tss.ThresholdSigner{}
 			err = signer.Unmarshal(content)
 			if err != nil {
@@ -125,7 +202,7 @@ func (ps *persistentStorage) readAll() (<-chan *keepSigner, <-chan error) {
 		wg.Done()
 	}()
 
-	return outputKeepSigner, outputErrors
+	return outputKeepSigner, outputKeepFile, outputErrors
 }
 
 func (ps *persistentStorage) archive(keepAddress string) error {
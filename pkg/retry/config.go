@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"time"
+)
+
+// Operation identifies a type of operation retried according to its own
+// policy.
+type Operation string
+
+// Operation types with separately configured retry policies.
+const (
+	// ChainCall covers reads from the chain and other calls of the chain
+	// client which do not submit transactions.
+	ChainCall Operation = "ChainCall"
+	// ChainSubmission covers submissions of transactions to the chain.
+	ChainSubmission Operation = "ChainSubmission"
+	// Protocol covers attempts of key generation and signing protocols.
+	Protocol Operation = "Protocol"
+	// Network covers opening of channels with other peers.
+	Network Operation = "Network"
+)
+
+var defaultPolicies = map[Operation]Policy{
+	ChainCall: {
+		InitialInterval: Duration{1 * time.Second},
+		MaxInterval:     Duration{1 * time.Minute},
+		Multiplier:      2,
+		Jitter:          0.2,
+	},
+	ChainSubmission: {
+		InitialInterval: Duration{12 * time.Second},
+		MaxInterval:     Duration{2 * time.Minute},
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     10,
+	},
+	Protocol: {
+		InitialInterval: Duration{1 * time.Second},
+		MaxInterval:     Duration{30 * time.Second},
+		Multiplier:      2,
+		Jitter:          0.2,
+	},
+	Network: {
+		InitialInterval: Duration{5 * time.Second},
+		MaxInterval:     Duration{30 * time.Second},
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     3,
+	},
+}
+
+// Config contains retry policies for each operation type. Fields which are
+// not set in the policy of an operation are taken from the default policy
+// and, if not set there either, from the built-in policy of the operation.
+type Config struct {
+	Default         PolicyConfig
+	ChainCall       PolicyConfig
+	ChainSubmission PolicyConfig
+	Protocol        PolicyConfig
+	Network         PolicyConfig
+}
+
+// PolicyConfig contains configured fields of a retry policy. Fields are
+// pointers so that a field which is not set can be told apart from a field
+// explicitly set to zero, e.g. zero jitter or an unlimited number of attempts.
+type PolicyConfig struct {
+	InitialInterval *Duration
+	MaxInterval     *Duration
+	Multiplier      *float64
+	Jitter          *float64
+	MaxElapsedTime  *Duration
+	MaxAttempts     *int
+}
+
+// Policy returns the retry policy for the given operation type.
+func (c *Config) Policy(operation Operation) *Policy {
+	policy := defaultPolicies[operation]
+
+	if c != nil {
+		c.Default.applyTo(&policy)
+
+		switch operation {
+		case ChainCall:
+			c.ChainCall.applyTo(&policy)
+		case ChainSubmission:
+			c.ChainSubmission.applyTo(&policy)
+		case Protocol:
+			c.Protocol.applyTo(&policy)
+		case Network:
+			c.Network.applyTo(&policy)
+		}
+	}
+
+	// Delays should never decrease.
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 1
+	}
+
+	return &policy
+}
+
+// applyTo overrides fields of the policy with fields set in the config.
+func (pc *PolicyConfig) applyTo(policy *Policy) {
+	if pc.InitialInterval != nil {
+		policy.InitialInterval = *pc.InitialInterval
+	}
+	if pc.MaxInterval != nil {
+		policy.MaxInterval = *pc.MaxInterval
+	}
+	if pc.Multiplier != nil {
+		policy.Multiplier = *pc.Multiplier
+	}
+	if pc.Jitter != nil {
+		policy.Jitter = *pc.Jitter
+	}
+	if pc.MaxElapsedTime != nil {
+		policy.MaxElapsedTime = *pc.MaxElapsedTime
+	}
+	if pc.MaxAttempts != nil {
+		policy.MaxAttempts = *pc.MaxAttempts
+	}
+}
+
+// Duration is a time.Duration which can be parsed from a configuration file.
+// We use BurntSushi/toml package to parse configuration file. Unfortunately it
+// doesn't support time.Duration out of the box. Here we introduce a workaround
+// to be able to parse values provided in more friendly way, e.g. "4m20s".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration from its text representation.
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
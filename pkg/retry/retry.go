@@ -0,0 +1,143 @@
+// Package retry provides context-aware retries of failed operations with
+// an exponential backoff, a jitter and limits on the number of attempts and
+// on the total time spent on retrying.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrExhausted is returned when the limits of the retry policy have been
+// reached and no more attempts should be made.
+var ErrExhausted = errors.New("retry limits exhausted")
+
+// Policy defines delays between consecutive attempts of an operation and
+// limits of retrying.
+type Policy struct {
+	// Delay after the first failed attempt.
+	InitialInterval Duration
+	// Maximum delay between two attempts.
+	MaxInterval Duration
+	// Factor by which the delay grows after each failed attempt.
+	Multiplier float64
+	// Fraction of the delay, from 0 to 1, by which each delay is randomly
+	// increased or decreased, so that clients retrying at the same time do
+	// not hit the same endpoint in the same moment.
+	Jitter float64
+	// Time after which retrying is given up, counted from the first attempt.
+	// Zero means no limit.
+	MaxElapsedTime Duration
+	// Maximum number of attempts, including the first one. Zero means
+	// no limit.
+	MaxAttempts int
+}
+
+// Backoff tracks delays between consecutive attempts of a single operation
+// executed according to a retry policy. Backoff is not safe for concurrent
+// use.
+type Backoff struct {
+	policy *Policy
+
+	startTime time.Time
+	attempts  int
+	interval  time.Duration
+}
+
+// NewBackoff creates a new backoff for the given policy. The first attempt
+// is assumed to be made at the time of the call.
+func NewBackoff(policy *Policy) *Backoff {
+	backoff := &Backoff{policy: policy}
+	backoff.Reset()
+
+	return backoff
+}
+
+// Reset starts counting the delays and limits of the policy from the
+// beginning. It should be called once an operation succeeded and the
+// backoff is going to be used for the subsequent failures.
+func (b *Backoff) Reset() {
+	b.startTime = time.Now()
+	b.attempts = 1
+	b.interval = b.policy.InitialInterval.Duration
+}
+
+// Attempts returns the number of attempts made so far.
+func (b *Backoff) Attempts() int {
+	return b.attempts
+}
+
+// Next returns the delay before the next attempt. It returns false if the
+// limits of the policy do not allow for any more attempts.
+func (b *Backoff) Next() (time.Duration, bool) {
+	if b.policy.MaxAttempts > 0 && b.attempts >= b.policy.MaxAttempts {
+		return 0, false
+	}
+
+	delay := b.interval
+	if b.policy.Jitter > 0 {
+		delta := b.policy.Jitter * float64(delay)
+		delay = time.Duration(float64(delay) - delta + 2*delta*rand.Float64())
+	}
+
+	maxElapsedTime := b.policy.MaxElapsedTime.Duration
+	if maxElapsedTime > 0 && time.Since(b.startTime)+delay > maxElapsedTime {
+		return 0, false
+	}
+
+	b.attempts++
+
+	b.interval = time.Duration(float64(b.interval) * b.policy.Multiplier)
+	if maxInterval := b.policy.MaxInterval.Duration; maxInterval > 0 &&
+		b.interval > maxInterval {
+		b.interval = maxInterval
+	}
+
+	return delay, true
+}
+
+// Wait blocks for the delay before the next attempt. It returns ErrExhausted
+// if the limits of the policy do not allow for any more attempts or the
+// context error if the context is done before the delay elapses.
+func (b *Backoff) Wait(ctx context.Context) error {
+	delay, ok := b.Next()
+	if !ok {
+		return ErrExhausted
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do executes the given function until it succeeds, the limits of the policy
+// are reached or the context is done. In the last two cases, the error
+// returned from the last attempt is returned.
+func Do(ctx context.Context, policy *Policy, fn func() error) error {
+	backoff := NewBackoff(policy)
+
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if waitErr := backoff.Wait(ctx); waitErr != nil {
+			return fmt.Errorf(
+				"failed after [%v] attempts: [%v]; last error: [%v]",
+				backoff.Attempts(),
+				waitErr,
+				err,
+			)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestBackoffExponentialDelays(t *testing.T) {
+	backoff := NewBackoff(&Policy{
+		InitialInterval: Duration{1 * time.Second},
+		MaxInterval:     Duration{5 * time.Second},
+		Multiplier:      2,
+	})
+
+	expectedDelays := []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	}
+
+	for i, expectedDelay := range expectedDelays {
+		delay, ok := backoff.Next()
+		if !ok {
+			t.Fatalf("backoff should allow attempt [%v]", i+2)
+		}
+
+		if delay != expectedDelay {
+			t.Errorf(
+				"unexpected delay [%v]\nexpected: [%v]\nactual:   [%v]",
+				i,
+				expectedDelay,
+				delay,
+			)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	backoff := NewBackoff(&Policy{
+		InitialInterval: Duration{1 * time.Second},
+		Multiplier:      1,
+		Jitter:          0.5,
+	})
+
+	for i := 0; i < 100; i++ {
+		delay, _ := backoff.Next()
+		if delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+			t.Fatalf("delay [%v] out of jitter range", delay)
+		}
+	}
+}
+
+func TestBackoffMaxAttempts(t *testing.T) {
+	backoff := NewBackoff(&Policy{
+		InitialInterval: Duration{1 * time.Millisecond},
+		Multiplier:      1,
+		MaxAttempts:     3,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, ok := backoff.Next(); !ok {
+			t.Fatalf("backoff should allow attempt [%v]", i+2)
+		}
+	}
+
+	if _, ok := backoff.Next(); ok {
+		t.Errorf("backoff should not allow more than 3 attempts")
+	}
+
+	backoff.Reset()
+
+	if _, ok := backoff.Next(); !ok {
+		t.Errorf("backoff should allow attempts after reset")
+	}
+}
+
+func TestBackoffMaxElapsedTime(t *testing.T) {
+	backoff := NewBackoff(&Policy{
+		InitialInterval: Duration{1 * time.Second},
+		Multiplier:      1,
+		MaxElapsedTime:  Duration{500 * time.Millisecond},
+	})
+
+	if _, ok := backoff.Next(); ok {
+		t.Errorf("backoff should not allow delay exceeding max elapsed time")
+	}
+}
+
+func TestBackoffWaitContextDone(t *testing.T) {
+	backoff := NewBackoff(&Policy{
+		InitialInterval: Duration{1 * time.Minute},
+		Multiplier:      1,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := backoff.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf(
+			"unexpected error\nexpected: [%v]\nactual:   [%v]",
+			context.DeadlineExceeded,
+			err,
+		)
+	}
+}
+
+func TestDo(t *testing.T) {
+	policy := &Policy{
+		InitialInterval: Duration{1 * time.Millisecond},
+		Multiplier:      1,
+		MaxAttempts:     3,
+	}
+
+	var tests = map[string]struct {
+		failures         int
+		expectedAttempts int
+		expectError      bool
+	}{
+		"succeeds in the first attempt": {
+			failures:         0,
+			expectedAttempts: 1,
+		},
+		"succeeds in the last attempt": {
+			failures:         2,
+			expectedAttempts: 3,
+		},
+		"fails in all attempts": {
+			failures:         5,
+			expectedAttempts: 3,
+			expectError:      true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			attempts := 0
+			err := Do(context.Background(), policy, func() error {
+				attempts++
+				if attempts <= test.failures {
+					return fmt.Errorf("failure")
+				}
+				return nil
+			})
+
+			if (err != nil) != test.expectError {
+				t.Errorf("unexpected error: [%v]", err)
+			}
+
+			if attempts != test.expectedAttempts {
+				t.Errorf(
+					"unexpected number of attempts\nexpected: [%v]\nactual:   [%v]",
+					test.expectedAttempts,
+					attempts,
+				)
+			}
+		})
+	}
+}
+
+func TestConfigPolicy(t *testing.T) {
+	config := &Config{
+		Default:   PolicyConfig{MaxInterval: &Duration{10 * time.Second}},
+		ChainCall: PolicyConfig{InitialInterval: &Duration{3 * time.Second}},
+	}
+
+	policy := config.Policy(ChainCall)
+
+	if policy.InitialInterval.Duration != 3*time.Second {
+		t.Errorf("operation policy should be used; got [%v]", policy.InitialInterval)
+	}
+	if policy.MaxInterval.Duration != 10*time.Second {
+		t.Errorf("default policy should be used; got [%v]", policy.MaxInterval)
+	}
+	if policy.Multiplier != defaultPolicies[ChainCall].Multiplier {
+		t.Errorf("built-in policy should be used; got [%v]", policy.Multiplier)
+	}
+
+	var nilConfig *Config
+	if nilConfig.Policy(Network).MaxAttempts != defaultPolicies[Network].MaxAttempts {
+		t.Errorf("built-in policy should be used for nil config")
+	}
+}
+
+func TestConfigPolicyExplicitZero(t *testing.T) {
+	var config Config
+	if _, err := toml.Decode(`
+[Default]
+Jitter = 0.0
+
+[Network]
+MaxAttempts = 0
+MaxInterval = "1m30s"
+`, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := config.Policy(Network)
+
+	if policy.Jitter != 0 {
+		t.Errorf("jitter should be disabled; got [%v]", policy.Jitter)
+	}
+	if policy.MaxAttempts != 0 {
+		t.Errorf("attempts should be unlimited; got [%v]", policy.MaxAttempts)
+	}
+	if policy.MaxInterval.Duration != 90*time.Second {
+		t.Errorf("operation policy should be used; got [%v]", policy.MaxInterval)
+	}
+	if policy.InitialInterval != defaultPolicies[Network].InitialInterval {
+		t.Errorf("built-in policy should be used; got [%v]", policy.InitialInterval)
+	}
+}